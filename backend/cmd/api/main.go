@@ -1,19 +1,32 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"wedding-app/internal/auth"
+	"wedding-app/internal/campaign"
+	"wedding-app/internal/event"
 	"wedding-app/internal/guest"
+	"wedding-app/internal/jobs"
 	"wedding-app/internal/message"
+	"wedding-app/internal/notification"
 	"wedding-app/internal/photo"
+	"wedding-app/internal/questionnaire"
 	"wedding-app/internal/rsvp"
+	"wedding-app/pkg/cache"
 	"wedding-app/pkg/database"
 	"wedding-app/pkg/logger"
+	"wedding-app/pkg/notify"
+	"wedding-app/pkg/storage"
 )
 
 func main() {
@@ -26,18 +39,67 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// Initialize email and SMS delivery
+	mailer, err := notify.NewMailerFromEnv()
+	if err != nil {
+		log.Fatal("Failed to configure mailer:", err)
+	}
+	smsSender, err := notify.NewSMSSenderFromEnv()
+	if err != nil {
+		log.Fatal("Failed to configure SMS sender:", err)
+	}
+	templates, err := notify.LoadTemplates()
+	if err != nil {
+		log.Fatal("Failed to load email templates:", err)
+	}
+
+	// Initialize photo storage
+	storageClient, err := storage.NewClientFromEnv()
+	if err != nil {
+		log.Fatal("Failed to configure storage:", err)
+	}
+
+	// Initialize session store
+	sessionCache := cache.NewRedisClient()
+
+	// Initialize background job queue
+	jobQueue := jobs.NewQueue(db, logger, jobs.ConfigFromEnv())
+
 	// Initialize services
-	authService := auth.NewService(db, logger)
-	guestService := guest.NewService(db, logger)
-	rsvpService := rsvp.NewService(db, logger)
-	photoService := photo.NewService(db, logger)
+	notificationService := notification.NewService(db, logger, mailer, smsSender, templates)
+	authService, err := auth.NewService(db, logger, sessionCache, notificationService, jobQueue)
+	if err != nil {
+		log.Fatal("Failed to configure authentication:", err)
+	}
+	guestService := guest.NewService(db, logger, notificationService, jobQueue)
+	rsvpService := rsvp.NewService(db, logger, notificationService, jobQueue)
+	eventService := event.NewService(db, logger)
+	questionService := questionnaire.NewService(db, logger)
+	photoService := photo.NewService(db, logger, storageClient, jobQueue)
+	campaignService := campaign.NewService(db, logger, notificationService)
 
 	// Initialize handlers
 	authHandler := auth.NewHandler(authService)
 	guestHandler := guest.NewHandler(guestService)
 	rsvpHandler := rsvp.NewHandler(rsvpService)
+	eventHandler := event.NewHandler(eventService)
+	notificationHandler := notification.NewHandler(notificationService)
+	questionHandler := questionnaire.NewHandler(questionService)
 	photoHandler := photo.NewHandler(photoService)
 	messageHandler := message.NewHandler(db)
+	campaignHandler := campaign.NewHandler(campaignService)
+	jobHandler := jobs.NewHandler(jobQueue)
+
+	// Start background workers once every job handler is registered
+	jobQueue.Start()
+
+	// Expire abandoned uploads and watch for storage drift
+	photoJanitor := photo.NewJanitor(photoService, time.Hour)
+	photoJanitor.Start()
+
+	// Send reminder campaigns as they fall due
+	campaignScheduler := campaign.NewScheduler(campaignService, time.Minute)
+	campaignScheduler.Start()
 
 	// Setup router
 	router := gin.New()
@@ -49,6 +111,8 @@ func main() {
 	config.AllowOrigins = []string{"http://localhost:3000", "https://yourdomain.com"}
 	config.AllowCredentials = true
 	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	// Video uploads read each part's ETag to complete the upload
+	config.ExposeHeaders = []string{"ETag"}
 	router.Use(cors.New(config))
 
 	// Health check
@@ -61,21 +125,47 @@ func main() {
 	{
 		// Public routes
 		api.POST("/auth/login", authHandler.Login)
-		api.POST("/auth/register", authHandler.Register) // Temporary for creating admin user
+		api.POST("/auth/login/2fa", authHandler.CompleteLogin)
+		api.POST("/auth/refresh", authHandler.Refresh)
+		api.POST("/auth/logout", authHandler.Logout)
+		api.POST("/auth/password-resets", authHandler.RequestPasswordReset)
+		api.GET("/auth/password-resets/:token", authHandler.GetPasswordReset)
+		api.POST("/auth/password-resets/:token/complete", authHandler.ResetPassword)
+		api.GET("/auth/invitations/:token", authHandler.GetInvitation)
+		api.POST("/auth/invitations/:token/accept", authHandler.AcceptInvitation)
 		api.GET("/rsvp/:token", rsvpHandler.GetRSVP)
 		api.POST("/rsvp/:token/submit", rsvpHandler.SubmitRSVP)
+		api.GET("/events", eventHandler.GetEvents)
+		api.GET("/questions", questionHandler.GetQuestions)
 		api.GET("/photos", photoHandler.GetPhotos)
 		api.POST("/photos/upload-url", photoHandler.GetUploadURL)
 		api.POST("/photos/complete", photoHandler.CompleteUpload)
+		api.POST("/photos/archive", photoHandler.DownloadArchive)
+		api.GET("/photos/archives/:token", photoHandler.GetArchive)
+		api.GET("/albums", photoHandler.GetAlbums)
+		api.GET("/albums/:id", photoHandler.GetAlbum)
+
+		// Signed upload and download URLs for filesystem storage
+		if fsClient, ok := storageClient.(*storage.FSClient); ok {
+			fileHandler := storage.NewFileHandler(fsClient)
+			api.GET("/files/*key", fileHandler.Download)
+			api.PUT("/files/*key", fileHandler.Upload)
+		}
 		
 		// Guest registration (public)
 		api.POST("/guests/register", guestHandler.RegisterGuest)
 		
 		// Guest portal (public with token)
 		api.GET("/guest-portal/:token", guestHandler.GetGuestPortal)
+		api.PUT("/guest-portal/:token/sms", guestHandler.SetPortalSMSOptIn)
+		api.GET("/guest-portal/:token/photos", photoHandler.GetGuestPhotos)
+		api.DELETE("/guest-portal/:token/photos/:id", photoHandler.DeleteGuestPhoto)
 		
 		// Guest messaging (public)
 		api.POST("/messages", messageHandler.SendMessage)
+
+		// Mail provider bounce webhook (authenticated by shared secret)
+		api.POST("/webhooks/email-bounce", notificationHandler.ReportBounce)
 		
 		// Debug route (public for testing)
 		api.GET("/debug/routes", func(c *gin.Context) {
@@ -91,37 +181,147 @@ func main() {
 		protected := api.Group("/")
 		protected.Use(authHandler.RequireAuth())
 		{
+			// Apart from the auth routes, each route needs a permission granted
+			// by the caller's role
+			can := authHandler.RequirePermission
+
 			// Auth
-			protected.POST("/auth/logout", authHandler.Logout)
 			protected.GET("/auth/me", authHandler.Me)
+			protected.PUT("/auth/password", authHandler.ChangePassword)
+			protected.GET("/auth/2fa", authHandler.GetTwoFactorStatus)
+			protected.POST("/auth/2fa/setup", authHandler.StartTwoFactorSetup)
+			protected.POST("/auth/2fa/enable", authHandler.EnableTwoFactor)
+			protected.POST("/auth/2fa/disable", authHandler.DisableTwoFactor)
+			protected.POST("/auth/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+			protected.GET("/auth/sessions", authHandler.GetSessions)
+			protected.DELETE("/auth/sessions", authHandler.RevokeOtherSessions)
+			protected.DELETE("/auth/sessions/:id", authHandler.RevokeSession)
+
+			// Admin users and invitations
+			protected.GET("/users", can(auth.PermUsersManage), authHandler.GetUsers)
+			protected.PUT("/users/:id/role", can(auth.PermUsersManage), authHandler.SetUserRole)
+			protected.POST("/users/:id/deactivate", can(auth.PermUsersManage), authHandler.DeactivateUser)
+			protected.POST("/users/:id/activate", can(auth.PermUsersManage), authHandler.ActivateUser)
+			protected.DELETE("/users/:id/sessions", can(auth.PermUsersManage), authHandler.RevokeUserSessions)
+			protected.POST("/users/:id/2fa/reset", can(auth.PermUsersManage), authHandler.ResetUserTwoFactor)
+			protected.GET("/settings/security", can(auth.PermUsersManage), authHandler.GetSecuritySettings)
+			protected.PUT("/settings/security", can(auth.PermUsersManage), authHandler.UpdateSecuritySettings)
+			protected.GET("/sessions", can(auth.PermUsersManage), authHandler.GetAllSessions)
+			protected.DELETE("/sessions/:id", can(auth.PermUsersManage), authHandler.RevokeAnySession)
+			protected.GET("/invitations", can(auth.PermUsersManage), authHandler.GetInvitations)
+			protected.POST("/invitations", can(auth.PermUsersManage), authHandler.InviteUser)
+			protected.DELETE("/invitations/:id", can(auth.PermUsersManage), authHandler.RevokeInvitation)
 
 			// Guests
-			protected.GET("/guests", guestHandler.GetGuests)
-			protected.GET("/guests/pending", guestHandler.GetPendingRegistrations)
-			protected.POST("/guests/:id/approve", guestHandler.ApproveRegistration)
-			protected.POST("/guests/:id/reject", guestHandler.RejectRegistration)
-			protected.DELETE("/guests/all", guestHandler.DeleteAllGuests)
-			protected.POST("/guests/delete-selected", guestHandler.DeleteSelectedGuests)
-			protected.GET("/guests/test", func(c *gin.Context) {
+			protected.GET("/guests", can(auth.PermGuestsRead), guestHandler.GetGuests)
+			protected.GET("/guests/pending", can(auth.PermGuestsRead), guestHandler.GetPendingRegistrations)
+			protected.POST("/guests/import", can(auth.PermGuestsWrite), guestHandler.ImportGuests)
+			protected.POST("/guests/:id/approve", can(auth.PermGuestsWrite), guestHandler.ApproveRegistration)
+			protected.POST("/guests/:id/reject", can(auth.PermGuestsWrite), guestHandler.RejectRegistration)
+			protected.DELETE("/guests/all", can(auth.PermGuestsDelete), guestHandler.DeleteAllGuests)
+			protected.POST("/guests/delete-selected", can(auth.PermGuestsDelete), guestHandler.DeleteSelectedGuests)
+			protected.POST("/guests/bulk", can(auth.PermGuestsWrite), guestHandler.BulkGuests)
+			protected.GET("/guests/export", can(auth.PermGuestsRead), guestHandler.ExportGuests)
+			protected.PUT("/guests/:id/tags", can(auth.PermGuestsWrite), guestHandler.SetGuestTags)
+			protected.PUT("/guests/:id/sms", can(auth.PermGuestsWrite), guestHandler.SetSMSOptIn)
+			protected.GET("/guests/:id/reminders", can(auth.PermCampaignsRead), campaignHandler.GetGuestReminders)
+			protected.GET("/guests/test", can(auth.PermGuestsRead), func(c *gin.Context) {
 				c.JSON(200, gin.H{"message": "Guest routes are working"})
 			})
+
+			// Households
+			protected.GET("/households", can(auth.PermGuestsRead), guestHandler.GetHouseholds)
+			protected.POST("/households", can(auth.PermGuestsWrite), guestHandler.CreateHousehold)
+			protected.GET("/households/:id", can(auth.PermGuestsRead), guestHandler.GetHousehold)
+			protected.PUT("/households/:id", can(auth.PermGuestsWrite), guestHandler.UpdateHousehold)
+			protected.DELETE("/households/:id", can(auth.PermGuestsWrite), guestHandler.DeleteHousehold)
+			protected.POST("/households/:id/members", can(auth.PermGuestsWrite), guestHandler.AddHouseholdMembers)
+			protected.DELETE("/households/:id/members/:guestId", can(auth.PermGuestsWrite), guestHandler.RemoveHouseholdMember)
+			protected.POST("/households/:id/regenerate-token", can(auth.PermGuestsWrite), guestHandler.RegenerateHouseholdToken)
+
+			// Guest tags and saved segments
+			protected.GET("/tags", can(auth.PermGuestsRead), guestHandler.GetTags)
+			protected.POST("/tags", can(auth.PermGuestsWrite), guestHandler.CreateTag)
+			protected.PUT("/tags/:id", can(auth.PermGuestsWrite), guestHandler.UpdateTag)
+			protected.DELETE("/tags/:id", can(auth.PermGuestsWrite), guestHandler.DeleteTag)
+			protected.GET("/segments", can(auth.PermGuestsRead), guestHandler.GetSegments)
+			protected.POST("/segments", can(auth.PermGuestsWrite), guestHandler.CreateSegment)
+			protected.PUT("/segments/:id", can(auth.PermGuestsWrite), guestHandler.UpdateSegment)
+			protected.DELETE("/segments/:id", can(auth.PermGuestsWrite), guestHandler.DeleteSegment)
 			
 			// Messages
-			protected.GET("/messages", messageHandler.GetMessages)
-			protected.PATCH("/messages/:id/read", messageHandler.MarkMessageAsRead)
+			protected.GET("/messages", can(auth.PermMessagesRead), messageHandler.GetMessages)
+			protected.PATCH("/messages/:id/read", can(auth.PermMessagesWrite), messageHandler.MarkMessageAsRead)
 
 			// RSVPs
-			protected.GET("/rsvps", rsvpHandler.GetRSVPs)
-			protected.GET("/rsvps/export", rsvpHandler.ExportRSVPs)
+			protected.GET("/rsvps", can(auth.PermRSVPsRead), rsvpHandler.GetRSVPs)
+			protected.GET("/rsvps/export", can(auth.PermRSVPsRead), rsvpHandler.ExportRSVPs)
+
+			// Events
+			protected.GET("/events/stats", can(auth.PermRSVPsRead), eventHandler.GetEventStats)
+			protected.POST("/events", can(auth.PermEventsWrite), eventHandler.CreateEvent)
+			protected.PUT("/events/:id", can(auth.PermEventsWrite), eventHandler.UpdateEvent)
+			protected.DELETE("/events/:id", can(auth.PermEventsWrite), eventHandler.DeleteEvent)
+
+			// Notification delivery log
+			protected.GET("/deliveries", can(auth.PermCampaignsRead), notificationHandler.GetDeliveries)
+
+			// RSVP reminder campaigns
+			protected.GET("/campaigns", can(auth.PermCampaignsRead), campaignHandler.GetAllCampaigns)
+			protected.POST("/campaigns", can(auth.PermCampaignsWrite), campaignHandler.CreateCampaign)
+			protected.POST("/campaigns/preview", can(auth.PermCampaignsWrite), campaignHandler.PreviewDraft)
+			protected.GET("/campaigns/:id", can(auth.PermCampaignsRead), campaignHandler.GetCampaign)
+			protected.PUT("/campaigns/:id", can(auth.PermCampaignsWrite), campaignHandler.UpdateCampaign)
+			protected.DELETE("/campaigns/:id", can(auth.PermCampaignsWrite), campaignHandler.DeleteCampaign)
+			protected.POST("/campaigns/:id/cancel", can(auth.PermCampaignsWrite), campaignHandler.CancelCampaign)
+			protected.GET("/campaigns/:id/preview", can(auth.PermCampaignsRead), campaignHandler.PreviewCampaign)
+			protected.GET("/campaigns/:id/sends", can(auth.PermCampaignsRead), campaignHandler.GetCampaignSends)
+
+			// Background jobs and dead letter queue
+			protected.GET("/jobs", can(auth.PermJobsRead), jobHandler.GetJobs)
+			protected.GET("/jobs/stats", can(auth.PermJobsRead), jobHandler.GetJobStats)
+			protected.POST("/jobs/dead/retry", can(auth.PermJobsManage), jobHandler.RetryDeadJobs)
+			protected.GET("/jobs/:id", can(auth.PermJobsRead), jobHandler.GetJob)
+			protected.POST("/jobs/:id/retry", can(auth.PermJobsManage), jobHandler.RetryJob)
+			protected.DELETE("/jobs/:id", can(auth.PermJobsManage), jobHandler.DeleteJob)
+
+			// Custom questionnaire
+			protected.POST("/questions", can(auth.PermQuestionsWrite), questionHandler.CreateQuestion)
+			protected.PUT("/questions/:id", can(auth.PermQuestionsWrite), questionHandler.UpdateQuestion)
+			protected.DELETE("/questions/:id", can(auth.PermQuestionsWrite), questionHandler.DeleteQuestion)
 
 			// Admin photo management
 			admin := protected.Group("/admin")
 			{
-				admin.GET("/photos", photoHandler.GetAdminPhotos)
-				admin.GET("/photos/pending", photoHandler.GetPendingPhotos)
-				admin.PATCH("/photos/:id/approve", photoHandler.ApprovePhoto)
-				admin.PATCH("/photos/:id/reject", photoHandler.RejectPhoto)
-				admin.DELETE("/photos/:id", photoHandler.DeletePhoto)
+				admin.GET("/photos", can(auth.PermPhotosRead), photoHandler.GetAdminPhotos)
+				admin.GET("/photos/pending", can(auth.PermPhotosRead), photoHandler.GetPendingPhotos)
+				admin.GET("/photos/reconcile", can(auth.PermStorageManage), photoHandler.GetStorageDrift)
+				admin.POST("/photos/reconcile", can(auth.PermStorageManage), photoHandler.RepairStorageDrift)
+				admin.PATCH("/photos/:id/approve", can(auth.PermPhotosModerate), photoHandler.ApprovePhoto)
+				admin.PATCH("/photos/:id/reject", can(auth.PermPhotosModerate), photoHandler.RejectPhoto)
+				admin.DELETE("/photos/:id", can(auth.PermPhotosModerate), photoHandler.DeletePhoto)
+				admin.POST("/photos/bulk-approve", can(auth.PermPhotosModerate), photoHandler.BulkApprovePhotos)
+				admin.POST("/photos/bulk-reject", can(auth.PermPhotosModerate), photoHandler.BulkRejectPhotos)
+				admin.POST("/photos/bulk-delete", can(auth.PermPhotosModerate), photoHandler.BulkDeletePhotos)
+				admin.POST("/photos/download-zip", can(auth.PermPhotosRead), photoHandler.DownloadAdminArchive)
+				admin.POST("/photos/duplicates/reject", can(auth.PermPhotosModerate), photoHandler.RejectDuplicates)
+				admin.POST("/photos/duplicates/scan", can(auth.PermPhotosModerate), photoHandler.ScanForDuplicates)
+				admin.POST("/photos/:id/merge", can(auth.PermPhotosModerate), photoHandler.MergeDuplicate)
+				admin.DELETE("/photos/:id/duplicate", can(auth.PermPhotosModerate), photoHandler.DismissDuplicate)
+				admin.GET("/photos/moderation", can(auth.PermPhotosRead), photoHandler.GetModerationHistory)
+				admin.POST("/photos/moderation/:batchId/undo", can(auth.PermPhotosModerate), photoHandler.UndoModeration)
+				admin.GET("/photos/:id/moderation", can(auth.PermPhotosRead), photoHandler.GetPhotoModerationHistory)
+
+				// Albums
+				admin.GET("/albums", can(auth.PermPhotosRead), photoHandler.GetAdminAlbums)
+				admin.POST("/albums", can(auth.PermAlbumsWrite), photoHandler.CreateAlbum)
+				admin.GET("/albums/:id", can(auth.PermPhotosRead), photoHandler.GetAdminAlbum)
+				admin.PUT("/albums/:id", can(auth.PermAlbumsWrite), photoHandler.UpdateAlbum)
+				admin.DELETE("/albums/:id", can(auth.PermAlbumsWrite), photoHandler.DeleteAlbum)
+				admin.POST("/albums/:id/photos", can(auth.PermAlbumsWrite), photoHandler.AddAlbumPhotos)
+				admin.POST("/albums/:id/photos/remove", can(auth.PermAlbumsWrite), photoHandler.RemoveAlbumPhotos)
+				admin.PUT("/albums/:id/photos/order", can(auth.PermAlbumsWrite), photoHandler.ReorderAlbumPhotos)
+				admin.PUT("/albums/:id/cover", can(auth.PermAlbumsWrite), photoHandler.SetAlbumCover)
 			}
 		}
 	}
@@ -131,8 +331,30 @@ func main() {
 		port = "8080"
 	}
 
-	logger.Info("Server starting on port " + port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Server failed to start:", err)
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
+
+	go func() {
+		logger.Info("Server starting on port " + port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server failed to start:", err)
+		}
+	}()
+
+	// Wait for a shutdown signal, then let in-flight requests and jobs finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	logger.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Server shutdown failed", "error", err)
 	}
+	campaignScheduler.Stop()
+	photoJanitor.Stop()
+	jobQueue.Stop()
 }
\ No newline at end of file
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"os"
 	"wedding-app/internal/auth"
 	"wedding-app/pkg/database"
 	"wedding-app/pkg/logger"
@@ -15,7 +18,12 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	authService := auth.NewService(db, logger)
+	// Only creates a user, so no session store, notifier or job queue is
+	// needed
+	authService, err := auth.NewService(db, logger, nil, nil, nil)
+	if err != nil {
+		log.Fatal("Failed to configure authentication:", err)
+	}
 	
 	// Check if admin user already exists
 	var existingUser auth.User
@@ -25,8 +33,18 @@ func main() {
 		return
 	}
 	
-	// Create default admin user
-	user, err := authService.CreateUser("admin@wedding.com", "password123")
+	// Create default admin user with ADMIN_PASSWORD, or a random password
+	// that meets the password policy
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		bytes := make([]byte, 12)
+		if _, err := rand.Read(bytes); err != nil {
+			log.Fatal("Failed to generate password:", err)
+		}
+		password = hex.EncodeToString(bytes)
+	}
+
+	user, err := authService.CreateUser("admin@wedding.com", password, auth.RoleOwner)
 	if err != nil {
 		fmt.Printf("Error creating user: %v\n", err)
 		return
@@ -35,5 +53,5 @@ func main() {
 	fmt.Printf("Created admin user: %s\n", user.Email)
 	fmt.Println("Login credentials:")
 	fmt.Println("  Email: admin@wedding.com")
-	fmt.Println("  Password: password123")
+	fmt.Printf("  Password: %s\n", password)
 }
\ No newline at end of file
@@ -1,10 +1,13 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type Handler struct {
@@ -22,14 +25,12 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 }
 
-type RegisterRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
-}
-
+// LoginResponse is either a signed-in session or, for users with two-factor
+// authentication, the challenge to answer with a code.
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  *User  `json:"user"`
+	*Tokens
+	User      *User      `json:"user,omitempty"`
+	TwoFactor *Challenge `json:"twoFactor,omitempty"`
 }
 
 func (h *Handler) Login(c *gin.Context) {
@@ -39,53 +40,162 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	token, user, err := h.service.Login(req.Email, req.Password)
+	tokens, user, challenge, err := h.service.Login(req.Email, req.Password, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		if errors.Is(err, ErrDeactivated) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This account has been deactivated"})
+			return
+		}
+		if errors.Is(err, ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusOK, LoginResponse{TwoFactor: challenge})
 		return
 	}
 
-	// Set HTTP-only cookie
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("authToken", token, 3600*24*7, "/", "", false, true) // 7 days
-
+	setAuthCookies(c, tokens)
 	c.JSON(http.StatusOK, LoginResponse{
-		Token: token,
-		User:  user,
+		Tokens: tokens,
+		User:   user,
 	})
 }
 
-func (h *Handler) Logout(c *gin.Context) {
-	c.SetCookie("authToken", "", -1, "/", "", false, true)
-	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
 }
 
-func (h *Handler) Register(c *gin.Context) {
-	var req RegisterRequest
+// CompleteLogin is the second step of a login with two-factor
+// authentication: a code from the user's app or a recovery code.
+func (h *Handler) CompleteLogin(c *gin.Context) {
+	var req TwoFactorLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	user, err := h.service.CreateUser(req.Email, req.Password)
+	tokens, user, err := h.service.CompleteLogin(req.ChallengeToken, req.Code, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if errors.Is(err, ErrDeactivated) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This account has been deactivated"})
+			return
+		}
+		respondUserError(c, err)
+		return
+	}
+
+	setAuthCookies(c, tokens)
+	c.JSON(http.StatusOK, LoginResponse{
+		Tokens: tokens,
+		User:   user,
+	})
+}
+
+// Cookie names. The refresh token is only sent to the auth routes.
+const (
+	accessCookie  = "authToken"
+	refreshCookie = "refreshToken"
+	refreshPath   = "/api/auth"
+)
+
+func setAuthCookies(c *gin.Context, tokens *Tokens) {
+	// Set HTTP-only cookies
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(accessCookie, tokens.AccessToken, int(accessTokenTTL.Seconds()), "/", "", false, true)
+	c.SetCookie(refreshCookie, tokens.RefreshToken, int(sessionTTL.Seconds()), refreshPath, "", false, true)
+}
+
+func clearAuthCookies(c *gin.Context) {
+	c.SetCookie(accessCookie, "", -1, "/", "", false, true)
+	c.SetCookie(refreshCookie, "", -1, refreshPath, "", false, true)
+}
+
+func clientInfo(c *gin.Context) ClientInfo {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	return ClientInfo{IP: c.ClientIP(), UserAgent: userAgent}
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// refreshToken reads the refresh token from the body, for clients that
+// don't use cookies, or the refresh cookie.
+func refreshToken(c *gin.Context) string {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err == nil && req.RefreshToken != "" {
+		return req.RefreshToken
+	}
+	token, _ := c.Cookie(refreshCookie)
+	return token
+}
+
+// Refresh swaps a refresh token for a new pair of tokens.
+func (h *Handler) Refresh(c *gin.Context) {
+	token := refreshToken(c)
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token required"})
+		return
+	}
+
+	tokens, user, err := h.service.Refresh(token, clientInfo(c))
+	if err != nil {
+		clearAuthCookies(c)
+		if errors.Is(err, ErrInvalidSession) || errors.Is(err, ErrDeactivated) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "User created successfully",
-		"user": user,
+	user.Permissions = PermissionsFor(user.Role)
+	setAuthCookies(c, tokens)
+	c.JSON(http.StatusOK, LoginResponse{
+		Tokens: tokens,
+		User:   user,
 	})
 }
 
+// Logout ends the session of the refresh token or, failing that, the
+// access token presented. It works without a valid access token so an
+// expired session can still sign out cleanly.
+func (h *Handler) Logout(c *gin.Context) {
+	userID, sessionID, err := h.service.SessionFromRefreshToken(refreshToken(c))
+	if err != nil {
+		if user, sid, err := h.service.ValidateToken(extractToken(c)); err == nil {
+			userID, sessionID = user.ID, sid
+		}
+	}
+	if sessionID != "" {
+		if err := h.service.RevokeSession(userID, sessionID); err != nil && !errors.Is(err, ErrInvalidSession) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end session"})
+			return
+		}
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// Me returns the signed-in user with the permissions their role grants.
 func (h *Handler) Me(c *gin.Context) {
-	user, exists := c.Get("user")
-	if !exists {
+	value, _ := c.Get("user")
+	user, ok := value.(*User)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 		return
 	}
 
+	user.Permissions = PermissionsFor(user.Role)
 	c.JSON(http.StatusOK, user)
 }
 
@@ -98,7 +208,7 @@ func (h *Handler) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		user, err := h.service.ValidateToken(token)
+		user, sessionID, err := h.service.ValidateToken(token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
@@ -106,10 +216,477 @@ func (h *Handler) RequireAuth() gin.HandlerFunc {
 		}
 
 		c.Set("user", user)
+		c.Set("userID", user.ID)
+		c.Set("sessionID", sessionID)
 		c.Next()
 	}
 }
 
+// User management
+
+func (h *Handler) GetUsers(c *gin.Context) {
+	users, err := h.service.ListUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+type RoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+func (h *Handler) SetUserRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.service.SetRole(c.GetUint("userID"), uint(id), req.Role)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeactivateUser signs a user out everywhere and stops them signing in.
+func (h *Handler) DeactivateUser(c *gin.Context) {
+	h.setUserStatus(c, StatusDeactivated)
+}
+
+func (h *Handler) ActivateUser(c *gin.Context) {
+	h.setUserStatus(c, StatusActive)
+}
+
+func (h *Handler) setUserStatus(c *gin.Context, status string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.service.SetStatus(c.GetUint("userID"), uint(id), status)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+type InviteRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+func (h *Handler) InviteUser(c *gin.Context) {
+	var req InviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitation, err := h.service.Invite(req.Email, req.Role, c.GetUint("userID"))
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+func (h *Handler) GetInvitations(c *gin.Context) {
+	invitations, err := h.service.ListInvitations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+func (h *Handler) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	if err := h.service.RevokeInvitation(uint(id)); err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// GetInvitation shows who an invitation link is for, before it's accepted.
+func (h *Handler) GetInvitation(c *gin.Context) {
+	invitation, err := h.service.GetInvitation(c.Param("token"))
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"email":     invitation.Email,
+		"role":      invitation.Role,
+		"expiresAt": invitation.ExpiresAt,
+	})
+}
+
+type AcceptInvitationRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// AcceptInvitation creates the invited account and signs it in.
+func (h *Handler) AcceptInvitation(c *gin.Context) {
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, user, err := h.service.AcceptInvitation(c.Param("token"), req.Password, clientInfo(c))
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	setAuthCookies(c, tokens)
+	c.JSON(http.StatusCreated, LoginResponse{
+		Tokens: tokens,
+		User:   user,
+	})
+}
+
+// Passwords
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+}
+
+// ChangePassword sets a new password for the signed-in user and signs out
+// their other sessions.
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revoked, err := h.service.ChangePassword(c.GetUint("userID"), req.CurrentPassword, req.NewPassword, c.GetString("sessionID"))
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed", "revoked": revoked})
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// RequestPasswordReset emails a reset link. The response is the same
+// whether or not the address has an account.
+func (h *Handler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request password reset"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If that email has an account, a reset link is on its way"})
+}
+
+// GetPasswordReset checks a reset link before the new password is chosen.
+func (h *Handler) GetPasswordReset(c *gin.Context) {
+	reset, email, err := h.service.GetPasswordReset(c.Param("token"))
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"email": email, "expiresAt": reset.ExpiresAt})
+}
+
+type ResetPasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// ResetPassword sets a new password from a reset link.
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.ResetPassword(c.Param("token"), req.Password); err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset. Sign in with your new password."})
+}
+
+// Two-factor authentication
+
+// GetTwoFactorStatus reports whether the signed-in user has two-factor
+// authentication.
+func (h *Handler) GetTwoFactorStatus(c *gin.Context) {
+	status, err := h.service.GetTwoFactorStatus(c.GetUint("userID"))
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// StartTwoFactorSetup returns a new secret and its QR code URI.
+func (h *Handler) StartTwoFactorSetup(c *gin.Context) {
+	setup, err := h.service.StartTwoFactorSetup(c.GetUint("userID"))
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// EnableTwoFactor confirms the setup with a code from the app and returns
+// the recovery codes.
+func (h *Handler) EnableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.service.EnableTwoFactor(c.GetUint("userID"), req.Code, c.GetString("sessionID"))
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "recoveryCodes": codes})
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.DisableTwoFactor(c.GetUint("userID"), req.Password, req.Code); err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the signed-in user's recovery codes.
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(c.GetUint("userID"), req.Code)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+// ResetUserTwoFactor turns off another user's two-factor authentication.
+func (h *Handler) ResetUserTwoFactor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.service.ResetTwoFactor(c.GetUint("userID"), uint(id))
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *Handler) GetSecuritySettings(c *gin.Context) {
+	settings, err := h.service.GetSettings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+type SecuritySettingsRequest struct {
+	RequireTwoFactor *bool `json:"requireTwoFactor" binding:"required"`
+}
+
+// UpdateSecuritySettings changes whether every admin must use two-factor
+// authentication.
+func (h *Handler) UpdateSecuritySettings(c *gin.Context) {
+	var req SecuritySettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.service.SetRequireTwoFactor(c.GetUint("userID"), *req.RequireTwoFactor)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// Sessions
+
+// GetSessions lists the signed-in user's own sessions.
+func (h *Handler) GetSessions(c *gin.Context) {
+	sessions, err := h.service.ListSessions(c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	current := c.GetString("sessionID")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession ends one of the signed-in user's sessions.
+func (h *Handler) RevokeSession(c *gin.Context) {
+	if err := h.service.RevokeSession(c.GetUint("userID"), c.Param("id")); err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session ended"})
+}
+
+// RevokeOtherSessions signs the user out everywhere but here.
+func (h *Handler) RevokeOtherSessions(c *gin.Context) {
+	revoked, err := h.service.RevokeAllSessions(c.GetUint("userID"), c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Other sessions ended", "revoked": revoked})
+}
+
+// GetAllSessions is the admin view of every user's active sessions.
+func (h *Handler) GetAllSessions(c *gin.Context) {
+	sessions, err := h.service.ListAllSessions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	current := c.GetString("sessionID")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeAnySession ends any user's session.
+func (h *Handler) RevokeAnySession(c *gin.Context) {
+	userID, err := h.service.SessionOwner(c.Param("id"))
+	if err == nil {
+		err = h.service.RevokeSession(userID, c.Param("id"))
+	}
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session ended"})
+}
+
+// RevokeUserSessions signs a user out everywhere.
+func (h *Handler) RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	revoked, err := h.service.RevokeAllSessions(uint(id), "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sessions ended", "revoked": revoked})
+}
+
+func respondUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case errors.Is(err, ErrInvitationInvalid), errors.Is(err, ErrInvalidSession), errors.Is(err, ErrResetInvalid),
+		errors.Is(err, ErrChallengeInvalid):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUserExists), errors.Is(err, ErrTwoFactorEnabled), errors.Is(err, ErrTwoFactorNotEnabled),
+		errors.Is(err, ErrTwoFactorRequired):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTooManyAttempts):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrOwnAccount), errors.Is(err, ErrUnknownRole),
+		errors.Is(err, ErrWeakPassword), errors.Is(err, ErrWrongPassword),
+		errors.Is(err, ErrInvalidCode), errors.Is(err, ErrTwoFactorSetupExpired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 func extractToken(c *gin.Context) string {
 	// Try Authorization header first
 	bearerToken := c.GetHeader("Authorization")
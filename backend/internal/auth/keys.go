@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"wedding-app/pkg/logger"
+)
+
+// minKeyLength is the shortest signing secret accepted, in bytes.
+const minKeyLength = 32
+
+// keyring holds the keys access tokens are signed with. New tokens are
+// signed with the current key and carry its ID in their "kid" header; any
+// configured key can verify. To rotate, add a new key, make it current,
+// and remove the old one once the tokens it signed have expired.
+type keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// loadKeyring reads AUTH_SIGNING_KEYS, a comma-separated list of id:secret
+// pairs, and AUTH_SIGNING_KEY_ID, the ID of the key to sign with (default:
+// the first listed). Without any keys a random one is used, so everyone is
+// signed out when the server restarts.
+func loadKeyring(log logger.Logger) (*keyring, error) {
+	ring := &keyring{keys: map[string][]byte{}}
+
+	for _, pair := range strings.Split(os.Getenv("AUTH_SIGNING_KEYS"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("AUTH_SIGNING_KEYS entries must be id:secret")
+		}
+		if len(secret) < minKeyLength {
+			return nil, fmt.Errorf("signing key %q is shorter than %d bytes", id, minKeyLength)
+		}
+		if _, dup := ring.keys[id]; dup {
+			return nil, fmt.Errorf("signing key %q is listed twice", id)
+		}
+		ring.keys[id] = []byte(secret)
+		if ring.current == "" {
+			ring.current = id
+		}
+	}
+
+	if id := os.Getenv("AUTH_SIGNING_KEY_ID"); id != "" {
+		if _, ok := ring.keys[id]; !ok {
+			return nil, fmt.Errorf("AUTH_SIGNING_KEY_ID %q is not in AUTH_SIGNING_KEYS", id)
+		}
+		ring.current = id
+	}
+
+	if len(ring.keys) == 0 {
+		secret := make([]byte, minKeyLength)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		ring.current = "ephemeral-" + hex.EncodeToString(secret[:4])
+		ring.keys[ring.current] = secret
+		log.Warn("AUTH_SIGNING_KEYS is not set; using a random signing key, sessions won't survive a restart")
+	}
+
+	return ring, nil
+}
+
+// sign returns claims as a JWT signed with the current key.
+func (k *keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = k.current
+	return token.SignedString(k.keys[k.current])
+}
+
+// verify is a jwt.Keyfunc that picks the key named by the token's "kid".
+func (k *keyring) verify(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, errors.New("invalid signing method")
+	}
+	id, _ := token.Header["kid"].(string)
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", id)
+	}
+	return key, nil
+}
@@ -10,10 +10,70 @@ type User struct {
 	ID           uint           `json:"id" gorm:"primarykey"`
 	Email        string         `json:"email" gorm:"unique;not null"`
 	PasswordHash string         `json:"-" gorm:"not null"`
-	Role         string         `json:"role" gorm:"default:'admin'"`
-	Status       string         `json:"status" gorm:"default:'active'"`
+	Role         string         `json:"role" gorm:"default:'viewer'"` // owner, planner, photo-moderator, viewer
+	Status       string         `json:"status" gorm:"default:'active'"` // active, deactivated
 	CreatedAt    time.Time      `json:"createdAt"`
 	UpdatedAt    time.Time      `json:"updatedAt"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
-	LastLogin    time.Time      `json:"lastLogin"`
-}
\ No newline at end of file
+	LastLogin    *time.Time     `json:"lastLogin"`
+
+	// Two-factor authentication
+	TwoFactorEnabled bool   `json:"twoFactorEnabled" gorm:"default:false"`
+	TOTPSecret       string `json:"-"`
+	TOTPLastStep     int64  `json:"-" gorm:"default:0"` // time step of the last code accepted, so none is used twice
+
+	// What the role allows, filled in for the signed-in user
+	Permissions []Permission `json:"permissions,omitempty" gorm:"-"`
+	// Set while two-factor authentication is required and the user hasn't
+	// set it up; until they do, only their own account routes work
+	TwoFactorSetupRequired bool `json:"twoFactorSetupRequired,omitempty" gorm:"-"`
+}
+
+// Invitation lets someone create an admin account with a given role. The
+// emailed link carries a random token; only its hash is stored, and the
+// link works once.
+type Invitation struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	Email      string     `json:"email" gorm:"not null;index"`
+	Role       string     `json:"role" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	InvitedBy  *uint      `json:"invitedBy"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	AcceptedAt *time.Time `json:"acceptedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// PasswordReset lets a user who forgot their password choose a new one.
+// Like invitations, only the hash of the emailed token is stored and the
+// link works once.
+type PasswordReset struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"userId" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// RecoveryCode is a single-use code that stands in for an authenticator
+// app code. Like passwords, they are stored as bcrypt hashes.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"userId" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Settings are the admin-wide security settings, kept in a single row.
+type Settings struct {
+	ID               uint      `json:"-" gorm:"primarykey"`
+	RequireTwoFactor bool      `json:"requireTwoFactor" gorm:"default:false"`
+	UpdatedBy        *uint     `json:"updatedBy"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+func (Settings) TableName() string {
+	return "auth_settings"
+}
@@ -0,0 +1,307 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"wedding-app/internal/jobs"
+	"wedding-app/pkg/notify"
+)
+
+const (
+	minPasswordLength = 10
+	// maxPasswordLength is in bytes; bcrypt ignores anything past it.
+	maxPasswordLength = 72
+	// passwordResetExpiry is how long a reset link can be used for.
+	passwordResetExpiry = time.Hour
+	// passwordResetThrottle is how often a user can be sent a reset link.
+	passwordResetThrottle = time.Minute
+)
+
+// JobSendPasswordReset is the background job that emails a reset link.
+const JobSendPasswordReset = "auth.password-reset"
+
+type PasswordResetJob struct {
+	ResetID uint `json:"resetId"`
+}
+
+var (
+	ErrWeakPassword  = errors.New("password is too weak")
+	ErrWrongPassword = errors.New("current password is incorrect")
+	// ErrResetInvalid covers unknown, expired and already used reset links
+	// alike.
+	ErrResetInvalid = errors.New("reset link is invalid or has expired")
+)
+
+// commonPasswords are refused outright. Only ones long enough to pass the
+// length check are worth listing.
+var commonPasswords = map[string]bool{
+	"1234567890":    true,
+	"0987654321":    true,
+	"1q2w3e4r5t":    true,
+	"abcdefghij":    true,
+	"administrator": true,
+	"changeme123":   true,
+	"iloveyou123":   true,
+	"letmein123":    true,
+	"passw0rd123":   true,
+	"password1!":    true,
+	"password12":    true,
+	"password123":   true,
+	"password1234":  true,
+	"qwerty12345":   true,
+	"qwerty123456":  true,
+	"qwertyuiop":    true,
+	"welcome123":    true,
+	"weddingday":    true,
+	"ourwedding":    true,
+	"justmarried":   true,
+}
+
+// ValidatePassword checks password against the policy: at least
+// minPasswordLength characters, at most maxPasswordLength bytes, not a
+// well-known password, not mostly one repeated character and not built
+// around the user's email address. Length matters more than character
+// classes, so none are required.
+func ValidatePassword(password, email string) error {
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return fmt.Errorf("%w: use at least %d characters", ErrWeakPassword, minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("%w: use at most %d characters", ErrWeakPassword, maxPasswordLength)
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return fmt.Errorf("%w: this password is too common", ErrWeakPassword)
+	}
+
+	distinct := map[rune]bool{}
+	for _, r := range lower {
+		distinct[r] = true
+	}
+	if len(distinct) < 4 {
+		return fmt.Errorf("%w: use more than a few different characters", ErrWeakPassword)
+	}
+
+	name, _, _ := strings.Cut(strings.ToLower(email), "@")
+	if len(name) >= 3 && strings.Contains(lower, name) {
+		return fmt.Errorf("%w: don't base it on your email address", ErrWeakPassword)
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// ChangePassword sets a new password for a signed-in user who knows their
+// current one. Any reset links they were sent stop working, and every
+// other session of theirs is ended; keepSession, the one making the
+// change, stays signed in. It returns how many sessions were ended.
+func (s *Service) ChangePassword(userID uint, current, password, keepSession string) (int, error) {
+	var user User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return 0, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(current)) != nil {
+		return 0, ErrWrongPassword
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil {
+		return 0, fmt.Errorf("%w: choose a different password from your current one", ErrWeakPassword)
+	}
+	if err := ValidatePassword(password, user.Email); err != nil {
+		return 0, err
+	}
+
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return 0, err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password_hash", hashed).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&PasswordReset{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Info("Password changed", "id", user.ID)
+
+	return s.RevokeAllSessions(user.ID, keepSession)
+}
+
+// RequestPasswordReset emails a single-use reset link to the active user
+// with email, replacing any earlier link. Unknown addresses get no email
+// but no error either, so the endpoint can't be used to find accounts.
+func (s *Service) RequestPasswordReset(email string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	var user User
+	err := s.db.Where("LOWER(email) = ? AND status = ?", email, StatusActive).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.Info("Password reset requested for unknown email", "email", email)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var recent int64
+	err = s.db.Model(&PasswordReset{}).
+		Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-passwordResetThrottle)).
+		Count(&recent).Error
+	if err != nil {
+		return err
+	}
+	if recent > 0 {
+		s.logger.Info("Password reset throttled", "id", user.ID)
+		return nil
+	}
+
+	// The link's token is only chosen when the email goes out, so it never
+	// sits in the job queue; until then the reset holds an unusable one
+	placeholder, err := newToken()
+	if err != nil {
+		return err
+	}
+	reset := PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashToken(placeholder),
+		ExpiresAt: time.Now().Add(passwordResetExpiry),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&PasswordReset{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&reset).Error; err != nil {
+			return err
+		}
+		// Sent in the background so known addresses don't answer slower
+		// than unknown ones
+		_, err := s.queue.EnqueueTx(tx, JobSendPasswordReset, PasswordResetJob{ResetID: reset.ID})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Password reset requested", "id", user.ID)
+	return nil
+}
+
+// sendPasswordReset runs as a background job to email a reset link. Each
+// attempt issues a fresh token, so only the link in the last email sent
+// works. Resets that were replaced or used in the meantime are skipped.
+func (s *Service) sendPasswordReset(ctx context.Context, job PasswordResetJob) error {
+	var reset PasswordReset
+	err := s.db.Where("id = ? AND used_at IS NULL AND expires_at > ?", job.ResetID, time.Now()).First(&reset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var user User
+	err = s.db.Where("status = ?", StatusActive).First(&user, reset.UserID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.notifier == nil {
+		return nil
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	result := s.db.Model(&reset).Where("used_at IS NULL").Update("token_hash", hashToken(token))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	err = s.notifier.SendUserEmail(user.Email, notify.TemplatePasswordReset, notify.Data{
+		ResetURL: fmt.Sprintf("%s/admin/reset-password/%s", s.baseURL, token),
+	})
+	if errors.Is(err, notify.ErrRejected) {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// GetPasswordReset looks up a usable reset by the token from its link and
+// returns the email of the account it resets.
+func (s *Service) GetPasswordReset(token string) (*PasswordReset, string, error) {
+	var reset PasswordReset
+	err := s.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(token), time.Now()).
+		First(&reset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrResetInvalid
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	var user User
+	err = s.db.Where("status = ?", StatusActive).First(&user, reset.UserID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrResetInvalid
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &reset, user.Email, nil
+}
+
+// ResetPassword sets a new password using the token from a reset link and
+// ends every session of the user, who then signs in with it.
+func (s *Service) ResetPassword(token, password string) error {
+	reset, email, err := s.GetPasswordReset(token)
+	if err != nil {
+		return err
+	}
+	if err := ValidatePassword(password, email); err != nil {
+		return err
+	}
+
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Only the first request to use the link wins
+		result := tx.Model(reset).Where("used_at IS NULL").Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrResetInvalid
+		}
+		return tx.Model(&User{}).Where("id = ?", reset.UserID).Update("password_hash", hashed).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Password reset", "id", reset.UserID)
+
+	if _, err := s.RevokeAllSessions(reset.UserID, ""); err != nil {
+		return fmt.Errorf("password was reset but sessions weren't ended: %w", err)
+	}
+	return nil
+}
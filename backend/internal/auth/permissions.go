@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Roles an admin user can have.
+const (
+	RoleOwner          = "owner"
+	RolePlanner        = "planner"
+	RolePhotoModerator = "photo-moderator"
+	RoleViewer         = "viewer"
+)
+
+// Permission is one thing an admin user may do. Each protected route
+// requires one.
+type Permission string
+
+const (
+	PermGuestsRead     Permission = "guests:read"
+	PermGuestsWrite    Permission = "guests:write"
+	PermGuestsDelete   Permission = "guests:delete" // bulk deletes, including every guest
+	PermMessagesRead   Permission = "messages:read"
+	PermMessagesWrite  Permission = "messages:write"
+	PermRSVPsRead      Permission = "rsvps:read"
+	PermEventsWrite    Permission = "events:write"
+	PermQuestionsWrite Permission = "questions:write"
+	PermCampaignsRead  Permission = "campaigns:read"
+	PermCampaignsWrite Permission = "campaigns:write"
+	PermJobsRead       Permission = "jobs:read"
+	PermJobsManage     Permission = "jobs:manage"
+	PermPhotosRead     Permission = "photos:read"
+	PermPhotosModerate Permission = "photos:moderate"
+	PermAlbumsWrite    Permission = "albums:write"
+	PermStorageManage  Permission = "storage:manage"
+	PermUsersManage    Permission = "users:manage"
+)
+
+// rolePermissions is the permission matrix. Owners can do everything;
+// planners run the guest list and communications but can't mass-delete
+// guests or touch storage and users; photo moderators only look after
+// photos and albums; viewers can look but not change anything.
+var rolePermissions = map[string][]Permission{
+	RoleOwner: {
+		PermGuestsRead, PermGuestsWrite, PermGuestsDelete,
+		PermMessagesRead, PermMessagesWrite, PermRSVPsRead,
+		PermEventsWrite, PermQuestionsWrite,
+		PermCampaignsRead, PermCampaignsWrite,
+		PermJobsRead, PermJobsManage,
+		PermPhotosRead, PermPhotosModerate, PermAlbumsWrite, PermStorageManage,
+		PermUsersManage,
+	},
+	RolePlanner: {
+		PermGuestsRead, PermGuestsWrite,
+		PermMessagesRead, PermMessagesWrite, PermRSVPsRead,
+		PermEventsWrite, PermQuestionsWrite,
+		PermCampaignsRead, PermCampaignsWrite,
+		PermJobsRead, PermJobsManage,
+		PermPhotosRead, PermPhotosModerate, PermAlbumsWrite,
+	},
+	RolePhotoModerator: {
+		PermPhotosRead, PermPhotosModerate, PermAlbumsWrite,
+	},
+	RoleViewer: {
+		PermGuestsRead, PermMessagesRead, PermRSVPsRead,
+		PermCampaignsRead, PermPhotosRead,
+	},
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Can reports whether a user with role has permission.
+func Can(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionsFor returns the permissions of role, sorted; none for an
+// unknown role.
+func PermissionsFor(role string) []Permission {
+	permissions := append([]Permission{}, rolePermissions[role]...)
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i] < permissions[j] })
+	return permissions
+}
+
+// HasPermission reports whether the user RequireAuth loaded for the
+// request has permission, for handlers whose actions need different ones.
+// Users who still have to set up required two-factor authentication have
+// none.
+func HasPermission(c *gin.Context, permission Permission) bool {
+	value, _ := c.Get("user")
+	user, ok := value.(*User)
+	return ok && !user.TwoFactorSetupRequired && Can(user.Role, permission)
+}
+
+// RequirePermission lets the request through only if the user RequireAuth
+// loaded has permission. The routes for a user's own account don't use
+// it, so a user made to set up two-factor authentication can still do so.
+func (h *Handler) RequirePermission(permission Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("user")
+		if user, ok := value.(*User); ok && user.TwoFactorSetupRequired {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Set up two-factor authentication to continue", "twoFactorSetupRequired": true})
+			c.Abort()
+			return
+		}
+		if !HasPermission(c, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to do this", "permission": permission})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
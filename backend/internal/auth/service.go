@@ -2,120 +2,236 @@ package auth
 
 import (
 	"errors"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"wedding-app/internal/jobs"
+	"wedding-app/pkg/cache"
 	"wedding-app/pkg/logger"
+	"wedding-app/pkg/notify"
 )
 
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrDeactivated is returned for users an owner has deactivated, at
+	// login and for any token they still hold.
+	ErrDeactivated = errors.New("account has been deactivated")
+)
+
+// Notifier sends the emails admin users get, such as invitations.
+type Notifier interface {
+	SendUserEmail(to, template string, data notify.Data) error
+}
+
 type Service struct {
-	db     *gorm.DB
-	logger logger.Logger
-	secret []byte
+	db       *gorm.DB
+	logger   logger.Logger
+	keys     *keyring
+	cache    cache.Client
+	notifier Notifier
+	queue    *jobs.Queue
+	baseURL  string
+	issuer   string // shown in authenticator apps
+
+	// settings caches the security settings, which every authenticated
+	// request reads, for settingsTTL.
+	settingsMu       sync.Mutex
+	settings         *Settings
+	settingsLoadedAt time.Time
 }
 
-func NewService(db *gorm.DB, logger logger.Logger) *Service {
-	// In production, load this from environment variable
-	secret := []byte("your-super-secret-key-change-this-in-production")
+func NewService(db *gorm.DB, logger logger.Logger, cache cache.Client, notifier Notifier, queue *jobs.Queue) (*Service, error) {
+	keys, err := loadKeyring(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := os.Getenv("APP_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:3000"
+	}
+
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Wedding Admin"
+	}
 	
-	return &Service{
-		db:     db,
-		logger: logger,
-		secret: secret,
+	s := &Service{
+		db:       db,
+		logger:   logger,
+		keys:     keys,
+		cache:    cache,
+		notifier: notifier,
+		queue:    queue,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		issuer:   issuer,
 	}
+	if queue != nil {
+		jobs.Register(queue, JobSendInvitation, s.sendInvitation)
+		jobs.Register(queue, JobSendPasswordReset, s.sendPasswordReset)
+	}
+	return s, nil
 }
 
-func (s *Service) Login(email, password string) (string, *User, error) {
+// Login checks a user's password and starts a new session for them. Users
+// with two-factor authentication get a challenge instead, which
+// CompleteLogin turns into a session once they give a code.
+func (s *Service) Login(email, password string, client ClientInfo) (*Tokens, *User, *Challenge, error) {
+	// Invitations store addresses lowercased; older accounts may not be
 	var user User
-	err := s.db.Where("email = ?", email).First(&user).Error
+	err := s.db.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", nil, errors.New("invalid credentials")
+			return nil, nil, nil, ErrInvalidCredentials
 		}
 		s.logger.Error("Database error during login", "error", err)
-		return "", nil, err
+		return nil, nil, nil, err
 	}
 
 	// Verify password
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
-		return "", nil, errors.New("invalid credentials")
+		return nil, nil, nil, ErrInvalidCredentials
+	}
+	if user.Status != StatusActive {
+		return nil, nil, nil, ErrDeactivated
+	}
+
+	if user.TwoFactorEnabled {
+		challenge, err := s.startChallenge(&user)
+		if err != nil {
+			s.logger.Error("Failed to start two-factor challenge", "error", err)
+			return nil, nil, nil, err
+		}
+		return nil, nil, challenge, nil
 	}
 
-	// Generate JWT token
-	token, err := s.generateToken(&user)
+	tokens, err := s.finishLogin(&user, client)
 	if err != nil {
-		s.logger.Error("Failed to generate token", "error", err)
-		return "", nil, err
+		return nil, nil, nil, err
+	}
+	return tokens, &user, nil, nil
+}
+
+// finishLogin starts a session for a user who has proven who they are.
+func (s *Service) finishLogin(user *User, client ClientInfo) (*Tokens, error) {
+	// Start a session
+	tokens, err := s.startSession(user, client)
+	if err != nil {
+		s.logger.Error("Failed to start session", "error", err)
+		return nil, err
 	}
 
 	// Update last login
-	user.LastLogin = time.Now()
-	s.db.Save(&user)
+	now := time.Now()
+	user.LastLogin = &now
+	s.db.Model(user).Update("last_login", now)
 
 	// Don't return password hash
 	user.PasswordHash = ""
+	user.Permissions = PermissionsFor(user.Role)
+	if err := s.checkTwoFactorSetup(user); err != nil {
+		return nil, err
+	}
 
-	return token, &user, nil
+	return tokens, nil
 }
 
-func (s *Service) ValidateToken(tokenString string) (*User, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return s.secret, nil
-	})
-
+// ValidateToken checks an access token and returns its user and the ID of
+// the session it belongs to. Tokens from revoked sessions and deactivated
+// users are refused.
+func (s *Service) ValidateToken(tokenString string) (*User, string, error) {
+	token, err := jwt.Parse(tokenString, s.keys.verify, jwt.WithValidMethods([]string{"HS256"}))
 	if err != nil || !token.Valid {
-		return nil, errors.New("invalid token")
+		return nil, "", ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return nil, errors.New("invalid token claims")
+		return nil, "", ErrInvalidToken
 	}
 
 	userID, ok := claims["user_id"].(float64)
 	if !ok {
-		return nil, errors.New("invalid user ID in token")
+		return nil, "", errors.New("invalid user ID in token")
+	}
+	sessionID, _ := claims["sid"].(string)
+	if sessionID == "" {
+		return nil, "", ErrInvalidToken
 	}
 
+	record, err := s.loadSession(sessionID)
+	if err != nil {
+		return nil, "", err
+	}
+	if record.UserID != uint(userID) {
+		return nil, "", ErrInvalidSession
+	}
+
+	user, err := s.activeUser(uint(userID))
+	if err != nil {
+		return nil, "", err
+	}
+	return user, sessionID, nil
+}
+
+// activeUser loads a user who may use the admin.
+func (s *Service) activeUser(id uint) (*User, error) {
 	var user User
-	err = s.db.First(&user, uint(userID)).Error
+	err := s.db.First(&user, id).Error
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
+	if user.Status != StatusActive {
+		return nil, ErrDeactivated
+	}
+	if err := s.checkTwoFactorSetup(&user); err != nil {
+		return nil, err
+	}
 
 	user.PasswordHash = "" // Don't expose password hash
 	return &user, nil
 }
 
-func (s *Service) generateToken(user *User) (string, error) {
+// generateToken signs a short-lived access token for a session.
+func (s *Service) generateToken(userID uint, sessionID string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(accessTokenTTL)
 	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(), // 7 days
-		"iat":     time.Now().Unix(),
+		"user_id": userID,
+		"sid":     sessionID,
+		"exp":     expiresAt.Unix(),
+		"iat":     now.Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secret)
+	token, err := s.keys.sign(claims)
+	return token, expiresAt, err
 }
 
-func (s *Service) CreateUser(email, password string) (*User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// CreateUser adds an admin user with role.
+func (s *Service) CreateUser(email, password, role string) (*User, error) {
+	if !ValidRole(role) {
+		return nil, ErrUnknownRole
+	}
+	if err := ValidatePassword(password, email); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := hashPassword(password)
 	if err != nil {
 		return nil, err
 	}
 
 	user := User{
 		Email:        email,
-		PasswordHash: string(hashedPassword),
-		Role:         "admin",
-		Status:       "active",
+		PasswordHash: hashedPassword,
+		Role:         role,
+		Status:       StatusActive,
 	}
 
 	err = s.db.Create(&user).Error
@@ -125,4 +241,5 @@ func (s *Service) CreateUser(email, password string) (*User, error) {
 
 	user.PasswordHash = ""
 	return &user, nil
-}
\ No newline at end of file
+}
+
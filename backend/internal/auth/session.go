@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// accessTokenTTL is how long an access token is good for. Revoking a
+	// session takes effect straight away regardless, since every request
+	// checks the session is still there.
+	accessTokenTTL = 15 * time.Minute
+	// sessionTTL is how long a session lasts without being refreshed.
+	sessionTTL = 30 * 24 * time.Hour
+	// refreshGrace is how long after a rotation the previous refresh token
+	// is turned away without ending the session, for two browser tabs
+	// refreshing at once.
+	refreshGrace = 30 * time.Second
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrInvalidSession covers unknown, expired, revoked and replayed
+	// refresh tokens alike.
+	ErrInvalidSession = errors.New("session is invalid or has expired")
+)
+
+// ClientInfo describes where a sign-in came from, so users can tell their
+// sessions apart.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// Session is one signed-in device. It is kept in the cache, not the
+// database, and expires on its own if it isn't refreshed.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     uint      `json:"userId"`
+	UserEmail  string    `json:"userEmail,omitempty"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current,omitempty"` // the session making the request
+}
+
+// sessionRecord is what the cache holds for a session: the session plus
+// hashes of its refresh token and the one it replaced.
+type sessionRecord struct {
+	Session
+	RefreshHash  string    `json:"refreshHash"`
+	PreviousHash string    `json:"previousHash"`
+	RotatedAt    time.Time `json:"rotatedAt"`
+}
+
+// Tokens are what a sign-in or refresh hands the client.
+type Tokens struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"` // of the access token
+	SessionID    string    `json:"sessionId"`
+}
+
+func sessionKey(id string) string {
+	return "auth:session:" + id
+}
+
+func userSessionsKey(userID uint) string {
+	return fmt.Sprintf("auth:user-sessions:%d", userID)
+}
+
+// startSession signs user in on a new session.
+func (s *Service) startSession(user *User, client ClientInfo) (*Tokens, error) {
+	id, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	id = id[:32]
+
+	now := time.Now()
+	record := &sessionRecord{Session: Session{
+		ID:         id,
+		UserID:     user.ID,
+		UserEmail:  user.Email,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}}
+	return s.issueTokens(record)
+}
+
+// issueTokens gives a session a new refresh token and access token, and
+// saves it. A session being refreshed is only saved if it is still there
+// with the refresh token that was presented, so a refresh racing a revoke
+// or another refresh can't bring back or fork the session.
+func (s *Service) issueTokens(record *sessionRecord) (*Tokens, error) {
+	secret, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	previous := record.RefreshHash
+	if previous != "" {
+		record.PreviousHash = previous
+		record.RotatedAt = now
+	}
+	record.RefreshHash = hashToken(secret)
+	record.ExpiresAt = now.Add(sessionTTL)
+
+	if previous == "" {
+		err = s.cache.Set(sessionKey(record.ID), record, sessionTTL)
+	} else {
+		var saved bool
+		saved, err = s.cache.SetIfMatch(sessionKey(record.ID), "refreshHash", previous, record, sessionTTL)
+		if err == nil && !saved {
+			return nil, ErrInvalidSession
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	index := userSessionsKey(record.UserID)
+	if err := s.cache.SAdd(index, record.ID); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	if err := s.cache.Expire(index, sessionTTL); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+
+	access, expiresAt, err := s.generateToken(record.UserID, record.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{
+		AccessToken:  access,
+		RefreshToken: record.ID + "." + secret,
+		ExpiresAt:    expiresAt,
+		SessionID:    record.ID,
+	}, nil
+}
+
+func (s *Service) loadSession(id string) (*sessionRecord, error) {
+	var record sessionRecord
+	if err := s.cache.Get(sessionKey(id), &record); err != nil {
+		return nil, err
+	}
+	if record.ID == "" {
+		return nil, ErrInvalidSession
+	}
+	return &record, nil
+}
+
+// Refresh swaps a refresh token for a new access token and refresh token.
+// Each refresh token works once; presenting one that has already been
+// swapped means it was copied, so the session is ended.
+func (s *Service) Refresh(refreshToken string, client ClientInfo) (*Tokens, *User, error) {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		return nil, nil, ErrInvalidSession
+	}
+	record, err := s.loadSession(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hash := hashToken(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(record.RefreshHash)) != 1 {
+		if record.PreviousHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(record.PreviousHash)) == 1 &&
+			time.Since(record.RotatedAt) > refreshGrace {
+			s.logger.Warn("Refresh token reused, ending session", "session", id, "user", record.UserID, "ip", client.IP)
+			if err := s.RevokeSession(record.UserID, id); err != nil {
+				s.logger.Error("Failed to revoke session", "error", err, "session", id)
+			}
+		}
+		return nil, nil, ErrInvalidSession
+	}
+
+	user, err := s.activeUser(record.UserID)
+	if err != nil {
+		if errors.Is(err, ErrDeactivated) {
+			s.RevokeSession(record.UserID, id)
+		}
+		return nil, nil, err
+	}
+
+	record.LastUsedAt = time.Now()
+	record.IP = client.IP
+	if client.UserAgent != "" {
+		record.UserAgent = client.UserAgent
+	}
+	tokens, err := s.issueTokens(record)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, user, nil
+}
+
+// SessionFromRefreshToken returns the ID of the session a refresh token
+// belongs to, if it is still valid.
+func (s *Service) SessionFromRefreshToken(refreshToken string) (uint, string, error) {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		return 0, "", ErrInvalidSession
+	}
+	record, err := s.loadSession(id)
+	if err != nil {
+		return 0, "", err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(record.RefreshHash)) != 1 {
+		return 0, "", ErrInvalidSession
+	}
+	return record.UserID, record.ID, nil
+}
+
+// ListSessions returns a user's active sessions, most recently used first.
+func (s *Service) ListSessions(userID uint) ([]Session, error) {
+	index := userSessionsKey(userID)
+	ids, err := s.cache.SMembers(index)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []Session{}
+	var expired []string
+	for _, id := range ids {
+		record, err := s.loadSession(id)
+		if errors.Is(err, ErrInvalidSession) {
+			expired = append(expired, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, record.Session)
+	}
+	if len(expired) > 0 {
+		// Expired on their own; tidy the index
+		if err := s.cache.SRem(index, expired...); err != nil {
+			s.logger.Warn("Failed to prune expired sessions", "error", err, "user", userID)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt) })
+	return sessions, nil
+}
+
+// ListAllSessions returns the active sessions of every admin user.
+func (s *Service) ListAllSessions() ([]Session, error) {
+	var users []User
+	if err := s.db.Select("id", "email").Order("email").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	sessions := []Session{}
+	for _, user := range users {
+		userSessions, err := s.ListSessions(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range userSessions {
+			userSessions[i].UserEmail = user.Email
+		}
+		sessions = append(sessions, userSessions...)
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of a user's sessions. Its access token stops
+// working on the next request.
+func (s *Service) RevokeSession(userID uint, id string) error {
+	record, err := s.loadSession(id)
+	if err != nil {
+		return err
+	}
+	if record.UserID != userID {
+		return ErrInvalidSession
+	}
+	if err := s.cache.Delete(sessionKey(id)); err != nil {
+		return err
+	}
+	return s.cache.SRem(userSessionsKey(userID), id)
+}
+
+// RevokeAllSessions ends every session of a user except keep, which may
+// be empty. It returns how many were ended.
+func (s *Service) RevokeAllSessions(userID uint, keep string) (int, error) {
+	index := userSessionsKey(userID)
+	ids, err := s.cache.SMembers(index)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, id := range ids {
+		if id == keep {
+			continue
+		}
+		if err := s.cache.Delete(sessionKey(id)); err != nil {
+			return revoked, err
+		}
+		if err := s.cache.SRem(index, id); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// SessionOwner returns the user a session belongs to, for admins revoking
+// sessions by ID.
+func (s *Service) SessionOwner(id string) (uint, error) {
+	record, err := s.loadSession(id)
+	if err != nil {
+		return 0, err
+	}
+	return record.UserID, nil
+}
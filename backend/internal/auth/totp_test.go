@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 test key from RFC 6238 appendix B.
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// The RFC lists 8-digit codes; ours are their last six digits.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCode(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, v := range rfc6238Vectors {
+		if got := totpCode(key, v.unix/totpPeriod); got != v.code {
+			t.Errorf("totpCode at %d = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		now := time.Unix(v.unix, 0)
+		step, ok := matchTOTP(rfc6238Secret, v.code, now)
+		if !ok || step != v.unix/totpPeriod {
+			t.Errorf("matchTOTP at %d = (%d, %v), want step %d", v.unix, step, ok, v.unix/totpPeriod)
+		}
+	}
+
+	// Secrets are accepted in lower case, as some apps display them
+	if _, ok := matchTOTP(strings.ToLower(rfc6238Secret), "287082", time.Unix(59, 0)); !ok {
+		t.Error("matchTOTP rejected a lower-case secret")
+	}
+}
+
+func TestMatchTOTPSkew(t *testing.T) {
+	at := time.Unix(1111111111, 0) // step 37037037
+	tests := []struct {
+		offset time.Duration
+		ok     bool
+	}{
+		{-2 * totpPeriod * time.Second, false},
+		{-totpPeriod * time.Second, true},
+		{0, true},
+		{totpPeriod * time.Second, true},
+		{2 * totpPeriod * time.Second, false},
+	}
+	for _, tt := range tests {
+		if _, ok := matchTOTP(rfc6238Secret, "050471", at.Add(tt.offset)); ok != tt.ok {
+			t.Errorf("code checked %s from its step: ok = %v, want %v", tt.offset, ok, tt.ok)
+		}
+	}
+}
+
+func TestMatchTOTPRejects(t *testing.T) {
+	now := time.Unix(59, 0)
+	tests := map[string]struct{ secret, code string }{
+		"wrong code":     {rfc6238Secret, "287083"},
+		"short code":     {rfc6238Secret, "28708"},
+		"long code":      {rfc6238Secret, "94287082"},
+		"empty code":     {rfc6238Secret, ""},
+		"invalid secret": {"not base32!", "287082"},
+	}
+	for name, tt := range tests {
+		if _, ok := matchTOTP(tt.secret, tt.code, now); ok {
+			t.Errorf("%s: matchTOTP accepted %q", name, tt.code)
+		}
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	a, err := newTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Error("newTOTPSecret returned the same secret twice")
+	}
+	key, err := totpEncoding.DecodeString(a)
+	if err != nil || len(key) != 20 {
+		t.Errorf("secret %q decodes to %d bytes (%v), want 20", a, len(key), err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	raw := provisioningURI("Our Wedding", "ann+admin@example.com", "JBSWY3DPEHPK3PXP")
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Errorf("URI = %s", raw)
+	}
+	if u.Path != "/Our Wedding:ann+admin@example.com" {
+		t.Errorf("label = %q", u.Path)
+	}
+
+	q := u.Query()
+	want := map[string]string{
+		"secret":    "JBSWY3DPEHPK3PXP",
+		"issuer":    "Our Wedding",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for key, value := range want {
+		if q.Get(key) != value {
+			t.Errorf("%s = %q, want %q", key, q.Get(key), value)
+		}
+	}
+}
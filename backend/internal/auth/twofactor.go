@@ -0,0 +1,501 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// twoFactorSetupTTL is how long a user has to confirm a new secret
+	// with a code from their app.
+	twoFactorSetupTTL = 15 * time.Minute
+	// challengeTTL is how long a user has to give a code after their
+	// password at login.
+	challengeTTL      = 5 * time.Minute
+	recoveryCodeCount = 10
+	// After maxTwoFactorFailures wrong codes within twoFactorFailureWindow a
+	// user's codes are refused until the window passes, since six digits
+	// fall to guessing otherwise.
+	maxTwoFactorFailures   = 10
+	twoFactorFailureWindow = 15 * time.Minute
+	// settingsTTL is how long GetSettings trusts its cached copy. Changes
+	// made through this process show at once; other API processes see
+	// them within the TTL.
+	settingsTTL = 30 * time.Second
+)
+
+var (
+	ErrTwoFactorEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled   = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorRequired     = errors.New("two-factor authentication is required for all admins")
+	ErrTwoFactorSetupExpired = errors.New("two-factor setup has expired, start again")
+	ErrInvalidCode           = errors.New("invalid authentication code")
+	ErrTooManyAttempts       = errors.New("too many invalid codes, try again later")
+	// ErrChallengeInvalid covers unknown, expired and already used login
+	// challenges alike.
+	ErrChallengeInvalid = errors.New("sign-in has expired, start again")
+)
+
+// TwoFactorSetup is what a user adds to their authenticator app, either
+// by scanning URI as a QR code or typing in Secret.
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorStatus describes a user's two-factor authentication.
+type TwoFactorStatus struct {
+	Enabled           bool  `json:"enabled"`
+	Required          bool  `json:"required"`
+	RecoveryCodesLeft int64 `json:"recoveryCodesLeft"`
+}
+
+// Challenge is the second step of a login by a user with two-factor
+// authentication: the token to present along with their code.
+type Challenge struct {
+	Token     string    `json:"challengeToken"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type challengeRecord struct {
+	UserID uint `json:"userId"`
+}
+
+func twoFactorSetupKey(userID uint) string {
+	return fmt.Sprintf("auth:2fa-setup:%d", userID)
+}
+
+func challengeKey(token string) string {
+	return "auth:2fa-challenge:" + hashToken(token)
+}
+
+func twoFactorFailuresKey(userID uint) string {
+	return fmt.Sprintf("auth:2fa-failures:%d", userID)
+}
+
+// GetSettings returns the admin-wide security settings. The row is
+// created when the database is migrated.
+func (s *Service) GetSettings() (*Settings, error) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+
+	if s.settings == nil || time.Since(s.settingsLoadedAt) > settingsTTL {
+		var settings Settings
+		if err := s.db.First(&settings, 1).Error; err != nil {
+			return nil, err
+		}
+		s.settings, s.settingsLoadedAt = &settings, time.Now()
+	}
+
+	settings := *s.settings
+	return &settings, nil
+}
+
+// SetRequireTwoFactor turns the requirement for every admin to use
+// two-factor authentication on or off. The owner turning it on must use it
+// already, so they know the setup works.
+func (s *Service) SetRequireTwoFactor(actorID uint, require bool) (*Settings, error) {
+	if require {
+		var actor User
+		if err := s.db.First(&actor, actorID).Error; err != nil {
+			return nil, err
+		}
+		if !actor.TwoFactorEnabled {
+			return nil, fmt.Errorf("%w on your own account", ErrTwoFactorNotEnabled)
+		}
+	}
+
+	settings := Settings{ID: 1, RequireTwoFactor: require, UpdatedBy: &actorID}
+	if err := s.db.Save(&settings).Error; err != nil {
+		return nil, err
+	}
+
+	s.settingsMu.Lock()
+	cached := settings
+	s.settings, s.settingsLoadedAt = &cached, time.Now()
+	s.settingsMu.Unlock()
+
+	s.logger.Info("Two-factor requirement changed", "required", require, "by", actorID)
+	return &settings, nil
+}
+
+// checkTwoFactorSetup flags user if two-factor authentication is required
+// and they haven't set it up.
+func (s *Service) checkTwoFactorSetup(user *User) error {
+	if user.TwoFactorEnabled {
+		return nil
+	}
+	settings, err := s.GetSettings()
+	if err != nil {
+		return err
+	}
+	user.TwoFactorSetupRequired = settings.RequireTwoFactor
+	return nil
+}
+
+// GetTwoFactorStatus returns whether a user has two-factor authentication
+// and how many unused recovery codes they have left.
+func (s *Service) GetTwoFactorStatus(userID uint) (*TwoFactorStatus, error) {
+	var user User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	settings, err := s.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &TwoFactorStatus{Enabled: user.TwoFactorEnabled, Required: settings.RequireTwoFactor}
+	err = s.db.Model(&RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&status.RecoveryCodesLeft).Error
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// StartTwoFactorSetup generates a new secret for a user to add to their
+// authenticator app. It takes effect once EnableTwoFactor confirms a code
+// from the app.
+func (s *Service) StartTwoFactorSetup(userID uint) (*TwoFactorSetup, error) {
+	var user User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.Set(twoFactorSetupKey(userID), secret, twoFactorSetupTTL); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret: secret,
+		URI:    provisioningURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// EnableTwoFactor turns on two-factor authentication once the user proves
+// their app has the secret from StartTwoFactorSetup. Sessions other than
+// keepSession, which were started with just a password, are ended. It
+// returns the user's recovery codes, which are never shown again.
+func (s *Service) EnableTwoFactor(userID uint, code, keepSession string) ([]string, error) {
+	var secret string
+	if err := s.cache.Get(twoFactorSetupKey(userID), &secret); err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, ErrTwoFactorSetupExpired
+	}
+	step, ok := matchTOTP(secret, normalizeCode(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ? AND two_factor_enabled = ?", userID, false).
+			Updates(map[string]interface{}{
+				"two_factor_enabled": true,
+				"totp_secret":        secret,
+				"totp_last_step":     step,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTwoFactorEnabled
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Delete(twoFactorSetupKey(userID))
+	s.logger.Info("Two-factor authentication enabled", "id", userID)
+
+	if _, err := s.RevokeAllSessions(userID, keepSession); err != nil {
+		s.logger.Error("Failed to end sessions after enabling two-factor authentication", "error", err, "id", userID)
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor turns off two-factor authentication for a user who
+// gives their password and a current code. It can't be turned off while
+// an owner requires it.
+func (s *Service) DisableTwoFactor(userID uint, password, code string) error {
+	settings, err := s.GetSettings()
+	if err != nil {
+		return err
+	}
+	if settings.RequireTwoFactor {
+		return ErrTwoFactorRequired
+	}
+
+	var user User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return ErrWrongPassword
+	}
+	if err := s.checkSecondFactor(&user, code); err != nil {
+		return err
+	}
+
+	if err := s.clearTwoFactor(userID); err != nil {
+		return err
+	}
+	s.logger.Info("Two-factor authentication disabled", "id", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes, for when
+// they've used or lost them. It takes a current code.
+func (s *Service) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	var user User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.checkSecondFactor(&user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Recovery codes regenerated", "id", userID)
+	return codes, nil
+}
+
+// ResetTwoFactor turns off another user's two-factor authentication, for
+// when they've lost both their phone and their recovery codes, and ends
+// their sessions. If it's required they set it up again at their next
+// login.
+func (s *Service) ResetTwoFactor(actorID, userID uint) (*User, error) {
+	if actorID == userID {
+		return nil, ErrOwnAccount
+	}
+
+	var user User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.clearTwoFactor(userID); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Two-factor authentication reset", "id", userID, "by", actorID)
+
+	if _, err := s.RevokeAllSessions(userID, ""); err != nil {
+		s.logger.Error("Failed to end sessions after resetting two-factor authentication", "error", err, "id", userID)
+	}
+
+	user.TwoFactorEnabled = false
+	user.PasswordHash = ""
+	return &user, nil
+}
+
+func (s *Service) clearTwoFactor(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"two_factor_enabled": false,
+			"totp_secret":        "",
+			"totp_last_step":     0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// startChallenge records that user has given their password and now owes
+// a code.
+func (s *Service) startChallenge(user *User) (*Challenge, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.Set(challengeKey(token), challengeRecord{UserID: user.ID}, challengeTTL); err != nil {
+		return nil, err
+	}
+	return &Challenge{Token: token, ExpiresAt: time.Now().Add(challengeTTL)}, nil
+}
+
+// CompleteLogin finishes a login started by Login with a code from the
+// user's authenticator app or one of their recovery codes. A wrong code
+// leaves the challenge open for another try.
+func (s *Service) CompleteLogin(challengeToken, code string, client ClientInfo) (*Tokens, *User, error) {
+	var record challengeRecord
+	if err := s.cache.Get(challengeKey(challengeToken), &record); err != nil {
+		return nil, nil, err
+	}
+	if record.UserID == 0 {
+		return nil, nil, ErrChallengeInvalid
+	}
+
+	var user User
+	if err := s.db.First(&user, record.UserID).Error; err != nil {
+		return nil, nil, err
+	}
+	if user.Status != StatusActive {
+		return nil, nil, ErrDeactivated
+	}
+	if !user.TwoFactorEnabled {
+		// Reset by an owner since the password was given
+		return nil, nil, ErrChallengeInvalid
+	}
+	if err := s.checkSecondFactor(&user, code); err != nil {
+		return nil, nil, err
+	}
+	s.cache.Delete(challengeKey(challengeToken))
+
+	tokens, err := s.finishLogin(&user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, &user, nil
+}
+
+// checkSecondFactor accepts a current code from the user's app or an
+// unused recovery code, counting failures towards the user's limit.
+func (s *Service) checkSecondFactor(user *User, code string) error {
+	failuresKey := twoFactorFailuresKey(user.ID)
+	var failures int
+	if err := s.cache.Get(failuresKey, &failures); err != nil {
+		return err
+	}
+	if failures >= maxTwoFactorFailures {
+		return ErrTooManyAttempts
+	}
+
+	ok, err := s.verifySecondFactor(user, normalizeCode(code))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.logger.Warn("Invalid two-factor code", "id", user.ID)
+		if _, err := s.cache.Incr(failuresKey, twoFactorFailureWindow); err != nil {
+			s.logger.Error("Failed to count invalid two-factor code", "error", err, "id", user.ID)
+		}
+		return ErrInvalidCode
+	}
+
+	s.cache.Delete(failuresKey)
+	return nil
+}
+
+func (s *Service) verifySecondFactor(user *User, code string) (bool, error) {
+	if len(code) != totpDigits {
+		return s.useRecoveryCode(user.ID, code)
+	}
+
+	step, ok := matchTOTP(user.TOTPSecret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return false, nil
+	}
+	// Claim the time step so the code can't be replayed, even by a
+	// concurrent request
+	result := s.db.Model(&User{}).Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (s *Service) useRecoveryCode(userID uint, code string) (bool, error) {
+	var codes []RecoveryCode
+	if err := s.db.Where("user_id = ? AND used_at IS NULL", userID).Find(&codes).Error; err != nil {
+		return false, err
+	}
+
+	for _, recovery := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(recovery.CodeHash), []byte(code)) != nil {
+			continue
+		}
+		result := s.db.Model(&recovery).Where("used_at IS NULL").Update("used_at", time.Now())
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return false, nil
+		}
+		s.logger.Info("Recovery code used", "id", userID, "remaining", len(codes)-1)
+		return true, nil
+	}
+	return false, nil
+}
+
+// replaceRecoveryCodes gives a user a fresh set of recovery codes and
+// returns them; only their hashes are kept.
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code[:5] + "-" + code[5:]
+		records[i] = RecoveryCode{UserID: userID, CodeHash: string(hash)}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// newRecoveryCode returns 10 random base32 characters, 50 bits.
+func newRecoveryCode() (string, error) {
+	bytes := make([]byte, 7)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return strings.ToLower(base32.StdEncoding.EncodeToString(bytes))[:10], nil
+}
+
+// normalizeCode strips the spaces and dashes people type or paste along
+// with a code.
+func normalizeCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
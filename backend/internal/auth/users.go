@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"wedding-app/internal/jobs"
+	"wedding-app/pkg/notify"
+)
+
+// Account states.
+const (
+	StatusActive      = "active"
+	StatusDeactivated = "deactivated"
+)
+
+// invitationExpiry is how long an invitation link can be accepted for.
+const invitationExpiry = 7 * 24 * time.Hour
+
+// JobSendInvitation is the background job that emails an invitation link.
+const JobSendInvitation = "auth.invitation"
+
+type InvitationJob struct {
+	InvitationID uint `json:"invitationId"`
+}
+
+var (
+	ErrUserExists = errors.New("a user with this email already exists")
+	// ErrInvitationInvalid covers unknown, expired and already accepted
+	// invitation links alike.
+	ErrInvitationInvalid = errors.New("invitation is invalid or has expired")
+	ErrOwnAccount        = errors.New("you can't change your own role or status")
+	ErrUnknownRole       = errors.New("unknown role")
+)
+
+// ListUsers returns every admin user, oldest first.
+func (s *Service) ListUsers() ([]User, error) {
+	var users []User
+	if err := s.db.Order("created_at ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i].PasswordHash = ""
+	}
+	return users, nil
+}
+
+// SetRole changes another user's role.
+func (s *Service) SetRole(actorID, userID uint, role string) (*User, error) {
+	if !ValidRole(role) {
+		return nil, ErrUnknownRole
+	}
+	return s.updateUser(actorID, userID, "role", role)
+}
+
+// SetStatus activates or deactivates another user. A deactivated user's
+// sessions are ended and they can't sign in again.
+func (s *Service) SetStatus(actorID, userID uint, status string) (*User, error) {
+	user, err := s.updateUser(actorID, userID, "status", status)
+	if err != nil {
+		return nil, err
+	}
+	if status == StatusDeactivated {
+		if _, err := s.RevokeAllSessions(userID, ""); err != nil {
+			// ValidateToken refuses them anyway
+			s.logger.Error("Failed to end sessions of deactivated user", "error", err, "id", userID)
+		}
+	}
+	return user, nil
+}
+
+// updateUser sets one column on a user other than the actor, so an owner
+// can't lock themselves out.
+func (s *Service) updateUser(actorID, userID uint, column string, value string) (*User, error) {
+	if actorID == userID {
+		return nil, ErrOwnAccount
+	}
+
+	var user User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&user).Update(column, value).Error; err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("User updated", "id", user.ID, column, value, "by", actorID)
+	user.PasswordHash = ""
+	return &user, nil
+}
+
+// Invite emails email a one-time link to create an admin account with
+// role. Inviting the same address again replaces any earlier invitation,
+// which is also how to resend one.
+func (s *Service) Invite(email, role string, invitedBy uint) (*Invitation, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !ValidRole(role) {
+		return nil, ErrUnknownRole
+	}
+
+	var count int64
+	if err := s.db.Model(&User{}).Where("LOWER(email) = ?", email).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrUserExists
+	}
+
+	// As with password resets, the link's token is only chosen when the
+	// email goes out, so it never sits in the job queue
+	placeholder, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	invitation := Invitation{
+		Email:     email,
+		Role:      role,
+		TokenHash: hashToken(placeholder),
+		InvitedBy: &invitedBy,
+		ExpiresAt: time.Now().Add(invitationExpiry),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("email = ? AND accepted_at IS NULL", email).Delete(&Invitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&invitation).Error; err != nil {
+			return err
+		}
+		_, err := s.queue.EnqueueTx(tx, JobSendInvitation, InvitationJob{InvitationID: invitation.ID})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("User invited", "email", email, "role", role, "by", invitedBy)
+	return &invitation, nil
+}
+
+// sendInvitation runs as a background job to email an invitation link.
+// Each attempt issues a fresh token, so only the link in the last email
+// sent works. Invitations replaced, revoked or accepted in the meantime
+// are skipped.
+func (s *Service) sendInvitation(ctx context.Context, job InvitationJob) error {
+	var invitation Invitation
+	err := s.db.Where("id = ? AND accepted_at IS NULL AND expires_at > ?", job.InvitationID, time.Now()).
+		First(&invitation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.notifier == nil {
+		return nil
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	result := s.db.Model(&invitation).Where("accepted_at IS NULL").Update("token_hash", hashToken(token))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	var inviter User
+	if invitation.InvitedBy != nil {
+		s.db.Select("email").First(&inviter, *invitation.InvitedBy)
+	}
+	err = s.notifier.SendUserEmail(invitation.Email, notify.TemplateInvitation, notify.Data{
+		InviteURL: fmt.Sprintf("%s/admin/invitations/%s", s.baseURL, token),
+		Role:      invitation.Role,
+		InvitedBy: inviter.Email,
+	})
+	if errors.Is(err, notify.ErrRejected) {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// ListInvitations returns the invitations not yet accepted, newest first,
+// including expired ones.
+func (s *Service) ListInvitations() ([]Invitation, error) {
+	var invitations []Invitation
+	err := s.db.Where("accepted_at IS NULL").Order("created_at DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// RevokeInvitation deletes an invitation that hasn't been accepted.
+func (s *Service) RevokeInvitation(id uint) error {
+	result := s.db.Where("id = ? AND accepted_at IS NULL", id).Delete(&Invitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetInvitation looks up a pending invitation by the token from its link.
+func (s *Service) GetInvitation(token string) (*Invitation, error) {
+	var invitation Invitation
+	err := s.db.Where("token_hash = ? AND accepted_at IS NULL AND expires_at > ?", hashToken(token), time.Now()).
+		First(&invitation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvitationInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// AcceptInvitation creates the invited user with the password they chose
+// and signs them in.
+func (s *Service) AcceptInvitation(token, password string, client ClientInfo) (*Tokens, *User, error) {
+	invitation, err := s.GetInvitation(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := ValidatePassword(password, invitation.Email); err != nil {
+		return nil, nil, err
+	}
+
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	user := User{
+		Email:        invitation.Email,
+		PasswordHash: hashedPassword,
+		Role:         invitation.Role,
+		Status:       StatusActive,
+		LastLogin:    &now,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Only the first request to accept wins
+		result := tx.Model(invitation).Where("accepted_at IS NULL").Update("accepted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationInvalid
+		}
+
+		var count int64
+		if err := tx.Model(&User{}).Where("LOWER(email) = ?", invitation.Email).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUserExists
+		}
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	s.logger.Info("Invitation accepted", "email", user.Email, "role", user.Role)
+
+	tokens, err := s.startSession(&user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user.PasswordHash = ""
+	user.Permissions = PermissionsFor(user.Role)
+	return tokens, &user, nil
+}
+
+func newToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// hashToken is how one-time tokens are stored, so a database leak doesn't
+// leak working links.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
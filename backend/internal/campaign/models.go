@@ -0,0 +1,8 @@
+package campaign
+
+import (
+	"wedding-app/internal/models"
+)
+
+type ReminderCampaign = models.ReminderCampaign
+type CampaignSend = models.CampaignSend
@@ -0,0 +1,75 @@
+package campaign
+
+import (
+	"sync"
+	"time"
+)
+
+// Scheduler sends reminder campaigns once their send time passes. It runs
+// inside the API process; leasing each campaign before sending keeps
+// several instances from sending it twice.
+type Scheduler struct {
+	service  *Service
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewScheduler(service *Service, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		service:  service,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start begins checking for due campaigns every interval.
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.runDue()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.runDue()
+			}
+		}
+	}()
+}
+
+// Stop waits for the campaign being sent, if any, to finish.
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// runDue resumes campaigns whose runner died mid-send, then sends any that
+// are due.
+func (s *Scheduler) runDue() {
+	if err := s.service.ResumeInterrupted(); err != nil {
+		s.service.logger.Error("Failed to resume interrupted campaigns", "error", err)
+	}
+
+	ids, err := s.service.DueCampaignIDs(time.Now())
+	if err != nil {
+		s.service.logger.Error("Failed to find due campaigns", "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		if err := s.service.Run(id); err != nil {
+			s.service.logger.Error("Failed to send reminder campaign", "error", err, "campaign", id)
+		}
+	}
+}
@@ -0,0 +1,505 @@
+package campaign
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"wedding-app/internal/guest"
+	"wedding-app/internal/notification"
+	"wedding-app/pkg/logger"
+	"wedding-app/pkg/pagination"
+)
+
+// ErrNotEditable is returned when changing a campaign that has started
+// sending.
+var ErrNotEditable = errors.New("campaign has already been sent")
+
+const (
+	// campaignLease is how long a sending campaign stays with its runner
+	// after the last heartbeat before another runner may take it over.
+	campaignLease = 5 * time.Minute
+	// campaignHeartbeat is how often a runner renews its lease.
+	campaignHeartbeat = 30 * time.Second
+)
+
+// errLeaseLost stops a run whose campaign was taken over by another runner.
+var errLeaseLost = errors.New("campaign lease lost")
+
+type Service struct {
+	db       *gorm.DB
+	logger   logger.Logger
+	notifier *notification.Service
+	runnerID string
+}
+
+func NewService(db *gorm.DB, logger logger.Logger, notifier *notification.Service) *Service {
+	hostname, _ := os.Hostname()
+	return &Service{
+		db:       db,
+		logger:   logger,
+		notifier: notifier,
+		runnerID: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+func (s *Service) GetAllCampaigns() ([]ReminderCampaign, error) {
+	var campaigns []ReminderCampaign
+	err := s.db.Order("send_at DESC, id DESC").Find(&campaigns).Error
+	return campaigns, err
+}
+
+func (s *Service) GetCampaignByID(id uint) (*ReminderCampaign, error) {
+	var campaign ReminderCampaign
+	if err := s.db.First(&campaign, id).Error; err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+type CampaignInput struct {
+	Name    string     `json:"name"`
+	Segment *string    `json:"segment"`
+	Channel string     `json:"channel"`
+	Subject *string    `json:"subject"`
+	Body    *string    `json:"body"`
+	SendAt  *time.Time `json:"sendAt"`
+}
+
+func (s *Service) CreateCampaign(input CampaignInput) (*ReminderCampaign, error) {
+	campaign := ReminderCampaign{Channel: "email", Status: "scheduled"}
+	if err := applyInput(&campaign, input); err != nil {
+		return nil, err
+	}
+	if campaign.Name == "" {
+		return nil, fmt.Errorf("campaign name is required")
+	}
+	if input.SendAt == nil {
+		return nil, fmt.Errorf("sendAt is required")
+	}
+
+	if err := s.db.Create(&campaign).Error; err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Reminder campaign scheduled", "campaign", campaign.ID, "sendAt", campaign.SendAt)
+	return &campaign, nil
+}
+
+// UpdateCampaign edits a campaign that hasn't started sending. Updating a
+// cancelled campaign schedules it again.
+func (s *Service) UpdateCampaign(id uint, input CampaignInput) (*ReminderCampaign, error) {
+	campaign, err := s.GetCampaignByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.Status != "scheduled" && campaign.Status != "cancelled" {
+		return nil, ErrNotEditable
+	}
+
+	if err := applyInput(campaign, input); err != nil {
+		return nil, err
+	}
+	campaign.Status = "scheduled"
+
+	// Only save if the scheduler hasn't claimed it in the meantime
+	result := s.db.Model(campaign).Where("status IN ?", []string{"scheduled", "cancelled"}).
+		Select("name", "segment", "channel", "subject", "body", "send_at", "status").
+		Updates(campaign)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotEditable
+	}
+	return campaign, nil
+}
+
+func (s *Service) CancelCampaign(id uint) (*ReminderCampaign, error) {
+	campaign, err := s.GetCampaignByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.db.Model(campaign).Where("status = ?", "scheduled").Update("status", "cancelled")
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("only scheduled campaigns can be cancelled")
+	}
+	return campaign, nil
+}
+
+// DeleteCampaign removes a campaign and its send history. Campaigns that are
+// sending can't be deleted.
+func (s *Service) DeleteCampaign(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var campaign ReminderCampaign
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&campaign, id).Error; err != nil {
+			return err
+		}
+		if campaign.Status == "sending" {
+			return fmt.Errorf("campaign is sending and can't be deleted")
+		}
+
+		if err := tx.Where("campaign_id = ?", id).Delete(&CampaignSend{}).Error; err != nil {
+			return fmt.Errorf("failed to delete send history: %w", err)
+		}
+		return tx.Delete(&campaign).Error
+	})
+}
+
+func applyInput(campaign *ReminderCampaign, input CampaignInput) error {
+	if name := strings.TrimSpace(input.Name); name != "" {
+		campaign.Name = name
+	}
+	if input.Segment != nil {
+		segment := strings.TrimSpace(*input.Segment)
+		if _, err := guest.ParseSegment(segment); err != nil {
+			return err
+		}
+		campaign.Segment = segment
+	}
+	if input.Channel != "" {
+		if input.Channel != "email" && input.Channel != "sms" {
+			return fmt.Errorf("channel must be email or sms")
+		}
+		campaign.Channel = input.Channel
+	}
+	if input.Subject != nil {
+		campaign.Subject = strings.TrimSpace(*input.Subject)
+	}
+	if input.Body != nil {
+		campaign.Body = strings.TrimSpace(*input.Body)
+	}
+	if input.SendAt != nil {
+		campaign.SendAt = *input.SendAt
+	}
+	return nil
+}
+
+type Recipient struct {
+	GuestID uint   `json:"guestId"`
+	Name    string `json:"name"`
+	Email   string `json:"email,omitempty"`
+	Phone   string `json:"phone,omitempty"`
+	Reason  string `json:"reason,omitempty"` // why an excluded guest is left out
+}
+
+// Preview is who a campaign would contact if it ran now, and who it would
+// leave out and why.
+type Preview struct {
+	Recipients []Recipient `json:"recipients"`
+	Excluded   []Recipient `json:"excluded"`
+}
+
+// Preview works out the audience of campaign as of now. The campaign need
+// not be saved.
+func (s *Service) Preview(campaign *ReminderCampaign) (*Preview, error) {
+	segment, err := guest.ParseSegment(campaign.Segment)
+	if err != nil {
+		return nil, err
+	}
+
+	var guests []guest.Guest
+	err = s.db.Model(&guest.Guest{}).
+		Where("guests.registration_status = ?", "approved").
+		Scopes(segment.Scope).
+		Order("guests.last_name ASC, guests.first_name ASC, guests.id ASC").
+		Find(&guests).Error
+	if err != nil {
+		return nil, err
+	}
+
+	contacted := map[uint]bool{}
+	if campaign.ID != 0 {
+		var ids []uint
+		err := s.db.Model(&CampaignSend{}).
+			Where("campaign_id = ? AND status <> ?", campaign.ID, "suppressed").
+			Pluck("guest_id", &ids).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			contacted[id] = true
+		}
+	}
+
+	preview := &Preview{Recipients: []Recipient{}, Excluded: []Recipient{}}
+	for _, g := range guests {
+		r := Recipient{GuestID: g.ID, Name: g.FirstName + " " + g.LastName, Email: g.Email, Phone: g.Phone}
+		r.Reason = exclusionReason(campaign, &g, contacted[g.ID])
+		if r.Reason != "" {
+			preview.Excluded = append(preview.Excluded, r)
+		} else {
+			preview.Recipients = append(preview.Recipients, r)
+		}
+	}
+	return preview, nil
+}
+
+func exclusionReason(campaign *ReminderCampaign, g *guest.Guest, contacted bool) string {
+	switch {
+	case contacted:
+		return "already contacted by this campaign"
+	case g.RSVPStatus != "pending":
+		return "already responded"
+	case campaign.Channel == "email" && g.Email == "":
+		return "no email address"
+	case campaign.Channel == "sms" && g.Phone == "":
+		return "no phone number"
+	case campaign.Channel == "sms" && !g.SMSOptIn:
+		return "not opted in to SMS"
+	}
+	return ""
+}
+
+// Run sends a due campaign. It leases the campaign first so that only one
+// runner sends it, and claims each guest before contacting them, so it is
+// safe to call again after an interruption.
+func (s *Service) Run(id uint) error {
+	now := time.Now()
+	result := s.db.Model(&ReminderCampaign{}).
+		Where("id = ? AND status = ?", id, "scheduled").
+		Updates(map[string]interface{}{"status": "sending", "started_at": now, "locked_by": s.runnerID, "heartbeat_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	campaign, err := s.GetCampaignByID(id)
+	if err != nil {
+		return err
+	}
+
+	preview, err := s.Preview(campaign)
+	if err != nil {
+		s.revertClaim(id)
+		return fmt.Errorf("failed to resolve campaign audience: %w", err)
+	}
+
+	s.logger.Info("Sending reminder campaign", "campaign", id, "recipients", len(preview.Recipients))
+
+	for _, r := range preview.Excluded {
+		if r.Reason == "already contacted by this campaign" {
+			continue
+		}
+		s.recordSend(&CampaignSend{CampaignID: id, GuestID: r.GuestID, Status: "suppressed", Reason: r.Reason})
+	}
+
+	lastBeat := now
+	for _, r := range preview.Recipients {
+		if time.Since(lastBeat) >= campaignHeartbeat {
+			if err := s.heartbeat(id); err != nil {
+				if errors.Is(err, errLeaseLost) {
+					s.logger.Warn("Reminder campaign taken over by another runner", "campaign", id)
+					return nil
+				}
+				return err
+			}
+			lastBeat = time.Now()
+		}
+
+		// Claim the guest first; if a runner that lost the lease is still
+		// going, only one of them gets to contact each guest
+		claimed, err := s.claimSend(id, r.GuestID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		var g guest.Guest
+		if err := s.db.First(&g, r.GuestID).Error; err != nil {
+			s.recordSend(&CampaignSend{CampaignID: id, GuestID: r.GuestID, Status: "failed", Reason: err.Error()})
+			continue
+		}
+
+		// Last-moment check so a guest who just responded isn't nudged
+		if g.RSVPStatus != "pending" {
+			s.recordSend(&CampaignSend{CampaignID: id, GuestID: g.ID, Status: "suppressed", Reason: "already responded"})
+			continue
+		}
+
+		send := &CampaignSend{CampaignID: id, GuestID: g.ID, Status: "sent"}
+		delivery, err := s.notifier.SendReminder(&g, campaign.Channel, campaign.Subject, campaign.Body)
+		if delivery != nil {
+			send.DeliveryID = &delivery.ID
+		}
+		if err != nil {
+			send.Status = "failed"
+			send.Reason = err.Error()
+		}
+		s.recordSend(send)
+	}
+
+	return s.finish(id)
+}
+
+// heartbeat renews the lease on a sending campaign, or returns errLeaseLost
+// if another runner has taken it over.
+func (s *Service) heartbeat(id uint) error {
+	result := s.db.Model(&ReminderCampaign{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, "sending", s.runnerID).
+		Update("heartbeat_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errLeaseLost
+	}
+	return nil
+}
+
+// claimSend marks a guest as being contacted by a campaign. It reports false
+// if another run has already claimed or contacted them; guests only
+// suppressed before can be claimed.
+func (s *Service) claimSend(campaignID, guestID uint) (bool, error) {
+	send := CampaignSend{CampaignID: campaignID, GuestID: guestID, Status: "sending"}
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "campaign_id"}, {Name: "guest_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "reason", "updated_at"}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Eq{Column: "campaign_sends.status", Value: "suppressed"}}},
+	}).Create(&send)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// recordSend saves the outcome for a guest, replacing an earlier one.
+func (s *Service) recordSend(send *CampaignSend) {
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "campaign_id"}, {Name: "guest_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "reason", "delivery_id", "updated_at"}),
+	}).Create(send).Error
+	if err != nil {
+		s.logger.Error("Failed to record campaign send", "error", err, "campaign", send.CampaignID, "guest", send.GuestID)
+	}
+}
+
+// finish marks the campaign sent and totals its send history. Guests left
+// "sending" by an interrupted runner may or may not have been contacted;
+// they are counted as failed rather than risk contacting them twice.
+func (s *Service) finish(id uint) error {
+	err := s.db.Model(&CampaignSend{}).
+		Where("campaign_id = ? AND status = ?", id, "sending").
+		Updates(map[string]interface{}{"status": "failed", "reason": "interrupted while sending; delivery unknown"}).Error
+	if err != nil {
+		return err
+	}
+
+	var counts []struct {
+		Status string
+		Count  int
+	}
+	err = s.db.Model(&CampaignSend{}).Select("status, COUNT(*) AS count").
+		Where("campaign_id = ?", id).Group("status").Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"status": "sent", "sent_at": time.Now(), "sent": 0, "skipped": 0, "failed": 0, "locked_by": "", "heartbeat_at": nil}
+	for _, c := range counts {
+		switch c.Status {
+		case "sent":
+			updates["sent"] = c.Count
+		case "suppressed":
+			updates["skipped"] = c.Count
+		case "failed":
+			updates["failed"] = c.Count
+		}
+	}
+
+	result := s.db.Model(&ReminderCampaign{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, "sending", s.runnerID).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		s.logger.Warn("Reminder campaign taken over by another runner", "campaign", id)
+		return nil
+	}
+
+	s.logger.Info("Reminder campaign sent", "campaign", id, "sent", updates["sent"], "skipped", updates["skipped"], "failed", updates["failed"])
+	return nil
+}
+
+func (s *Service) revertClaim(id uint) {
+	err := s.db.Model(&ReminderCampaign{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, "sending", s.runnerID).
+		Updates(map[string]interface{}{"status": "scheduled", "locked_by": "", "heartbeat_at": nil}).Error
+	if err != nil {
+		s.logger.Error("Failed to release campaign", "error", err, "campaign", id)
+	}
+}
+
+// DueCampaignIDs returns scheduled campaigns whose send time has passed.
+func (s *Service) DueCampaignIDs(now time.Time) ([]uint, error) {
+	var ids []uint
+	err := s.db.Model(&ReminderCampaign{}).
+		Where("status = ? AND send_at <= ?", "scheduled", now).
+		Order("send_at ASC").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ResumeInterrupted puts campaigns whose runner stopped renewing its lease,
+// most likely because the process died mid-send, back on the schedule.
+// Guests they already reached are skipped when they run. Campaigns still
+// being sent elsewhere are left alone.
+func (s *Service) ResumeInterrupted() error {
+	cutoff := time.Now().Add(-campaignLease)
+	result := s.db.Model(&ReminderCampaign{}).
+		Where("status = ? AND COALESCE(heartbeat_at, started_at) < ?", "sending", cutoff).
+		Updates(map[string]interface{}{"status": "scheduled", "locked_by": "", "heartbeat_at": nil})
+	if result.RowsAffected > 0 {
+		s.logger.Warn("Resuming interrupted reminder campaigns", "count", result.RowsAffected)
+	}
+	return result.Error
+}
+
+// SendListOptions controls sorting on campaign send history.
+var SendListOptions = pagination.Options{
+	Sortable: map[string]string{
+		"createdAt": "campaign_sends.created_at",
+		"status":    "campaign_sends.status",
+	},
+	DefaultSort: "-createdAt",
+	IDColumn:    "campaign_sends.id",
+}
+
+// ListSends returns send history for a campaign, a guest, or both.
+func (s *Service) ListSends(params *pagination.Params, campaignID, guestID uint) (*pagination.Page[CampaignSend], error) {
+	query := s.db.Model(&CampaignSend{})
+	if campaignID != 0 {
+		query = query.Where("campaign_sends.campaign_id = ?", campaignID)
+	}
+	if guestID != 0 {
+		query = query.Where("campaign_sends.guest_id = ?", guestID)
+	}
+
+	var page pagination.Page[CampaignSend]
+	err := pagination.Find(query, params, &page, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Delivery").
+			Preload("Campaign", func(db *gorm.DB) *gorm.DB {
+				return db.Unscoped().Select("id", "name", "channel")
+			}).
+			Preload("Guest", func(db *gorm.DB) *gorm.DB {
+				return db.Unscoped().Select("id", "first_name", "last_name", "email", "phone")
+			})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
@@ -0,0 +1,9 @@
+package event
+
+import (
+	"wedding-app/internal/models"
+)
+
+type Event = models.Event
+type EventAttendance = models.EventAttendance
+type EventStats = models.EventStats
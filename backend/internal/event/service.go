@@ -0,0 +1,219 @@
+package event
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"wedding-app/pkg/logger"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+type Service struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+func NewService(db *gorm.DB, logger logger.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (s *Service) GetAllEvents() ([]Event, error) {
+	return ListEvents(s.db)
+}
+
+func (s *Service) GetEventByID(id uint) (*Event, error) {
+	var event Event
+	if err := s.db.First(&event, id).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+type EventInput struct {
+	Slug        string     `json:"slug"`
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Description string     `json:"description"`
+	Location    string     `json:"location"`
+	StartsAt    *time.Time `json:"startsAt"`
+	SortOrder   *int       `json:"sortOrder"`
+}
+
+func (s *Service) CreateEvent(input EventInput) (*Event, error) {
+	event := Event{Type: "event"}
+	if err := applyInput(&event, input); err != nil {
+		return nil, err
+	}
+	if event.Slug == "" || event.Name == "" {
+		return nil, fmt.Errorf("slug and name are required")
+	}
+	if err := s.ensureSlugFree(event.Slug, 0); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&event).Error; err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Event created", "event", event.Slug)
+	return &event, nil
+}
+
+func (s *Service) UpdateEvent(id uint, input EventInput) (*Event, error) {
+	event, err := s.GetEventByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyInput(event, input); err != nil {
+		return nil, err
+	}
+	if err := s.ensureSlugFree(event.Slug, id); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(event).Error; err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// DeleteEvent removes the event and every guest answer recorded for it.
+func (s *Service) DeleteEvent(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("event_id = ?", id).Delete(&EventAttendance{}).Error; err != nil {
+			return fmt.Errorf("failed to delete attendance: %w", err)
+		}
+
+		result := tx.Delete(&Event{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// GetEventStats returns attendance totals for every event.
+func (s *Service) GetEventStats() ([]EventStats, error) {
+	return Stats(s.db)
+}
+
+func (s *Service) ensureSlugFree(slug string, exceptID uint) error {
+	var count int64
+	err := s.db.Model(&Event{}).Where("slug = ? AND id <> ?", slug, exceptID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("an event with slug %q already exists", slug)
+	}
+	return nil
+}
+
+func applyInput(event *Event, input EventInput) error {
+	if slug := strings.ToLower(strings.TrimSpace(input.Slug)); slug != "" {
+		if !slugPattern.MatchString(slug) {
+			return fmt.Errorf("slug may only contain lowercase letters, numbers and dashes")
+		}
+		event.Slug = slug
+	}
+	if name := strings.TrimSpace(input.Name); name != "" {
+		event.Name = name
+	}
+	if input.Type != "" {
+		if input.Type != "event" && input.Type != "accommodation" {
+			return fmt.Errorf("type must be event or accommodation")
+		}
+		event.Type = input.Type
+	}
+	if input.Description != "" {
+		event.Description = input.Description
+	}
+	if input.Location != "" {
+		event.Location = input.Location
+	}
+	if input.StartsAt != nil {
+		event.StartsAt = input.StartsAt
+	}
+	if input.SortOrder != nil {
+		event.SortOrder = *input.SortOrder
+	}
+	return nil
+}
+
+// ListEvents returns the configured events in schedule order.
+func ListEvents(db *gorm.DB) ([]Event, error) {
+	var events []Event
+	err := db.Order("sort_order ASC, starts_at ASC NULLS LAST, id ASC").Find(&events).Error
+	return events, err
+}
+
+// SetAttendance records a guest's answers, keyed by event slug. Events not
+// mentioned keep their previous answer. Unknown slugs are rejected so typos
+// in a form don't silently disappear.
+func SetAttendance(tx *gorm.DB, guestID uint, answers map[string]bool) error {
+	if len(answers) == 0 {
+		return nil
+	}
+
+	slugs := make([]string, 0, len(answers))
+	for slug := range answers {
+		slugs = append(slugs, slug)
+	}
+
+	var events []Event
+	if err := tx.Where("slug IN ?", slugs).Find(&events).Error; err != nil {
+		return err
+	}
+	if len(events) != len(slugs) {
+		known := make(map[string]bool, len(events))
+		for _, e := range events {
+			known[e.Slug] = true
+		}
+		for _, slug := range slugs {
+			if !known[slug] {
+				return fmt.Errorf("unknown event %q", slug)
+			}
+		}
+	}
+
+	rows := make([]EventAttendance, 0, len(events))
+	for _, e := range events {
+		rows = append(rows, EventAttendance{
+			GuestID:   guestID,
+			EventID:   e.ID,
+			Attending: answers[e.Slug],
+		})
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "guest_id"}, {Name: "event_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"attending", "updated_at"}),
+	}).Create(&rows).Error
+}
+
+// Stats counts, for every event, the guests who said they will attend and
+// the headcount their parties add up to. Only guests who RSVP'd yes count.
+func Stats(db *gorm.DB) ([]EventStats, error) {
+	var stats []EventStats
+	err := db.Model(&Event{}).
+		Select("events.id AS event_id, events.slug, events.name, events.type, " +
+			"COUNT(guests.id) AS attending, COALESCE(SUM(GREATEST(guests.party_size, 1)), 0) AS headcount").
+		Joins("LEFT JOIN event_attendances ON event_attendances.event_id = events.id AND event_attendances.attending").
+		Joins("LEFT JOIN guests ON guests.id = event_attendances.guest_id AND guests.deleted_at IS NULL AND guests.rsvp_status = 'yes'").
+		Group("events.id").
+		Order("events.sort_order ASC, events.id ASC").
+		Scan(&stats).Error
+	return stats, err
+}
@@ -1,12 +1,18 @@
 package guest
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"wedding-app/internal/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"wedding-app/internal/auth"
+	"wedding-app/internal/questionnaire"
+	"wedding-app/pkg/notify"
+	"wedding-app/pkg/pagination"
 )
 
 type Handler struct {
@@ -19,41 +25,94 @@ func NewHandler(service *Service) *Handler {
 	}
 }
 
+// GetGuests lists one page of guests, optionally filtered by a segment query
+// in "q" (e.g. "tag:family rsvp:pending") or a saved segment ID in "segment".
 func (h *Handler) GetGuests(c *gin.Context) {
-	guests, err := h.service.GetAllGuests()
+	segment, err := h.segmentFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	params, err := pagination.Parse(c, GuestListOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.ListGuests(segment, params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch guests"})
 		return
 	}
 
-	c.JSON(http.StatusOK, guests)
+	c.JSON(http.StatusOK, page)
 }
 
+// ImportGuests accepts a multipart CSV upload in the "file" field. Passing
+// dryRun=true validates the file and reports errors and duplicates without
+// writing anything.
+func (h *Handler) ImportGuests(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is required"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dryRun", c.PostForm("dryRun")))
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	report, err := h.service.ImportGuests(file, dryRun)
+	if err != nil {
+		if errors.Is(err, ErrImportInvalid) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":  err.Error(),
+				"report": report,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"message": fmt.Sprintf("%d guests ready to import", report.Valid),
+			"report":  report,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": fmt.Sprintf("%d guests imported", report.Imported),
+		"report":  report,
+	})
+}
 
 type RegisterGuestRequest struct {
 	FirstName string `json:"firstName" binding:"required"`
 	LastName  string `json:"lastName" binding:"required"`
 	Email     string `json:"email" binding:"required,email"`
 	Phone     string `json:"phone"`
+	SMSOptIn  bool   `json:"smsOptIn"` // agree to RSVP links and reminders by text
 	
 	// Updated questionnaire fields
 	PartySize                    int                        `json:"partySize"`
 	PartyMembers                 models.PartyMembers        `json:"partyMembers"`
 	MainPersonDietaryPreference  string                     `json:"mainPersonDietaryPreference"`
-	Dec24                        EventAttendance            `json:"dec24"`
-	Dec25                        EventAttendance            `json:"dec25"`
-	Accommodation                AccommodationData          `json:"accommodation"`
 	Concerns                     string                     `json:"concerns"`
-}
 
-type EventAttendance struct {
-	Attendance bool `json:"attendance"`
-}
+	// Events maps event slug (see GET /api/events) to attendance
+	Events                       map[string]bool            `json:"events"`
 
-type AccommodationData struct {
-	Dec23 bool `json:"dec23"`
-	Dec24 bool `json:"dec24"`
-	Dec25 bool `json:"dec25"`
+	// Answers to the custom questions (see GET /api/questions), by key
+	Answers                      models.Answers             `json:"answers"`
 }
 
 func (h *Handler) RegisterGuest(c *gin.Context) {
@@ -64,12 +123,17 @@ func (h *Handler) RegisterGuest(c *gin.Context) {
 		return
 	}
 
-	fmt.Printf("Received registration request: %+v\n", req)
-	fmt.Printf("Party size: %d, Dec24 attendance: %t, Dec25 attendance: %t, Concerns: %s\n", 
-		req.PartySize, req.Dec24.Attendance, req.Dec25.Attendance, req.Concerns)
-
 	guest, err := h.service.RegisterGuestWithQuestionnaire(req)
 	if err != nil {
+		var invalid *questionnaire.ValidationError
+		if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": invalid.Error(), "question": invalid})
+			return
+		}
+		if errors.Is(err, notify.ErrInvalidPhone) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please enter a valid phone number, including the country code"})
+			return
+		}
 		fmt.Printf("Service error: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register guest"})
 		return
@@ -147,9 +211,60 @@ func (h *Handler) GetGuestPortal(c *gin.Context) {
 		"lastName":  guest.LastName,
 		"email":     guest.Email,
 		"phone":     guest.Phone,
+		"smsOptIn":  guest.SMSOptIn,
 	})
 }
 
+type SMSPreferenceRequest struct {
+	OptIn *bool `json:"optIn" binding:"required"`
+}
+
+// SetSMSOptIn lets an admin record a guest's SMS preference.
+func (h *Handler) SetSMSOptIn(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid guest ID"})
+		return
+	}
+
+	var req SMSPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	guest, err := h.service.SetSMSOptIn(uint(id), *req.OptIn)
+	if err != nil {
+		respondNotFoundOr(c, err, "Guest not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, guest)
+}
+
+// SetPortalSMSOptIn lets guests opt in to or out of text messages from their
+// portal.
+func (h *Handler) SetPortalSMSOptIn(c *gin.Context) {
+	guest, err := h.service.GetGuestByPortalToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid guest portal link"})
+		return
+	}
+
+	var req SMSPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.service.SetSMSOptIn(guest.ID, *req.OptIn); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"smsOptIn": *req.OptIn})
+}
+
 func (h *Handler) DeleteAllGuests(c *gin.Context) {
 	err := h.service.DeleteAllGuests()
 	if err != nil {
@@ -191,4 +306,419 @@ func (h *Handler) DeleteSelectedGuests(c *gin.Context) {
 		"message": fmt.Sprintf("%d guests deleted successfully", len(req.GuestIDs)),
 		"count":   len(req.GuestIDs),
 	})
-}
\ No newline at end of file
+}
+type HouseholdRequest struct {
+	Name     string `json:"name"`
+	GuestIDs []uint `json:"guestIds"`
+}
+
+func (h *Handler) GetHouseholds(c *gin.Context) {
+	households, err := h.service.GetAllHouseholds()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch households"})
+		return
+	}
+
+	c.JSON(http.StatusOK, households)
+}
+
+func (h *Handler) GetHousehold(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid household ID"})
+		return
+	}
+
+	household, err := h.service.GetHouseholdByID(uint(id))
+	if err != nil {
+		respondNotFoundOr(c, err, "Household not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, household)
+}
+
+func (h *Handler) CreateHousehold(c *gin.Context) {
+	var req HouseholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	household, err := h.service.CreateHousehold(req.Name, req.GuestIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, household)
+}
+
+func (h *Handler) UpdateHousehold(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid household ID"})
+		return
+	}
+
+	var req HouseholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	household, err := h.service.UpdateHousehold(uint(id), req.Name, req.GuestIDs)
+	if err != nil {
+		respondNotFoundOr(c, err, "Household not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, household)
+}
+
+func (h *Handler) DeleteHousehold(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid household ID"})
+		return
+	}
+
+	if err := h.service.DeleteHousehold(uint(id)); err != nil {
+		respondNotFoundOr(c, err, "Household not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Household deleted successfully"})
+}
+
+func (h *Handler) AddHouseholdMembers(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid household ID"})
+		return
+	}
+
+	var req HouseholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	household, err := h.service.AddHouseholdMembers(uint(id), req.GuestIDs)
+	if err != nil {
+		respondNotFoundOr(c, err, "Household not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, household)
+}
+
+func (h *Handler) RemoveHouseholdMember(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid household ID"})
+		return
+	}
+
+	guestID, err := strconv.ParseUint(c.Param("guestId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid guest ID"})
+		return
+	}
+
+	if err := h.service.RemoveHouseholdMember(uint(id), uint(guestID)); err != nil {
+		respondNotFoundOr(c, err, "Household not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Guest removed from household"})
+}
+
+func (h *Handler) RegenerateHouseholdToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid household ID"})
+		return
+	}
+
+	household, err := h.service.RegenerateHouseholdToken(uint(id))
+	if err != nil {
+		respondNotFoundOr(c, err, "Household not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, household)
+}
+
+
+func (h *Handler) segmentFromQuery(c *gin.Context) (*Segment, error) {
+	var segmentID uint64
+	if v := c.Query("segment"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid segment ID")
+		}
+		segmentID = id
+	}
+	return h.service.ResolveSegment(c.Query("q"), uint(segmentID))
+}
+
+type TagRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+func (h *Handler) GetTags(c *gin.Context) {
+	tags, err := h.service.GetAllTags()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+func (h *Handler) CreateTag(c *gin.Context) {
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.service.CreateTag(req.Name, req.Color)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+func (h *Handler) UpdateTag(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.service.UpdateTag(uint(id), req.Name, req.Color)
+	if err != nil {
+		respondNotFoundOr(c, err, "Tag not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+func (h *Handler) DeleteTag(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	if err := h.service.DeleteTag(uint(id)); err != nil {
+		respondNotFoundOr(c, err, "Tag not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
+
+type SetGuestTagsRequest struct {
+	TagIDs []uint `json:"tagIds"`
+}
+
+func (h *Handler) SetGuestTags(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid guest ID"})
+		return
+	}
+
+	var req SetGuestTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	guest, err := h.service.SetGuestTags(uint(id), req.TagIDs)
+	if err != nil {
+		respondNotFoundOr(c, err, "Guest not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, guest)
+}
+
+type SegmentRequest struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+func (h *Handler) GetSegments(c *gin.Context) {
+	segments, err := h.service.GetAllSegments()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch segments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, segments)
+}
+
+func (h *Handler) CreateSegment(c *gin.Context) {
+	var req SegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	segment, err := h.service.CreateSegment(req.Name, req.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, segment)
+}
+
+func (h *Handler) UpdateSegment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid segment ID"})
+		return
+	}
+
+	var req SegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	segment, err := h.service.UpdateSegment(uint(id), req.Name, req.Query)
+	if err != nil {
+		respondNotFoundOr(c, err, "Segment not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, segment)
+}
+
+func (h *Handler) DeleteSegment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid segment ID"})
+		return
+	}
+
+	if err := h.service.DeleteSegment(uint(id)); err != nil {
+		respondNotFoundOr(c, err, "Segment not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Segment deleted successfully"})
+}
+
+type BulkGuestsRequest struct {
+	GuestSelection
+	Action string `json:"action" binding:"required,oneof=delete approve reject tag untag export"`
+	TagIDs []uint `json:"tagIds"`
+}
+
+// BulkGuests applies one action to every selected guest. Guests are selected
+// by explicit IDs, an inline segment query, or a saved segment.
+func (h *Handler) BulkGuests(c *gin.Context) {
+	var req BulkGuestsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// The route only needs guests:write; deleting needs more
+	if req.Action == "delete" && !auth.HasPermission(c, auth.PermGuestsDelete) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to do this", "permission": auth.PermGuestsDelete})
+		return
+	}
+
+	guestIDs, err := h.service.ResolveSelection(req.GuestSelection)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(guestIDs) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "No guests matched", "result": BulkResult{}})
+		return
+	}
+
+	var result *BulkResult
+	switch req.Action {
+	case "export":
+		h.writeGuestsCSV(c, guestIDs)
+		return
+	case "approve":
+		result = h.service.ApproveGuests(guestIDs)
+	case "reject":
+		result = h.service.RejectGuests(guestIDs)
+	case "delete":
+		err = h.service.DeleteSelectedGuests(guestIDs)
+	case "tag":
+		err = h.service.TagGuests(guestIDs, req.TagIDs)
+	case "untag":
+		err = h.service.UntagGuests(guestIDs, req.TagIDs)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if result == nil {
+		result = &BulkResult{Matched: len(guestIDs), Succeeded: len(guestIDs)}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("%s applied to %d of %d guests", req.Action, result.Succeeded, result.Matched),
+		"result":  result,
+	})
+}
+
+// ExportGuests downloads the guests matching "q" or "segment" (or every guest
+// when neither is given) as CSV.
+func (h *Handler) ExportGuests(c *gin.Context) {
+	segment, err := h.segmentFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	guestIDs, err := h.service.GuestIDsInSegment(segment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch guests"})
+		return
+	}
+
+	h.writeGuestsCSV(c, guestIDs)
+}
+
+func (h *Handler) writeGuestsCSV(c *gin.Context, guestIDs []uint) {
+	csvData, err := h.service.ExportGuestsToCSV(guestIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export guests"})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment; filename=guests.csv")
+	c.String(http.StatusOK, csvData)
+}
+
+func respondNotFoundOr(c *gin.Context, err error, notFound string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
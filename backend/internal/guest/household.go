@@ -0,0 +1,193 @@
+package guest
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+func (s *Service) GetAllHouseholds() ([]Household, error) {
+	var households []Household
+	err := s.db.Preload("Guests").Order("name ASC").Find(&households).Error
+	return households, err
+}
+
+func (s *Service) GetHouseholdByID(id uint) (*Household, error) {
+	var household Household
+	err := s.db.Preload("Guests").First(&household, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &household, nil
+}
+
+// CreateHousehold creates a household with its own invite token and moves the
+// given guests into it.
+func (s *Service) CreateHousehold(name string, guestIDs []uint) (*Household, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("household name is required")
+	}
+
+	inviteToken, err := s.generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invite token: %w", err)
+	}
+
+	household := Household{
+		Name:        name,
+		InviteToken: inviteToken,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&household).Error; err != nil {
+			return err
+		}
+		return assignHouseholdMembers(tx, household.ID, guestIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Household created", "household", household.ID, "members", len(guestIDs))
+	return s.GetHouseholdByID(household.ID)
+}
+
+// UpdateHousehold renames a household and, when guestIDs is non-nil, replaces
+// its member list.
+func (s *Service) UpdateHousehold(id uint, name string, guestIDs []uint) (*Household, error) {
+	var household Household
+	if err := s.db.First(&household, id).Error; err != nil {
+		return nil, err
+	}
+
+	if name = strings.TrimSpace(name); name != "" {
+		household.Name = name
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&household).Error; err != nil {
+			return err
+		}
+		if guestIDs == nil {
+			return nil
+		}
+
+		err := tx.Model(&Guest{}).Where("household_id = ?", id).Update("household_id", nil).Error
+		if err != nil {
+			return fmt.Errorf("failed to clear household members: %w", err)
+		}
+		return assignHouseholdMembers(tx, id, guestIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetHouseholdByID(id)
+}
+
+// DeleteHousehold removes the household but keeps its guests, who go back to
+// answering with their individual invite tokens.
+func (s *Service) DeleteHousehold(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Guest{}).Where("household_id = ?", id).Update("household_id", nil).Error
+		if err != nil {
+			return fmt.Errorf("failed to clear household members: %w", err)
+		}
+
+		result := tx.Delete(&Household{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (s *Service) AddHouseholdMembers(id uint, guestIDs []uint) (*Household, error) {
+	if len(guestIDs) == 0 {
+		return nil, fmt.Errorf("no guest IDs provided")
+	}
+
+	var household Household
+	if err := s.db.First(&household, id).Error; err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return assignHouseholdMembers(tx, id, guestIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetHouseholdByID(id)
+}
+
+func (s *Service) RemoveHouseholdMember(id, guestID uint) error {
+	result := s.db.Model(&Guest{}).
+		Where("id = ? AND household_id = ?", guestID, id).
+		Update("household_id", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("guest is not a member of this household")
+	}
+	return nil
+}
+
+// RegenerateHouseholdToken invalidates the current household invite link.
+func (s *Service) RegenerateHouseholdToken(id uint) (*Household, error) {
+	var household Household
+	if err := s.db.First(&household, id).Error; err != nil {
+		return nil, err
+	}
+
+	inviteToken, err := s.generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invite token: %w", err)
+	}
+
+	household.InviteToken = inviteToken
+	if err := s.db.Save(&household).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetHouseholdByID(id)
+}
+
+func assignHouseholdMembers(tx *gorm.DB, householdID uint, guestIDs []uint) error {
+	if len(guestIDs) == 0 {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&Guest{}).Where("id IN ?", guestIDs).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(uniqueIDs(guestIDs)) {
+		return fmt.Errorf("one or more guests do not exist")
+	}
+
+	err := tx.Model(&Guest{}).Where("id IN ?", guestIDs).Update("household_id", householdID).Error
+	if err != nil {
+		return fmt.Errorf("failed to assign household members: %w", err)
+	}
+	return nil
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	var result []uint
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
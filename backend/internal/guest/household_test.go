@@ -0,0 +1,107 @@
+package guest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreateHousehold(t *testing.T) {
+	s, db := newTestService(t)
+	db.OnQuery(`INSERT INTO "households"`, []string{"id"}, []interface{}{int64(5)})
+	db.OnQuery(`SELECT count(*) FROM "guests"`, []string{"count"}, []interface{}{int64(2)})
+	db.OnQuery(`FROM "households" WHERE "households"."id" = $1`, []string{"id", "name", "invite_token"},
+		[]interface{}{int64(5), "The Lees", "token"})
+	db.OnQuery(`FROM "guests" WHERE "guests"."household_id" = $1`, []string{"id", "first_name", "household_id"},
+		[]interface{}{int64(1), "Ann", int64(5)},
+		[]interface{}{int64(2), "Bo", int64(5)},
+	)
+
+	// Repeated IDs count once when checking the guests exist
+	household, err := s.CreateHousehold("  The Lees ", []uint{1, 2, 2})
+	if err != nil {
+		t.Fatalf("CreateHousehold: %v\nstatements: %q", err, db.SQL())
+	}
+	if household.ID != 5 || len(household.Guests) != 2 {
+		t.Errorf("household = %+v", household)
+	}
+
+	if insert := db.Find(`INSERT INTO "households"`); len(insert) != 1 {
+		t.Errorf("%d household inserts, want 1", len(insert))
+	}
+	if household.Name != "The Lees" || len(household.InviteToken) == 0 {
+		t.Errorf("household = %+v, want a trimmed name and an invite token", household)
+	}
+	assign := db.Find(`UPDATE "guests" SET "household_id"=$1`)
+	if len(assign) != 1 || assign[0].Args[0] != uint(5) ||
+		!reflect.DeepEqual(assign[0].Args[2:], []interface{}{uint(1), uint(2), uint(2)}) {
+		t.Errorf("member assignment = %+v", assign)
+	}
+	if got := db.SQL(); !contains(got, "COMMIT") || contains(got, "ROLLBACK") {
+		t.Errorf("household not created in one transaction: %q", got)
+	}
+}
+
+func TestCreateHouseholdRejects(t *testing.T) {
+	s, db := newTestService(t)
+	if _, err := s.CreateHousehold("  ", []uint{1}); err == nil || !strings.Contains(err.Error(), "name is required") {
+		t.Errorf("blank name: err = %v", err)
+	}
+	if len(db.SQL()) != 0 {
+		t.Errorf("blank name reached the database: %q", db.SQL())
+	}
+
+	// Only one of the two guests exists
+	db.OnQuery(`INSERT INTO "households"`, []string{"id"}, []interface{}{int64(5)})
+	db.OnQuery(`SELECT count(*) FROM "guests"`, []string{"count"}, []interface{}{int64(1)})
+	if _, err := s.CreateHousehold("The Lees", []uint{1, 99}); err == nil || !strings.Contains(err.Error(), "do not exist") {
+		t.Errorf("missing guest: err = %v", err)
+	}
+	if got := db.SQL(); !contains(got, "ROLLBACK") || len(db.Find(`UPDATE "guests"`)) != 0 {
+		t.Errorf("household kept without its members: %q", got)
+	}
+}
+
+func TestUpdateHouseholdReplacesMembers(t *testing.T) {
+	s, db := newTestService(t)
+	db.OnQuery(`FROM "households" WHERE "households"."id" = $1`, []string{"id", "name", "invite_token"},
+		[]interface{}{int64(5), "The Lees", "token"})
+	db.OnQuery(`SELECT count(*) FROM "guests"`, []string{"count"}, []interface{}{int64(1)})
+
+	if _, err := s.UpdateHousehold(5, "", []uint{3}); err != nil {
+		t.Fatalf("UpdateHousehold: %v", err)
+	}
+
+	// The old members are cleared before the new ones are assigned, in
+	// the same transaction
+	var steps []string
+	for _, statement := range db.Statements() {
+		switch {
+		case statement.SQL == "BEGIN", statement.SQL == "COMMIT":
+			steps = append(steps, statement.SQL)
+		case strings.HasPrefix(statement.SQL, `UPDATE "guests" SET "household_id"`):
+			if statement.Args[0] == nil {
+				steps = append(steps, "clear")
+			} else {
+				steps = append(steps, "assign")
+			}
+		}
+	}
+	if want := []string{"BEGIN", "clear", "assign", "COMMIT"}; !reflect.DeepEqual(steps, want) {
+		t.Errorf("steps = %q, want %q", steps, want)
+	}
+
+	// Without a member list only the name changes
+	s, db = newTestService(t)
+	db.OnQuery(`FROM "households" WHERE "households"."id" = $1`, []string{"id", "name", "invite_token"},
+		[]interface{}{int64(5), "The Lees", "token"})
+	if _, err := s.UpdateHousehold(5, "Lee family", nil); err != nil {
+		t.Fatalf("UpdateHousehold: %v", err)
+	}
+	if updates := db.Find(`UPDATE "guests"`); len(updates) != 0 {
+		t.Errorf("members changed on a rename: %+v", updates)
+	}
+	if saves := db.Find(`UPDATE "households"`); len(saves) != 1 || saves[0].Args[0] != "Lee family" {
+		t.Errorf("rename = %+v", saves)
+	}
+}
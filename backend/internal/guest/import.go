@@ -0,0 +1,411 @@
+package guest
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"wedding-app/internal/models"
+	"wedding-app/pkg/notify"
+)
+
+// importColumns maps the accepted (normalized) CSV header names onto the
+// guest field they populate. Headers are matched case-insensitively with
+// spaces, dashes and underscores ignored, so "First Name", "first_name" and
+// "firstName" all work.
+var importColumns = map[string]string{
+	"firstname":                   "firstName",
+	"lastname":                    "lastName",
+	"email":                       "email",
+	"phone":                       "phone",
+	"side":                        "side",
+	"partysize":                   "partySize",
+	"maxpartysize":                "maxPartySize",
+	"partymembers":                "partyMembers",
+	"dietarypreference":           "mainPersonDietaryPreference",
+	"maindietarypreference":       "mainPersonDietaryPreference",
+	"mainpersondietary":           "mainPersonDietaryPreference",
+	"mainpersondietarypreference": "mainPersonDietaryPreference",
+	"concerns":                    "concerns",
+	"specialconcerns":             "concerns",
+}
+
+// maxImportRows caps the size of a single import so a bad upload can't hold
+// a transaction open forever.
+const maxImportRows = 2000
+
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportDuplicate struct {
+	Row             int    `json:"row"`
+	Name            string `json:"name"`
+	Email           string `json:"email"`
+	Reason          string `json:"reason"`
+	ExistingGuestID uint   `json:"existingGuestId,omitempty"`
+	DuplicateOfRow  int    `json:"duplicateOfRow,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun     bool              `json:"dryRun"`
+	TotalRows  int               `json:"totalRows"`
+	Valid      int               `json:"valid"`
+	Imported   int               `json:"imported"`
+	Skipped    int               `json:"skipped"`
+	Errors     []ImportRowError  `json:"errors"`
+	Duplicates []ImportDuplicate `json:"duplicates"`
+	Guests     []Guest           `json:"guests,omitempty"`
+}
+
+// HasErrors reports whether any row failed validation. A report with errors
+// is never committed.
+func (r *ImportReport) HasErrors() bool {
+	return len(r.Errors) > 0
+}
+
+// ErrImportInvalid is returned by ImportGuests when at least one row failed
+// validation; the accompanying report lists the offending rows.
+var ErrImportInvalid = errors.New("import contains invalid rows")
+
+type importRow struct {
+	row   int
+	guest Guest
+}
+
+// ImportGuests parses a CSV guest list and, unless dryRun is set, creates
+// every valid non-duplicate row in a single transaction. Imported guests are
+// pre-approved and receive fresh invite and portal tokens.
+func (s *Service) ImportGuests(r io.Reader, dryRun bool) (*ImportReport, error) {
+	report := &ImportReport{
+		DryRun:     dryRun,
+		Errors:     []ImportRowError{},
+		Duplicates: []ImportDuplicate{},
+	}
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("CSV file is empty")
+		}
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns, err := mapImportHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []importRow
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			report.Errors = append(report.Errors, ImportRowError{Row: line, Message: err.Error()})
+			continue
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		report.TotalRows++
+		if report.TotalRows > maxImportRows {
+			return nil, fmt.Errorf("import is limited to %d rows", maxImportRows)
+		}
+
+		g, rowErrs := parseImportRecord(line, record, columns)
+		if len(rowErrs) > 0 {
+			report.Errors = append(report.Errors, rowErrs...)
+			continue
+		}
+		rows = append(rows, importRow{row: line, guest: g})
+	}
+
+	unique, err := s.findImportDuplicates(rows, report)
+	if err != nil {
+		return nil, err
+	}
+	report.Valid = len(unique)
+	report.Skipped = report.TotalRows - report.Valid
+
+	if report.HasErrors() {
+		return report, ErrImportInvalid
+	}
+
+	if dryRun || len(unique) == 0 {
+		return report, nil
+	}
+
+	now := time.Now()
+	guests := make([]Guest, 0, len(unique))
+	for _, row := range unique {
+		g := row.guest
+		if g.InviteToken, err = s.generateToken(); err != nil {
+			return nil, fmt.Errorf("failed to generate invite token: %w", err)
+		}
+		if g.GuestPortalToken, err = s.generateToken(); err != nil {
+			return nil, fmt.Errorf("failed to generate portal token: %w", err)
+		}
+		g.RegistrationStatus = "approved"
+		g.ApprovedAt = &now
+		guests = append(guests, g)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&guests, 100).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import guests: %w", err)
+	}
+
+	report.Imported = len(guests)
+	report.Guests = guests
+
+	s.logger.Info("Guests imported", "imported", report.Imported, "skipped", report.Skipped)
+	return report, nil
+}
+
+// findImportDuplicates records rows that repeat an earlier row in the file or
+// match a guest already in the database, and returns the rows left to import.
+func (s *Service) findImportDuplicates(rows []importRow, report *ImportReport) ([]importRow, error) {
+	var emails []string
+	for _, row := range rows {
+		if row.guest.Email != "" {
+			emails = append(emails, row.guest.Email)
+		}
+	}
+
+	existingByEmail := map[string]uint{}
+	if len(emails) > 0 {
+		var existing []Guest
+		err := s.db.Select("id", "email").Where("LOWER(email) IN ?", emails).Find(&existing).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existing guests: %w", err)
+		}
+		for _, g := range existing {
+			existingByEmail[strings.ToLower(g.Email)] = g.ID
+		}
+	}
+
+	var existingNames []Guest
+	err := s.db.Select("id", "first_name", "last_name").Find(&existingNames).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing guests: %w", err)
+	}
+	existingByName := map[string]uint{}
+	for _, g := range existingNames {
+		existingByName[nameKey(g.FirstName, g.LastName)] = g.ID
+	}
+
+	seenEmail := map[string]int{}
+	seenName := map[string]int{}
+	var unique []importRow
+
+	for _, row := range rows {
+		g := row.guest
+		dup := ImportDuplicate{
+			Row:   row.row,
+			Name:  g.FirstName + " " + g.LastName,
+			Email: g.Email,
+		}
+		key := nameKey(g.FirstName, g.LastName)
+
+		switch {
+		case g.Email != "" && existingByEmail[g.Email] != 0:
+			dup.Reason = "email already exists"
+			dup.ExistingGuestID = existingByEmail[g.Email]
+		case existingByName[key] != 0:
+			dup.Reason = "name already exists"
+			dup.ExistingGuestID = existingByName[key]
+		case g.Email != "" && seenEmail[g.Email] != 0:
+			dup.Reason = "email repeated in file"
+			dup.DuplicateOfRow = seenEmail[g.Email]
+		case seenName[key] != 0:
+			dup.Reason = "name repeated in file"
+			dup.DuplicateOfRow = seenName[key]
+		default:
+			if g.Email != "" {
+				seenEmail[g.Email] = row.row
+			}
+			seenName[key] = row.row
+			unique = append(unique, row)
+			continue
+		}
+
+		report.Duplicates = append(report.Duplicates, dup)
+	}
+
+	return unique, nil
+}
+
+func mapImportHeader(header []string) (map[string]int, error) {
+	columns := map[string]int{}
+	for i, name := range header {
+		field, ok := importColumns[normalizeHeader(name)]
+		if !ok {
+			continue
+		}
+		if _, dup := columns[field]; dup {
+			return nil, fmt.Errorf("column %q appears more than once", name)
+		}
+		columns[field] = i
+	}
+
+	for _, required := range []string{"firstName", "lastName"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing required column %q", required)
+		}
+	}
+
+	return columns, nil
+}
+
+func parseImportRecord(line int, record []string, columns map[string]int) (Guest, []ImportRowError) {
+	get := func(field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var errs []ImportRowError
+	fail := func(field, message string) {
+		errs = append(errs, ImportRowError{Row: line, Field: field, Message: message})
+	}
+
+	g := Guest{
+		FirstName:                   get("firstName"),
+		LastName:                    get("lastName"),
+		Email:                       strings.ToLower(get("email")),
+		Phone:                       get("phone"),
+		Side:                        strings.ToLower(get("side")),
+		MainPersonDietaryPreference: get("mainPersonDietaryPreference"),
+		Concerns:                    get("concerns"),
+		MaxPartySize:                2,
+	}
+
+	if g.FirstName == "" {
+		fail("firstName", "first name is required")
+	}
+	if g.LastName == "" {
+		fail("lastName", "last name is required")
+	}
+	if g.Email != "" && !looksLikeEmail(g.Email) {
+		fail("email", "invalid email address")
+	}
+	if phone, err := notify.NormalizePhone(g.Phone, notify.DefaultCountryCode()); err != nil {
+		fail("phone", "invalid phone number")
+	} else {
+		g.Phone = phone
+	}
+	if g.Side != "" && !contains(validSides, g.Side) {
+		fail("side", "side must be bride, groom or both")
+	}
+
+	members, err := parsePartyMembers(get("partyMembers"))
+	if err != nil {
+		fail("partyMembers", err.Error())
+	}
+	g.PartyMembers = members
+
+	if v := get("maxPartySize"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			fail("maxPartySize", "max party size must be a positive number")
+		} else {
+			g.MaxPartySize = n
+		}
+	}
+
+	g.PartySize = 1 + len(members)
+	if v := get("partySize"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			fail("partySize", "party size must be a positive number")
+		} else {
+			g.PartySize = n
+		}
+	}
+
+	if get("maxPartySize") == "" && g.PartySize > g.MaxPartySize {
+		g.MaxPartySize = g.PartySize
+	}
+	if g.PartySize > g.MaxPartySize {
+		fail("partySize", fmt.Sprintf("party size %d exceeds max party size %d", g.PartySize, g.MaxPartySize))
+	}
+	if len(members)+1 > g.MaxPartySize {
+		fail("partyMembers", fmt.Sprintf("%d party members exceed max party size %d", len(members), g.MaxPartySize))
+	}
+
+	return g, errs
+}
+
+// parsePartyMembers reads a semicolon-separated list of members, each written
+// as "First Last" with an optional dietary preference after a colon, e.g.
+// "Jane Doe: vegetarian; Sam Doe".
+func parsePartyMembers(value string) (models.PartyMembers, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var members models.PartyMembers
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, dietary, _ := strings.Cut(entry, ":")
+		parts := strings.Fields(name)
+		if len(parts) == 0 {
+			return nil, fmt.Errorf("party member %q has no name", entry)
+		}
+
+		members = append(members, models.PartyMember{
+			FirstName:         parts[0],
+			LastName:          strings.Join(parts[1:], " "),
+			DietaryPreference: strings.TrimSpace(dietary),
+		})
+	}
+
+	return members, nil
+}
+
+func normalizeHeader(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name)
+}
+
+func nameKey(firstName, lastName string) string {
+	return strings.ToLower(strings.TrimSpace(firstName)) + "|" + strings.ToLower(strings.TrimSpace(lastName))
+}
+
+func looksLikeEmail(email string) bool {
+	at := strings.Index(email, "@")
+	return at > 0 && at < len(email)-1 && !strings.ContainsAny(email, " ,;")
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
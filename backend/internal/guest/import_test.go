@@ -0,0 +1,298 @@
+package guest
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"wedding-app/internal/models"
+)
+
+func TestMapImportHeader(t *testing.T) {
+	tests := []struct {
+		header []string
+		want   map[string]int
+		err    string
+	}{
+		{
+			header: []string{"First Name", "Last Name", "Email"},
+			want:   map[string]int{"firstName": 0, "lastName": 1, "email": 2},
+		},
+		{
+			header: []string{"\ufefffirst_name", "LAST-NAME", "Party Members", "Dietary Preference"},
+			want:   map[string]int{"firstName": 0, "lastName": 1, "partyMembers": 2, "mainPersonDietaryPreference": 3},
+		},
+		{
+			header: []string{"Notes", "firstName", "lastName", "Special Concerns"},
+			want:   map[string]int{"firstName": 1, "lastName": 2, "concerns": 3},
+		},
+		{header: []string{"First Name", "first_name", "Last Name"}, err: "appears more than once"},
+		{header: []string{"Dietary Preference", "Main Person Dietary", "First Name", "Last Name"}, err: "appears more than once"},
+		{header: []string{"First Name", "Email"}, err: `missing required column "lastName"`},
+		{header: []string{}, err: `missing required column "firstName"`},
+	}
+	for _, tt := range tests {
+		got, err := mapImportHeader(tt.header)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: error = %v, want %q", tt.header, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.header, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: columns = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestParsePartyMembers(t *testing.T) {
+	tests := []struct {
+		value string
+		want  models.PartyMembers
+		err   bool
+	}{
+		{"", nil, false},
+		{"Jane Doe", models.PartyMembers{{FirstName: "Jane", LastName: "Doe"}}, false},
+		{
+			"Jane Doe: vegetarian; Sam",
+			models.PartyMembers{
+				{FirstName: "Jane", LastName: "Doe", DietaryPreference: "vegetarian"},
+				{FirstName: "Sam"},
+			},
+			false,
+		},
+		{
+			" Mary  Ann Smith :vegan ;; ",
+			models.PartyMembers{{FirstName: "Mary", LastName: "Ann Smith", DietaryPreference: "vegan"}},
+			false,
+		},
+		{"Jane Doe; : vegan", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parsePartyMembers(tt.value)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: error = %v, want error %v", tt.value, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: members = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+var importTestColumns = map[string]int{
+	"firstName":    0,
+	"lastName":     1,
+	"email":        2,
+	"phone":        3,
+	"side":         4,
+	"partySize":    5,
+	"maxPartySize": 6,
+	"partyMembers": 7,
+}
+
+func TestParseImportRecord(t *testing.T) {
+	t.Setenv("PHONE_DEFAULT_COUNTRY_CODE", "91")
+
+	tests := []struct {
+		name   string
+		record []string
+		check  func(Guest) bool
+		errors []string // fields that fail
+	}{
+		{
+			name:   "minimal",
+			record: []string{"Ann", "Lee"},
+			check: func(g Guest) bool {
+				return g.FirstName == "Ann" && g.LastName == "Lee" && g.PartySize == 1 && g.MaxPartySize == 2
+			},
+		},
+		{
+			name:   "normalized",
+			record: []string{" Ann ", "Lee", "Ann@Example.COM", "098765 43210", "Bride"},
+			check: func(g Guest) bool {
+				return g.Email == "ann@example.com" && g.Phone == "+919876543210" && g.Side == "bride"
+			},
+		},
+		{
+			name:   "party size follows members",
+			record: []string{"Ann", "Lee", "", "", "", "", "", "Bo Lee; Cy Lee: vegan"},
+			check: func(g Guest) bool {
+				return g.PartySize == 3 && g.MaxPartySize == 3 && len(g.PartyMembers) == 2
+			},
+		},
+		{
+			name:   "explicit sizes",
+			record: []string{"Ann", "Lee", "", "", "", "2", "4"},
+			check: func(g Guest) bool {
+				return g.PartySize == 2 && g.MaxPartySize == 4
+			},
+		},
+		{
+			name:   "missing names",
+			record: []string{"", " "},
+			errors: []string{"firstName", "lastName"},
+		},
+		{
+			name:   "bad contact details",
+			record: []string{"Ann", "Lee", "ann.example.com", "12ab", "family"},
+			errors: []string{"email", "phone", "side"},
+		},
+		{
+			name:   "bad sizes",
+			record: []string{"Ann", "Lee", "", "", "", "0", "many"},
+			errors: []string{"maxPartySize", "partySize"},
+		},
+		{
+			name:   "party over the limit",
+			record: []string{"Ann", "Lee", "", "", "", "3", "2", "Bo Lee; Cy Lee"},
+			errors: []string{"partySize", "partyMembers"},
+		},
+		{
+			name:   "unnamed member",
+			record: []string{"Ann", "Lee", "", "", "", "", "", ": vegan"},
+			errors: []string{"partyMembers"},
+		},
+	}
+	for _, tt := range tests {
+		g, errs := parseImportRecord(7, tt.record, importTestColumns)
+
+		var fields []string
+		for _, e := range errs {
+			if e.Row != 7 {
+				t.Errorf("%s: error reported on row %d, want 7", tt.name, e.Row)
+			}
+			fields = append(fields, e.Field)
+		}
+		if !reflect.DeepEqual(fields, tt.errors) {
+			t.Errorf("%s: failed fields = %q, want %q (%+v)", tt.name, fields, tt.errors, errs)
+		}
+		if tt.check != nil && !tt.check(g) {
+			t.Errorf("%s: guest = %+v", tt.name, g)
+		}
+	}
+}
+
+func TestImportGuestsDuplicates(t *testing.T) {
+	t.Setenv("PHONE_DEFAULT_COUNTRY_CODE", "91")
+
+	csv := strings.Join([]string{
+		"First Name,Last Name,Email",
+		"Ann,Lee,ann@example.com", // row 2: new
+		"Bo,Kim,BO@example.com",   // row 3: email already exists
+		"Cy,Park,",                // row 4: name already exists
+		"Di,Wu,ann@example.com",   // row 5: repeats row 2's email
+		"ann,LEE,",                // row 6: repeats row 2's name
+		",,",                      // blank, ignored
+		"Ed,Fox,ed@example.com",   // row 8: new
+	}, "\n")
+
+	for _, dryRun := range []bool{true, false} {
+		s, db := newTestService(t)
+		db.OnQuery("LOWER(email) IN", []string{"id", "email"}, []interface{}{int64(11), "bo@example.com"})
+		db.OnQuery(`SELECT "id","first_name","last_name" FROM "guests"`, []string{"id", "first_name", "last_name"},
+			[]interface{}{int64(11), "Bo", "Kim"},
+			[]interface{}{int64(12), "CY", "Park "},
+		)
+
+		report, err := s.ImportGuests(strings.NewReader(csv), dryRun)
+		if err != nil {
+			t.Fatalf("dryRun %v: %v", dryRun, err)
+		}
+		if report.TotalRows != 6 || report.Valid != 2 || report.Skipped != 4 {
+			t.Errorf("dryRun %v: total %d, valid %d, skipped %d; want 6, 2, 4",
+				dryRun, report.TotalRows, report.Valid, report.Skipped)
+		}
+
+		want := []ImportDuplicate{
+			{Row: 3, Name: "Bo Kim", Email: "bo@example.com", Reason: "email already exists", ExistingGuestID: 11},
+			{Row: 4, Name: "Cy Park", Reason: "name already exists", ExistingGuestID: 12},
+			{Row: 5, Name: "Di Wu", Email: "ann@example.com", Reason: "email repeated in file", DuplicateOfRow: 2},
+			{Row: 6, Name: "ann LEE", Reason: "name repeated in file", DuplicateOfRow: 2},
+		}
+		if !reflect.DeepEqual(report.Duplicates, want) {
+			t.Errorf("dryRun %v: duplicates = %+v, want %+v", dryRun, report.Duplicates, want)
+		}
+
+		emailQuery := db.Find("LOWER(email) IN")
+		if len(emailQuery) != 1 || !reflect.DeepEqual(emailQuery[0].Args, []interface{}{
+			"ann@example.com", "bo@example.com", "ann@example.com", "ed@example.com",
+		}) {
+			t.Errorf("dryRun %v: email lookup = %+v", dryRun, emailQuery)
+		}
+
+		inserts := db.Find(`INSERT INTO "guests"`)
+		if dryRun {
+			if len(inserts) != 0 || report.Imported != 0 || report.Guests != nil {
+				t.Errorf("dry run wrote guests: %d inserts, report %+v", len(inserts), report)
+			}
+			continue
+		}
+
+		if len(inserts) != 1 || report.Imported != 2 {
+			t.Fatalf("%d inserts, %d imported; want 1 and 2", len(inserts), report.Imported)
+		}
+		if got := db.SQL(); !contains(got, "BEGIN") || !contains(got, "COMMIT") {
+			t.Errorf("import not run in a transaction: %q", got)
+		}
+		for _, g := range report.Guests {
+			if g.RegistrationStatus != "approved" || g.ApprovedAt == nil ||
+				len(g.InviteToken) != 64 || len(g.GuestPortalToken) != 64 || g.InviteToken == g.GuestPortalToken {
+				t.Errorf("imported guest %s %s = %+v", g.FirstName, g.LastName, g)
+			}
+		}
+		if names := []string{report.Guests[0].FirstName, report.Guests[1].FirstName}; !reflect.DeepEqual(names, []string{"Ann", "Ed"}) {
+			t.Errorf("imported %q, want Ann and Ed", names)
+		}
+	}
+}
+
+func TestImportGuestsInvalidRows(t *testing.T) {
+	t.Setenv("PHONE_DEFAULT_COUNTRY_CODE", "91")
+	s, db := newTestService(t)
+
+	csv := "First Name,Last Name,Email\nAnn,Lee,ann@example.com\nBo,,bo@\n\"Cy,Park\n"
+	report, err := s.ImportGuests(strings.NewReader(csv), false)
+	if !errors.Is(err, ErrImportInvalid) {
+		t.Fatalf("err = %v, want ErrImportInvalid", err)
+	}
+
+	var rows []int
+	var fields []string
+	for _, e := range report.Errors {
+		rows = append(rows, e.Row)
+		fields = append(fields, e.Field)
+	}
+	if !reflect.DeepEqual(rows, []int{3, 3, 4}) || !reflect.DeepEqual(fields, []string{"lastName", "email", ""}) {
+		t.Errorf("errors = %+v", report.Errors)
+	}
+	if report.Valid != 1 {
+		t.Errorf("valid = %d, want 1", report.Valid)
+	}
+	if inserts := db.Find("INSERT"); len(inserts) != 0 {
+		t.Errorf("guests written despite invalid rows: %+v", inserts)
+	}
+}
+
+func TestImportGuestsRejectsFile(t *testing.T) {
+	s, _ := newTestService(t)
+
+	tooMany := "First Name,Last Name\n" + strings.Repeat("Ann,Lee\n", maxImportRows+1)
+	tests := map[string]string{
+		"":                                  "empty",
+		"Email\nann@example.com\n":          "missing required column",
+		tooMany:                             "limited to",
+		"First Name,Last Name,first_name\n": "appears more than once",
+	}
+	for csv, want := range tests {
+		_, err := s.ImportGuests(strings.NewReader(csv), true)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%.30q: error = %v, want %q", csv, err, want)
+		}
+	}
+}
@@ -6,3 +6,6 @@ import (
 
 type Guest = models.Guest
 type RSVP = models.RSVP
+type Household = models.Household
+type GuestTag = models.GuestTag
+type GuestSegment = models.GuestSegment
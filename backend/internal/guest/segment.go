@@ -0,0 +1,188 @@
+package guest
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Segment is a parsed guest filter. The query language is a space-separated
+// list of terms that must all match:
+//
+//	tag:family rsvp:pending side:bride
+//
+// Each term is key:value, where value may list alternatives separated by
+// commas (tag:family,friends) and may be double-quoted to include spaces
+// (tag:"college friends"). A leading "-" negates the term. Words without a
+// key are matched against name, email and phone.
+type Segment struct {
+	Terms []SegmentTerm `json:"terms"`
+}
+
+type SegmentTerm struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+	Negate bool     `json:"negate,omitempty"`
+}
+
+var validSides = []string{"bride", "groom", "both"}
+
+// segmentValues lists the accepted values for keys with a closed vocabulary.
+var segmentValues = map[string][]string{
+	"rsvp":   {"pending", "yes", "no"},
+	"status": {"pending", "approved", "rejected"},
+	"side":   {"bride", "groom", "both", "none"},
+}
+
+var segmentKeys = map[string]bool{
+	"tag":       true,
+	"rsvp":      true,
+	"status":    true,
+	"side":      true,
+	"household": true,
+	"text":      true,
+}
+
+func ParseSegment(query string) (*Segment, error) {
+	tokens, err := splitSegmentQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	segment := &Segment{}
+	for _, token := range tokens {
+		term := SegmentTerm{}
+		if strings.HasPrefix(token, "-") && len(token) > 1 {
+			term.Negate = true
+			token = token[1:]
+		}
+
+		key, value, found := strings.Cut(token, ":")
+		if !found {
+			key, value = "text", token
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !segmentKeys[key] {
+			return nil, fmt.Errorf("unknown segment filter %q", key)
+		}
+		term.Key = key
+
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if key != "text" {
+				v = strings.ToLower(v)
+			}
+			if v == "" {
+				continue
+			}
+			if allowed, ok := segmentValues[key]; ok && !contains(allowed, v) {
+				return nil, fmt.Errorf("invalid value %q for %s (expected one of %s)", v, key, strings.Join(allowed, ", "))
+			}
+			term.Values = append(term.Values, v)
+		}
+		if len(term.Values) == 0 {
+			return nil, fmt.Errorf("segment filter %q has no value", key)
+		}
+
+		segment.Terms = append(segment.Terms, term)
+	}
+
+	return segment, nil
+}
+
+// Scope returns a GORM scope restricting a guests query to the segment.
+func (seg *Segment) Scope(db *gorm.DB) *gorm.DB {
+	if seg == nil {
+		return db
+	}
+
+	for _, term := range seg.Terms {
+		clause, args := term.condition()
+		if term.Negate {
+			db = db.Where("NOT ("+clause+")", args...)
+		} else {
+			db = db.Where(clause, args...)
+		}
+	}
+	return db
+}
+
+func (t SegmentTerm) condition() (string, []interface{}) {
+	switch t.Key {
+	case "tag":
+		return `guests.id IN (SELECT guest_tag_maps.guest_id FROM guest_tag_maps
+			JOIN guest_tags ON guest_tags.id = guest_tag_maps.tag_id
+			WHERE guest_tags.deleted_at IS NULL AND LOWER(guest_tags.name) IN ?)`, []interface{}{t.Values}
+	case "rsvp":
+		return "guests.rsvp_status IN ?", []interface{}{t.Values}
+	case "status":
+		return "guests.registration_status IN ?", []interface{}{t.Values}
+	case "side":
+		if contains(t.Values, "none") {
+			return "(guests.side IS NULL OR guests.side = '' OR guests.side IN ?)", []interface{}{t.Values}
+		}
+		return "guests.side IN ?", []interface{}{t.Values}
+	case "household":
+		if contains(t.Values, "none") {
+			return "guests.household_id IS NULL", nil
+		}
+		if contains(t.Values, "any") {
+			return "guests.household_id IS NOT NULL", nil
+		}
+		return `guests.household_id IN (SELECT households.id FROM households
+			WHERE households.deleted_at IS NULL AND LOWER(households.name) IN ?)`, []interface{}{t.Values}
+	default:
+		var clauses []string
+		var args []interface{}
+		for _, v := range t.Values {
+			pattern := "%" + escapeLike(v) + "%"
+			clauses = append(clauses, "(guests.first_name ILIKE ? OR guests.last_name ILIKE ? OR guests.email ILIKE ? OR guests.phone ILIKE ?)")
+			args = append(args, pattern, pattern, pattern, pattern)
+		}
+		return strings.Join(clauses, " OR "), args
+	}
+}
+
+// splitSegmentQuery splits on whitespace, keeping double-quoted runs intact
+// and dropping the quotes.
+func splitSegmentQuery(query string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case (r == ' ' || r == '\t' || r == '\n') && !inQuotes:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in segment query")
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
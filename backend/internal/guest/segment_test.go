@@ -0,0 +1,133 @@
+package guest
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestParseSegment(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []SegmentTerm
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"tag:family", []SegmentTerm{{Key: "tag", Values: []string{"family"}}}},
+		{"TAG:Family,Friends", []SegmentTerm{{Key: "tag", Values: []string{"family", "friends"}}}},
+		{`tag:"college friends" rsvp:pending`, []SegmentTerm{
+			{Key: "tag", Values: []string{"college friends"}},
+			{Key: "rsvp", Values: []string{"pending"}},
+		}},
+		{"-side:groom status:approved,pending", []SegmentTerm{
+			{Key: "side", Values: []string{"groom"}, Negate: true},
+			{Key: "status", Values: []string{"approved", "pending"}},
+		}},
+		{"Priya -Sharma", []SegmentTerm{
+			{Key: "text", Values: []string{"Priya"}},
+			{Key: "text", Values: []string{"Sharma"}, Negate: true},
+		}},
+		{"household:none side:none", []SegmentTerm{
+			{Key: "household", Values: []string{"none"}},
+			{Key: "side", Values: []string{"none"}},
+		}},
+		{"tag:a,,b,", []SegmentTerm{{Key: "tag", Values: []string{"a", "b"}}}},
+		{"-", []SegmentTerm{{Key: "text", Values: []string{"-"}}}},
+	}
+	for _, tt := range tests {
+		seg, err := ParseSegment(tt.query)
+		if err != nil {
+			t.Errorf("ParseSegment(%q): %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(seg.Terms, tt.want) {
+			t.Errorf("ParseSegment(%q) = %+v, want %+v", tt.query, seg.Terms, tt.want)
+		}
+	}
+}
+
+func TestParseSegmentErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":        "email:a@example.com",
+		"no value":           "tag:",
+		"only commas":        "tag:,,",
+		"invalid rsvp":       "rsvp:maybe",
+		"invalid status":     "status:yes",
+		"invalid side":       "side:left",
+		"unterminated quote": `tag:"college friends`,
+	}
+	for name, query := range tests {
+		if _, err := ParseSegment(query); err == nil {
+			t.Errorf("%s: ParseSegment(%q) succeeded", name, query)
+		}
+	}
+}
+
+func TestSegmentScope(t *testing.T) {
+	conn, err := sql.Open("pgx", "host=unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		where []string
+		vars  []interface{}
+	}{
+		{"rsvp:yes,no", []string{"guests.rsvp_status IN ($1,$2)"}, []interface{}{"yes", "no"}},
+		{"-status:rejected", []string{"NOT (guests.registration_status IN ($1))"}, []interface{}{"rejected"}},
+		{"side:none", []string{"guests.side IS NULL OR guests.side = '' OR guests.side IN ($1)"}, []interface{}{"none"}},
+		{"household:any", []string{"guests.household_id IS NOT NULL"}, nil},
+		{"household:none", []string{"guests.household_id IS NULL"}, nil},
+		{"household:smiths", []string{"LOWER(households.name) IN ($1)"}, []interface{}{"smiths"}},
+		{"tag:family", []string{"LOWER(guest_tags.name) IN ($1)"}, []interface{}{"family"}},
+		{"100%", []string{"guests.first_name ILIKE $1 OR guests.last_name ILIKE $2"},
+			[]interface{}{`%100\%%`, `%100\%%`, `%100\%%`, `%100\%%`}},
+		{"rsvp:pending side:bride", []string{"guests.rsvp_status IN ($1)", "guests.side IN ($2)"}, []interface{}{"pending", "bride"}},
+	}
+	for _, tt := range tests {
+		seg, err := ParseSegment(tt.query)
+		if err != nil {
+			t.Fatalf("ParseSegment(%q): %v", tt.query, err)
+		}
+
+		var guests []Guest
+		stmt := db.Scopes(seg.Scope).Find(&guests).Statement
+		query := stmt.SQL.String()
+		for _, where := range tt.where {
+			if !strings.Contains(query, where) {
+				t.Errorf("%q: SQL %q\nis missing %q", tt.query, query, where)
+			}
+		}
+		if len(stmt.Vars) != len(tt.vars) {
+			t.Errorf("%q: args = %v, want %v", tt.query, stmt.Vars, tt.vars)
+			continue
+		}
+		for i, v := range tt.vars {
+			if stmt.Vars[i] != v {
+				t.Errorf("%q: arg %d = %v, want %v", tt.query, i, stmt.Vars[i], v)
+			}
+		}
+	}
+
+	// A nil segment matches every guest
+	var guests []Guest
+	var seg *Segment
+	if query := db.Scopes(seg.Scope).Find(&guests).Statement.SQL.String(); strings.Contains(query, "WHERE guests.") {
+		t.Errorf("nil segment added conditions: %q", query)
+	}
+}
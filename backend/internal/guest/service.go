@@ -1,31 +1,85 @@
 package guest
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
 	"gorm.io/gorm"
+	"wedding-app/internal/event"
+	"wedding-app/internal/jobs"
+	"wedding-app/internal/notification"
+	"wedding-app/internal/questionnaire"
+	"wedding-app/pkg/notify"
 	"wedding-app/pkg/logger"
+	"wedding-app/pkg/pagination"
 )
 
+// JobSendApproval is the background job that sends an approved guest their
+// links over one channel.
+const JobSendApproval = "guest.approval"
+
+type ApprovalJob struct {
+	GuestID uint   `json:"guestId"`
+	Channel string `json:"channel"` // email, sms
+}
+
 type Service struct {
-	db     *gorm.DB
-	logger logger.Logger
+	db       *gorm.DB
+	logger   logger.Logger
+	notifier *notification.Service
+	queue    *jobs.Queue
 }
 
-func NewService(db *gorm.DB, logger logger.Logger) *Service {
-	return &Service{
-		db:     db,
-		logger: logger,
+func NewService(db *gorm.DB, logger logger.Logger, notifier *notification.Service, queue *jobs.Queue) *Service {
+	s := &Service{
+		db:       db,
+		logger:   logger,
+		notifier: notifier,
+		queue:    queue,
 	}
+	jobs.Register(queue, JobSendApproval, s.sendApprovalNotification)
+	return s
 }
 
-func (s *Service) GetAllGuests() ([]Guest, error) {
-	var guests []Guest
-	err := s.db.Preload("RSVPs").Find(&guests).Error
-	return guests, err
+// GuestListOptions controls sorting and search on the admin guest list.
+var GuestListOptions = pagination.Options{
+	Sortable: map[string]string{
+		"firstName":          "guests.first_name",
+		"lastName":           "guests.last_name",
+		"email":              "COALESCE(guests.email, '')",
+		"rsvpStatus":         "COALESCE(guests.rsvp_status, '')",
+		"registrationStatus": "COALESCE(guests.registration_status, '')",
+		"partySize":          "COALESCE(guests.party_size, 0)",
+		"createdAt":          "guests.created_at",
+		"updatedAt":          "guests.updated_at",
+	},
+	DefaultSort: "-createdAt",
+	Searchable: []string{
+		"guests.first_name",
+		"guests.last_name",
+		"guests.email",
+		"guests.phone",
+		"guests.concerns",
+	},
+	IDColumn: "guests.id",
+}
+
+// ListGuests returns one page of guests, narrowed to segment when it is
+// non-nil. RSVP history is not loaded; use GetGuestByID for that.
+func (s *Service) ListGuests(segment *Segment, params *pagination.Params) (*pagination.Page[Guest], error) {
+	var page pagination.Page[Guest]
+	query := s.db.Model(&Guest{}).Scopes(segment.Scope)
+	err := pagination.Find(query, params, &page, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Tags").Preload("Household").Preload("Attendance.Event")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
 }
 
 func (s *Service) GetGuestByID(id uint) (*Guest, error) {
@@ -42,6 +96,11 @@ func (s *Service) GetGuestByToken(token string) (*Guest, error) {
 
 
 func (s *Service) RegisterGuest(firstName, lastName, email, phone string) (*Guest, error) {
+	phone, err := notify.NormalizePhone(phone, notify.DefaultCountryCode())
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate unique tokens for registration
 	inviteToken, err := s.generateToken()
 	if err != nil {
@@ -72,6 +131,11 @@ func (s *Service) RegisterGuest(firstName, lastName, email, phone string) (*Gues
 }
 
 func (s *Service) RegisterGuestWithQuestionnaire(req RegisterGuestRequest) (*Guest, error) {
+	phone, err := notify.NormalizePhone(req.Phone, notify.DefaultCountryCode())
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate unique tokens for registration
 	inviteToken, err := s.generateToken()
 	if err != nil {
@@ -87,26 +151,37 @@ func (s *Service) RegisterGuestWithQuestionnaire(req RegisterGuestRequest) (*Gue
 		FirstName:                   req.FirstName,
 		LastName:                    req.LastName,
 		Email:                       req.Email,
-		Phone:                       req.Phone,
+		Phone:                       phone,
+		SMSOptIn:                    req.SMSOptIn && phone != "",
 		InviteToken:                 inviteToken,
 		GuestPortalToken:            portalToken,
 		RegistrationStatus:          "pending",
 		PartySize:                   req.PartySize,
 		PartyMembers:                req.PartyMembers,
 		MainPersonDietaryPreference: req.MainPersonDietaryPreference,
-		Dec24Attendance:             req.Dec24.Attendance,
-		Dec25Attendance:             req.Dec25.Attendance,
-		AccommodationDec23:          req.Accommodation.Dec23,
-		AccommodationDec24:          req.Accommodation.Dec24,
-		AccommodationDec25:          req.Accommodation.Dec25,
 		Concerns:                    req.Concerns,
 	}
 
-	err = s.db.Create(&guest).Error
+	form, err := questionnaire.LoadForm(s.db, questionnaire.StageRegistration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load questionnaire: %w", err)
+	}
+	guest.Answers, err = form.Apply(nil, req.Answers, guest.PartyMembers, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&guest).Error; err != nil {
+			return err
+		}
+		return event.SetAttendance(tx, guest.ID, req.Events)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	guest.Events = req.Events
 	return &guest, nil
 }
 
@@ -144,15 +219,32 @@ func (s *Service) ApproveGuestRegistration(guestID uint) (*Guest, error) {
 	guest.RegistrationStatus = "approved"
 	guest.ApprovedAt = &now
 
-	err = s.db.Save(&guest).Error
-	if err != nil {
-		return nil, err
+	// Send notification email/SMS with invite token once the approval is
+	// saved; one job per channel so a failed text doesn't resend the email
+	var channels []string
+	if guest.Email != "" {
+		channels = append(channels, "email")
+	}
+	if guest.Phone != "" && guest.SMSOptIn {
+		channels = append(channels, "sms")
+	}
+	if len(channels) == 0 {
+		s.logger.Warn("Approved guest has no way to be notified", "guest", guest.ID)
 	}
 
-	// Send notification email/SMS with invite token
-	err = s.sendApprovalNotification(&guest)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&guest).Error; err != nil {
+			return err
+		}
+		for _, channel := range channels {
+			if _, err := s.queue.EnqueueTx(tx, JobSendApproval, ApprovalJob{GuestID: guest.ID, Channel: channel}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		s.logger.Error("Failed to send approval notification", "error", err, "guest", guest.ID)
+		return nil, err
 	}
 
 	return &guest, nil
@@ -189,58 +281,47 @@ func (s *Service) generateToken() (string, error) {
 }
 
 
-func (s *Service) sendApprovalNotification(guest *Guest) error {
-	// Generate URLs for the guest
-	rsvpURL := fmt.Sprintf("https://yourdomain.com/rsvp/%s", guest.InviteToken)
-	portalURL := fmt.Sprintf("https://yourdomain.com/guest-portal/%s", guest.GuestPortalToken)
-	
-	// Email content
-	emailSubject := "Your Wedding RSVP is Ready!"
-	emailBody := fmt.Sprintf(`
-		Hi %s,
-
-		Great news! Your registration has been approved. You can now RSVP for the wedding.
-
-		🎉 RSVP Link: %s
-		📸 Your Personal Portal: %s
-
-		After you complete your RSVP, you'll be able to use your personal portal to:
-		- Upload photos from the wedding
-		- Send messages to the couple
-		- View wedding updates
+// sendApprovalNotification runs as a background job after a guest is
+// approved. Guests who were since deleted or un-approved, or who no longer
+// have the address or SMS opt-in the channel needs, are skipped.
+func (s *Service) sendApprovalNotification(ctx context.Context, job ApprovalJob) error {
+	var guest Guest
+	if err := s.db.First(&guest, job.GuestID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if guest.RegistrationStatus != "approved" || (job.Channel == "email" && guest.Email == "") {
+		return nil
+	}
 
-		We can't wait to celebrate with you!
+	err := s.notifier.SendApproval(&guest, job.Channel)
+	switch {
+	case errors.Is(err, notification.ErrSMSNotAllowed):
+		return nil
+	case errors.Is(err, notify.ErrRejected):
+		return jobs.Permanent(err)
+	}
+	return err
+}
 
-		Best regards,
-		The Happy Couple
-	`, guest.FirstName, rsvpURL, portalURL)
+// SetSMSOptIn records whether the guest agrees to receive text messages.
+// Opting in requires a phone number on file.
+func (s *Service) SetSMSOptIn(guestID uint, optIn bool) (*Guest, error) {
+	var guest Guest
+	if err := s.db.First(&guest, guestID).Error; err != nil {
+		return nil, err
+	}
+	if optIn && guest.Phone == "" {
+		return nil, fmt.Errorf("guest has no phone number")
+	}
 
-	// SMS content (shorter version)
-	smsMessage := fmt.Sprintf("Hi %s! Your wedding RSVP is ready: %s. Your personal portal: %s", 
-		guest.FirstName, rsvpURL, portalURL)
-	
-	// TODO: Implement actual email/SMS sending
-	// Example integrations:
-	
-	// For Email (using SendGrid, SES, etc.):
-	// emailService.Send(guest.Email, emailSubject, emailBody)
-	
-	// For SMS (using Twilio, AWS SNS, etc.):
-	// if guest.Phone != "" {
-	//     smsService.Send(guest.Phone, smsMessage)
-	// }
-	
-	s.logger.Info("Approval notification sent", 
-		"email", guest.Email, 
-		"phone", guest.Phone,
-		"rsvpURL", rsvpURL,
-		"portalURL", portalURL,
-		"emailSubject", emailSubject,
-		"emailBody", emailBody,
-		"smsMessage", smsMessage,
-	)
-	
-	return nil
+	if err := s.notifier.SetSMSOptIn(guest.ID, optIn); err != nil {
+		return nil, err
+	}
+	guest.SMSOptIn = optIn
+	return &guest, nil
 }
 
 func (s *Service) DeleteAllGuests() error {
@@ -262,13 +343,32 @@ func (s *Service) DeleteAllGuests() error {
 		s.logger.Warn("Failed to delete guest photos", "error", err)
 	}
 
-	// Delete all guests
-	err = s.db.Exec("DELETE FROM guests").Error
+	// Delete all guests along with their tags and private album access.
+	// Join tables created by AutoMigrate before the cascade was declared
+	// still restrict deletes, so the rows are removed explicitly.
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM guest_tag_maps").Error; err != nil {
+			return fmt.Errorf("failed to remove guest tags: %w", err)
+		}
+		if err := tx.Exec("DELETE FROM album_guests").Error; err != nil {
+			return fmt.Errorf("failed to remove album access: %w", err)
+		}
+		if err := tx.Exec("DELETE FROM guests").Error; err != nil {
+			return fmt.Errorf("failed to delete guests: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Delete the now-empty households
+	err = s.db.Exec("DELETE FROM households").Error
 	if err != nil {
-		return fmt.Errorf("failed to delete guests: %w", err)
+		s.logger.Warn("Failed to delete households", "error", err)
 	}
 
-	s.logger.Info("All guests, RSVPs, messages, and guest photos deleted")
+	s.logger.Info("All guests, households, RSVPs, messages, and guest photos deleted")
 	return nil
 }
 
@@ -290,7 +390,7 @@ func (s *Service) DeleteSelectedGuests(guestIDs []uint) error {
 	}
 
 	// Delete photos uploaded by selected guests
-	err = s.db.Exec("DELETE FROM photos WHERE guest_token IN (SELECT guest_portal_token FROM guests WHERE id IN ?)", guestIDs).Error
+	err = s.db.Exec("DELETE FROM photos WHERE guest_id IN ? OR guest_token IN (SELECT guest_portal_token FROM guests WHERE id IN ?)", guestIDs, guestIDs).Error
 	if err != nil {
 		s.logger.Warn("Failed to delete photos for selected guests", "error", err)
 	}
@@ -0,0 +1,89 @@
+package guest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+	"wedding-app/internal/models"
+	"wedding-app/internal/testdb"
+)
+
+type discardLogger struct{}
+
+func (discardLogger) Debug(string, ...interface{}) {}
+func (discardLogger) Info(string, ...interface{})  {}
+func (discardLogger) Warn(string, ...interface{})  {}
+func (discardLogger) Error(string, ...interface{}) {}
+
+// newTestService returns a service on a stand-in database.
+func newTestService(t *testing.T) (*Service, *testdb.DB) {
+	t.Helper()
+	db, fake := testdb.New(t)
+	return &Service{db: db, logger: discardLogger{}}, fake
+}
+
+func TestDeleteAllGuestsClearsJoinTables(t *testing.T) {
+	s, db := newTestService(t)
+
+	// Deleting guests fails, like a foreign key violation, unless the rows
+	// referencing them were deleted earlier in the same transaction
+	db.OnExec(func(query string, _ []interface{}) error {
+		if query != "DELETE FROM guests" {
+			return nil
+		}
+		statements := db.SQL()
+		begin := -1
+		for i, statement := range statements {
+			if statement == "BEGIN" {
+				begin = i
+			}
+		}
+		if begin < 0 {
+			return fmt.Errorf("guests deleted outside a transaction")
+		}
+		for _, table := range []string{"guest_tag_maps", "album_guests"} {
+			if !contains(statements[begin:], "DELETE FROM "+table) {
+				return fmt.Errorf("violates foreign key constraint on %s", table)
+			}
+		}
+		return nil
+	})
+
+	if err := s.DeleteAllGuests(); err != nil {
+		t.Fatalf("DeleteAllGuests: %v\nstatements: %q", err, db.SQL())
+	}
+	if contains(db.SQL(), "ROLLBACK") {
+		t.Errorf("transaction rolled back: %q", db.SQL())
+	}
+}
+
+// The join table's foreign keys must cascade, so AutoMigrate creates them
+// that way on new databases.
+func TestGuestJoinTablesCascade(t *testing.T) {
+	tests := []struct {
+		model    interface{}
+		relation string
+	}{
+		{&Guest{}, "Tags"},
+		{&models.Album{}, "Guests"},
+		{&models.Album{}, "Tags"},
+	}
+	for _, tt := range tests {
+		s, err := schema.Parse(tt.model, &sync.Map{}, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel, ok := s.Relationships.Relations[tt.relation]
+		if !ok || rel.JoinTable == nil {
+			t.Fatalf("%s.%s is not a many2many relation", s.Name, tt.relation)
+		}
+		for _, joinRel := range rel.JoinTable.Relationships.Relations {
+			constraint := joinRel.ParseConstraint()
+			if constraint == nil || constraint.OnDelete != "CASCADE" {
+				t.Errorf("%s.%s: %s foreign key = %+v, want ON DELETE CASCADE", s.Name, tt.relation, joinRel.Name, constraint)
+			}
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package guest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+func (s *Service) GetAllTags() ([]GuestTag, error) {
+	var tags []GuestTag
+	err := s.db.Order("name ASC").Find(&tags).Error
+	return tags, err
+}
+
+func (s *Service) CreateTag(name, color string) (*GuestTag, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("tag name is required")
+	}
+	if err := s.ensureTagNameFree(name, 0); err != nil {
+		return nil, err
+	}
+
+	tag := GuestTag{Name: name, Color: color}
+	if tag.Color == "" {
+		tag.Color = "blue"
+	}
+
+	if err := s.db.Create(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (s *Service) UpdateTag(id uint, name, color string) (*GuestTag, error) {
+	var tag GuestTag
+	if err := s.db.First(&tag, id).Error; err != nil {
+		return nil, err
+	}
+
+	if name = strings.TrimSpace(name); name != "" {
+		if err := s.ensureTagNameFree(name, id); err != nil {
+			return nil, err
+		}
+		tag.Name = name
+	}
+	if color != "" {
+		tag.Color = color
+	}
+
+	if err := s.db.Save(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (s *Service) DeleteTag(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM guest_tag_maps WHERE tag_id = ?", id).Error; err != nil {
+			return fmt.Errorf("failed to remove tag from guests: %w", err)
+		}
+
+		result := tx.Delete(&GuestTag{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// SetGuestTags replaces the guest's tags with tagIDs.
+func (s *Service) SetGuestTags(guestID uint, tagIDs []uint) (*Guest, error) {
+	var guest Guest
+	if err := s.db.First(&guest, guestID).Error; err != nil {
+		return nil, err
+	}
+
+	tags, err := s.findTags(tagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&guest).Association("Tags").Replace(tags); err != nil {
+		return nil, fmt.Errorf("failed to update guest tags: %w", err)
+	}
+
+	guest.Tags = tags
+	return &guest, nil
+}
+
+// TagGuests adds every tag in tagIDs to every guest in guestIDs.
+func (s *Service) TagGuests(guestIDs, tagIDs []uint) error {
+	tags, err := s.findTags(tagIDs)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("no tag IDs provided")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, guestID := range guestIDs {
+			for _, tag := range tags {
+				err := tx.Exec("INSERT INTO guest_tag_maps (guest_id, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING", guestID, tag.ID).Error
+				if err != nil {
+					return fmt.Errorf("failed to tag guest %d: %w", guestID, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// UntagGuests removes every tag in tagIDs from every guest in guestIDs.
+func (s *Service) UntagGuests(guestIDs, tagIDs []uint) error {
+	if len(tagIDs) == 0 {
+		return fmt.Errorf("no tag IDs provided")
+	}
+	return s.db.Exec("DELETE FROM guest_tag_maps WHERE guest_id IN ? AND tag_id IN ?", guestIDs, tagIDs).Error
+}
+
+func (s *Service) findTags(tagIDs []uint) ([]GuestTag, error) {
+	tagIDs = uniqueIDs(tagIDs)
+	if len(tagIDs) == 0 {
+		return []GuestTag{}, nil
+	}
+
+	var tags []GuestTag
+	if err := s.db.Where("id IN ?", tagIDs).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) != len(tagIDs) {
+		return nil, fmt.Errorf("one or more tags do not exist")
+	}
+	return tags, nil
+}
+
+func (s *Service) ensureTagNameFree(name string, exceptID uint) error {
+	var count int64
+	err := s.db.Model(&GuestTag{}).Where("LOWER(name) = LOWER(?) AND id <> ?", name, exceptID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("a tag named %q already exists", name)
+	}
+	return nil
+}
+
+// Saved segments
+
+func (s *Service) GetAllSegments() ([]GuestSegment, error) {
+	var segments []GuestSegment
+	err := s.db.Order("name ASC").Find(&segments).Error
+	return segments, err
+}
+
+func (s *Service) GetSegmentByID(id uint) (*GuestSegment, error) {
+	var segment GuestSegment
+	if err := s.db.First(&segment, id).Error; err != nil {
+		return nil, err
+	}
+	return &segment, nil
+}
+
+func (s *Service) CreateSegment(name, query string) (*GuestSegment, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("segment name is required")
+	}
+	if _, err := ParseSegment(query); err != nil {
+		return nil, err
+	}
+
+	segment := GuestSegment{Name: name, Query: strings.TrimSpace(query)}
+	if err := s.db.Create(&segment).Error; err != nil {
+		return nil, err
+	}
+	return &segment, nil
+}
+
+func (s *Service) UpdateSegment(id uint, name, query string) (*GuestSegment, error) {
+	segment, err := s.GetSegmentByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name = strings.TrimSpace(name); name != "" {
+		segment.Name = name
+	}
+	if query = strings.TrimSpace(query); query != "" {
+		if _, err := ParseSegment(query); err != nil {
+			return nil, err
+		}
+		segment.Query = query
+	}
+
+	if err := s.db.Save(segment).Error; err != nil {
+		return nil, err
+	}
+	return segment, nil
+}
+
+func (s *Service) DeleteSegment(id uint) error {
+	result := s.db.Delete(&GuestSegment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Bulk actions
+
+// GuestSelection picks the guests a bulk action applies to: explicit IDs, an
+// inline segment query, or a saved segment. Exactly one must be set.
+type GuestSelection struct {
+	GuestIDs  []uint `json:"guestIds"`
+	Query     string `json:"query"`
+	SegmentID uint   `json:"segmentId"`
+}
+
+// validate checks that exactly one way of selecting guests is used, so a
+// request can't quietly act on a different set than the caller meant.
+func (sel GuestSelection) validate() error {
+	set := 0
+	if len(sel.GuestIDs) > 0 {
+		set++
+	}
+	if strings.TrimSpace(sel.Query) != "" {
+		set++
+	}
+	if sel.SegmentID != 0 {
+		set++
+	}
+	switch set {
+	case 0:
+		return fmt.Errorf("no guests selected")
+	case 1:
+		return nil
+	default:
+		return fmt.Errorf("select guests by guestIds, query or segmentId, not more than one")
+	}
+}
+
+// ResolveSegment turns an inline query or saved segment ID into a parsed
+// segment. It returns nil when neither is set.
+func (s *Service) ResolveSegment(query string, segmentID uint) (*Segment, error) {
+	if segmentID != 0 {
+		saved, err := s.GetSegmentByID(segmentID)
+		if err != nil {
+			return nil, fmt.Errorf("segment not found")
+		}
+		query = saved.Query
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	return ParseSegment(query)
+}
+
+// ResolveSelection returns the IDs of the selected guests.
+func (s *Service) ResolveSelection(sel GuestSelection) ([]uint, error) {
+	if err := sel.validate(); err != nil {
+		return nil, err
+	}
+	if len(sel.GuestIDs) > 0 {
+		return uniqueIDs(sel.GuestIDs), nil
+	}
+
+	segment, err := s.ResolveSegment(sel.Query, sel.SegmentID)
+	if err != nil {
+		return nil, err
+	}
+	if segment == nil {
+		return nil, fmt.Errorf("no guests selected")
+	}
+
+	return s.GuestIDsInSegment(segment)
+}
+
+// GuestIDsInSegment returns the IDs of every guest in segment, or of every
+// guest when segment is nil.
+func (s *Service) GuestIDsInSegment(segment *Segment) ([]uint, error) {
+	var ids []uint
+	err := s.db.Model(&Guest{}).Scopes(segment.Scope).Pluck("guests.id", &ids).Error
+	return ids, err
+}
+
+type BulkResult struct {
+	Matched   int             `json:"matched"`
+	Succeeded int             `json:"succeeded"`
+	Failed    map[uint]string `json:"failed,omitempty"`
+}
+
+// ApproveGuests approves each pending guest in guestIDs, sending the usual
+// approval notification. Guests that are not pending are reported as failed.
+func (s *Service) ApproveGuests(guestIDs []uint) *BulkResult {
+	result := &BulkResult{Matched: len(guestIDs), Failed: map[uint]string{}}
+	for _, id := range guestIDs {
+		if _, err := s.ApproveGuestRegistration(id); err != nil {
+			result.Failed[id] = err.Error()
+			continue
+		}
+		result.Succeeded++
+	}
+	return result
+}
+
+// RejectGuests rejects each pending guest in guestIDs.
+func (s *Service) RejectGuests(guestIDs []uint) *BulkResult {
+	result := &BulkResult{Matched: len(guestIDs), Failed: map[uint]string{}}
+	for _, id := range guestIDs {
+		if err := s.RejectGuestRegistration(id); err != nil {
+			result.Failed[id] = err.Error()
+			continue
+		}
+		result.Succeeded++
+	}
+	return result
+}
+
+// ExportGuestsToCSV writes the selected guests using the same column names
+// the importer accepts, so an export can be edited and imported elsewhere.
+func (s *Service) ExportGuestsToCSV(guestIDs []uint) (string, error) {
+	var guests []Guest
+	err := s.db.Preload("Tags").Preload("Household").
+		Where("id IN ?", guestIDs).Order("last_name ASC, first_name ASC").
+		Find(&guests).Error
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	writer.Write([]string{
+		"First Name", "Last Name", "Email", "Phone", "Side",
+		"Party Size", "Max Party Size", "Party Members", "Dietary Preference",
+		"Concerns", "Household", "Tags", "Registration Status", "RSVP Status",
+	})
+
+	for _, g := range guests {
+		var members []string
+		for _, m := range g.PartyMembers {
+			member := strings.TrimSpace(m.FirstName + " " + m.LastName)
+			if m.DietaryPreference != "" {
+				member += ": " + m.DietaryPreference
+			}
+			members = append(members, member)
+		}
+
+		var tags []string
+		for _, t := range g.Tags {
+			tags = append(tags, t.Name)
+		}
+
+		household := ""
+		if g.Household != nil {
+			household = g.Household.Name
+		}
+
+		writer.Write([]string{
+			g.FirstName,
+			g.LastName,
+			g.Email,
+			g.Phone,
+			g.Side,
+			strconv.Itoa(g.PartySize),
+			strconv.Itoa(g.MaxPartySize),
+			strings.Join(members, "; "),
+			g.MainPersonDietaryPreference,
+			g.Concerns,
+			household,
+			strings.Join(tags, "; "),
+			g.RegistrationStatus,
+			g.RSVPStatus,
+		})
+	}
+
+	writer.Flush()
+	return buffer.String(), writer.Error()
+}
@@ -0,0 +1,45 @@
+package guest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGuestSelectionValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		sel  GuestSelection
+		ok   bool
+	}{
+		{"ids", GuestSelection{GuestIDs: []uint{1, 2}}, true},
+		{"query", GuestSelection{Query: "status:approved"}, true},
+		{"segment", GuestSelection{SegmentID: 3}, true},
+		{"nothing", GuestSelection{}, false},
+		{"blank query", GuestSelection{Query: "  "}, false},
+		{"empty ids", GuestSelection{GuestIDs: []uint{}}, false},
+		{"ids and query", GuestSelection{GuestIDs: []uint{1}, Query: "tag:family"}, false},
+		{"ids and segment", GuestSelection{GuestIDs: []uint{1}, SegmentID: 3}, false},
+		{"query and segment", GuestSelection{Query: "tag:family", SegmentID: 3}, false},
+		{"ids and blank query", GuestSelection{GuestIDs: []uint{1}, Query: " "}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.sel.validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: validate() = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestResolveSelectionIDs(t *testing.T) {
+	s := &Service{}
+	ids, err := s.ResolveSelection(GuestSelection{GuestIDs: []uint{3, 1, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint{3, 1}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+
+	if _, err := s.ResolveSelection(GuestSelection{GuestIDs: []uint{1}, SegmentID: 2}); err == nil {
+		t.Error("ResolveSelection accepted both guestIds and segmentId")
+	}
+}
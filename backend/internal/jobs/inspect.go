@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"wedding-app/pkg/pagination"
+)
+
+// JobListOptions controls sorting and search on the job list.
+var JobListOptions = pagination.Options{
+	Sortable: map[string]string{
+		"createdAt": "jobs.created_at",
+		"runAt":     "jobs.run_at",
+		"attempts":  "jobs.attempts",
+		"type":      "jobs.type",
+	},
+	DefaultSort: "-createdAt",
+	Searchable:  []string{"jobs.type", "jobs.last_error"},
+	IDColumn:    "jobs.id",
+}
+
+// ListJobs returns one page of jobs, optionally limited to a status (e.g.
+// "dead" for the dead letter queue) and job type.
+func (q *Queue) ListJobs(params *pagination.Params, status, jobType string) (*pagination.Page[Job], error) {
+	query := q.db.Model(&Job{})
+	if status != "" {
+		query = query.Where("jobs.status = ?", status)
+	}
+	if jobType != "" {
+		query = query.Where("jobs.type = ?", jobType)
+	}
+
+	var page pagination.Page[Job]
+	if err := pagination.Find(query, params, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (q *Queue) GetJob(id uint) (*Job, error) {
+	var job Job
+	if err := q.db.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// JobCount is the number of jobs of one type in one state.
+type JobCount struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+func (q *Queue) Stats() ([]JobCount, error) {
+	var counts []JobCount
+	err := q.db.Model(&Job{}).
+		Select("type, status, COUNT(*) AS count").
+		Group("type, status").
+		Order("type, status").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// RetryJob puts a dead job back on the queue with a fresh set of attempts.
+func (q *Queue) RetryJob(id uint) (*Job, error) {
+	job, err := q.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != "dead" {
+		return nil, fmt.Errorf("only dead jobs can be retried")
+	}
+
+	result := q.db.Model(job).Where("status = ?", "dead").Updates(map[string]interface{}{
+		"status":      "pending",
+		"attempts":    0,
+		"run_at":      time.Now(),
+		"finished_at": nil,
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	q.notify()
+	q.logger.Info("Dead job requeued", "job", job.ID, "type", job.Type)
+	return q.GetJob(id)
+}
+
+// RetryDead requeues every dead job, or only those of jobType.
+func (q *Queue) RetryDead(jobType string) (int64, error) {
+	query := q.db.Model(&Job{}).Where("status = ?", "dead")
+	if jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"status":      "pending",
+		"attempts":    0,
+		"run_at":      time.Now(),
+		"finished_at": nil,
+	})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	q.notify()
+	q.logger.Info("Dead jobs requeued", "type", jobType, "count", result.RowsAffected)
+	return result.RowsAffected, nil
+}
+
+// DeleteJob discards a job that isn't running.
+func (q *Queue) DeleteJob(id uint) error {
+	return q.db.Transaction(func(tx *gorm.DB) error {
+		var job Job
+		if err := tx.First(&job, id).Error; err != nil {
+			return err
+		}
+		if job.Status == "running" {
+			return fmt.Errorf("job is running and can't be deleted")
+		}
+		return tx.Where("status <> ?", "running").Delete(&job).Error
+	})
+}
@@ -0,0 +1,7 @@
+package jobs
+
+import (
+	"wedding-app/internal/models"
+)
+
+type Job = models.Job
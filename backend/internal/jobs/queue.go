@@ -0,0 +1,401 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"wedding-app/pkg/logger"
+)
+
+// HandlerFunc runs one job. Returning an error retries the job with backoff
+// until it runs out of attempts; wrap the error with Permanent to give up
+// straight away.
+type HandlerFunc func(ctx context.Context, payload []byte) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as one that retrying won't fix, such as a record that
+// no longer exists. The job goes to the dead letter queue immediately.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type Config struct {
+	Workers         int
+	MaxAttempts     int
+	PollInterval    time.Duration
+	Lease           time.Duration // how long a job may run before its worker is presumed dead
+	Retention       time.Duration // how long finished jobs are kept
+	ShutdownTimeout time.Duration
+}
+
+// ConfigFromEnv reads JOB_WORKERS and JOB_MAX_ATTEMPTS, with defaults for
+// everything else.
+func ConfigFromEnv() Config {
+	config := Config{
+		Workers:         4,
+		MaxAttempts:     5,
+		PollInterval:    2 * time.Second,
+		Lease:           15 * time.Minute,
+		Retention:       7 * 24 * time.Hour,
+		ShutdownTimeout: 30 * time.Second,
+	}
+	if n, err := strconv.Atoi(os.Getenv("JOB_WORKERS")); err == nil && n > 0 {
+		config.Workers = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("JOB_MAX_ATTEMPTS")); err == nil && n > 0 {
+		config.MaxAttempts = n
+	}
+	return config
+}
+
+// Queue is a durable job queue stored in the jobs table. Jobs survive
+// restarts, are retried with exponential backoff, and end up "dead" for an
+// admin to inspect once they run out of attempts.
+type Queue struct {
+	db       *gorm.DB
+	logger   logger.Logger
+	config   Config
+	workerID string
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+
+	wake   chan struct{}
+	stop   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewQueue(db *gorm.DB, logger logger.Logger, config Config) *Queue {
+	hostname, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Queue{
+		db:       db,
+		logger:   logger,
+		config:   config,
+		workerID: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		handlers: make(map[string]HandlerFunc),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Handle registers the handler for a job type.
+func (q *Queue) Handle(jobType string, fn HandlerFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = fn
+}
+
+// Register adds a handler whose payload is decoded into T. A payload that
+// doesn't decode fails the job permanently.
+func Register[T any](q *Queue, jobType string, fn func(ctx context.Context, payload T) error) {
+	q.Handle(jobType, func(ctx context.Context, raw []byte) error {
+		var payload T
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return Permanent(fmt.Errorf("invalid %s payload: %w", jobType, err))
+		}
+		return fn(ctx, payload)
+	})
+}
+
+type Option func(*Job)
+
+// Delay holds the job back for d.
+func Delay(d time.Duration) Option {
+	return func(job *Job) {
+		job.RunAt = job.RunAt.Add(d)
+	}
+}
+
+// MaxAttempts overrides the queue's default number of attempts.
+func MaxAttempts(n int) Option {
+	return func(job *Job) {
+		job.MaxAttempts = n
+	}
+}
+
+// Enqueue adds a job to the queue.
+func (q *Queue) Enqueue(jobType string, payload interface{}, opts ...Option) (*Job, error) {
+	return q.EnqueueTx(q.db, jobType, payload, opts...)
+}
+
+// EnqueueTx adds a job as part of tx, so the job only exists if the work
+// that caused it commits.
+func (q *Queue) EnqueueTx(tx *gorm.DB, jobType string, payload interface{}, opts ...Option) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", jobType, err)
+	}
+
+	job := &Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      "pending",
+		MaxAttempts: q.config.MaxAttempts,
+		RunAt:       time.Now(),
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	if err := tx.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to enqueue %s job: %w", jobType, err)
+	}
+
+	q.notify()
+	return job, nil
+}
+
+// notify nudges an idle worker so new jobs don't wait for the next poll.
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start launches the worker pool and the maintenance loop that recovers jobs
+// from crashed workers and purges old finished jobs.
+func (q *Queue) Start() {
+	for i := 0; i < q.config.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+
+	q.wg.Add(1)
+	go q.maintain()
+
+	q.logger.Info("Job queue started", "workers", q.config.Workers, "worker", q.workerID)
+}
+
+// Stop stops claiming new jobs and waits for running ones to finish. Jobs
+// still running after the shutdown timeout have their context cancelled;
+// anything left unfinished is picked up again once its lease expires.
+func (q *Queue) Stop() {
+	close(q.stop)
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(q.config.ShutdownTimeout):
+		q.logger.Warn("Job queue shutdown timed out, cancelling running jobs")
+		q.cancel()
+		<-done
+	}
+	q.cancel()
+
+	q.logger.Info("Job queue stopped")
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		job, err := q.claim()
+		if err != nil {
+			q.logger.Error("Failed to claim job", "error", err)
+		}
+		if job != nil {
+			q.run(job)
+			continue
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-time.After(q.config.PollInterval):
+		}
+	}
+}
+
+// claim takes the next due job, skipping rows other workers have locked.
+func (q *Queue) claim() (*Job, error) {
+	var job Job
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ?", "pending", time.Now()).
+			Order("run_at ASC, id ASC").
+			Limit(1).
+			Find(&job)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		now := time.Now()
+		job.Status = "running"
+		job.Attempts++
+		job.LockedAt = &now
+		job.LockedBy = q.workerID
+		return tx.Model(&job).Select("status", "attempts", "locked_at", "locked_by").Updates(&job).Error
+	})
+	if err != nil || job.ID == 0 {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (q *Queue) run(job *Job) {
+	q.mu.RLock()
+	handler := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	start := time.Now()
+	var err error
+	if handler == nil {
+		err = Permanent(fmt.Errorf("no handler registered for job type %q", job.Type))
+	} else {
+		err = q.call(handler, job)
+	}
+
+	q.finish(job, err, time.Since(start))
+}
+
+// call runs the handler, turning a panic into an ordinary failure.
+func (q *Queue) call(handler HandlerFunc, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(context.WithValue(q.ctx, jobKey{}, job), []byte(job.Payload))
+}
+
+type jobKey struct{}
+
+// LastAttempt reports whether the job running with ctx will not be retried
+// if it fails, so a handler can record the failure for good.
+func LastAttempt(ctx context.Context) bool {
+	job, ok := ctx.Value(jobKey{}).(*Job)
+	return ok && job.Attempts >= job.MaxAttempts
+}
+
+func (q *Queue) finish(job *Job, err error, took time.Duration) {
+	now := time.Now()
+	updates := map[string]interface{}{"locked_at": nil, "locked_by": ""}
+
+	var permanent *permanentError
+	switch {
+	case err == nil:
+		updates["status"] = "done"
+		updates["finished_at"] = now
+		updates["last_error"] = ""
+		q.logger.Info("Job done", "job", job.ID, "type", job.Type, "attempt", job.Attempts, "took", took.String())
+	case errors.As(err, &permanent) || job.Attempts >= job.MaxAttempts:
+		updates["status"] = "dead"
+		updates["finished_at"] = now
+		updates["last_error"] = err.Error()
+		q.logger.Error("Job failed permanently", "error", err, "job", job.ID, "type", job.Type, "attempt", job.Attempts)
+	default:
+		retryAt := now.Add(backoff(job.Attempts))
+		updates["status"] = "pending"
+		updates["run_at"] = retryAt
+		updates["last_error"] = err.Error()
+		q.logger.Warn("Job failed, will retry", "error", err, "job", job.ID, "type", job.Type, "attempt", job.Attempts, "retryAt", retryAt)
+	}
+
+	err = q.db.Model(&Job{}).
+		Where("id = ? AND status = ? AND locked_by = ?", job.ID, "running", q.workerID).
+		Updates(updates).Error
+	if err != nil {
+		q.logger.Error("Failed to record job result", "error", err, "job", job.ID)
+	}
+}
+
+// backoff is the wait before retry number attempt: 15s doubling each time,
+// capped at an hour, with up to 20% jitter so failures don't retry in step.
+func backoff(attempt int) time.Duration {
+	d := 15 * time.Second
+	for i := 1; i < attempt && d < time.Hour; i++ {
+		d *= 2
+	}
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}
+
+func (q *Queue) maintain() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		q.recoverExpired()
+		q.purgeFinished()
+
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recoverExpired releases jobs whose worker stopped reporting back within
+// the lease, most likely because the process died mid-job.
+func (q *Queue) recoverExpired() {
+	cutoff := time.Now().Add(-q.config.Lease)
+	now := time.Now()
+
+	dead := q.db.Model(&Job{}).
+		Where("status = ? AND locked_at < ? AND attempts >= max_attempts", "running", cutoff).
+		Updates(map[string]interface{}{"status": "dead", "finished_at": now, "locked_at": nil, "locked_by": "", "last_error": "worker lease expired"})
+	if dead.Error != nil {
+		q.logger.Error("Failed to expire stuck jobs", "error", dead.Error)
+	}
+
+	retry := q.db.Model(&Job{}).
+		Where("status = ? AND locked_at < ?", "running", cutoff).
+		Updates(map[string]interface{}{"status": "pending", "run_at": now, "locked_at": nil, "locked_by": "", "last_error": "worker lease expired"})
+	if retry.Error != nil {
+		q.logger.Error("Failed to recover stuck jobs", "error", retry.Error)
+	}
+
+	if n := dead.RowsAffected + retry.RowsAffected; n > 0 {
+		q.logger.Warn("Recovered jobs from expired leases", "retried", retry.RowsAffected, "dead", dead.RowsAffected)
+	}
+}
+
+func (q *Queue) purgeFinished() {
+	cutoff := time.Now().Add(-q.config.Retention)
+	err := q.db.Where("status = ? AND finished_at < ?", "done", cutoff).Delete(&Job{}).Error
+	if err != nil {
+		q.logger.Error("Failed to purge finished jobs", "error", err)
+	}
+}
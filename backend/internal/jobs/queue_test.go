@@ -0,0 +1,228 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type discardLogger struct{}
+
+func (discardLogger) Debug(string, ...interface{}) {}
+func (discardLogger) Info(string, ...interface{})  {}
+func (discardLogger) Warn(string, ...interface{})  {}
+func (discardLogger) Error(string, ...interface{}) {}
+
+// dryRun returns a session that builds SQL without a database. Every update
+// it would have run is passed to onUpdate.
+func dryRun(t *testing.T, onUpdate func(*gorm.Statement)) *gorm.DB {
+	t.Helper()
+	conn, err := sql.Open("pgx", "host=unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if onUpdate != nil {
+		err := db.Callback().Update().After("gorm:update").Register("test:capture", func(tx *gorm.DB) {
+			onUpdate(tx.Statement)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func newTestQueue(db *gorm.DB) *Queue {
+	return NewQueue(db, discardLogger{}, Config{MaxAttempts: 3})
+}
+
+func TestPermanent(t *testing.T) {
+	if Permanent(nil) != nil {
+		t.Error("Permanent(nil) should be nil")
+	}
+
+	cause := errors.New("guest not found")
+	err := Permanent(cause)
+	var permanent *permanentError
+	if !errors.As(err, &permanent) {
+		t.Error("Permanent error is not recognised as permanent")
+	}
+	if !errors.Is(err, cause) || err.Error() != cause.Error() {
+		t.Errorf("Permanent lost its cause: %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, 15 * time.Second},
+		{2, 30 * time.Second},
+		{3, time.Minute},
+		{9, time.Hour}, // doubling would reach 64m
+		{30, time.Hour},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 50; i++ {
+			d := backoff(tt.attempt)
+			if d < tt.base || d > tt.base+tt.base/5 {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", tt.attempt, d, tt.base, tt.base+tt.base/5)
+			}
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	type payload struct {
+		GuestID uint `json:"guestId"`
+	}
+
+	q := newTestQueue(nil)
+	var got payload
+	Register(q, "test.job", func(ctx context.Context, p payload) error {
+		got = p
+		return nil
+	})
+
+	if err := q.call(q.handlers["test.job"], &Job{Payload: `{"guestId":7}`}); err != nil {
+		t.Fatal(err)
+	}
+	if got.GuestID != 7 {
+		t.Errorf("handler got %+v", got)
+	}
+
+	err := q.call(q.handlers["test.job"], &Job{Payload: `{"guestId":"seven"}`})
+	var permanent *permanentError
+	if !errors.As(err, &permanent) {
+		t.Errorf("an undecodable payload should fail permanently, got %v", err)
+	}
+}
+
+func TestCallRecoversPanics(t *testing.T) {
+	q := newTestQueue(nil)
+	err := q.call(func(context.Context, []byte) error { panic("boom") }, &Job{})
+	if err == nil || err.Error() != "job panicked: boom" {
+		t.Errorf("call = %v", err)
+	}
+}
+
+func TestLastAttempt(t *testing.T) {
+	if LastAttempt(context.Background()) {
+		t.Error("LastAttempt outside a job should be false")
+	}
+
+	q := newTestQueue(nil)
+	for _, tt := range []struct {
+		attempts, max int
+		want          bool
+	}{{1, 3, false}, {2, 3, false}, {3, 3, true}, {4, 3, true}} {
+		var got bool
+		q.call(func(ctx context.Context, _ []byte) error {
+			got = LastAttempt(ctx)
+			return nil
+		}, &Job{Attempts: tt.attempts, MaxAttempts: tt.max})
+		if got != tt.want {
+			t.Errorf("LastAttempt on attempt %d of %d = %v", tt.attempts, tt.max, got)
+		}
+	}
+}
+
+func TestEnqueueTx(t *testing.T) {
+	q := newTestQueue(dryRun(t, nil))
+
+	before := time.Now()
+	job, err := q.EnqueueTx(q.db, "test.job", map[string]int{"id": 1}, Delay(time.Hour), MaxAttempts(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Type != "test.job" || job.Status != "pending" || job.MaxAttempts != 9 {
+		t.Errorf("job = %+v", job)
+	}
+	if job.RunAt.Before(before.Add(time.Hour)) || job.RunAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("RunAt = %s, want an hour from now", job.RunAt)
+	}
+	var payload map[string]int
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil || payload["id"] != 1 {
+		t.Errorf("Payload = %s", job.Payload)
+	}
+
+	job, err = q.Enqueue("test.job", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.MaxAttempts != 3 {
+		t.Errorf("MaxAttempts = %d, want the queue default", job.MaxAttempts)
+	}
+
+	// Both enqueues nudged the workers without blocking on the full channel
+	select {
+	case <-q.wake:
+	default:
+		t.Error("enqueue did not wake a worker")
+	}
+
+	if _, err := q.Enqueue("test.job", func() {}); err == nil {
+		t.Error("an unencodable payload was enqueued")
+	}
+}
+
+func TestFinish(t *testing.T) {
+	var updates map[string]interface{}
+	q := newTestQueue(dryRun(t, func(stmt *gorm.Statement) {
+		updates, _ = stmt.Dest.(map[string]interface{})
+	}))
+
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		status   string
+	}{
+		{"success", 1, nil, "done"},
+		{"retryable failure", 1, errors.New("smtp timeout"), "pending"},
+		{"permanent failure", 1, Permanent(errors.New("no such guest")), "dead"},
+		{"out of attempts", 3, errors.New("smtp timeout"), "dead"},
+	}
+	for _, tt := range tests {
+		updates = nil
+		q.finish(&Job{ID: 1, Type: "test.job", Attempts: tt.attempts, MaxAttempts: 3}, tt.err, time.Second)
+		if updates == nil {
+			t.Fatalf("%s: no update recorded", tt.name)
+		}
+
+		if updates["status"] != tt.status {
+			t.Errorf("%s: status = %v, want %s", tt.name, updates["status"], tt.status)
+		}
+		if updates["locked_at"] != nil || updates["locked_by"] != "" {
+			t.Errorf("%s: lock not released: %v", tt.name, updates)
+		}
+		_, retrying := updates["run_at"]
+		if retrying != (tt.status == "pending") {
+			t.Errorf("%s: run_at set = %v", tt.name, retrying)
+		}
+		_, finished := updates["finished_at"]
+		if finished != (tt.status != "pending") {
+			t.Errorf("%s: finished_at set = %v", tt.name, finished)
+		}
+		if tt.err != nil && updates["last_error"] != tt.err.Error() {
+			t.Errorf("%s: last_error = %v", tt.name, updates["last_error"])
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"wedding-app/internal/models"
+	"wedding-app/pkg/pagination"
 )
 
 type Handler struct {
@@ -49,10 +50,35 @@ func (h *Handler) SendMessage(c *gin.Context) {
 	})
 }
 
+// MessageListOptions controls sorting and search on the admin message list.
+var MessageListOptions = pagination.Options{
+	Sortable: map[string]string{
+		"createdAt": "messages.created_at",
+		"guestName": "messages.guest_name",
+		"status":    "COALESCE(messages.status, '')",
+	},
+	DefaultSort: "-createdAt",
+	Searchable: []string{
+		"messages.guest_name",
+		"messages.content",
+	},
+	IDColumn: "messages.id",
+}
+
 func (h *Handler) GetMessages(c *gin.Context) {
-	var messages []models.Message
-	err := h.db.Order("created_at DESC").Find(&messages).Error
+	params, err := pagination.Parse(c, MessageListOptions)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.db.Model(&models.Message{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("messages.status = ?", status)
+	}
+
+	var page pagination.Page[models.Message]
+	if err := pagination.Find(query, params, &page); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
 		return
 	}
@@ -62,7 +88,9 @@ func (h *Handler) GetMessages(c *gin.Context) {
 	h.db.Model(&models.Message{}).Where("status = ?", "unread").Count(&unreadCount)
 
 	c.JSON(http.StatusOK, gin.H{
-		"messages":     messages,
+		"items":       page.Items,
+		"nextCursor":  page.NextCursor,
+		"total":       page.Total,
 		"unreadCount": unreadCount,
 	})
 }
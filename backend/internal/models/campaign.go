@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReminderCampaign is a scheduled nudge to guests who haven't RSVP'd yet.
+// Segment uses the guest segment query language; guests who have responded
+// by the time the campaign runs are always left out.
+type ReminderCampaign struct {
+	ID          uint           `json:"id" gorm:"primarykey"`
+	Name        string         `json:"name" gorm:"not null"`
+	Segment     string         `json:"segment" gorm:"type:text"`                // e.g. "tag:family side:bride"; empty means everyone
+	Channel     string         `json:"channel" gorm:"not null;default:'email'"` // email, sms
+	Subject     string         `json:"subject"`                                 // email only; empty uses the default
+	Body        string         `json:"body" gorm:"type:text"`                   // empty uses the default reminder text
+	SendAt      time.Time      `json:"sendAt" gorm:"not null;index"`
+	Status      string         `json:"status" gorm:"not null;default:'scheduled';index"` // scheduled, sending, sent, cancelled
+	StartedAt   *time.Time     `json:"startedAt"`
+	LockedBy    string         `json:"-"` // runner sending the campaign
+	HeartbeatAt *time.Time     `json:"-"` // renewed while sending; stale means the runner died
+	SentAt      *time.Time     `json:"sentAt"`
+	Sent        int            `json:"sent" gorm:"default:0"`
+	Skipped     int            `json:"skipped" gorm:"default:0"`
+	Failed      int            `json:"failed" gorm:"default:0"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// CampaignSend is the outcome of a campaign for one guest. A guest is only
+// ever contacted once per campaign, so an interrupted run can resume safely.
+type CampaignSend struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	CampaignID uint      `json:"campaignId" gorm:"not null;uniqueIndex:idx_campaign_sends_campaign_guest"`
+	GuestID    uint      `json:"guestId" gorm:"not null;uniqueIndex:idx_campaign_sends_campaign_guest;index"`
+	DeliveryID *uint     `json:"deliveryId"`
+	Status     string    `json:"status" gorm:"not null"` // sending, sent, failed, suppressed
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+
+	// Relationships
+	Campaign *ReminderCampaign `json:"campaign,omitempty" gorm:"foreignKey:CampaignID;constraint:OnDelete:CASCADE"`
+	Guest    *Guest            `json:"guest,omitempty" gorm:"foreignKey:GuestID;constraint:OnDelete:CASCADE"`
+	Delivery *Delivery         `json:"delivery,omitempty" gorm:"foreignKey:DeliveryID;constraint:OnDelete:SET NULL"`
+}
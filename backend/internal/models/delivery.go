@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// Delivery records one notification sent to a guest, so the admin can see
+// what went out and what bounced.
+type Delivery struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	GuestID    *uint      `json:"guestId" gorm:"index"`
+	Channel    string     `json:"channel" gorm:"not null;default:'email'"` // email, sms
+	Template   string     `json:"template" gorm:"not null"`                // approval, rsvp_confirmation, reminder
+	Recipient  string     `json:"recipient" gorm:"not null"`
+	Subject    string     `json:"subject"`
+	Status     string     `json:"status" gorm:"not null;index"` // sent, failed, bounced
+	ProviderID string     `json:"providerId" gorm:"index"`      // Message-ID for email, provider SID for SMS
+	Error      string     `json:"error,omitempty" gorm:"type:text"`
+	SentAt     *time.Time `json:"sentAt"`
+	BouncedAt  *time.Time `json:"bouncedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+
+	// Relationships
+	Guest *Guest `json:"guest,omitempty" gorm:"foreignKey:GuestID;constraint:OnDelete:SET NULL"`
+}
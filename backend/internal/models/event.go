@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Event is one entry in the wedding schedule guests can attend, such as a
+// mehndi, ceremony or reception. Accommodation nights are modelled as events
+// of type "accommodation" so they share the same per-guest answers.
+type Event struct {
+	ID          uint           `json:"id" gorm:"primarykey"`
+	Slug        string         `json:"slug" gorm:"uniqueIndex:idx_events_slug,where:deleted_at IS NULL;not null"`
+	Name        string         `json:"name" gorm:"not null"`
+	Type        string         `json:"type" gorm:"default:'event'"` // event, accommodation
+	Description string         `json:"description" gorm:"type:text"`
+	Location    string         `json:"location"`
+	StartsAt    *time.Time     `json:"startsAt"`
+	SortOrder   int            `json:"sortOrder" gorm:"default:0"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// EventAttendance is a guest's answer for a single event.
+type EventAttendance struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	GuestID   uint      `json:"guestId" gorm:"not null;uniqueIndex:idx_event_attendances_guest_event"`
+	EventID   uint      `json:"eventId" gorm:"not null;uniqueIndex:idx_event_attendances_guest_event;index"`
+	Attending bool      `json:"attending"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Relationships
+	Event Event `json:"event" gorm:"foreignKey:EventID;constraint:OnDelete:CASCADE"`
+}
+
+type EventStats struct {
+	EventID   uint   `json:"eventId"`
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Attending int64  `json:"attending"` // guests who said yes
+	Headcount int64  `json:"headcount"` // sum of their party sizes
+}
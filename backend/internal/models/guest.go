@@ -14,6 +14,7 @@ type PartyMember struct {
 	FirstName        string `json:"firstName"`
 	LastName         string `json:"lastName"`
 	DietaryPreference string `json:"dietaryPreference"`
+	Answers          Answers `json:"answers,omitempty"` // per-member questions
 }
 
 // PartyMembers is a slice of PartyMember that implements driver.Valuer and sql.Scanner
@@ -63,7 +64,8 @@ type Guest struct {
 	FirstName          string         `json:"firstName" gorm:"not null"`
 	LastName           string         `json:"lastName" gorm:"not null"`
 	Email              string         `json:"email"`
-	Phone              string         `json:"phone"`
+	Phone              string         `json:"phone"` // E.164, e.g. +919876543210
+	SMSOptIn           bool           `json:"smsOptIn" gorm:"default:false"`
 	InviteToken        string         `json:"inviteToken" gorm:"uniqueIndex;default:null"`
 	GuestPortalToken   string         `json:"guestPortalToken" gorm:"uniqueIndex;default:null"`
 	RegistrationStatus string         `json:"registrationStatus" gorm:"default:'pending'"` // pending, approved, rejected
@@ -71,6 +73,8 @@ type Guest struct {
 	RSVPStatus         string         `json:"rsvpStatus" gorm:"default:'pending'"` // pending, yes, no
 	PartySize          int            `json:"partySize" gorm:"default:0"`
 	MaxPartySize       int            `json:"maxPartySize" gorm:"default:2"`
+	Side               string         `json:"side"` // bride, groom, both
+	HouseholdID        *uint          `json:"householdId" gorm:"index;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
 	CreatedAt          time.Time      `json:"createdAt"`
 	UpdatedAt          time.Time      `json:"updatedAt"`
 	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
@@ -78,14 +82,33 @@ type Guest struct {
 	// Updated questionnaire fields
 	PartyMembers                 PartyMembers       `json:"partyMembers" gorm:"type:jsonb"`
 	MainPersonDietaryPreference  string             `json:"mainPersonDietaryPreference"`
-	Dec24Attendance              bool               `json:"dec24Attendance"`
-	Dec25Attendance              bool               `json:"dec25Attendance"`
-	AccommodationDec23           bool               `json:"accommodationDec23"`
-	AccommodationDec24           bool               `json:"accommodationDec24"`
-	AccommodationDec25           bool               `json:"accommodationDec25"`
 	Concerns                     string             `json:"concerns"`
+	Answers                      Answers            `json:"answers" gorm:"type:jsonb"` // custom questions, by key
+
+	// Events maps event slug to attendance. It is filled from Attendance
+	// after a query that preloads "Attendance.Event".
+	Events                       map[string]bool    `json:"events,omitempty" gorm:"-"`
 	
 	// Relationships
 	RSVPs              []RSVP         `json:"rsvps,omitempty" gorm:"foreignKey:GuestID"`
+	Household          *Household     `json:"household,omitempty" gorm:"foreignKey:HouseholdID"`
+	Tags               []GuestTag     `json:"tags,omitempty" gorm:"many2many:guest_tag_maps;joinForeignKey:GuestID;joinReferences:TagID;constraint:OnDelete:CASCADE"`
+	Attendance         []EventAttendance `json:"attendance,omitempty" gorm:"foreignKey:GuestID;constraint:OnDelete:CASCADE"`
+}
+
+// AfterFind builds the Events map when attendance rows were preloaded along
+// with their events.
+func (g *Guest) AfterFind(tx *gorm.DB) error {
+	if len(g.Attendance) == 0 {
+		return nil
+	}
+
+	g.Events = make(map[string]bool, len(g.Attendance))
+	for _, a := range g.Attendance {
+		if a.Event.Slug != "" {
+			g.Events[a.Event.Slug] = a.Attending
+		}
+	}
+	return nil
 }
 
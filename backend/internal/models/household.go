@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Household struct {
+	ID          uint           `json:"id" gorm:"primarykey"`
+	Name        string         `json:"name" gorm:"not null"`
+	InviteToken string         `json:"inviteToken" gorm:"uniqueIndex;default:null"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Guests []Guest `json:"guests,omitempty" gorm:"foreignKey:HouseholdID"`
+}
+
+type HouseholdStats struct {
+	Total     int64 `json:"total"`
+	Responded int64 `json:"responded"`
+	Pending   int64 `json:"pending"`
+	Empty     int64 `json:"empty"`     // no members yet, so nobody to respond
+	Attending int64 `json:"attending"` // every member said yes
+	Partial   int64 `json:"partial"`   // members answered differently
+	Declined  int64 `json:"declined"`  // every member said no
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Job is a unit of background work in the Postgres-backed job queue.
+// Workers claim pending jobs with SELECT ... FOR UPDATE SKIP LOCKED, so any
+// number of API processes can share the table.
+type Job struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	Type        string     `json:"type" gorm:"not null;index"`                                                       // e.g. photo.process, rsvp.confirmation
+	Payload     string     `json:"payload" gorm:"type:jsonb;not null"`                                               // handler-specific JSON
+	Status      string     `json:"status" gorm:"not null;default:'pending';index:idx_jobs_status_run_at,priority:1"` // pending, running, done, dead
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts int        `json:"maxAttempts" gorm:"not null;default:5"`
+	RunAt       time.Time  `json:"runAt" gorm:"not null;index:idx_jobs_status_run_at,priority:2"`
+	LockedAt    *time.Time `json:"lockedAt"`
+	LockedBy    string     `json:"lockedBy,omitempty"`
+	LastError   string     `json:"lastError,omitempty" gorm:"type:text"`
+	FinishedAt  *time.Time `json:"finishedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
@@ -11,24 +11,45 @@ type Photo struct {
 	FileName     string         `json:"fileName" gorm:"not null"`
 	S3Key        string         `json:"s3Key" gorm:"unique;not null"`
 	ThumbnailKey string         `json:"thumbnailKey"`
+	MediumKey    string         `json:"mediumKey"`
+	FullKey      string         `json:"fullKey"` // web-sized rendition; S3Key stays the untouched original
 	ContentType  string         `json:"contentType" gorm:"not null"`
+	MediaType    string         `json:"mediaType" gorm:"default:'image'"` // image, video
+	UploadID     string         `json:"-"`                                // multipart upload in progress (videos)
 	FileSize     int64          `json:"fileSize" gorm:"not null"`
 	Status       string         `json:"status" gorm:"default:'pending'"` // uploading, pending, approved, rejected
 	UploadedBy   string         `json:"uploadedBy"`
-	GuestToken   string         `json:"guestToken"`
+	GuestID      *uint          `json:"guestId" gorm:"index"`  // the guest whose portal the upload came from
+	GuestToken   string         `json:"-"`                     // their portal token at upload time
 	GuestName    string         `json:"guestName"`
 	UploadedAt   time.Time      `json:"uploadedAt"`
 	ModeratedAt  *time.Time     `json:"moderatedAt"`
+	ModeratedBy  *uint          `json:"moderatedBy"` // user ID of the last moderator
+	RejectionReason string      `json:"rejectionReason"`
 	Width        int            `json:"width" gorm:"default:0"`
 	Height       int            `json:"height" gorm:"default:0"`
+	CapturedAt   *time.Time     `json:"capturedAt" gorm:"index"` // from EXIF, when the camera recorded it
+	CameraMake   string         `json:"cameraMake"`
+	CameraModel  string         `json:"cameraModel"`
+	ProcessedAt  *time.Time     `json:"processedAt"`
+	ContentHash  string         `json:"contentHash" gorm:"index"` // SHA-256 of the original
+	PerceptualHash string       `json:"perceptualHash"`            // 64-bit difference hash, hex
+	DuplicateOfID *uint         `json:"duplicateOfId" gorm:"index"`
+	DuplicateKind string        `json:"duplicateKind"` // exact, similar
 	AlbumID      *uint          `json:"albumId" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	AlbumPosition int           `json:"albumPosition" gorm:"default:0"` // order within the album
 	CreatedAt    time.Time      `json:"createdAt"`
 	UpdatedAt    time.Time      `json:"updatedAt"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 	
+	// Relationships
+	DuplicateOf  *Photo         `json:"duplicateOf,omitempty" gorm:"foreignKey:DuplicateOfID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+
 	// Generated URLs (not stored in DB)
 	ThumbnailURL string         `json:"thumbnailUrl" gorm:"-"`
+	MediumURL    string         `json:"mediumUrl" gorm:"-"`
 	FullURL      string         `json:"fullUrl" gorm:"-"`
+	VideoURL     string         `json:"videoUrl,omitempty" gorm:"-"` // the clip itself; the other URLs are its poster frame
 }
 
 type Album struct {
@@ -37,7 +58,7 @@ type Album struct {
 	Description string         `json:"description" gorm:"type:text"`
 	Visibility  string         `json:"visibility" gorm:"default:'public'"` // public, private
 	CoverPhotoID *uint         `json:"coverPhotoId"`
-	PhotoCount  int            `json:"photoCount" gorm:"default:0"`
+	PhotoCount  int            `json:"photoCount" gorm:"default:0"` // approved photos, as guests see them
 	CreatedAt   time.Time      `json:"createdAt"`
 	UpdatedAt   time.Time      `json:"updatedAt"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
@@ -45,4 +66,53 @@ type Album struct {
 	// Relationships
 	Photos      []Photo        `json:"photos,omitempty" gorm:"foreignKey:AlbumID"`
 	CoverPhoto  *Photo         `json:"coverPhoto,omitempty" gorm:"foreignKey:CoverPhotoID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
-}
\ No newline at end of file
+
+	// Who may see a private album: these guests, and guests with any of
+	// these tags
+	Guests      []Guest        `json:"guests,omitempty" gorm:"many2many:album_guests;constraint:OnDelete:CASCADE"`
+	Tags        []GuestTag     `json:"tags,omitempty" gorm:"many2many:album_tags;joinForeignKey:AlbumID;joinReferences:TagID;constraint:OnDelete:CASCADE"`
+}
+
+// PhotoModeration records one moderation decision on one photo. Decisions
+// made together share a BatchID so they can be undone together.
+type PhotoModeration struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	BatchID    string     `json:"batchId" gorm:"not null;index"`
+	PhotoID    uint       `json:"photoId" gorm:"not null;index"`
+	Action     string     `json:"action" gorm:"not null"` // approve, reject, delete
+	FromStatus string     `json:"fromStatus"`
+	ToStatus   string     `json:"toStatus"`
+	Reason     string     `json:"reason" gorm:"type:text"`
+	WasCover   bool       `json:"wasCover" gorm:"default:false"` // a delete cleared it as its album's cover
+	UserID     *uint      `json:"userId" gorm:"index"`
+	UndoneAt   *time.Time `json:"undoneAt"`
+	UndoneBy   *uint      `json:"undoneBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+
+	// Filled in when listing (not stored in DB)
+	UserEmail string `json:"userEmail,omitempty" gorm:"->;-:migration"`
+}
+
+// PhotoArchive is a ZIP of photos built in the background, for sets too
+// large to stream in a single request.
+type PhotoArchive struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	Token       string     `json:"token" gorm:"uniqueIndex;not null"` // how guests refer to it
+	Scope       string     `json:"scope" gorm:"not null"`             // gallery, album, uploader, selection
+	AlbumID     *uint      `json:"albumId"`
+	Uploader    string     `json:"uploader"`
+	PhotoIDs    string     `json:"-" gorm:"type:text"` // JSON array of the photos to include
+	Fingerprint string     `json:"-" gorm:"index"`     // identifies the photo set, to reuse archives
+	Status      string     `json:"status" gorm:"default:'pending'"` // pending, ready, failed
+	PhotoCount  int        `json:"photoCount"`
+	Size        int64      `json:"size"`
+	StorageKey  string     `json:"-"`
+	Error       string     `json:"error,omitempty"`
+	RequestedBy *uint      `json:"requestedBy"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+
+	// Generated URL (not stored in DB)
+	DownloadURL string `json:"downloadUrl,omitempty" gorm:"-"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Question is an admin-defined registration or RSVP question. Answers are
+// stored under Key in Guest.Answers, and in PartyMember.Answers for
+// per-member questions, so adding a question needs no schema change.
+type Question struct {
+	ID        uint           `json:"id" gorm:"primarykey"`
+	Key       string         `json:"key" gorm:"uniqueIndex:idx_questions_key,where:deleted_at IS NULL;not null"`
+	Label     string         `json:"label" gorm:"not null"`
+	Type      string         `json:"type" gorm:"not null"` // text, single_choice, multi_choice, boolean, number
+	Options   StringList     `json:"options" gorm:"type:jsonb"`
+	HelpText  string         `json:"helpText"`
+	Required  bool           `json:"required" gorm:"default:false"`
+	PerMember bool           `json:"perMember" gorm:"default:false"` // asked for every party member
+	Stage     string         `json:"stage" gorm:"default:'both'"`    // registration, rsvp, both
+	Min       *float64       `json:"min"`
+	Max       *float64       `json:"max"`
+	SortOrder int            `json:"sortOrder" gorm:"default:0"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// StringList is a slice of strings that implements driver.Valuer and sql.Scanner
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	return json.Marshal(l)
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, &l)
+}
+
+// Answers maps question key to a guest's answer: a string, a list of
+// strings, a bool or a number depending on the question type.
+type Answers map[string]interface{}
+
+func (a Answers) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
+
+func (a *Answers) Scan(value interface{}) error {
+	if value == nil {
+		*a = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, &a)
+}
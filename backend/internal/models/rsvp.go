@@ -40,4 +40,7 @@ type RSVPStats struct {
 	No             int64 `json:"no"`
 	Pending        int64 `json:"pending"`
 	TotalAttending int64 `json:"totalAttending"`
+
+	Households HouseholdStats `json:"households"`
+	Events     []EventStats   `json:"events"`
 }
\ No newline at end of file
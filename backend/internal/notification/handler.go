@@ -0,0 +1,90 @@
+package notification
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"wedding-app/pkg/pagination"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// GetDeliveries lists sent notifications, filtered by ?channel=, ?status=
+// and ?guestId=.
+func (h *Handler) GetDeliveries(c *gin.Context) {
+	params, err := pagination.Parse(c, DeliveryListOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := DeliveryFilter{
+		Channel: c.Query("channel"),
+		Status:  c.Query("status"),
+	}
+	if v := c.Query("guestId"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid guest ID"})
+			return
+		}
+		filter.GuestID = uint(id)
+	}
+
+	page, err := h.service.ListDeliveries(params, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+type BounceRequest struct {
+	MessageID string `json:"messageId"`
+	Recipient string `json:"recipient"`
+	Reason    string `json:"reason"`
+}
+
+// ReportBounce is the webhook a mail provider calls when a message bounces
+// after it was accepted. It must carry MAIL_WEBHOOK_SECRET in the
+// X-Webhook-Secret header; without a configured secret the webhook is off.
+func (h *Handler) ReportBounce(c *gin.Context) {
+	secret := os.Getenv("MAIL_WEBHOOK_SECRET")
+	given := c.GetHeader("X-Webhook-Secret")
+	if secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(given)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook secret"})
+		return
+	}
+
+	var req BounceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	delivery, err := h.service.MarkBounced(req.MessageID, req.Recipient, req.Reason)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
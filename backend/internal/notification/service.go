@@ -0,0 +1,314 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"wedding-app/internal/models"
+	"wedding-app/pkg/logger"
+	"wedding-app/pkg/notify"
+	"wedding-app/pkg/pagination"
+)
+
+// ErrSMSNotAllowed is returned when a guest has no phone number or hasn't
+// opted in to text messages.
+var ErrSMSNotAllowed = errors.New("guest has not opted in to SMS")
+
+type Service struct {
+	db        *gorm.DB
+	logger    logger.Logger
+	mailer    notify.Mailer
+	sms       notify.SMSSender
+	templates *notify.Templates
+	baseURL   string
+}
+
+func NewService(db *gorm.DB, logger logger.Logger, mailer notify.Mailer, sms notify.SMSSender, templates *notify.Templates) *Service {
+	baseURL := os.Getenv("APP_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:3000"
+	}
+
+	return &Service{
+		db:        db,
+		logger:    logger,
+		mailer:    mailer,
+		sms:       sms,
+		templates: templates,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (s *Service) RSVPURL(g *models.Guest) string {
+	return fmt.Sprintf("%s/rsvp/%s", s.baseURL, g.InviteToken)
+}
+
+func (s *Service) PortalURL(g *models.Guest) string {
+	return fmt.Sprintf("%s/guest-portal/%s", s.baseURL, g.GuestPortalToken)
+}
+
+// SendApproval sends a newly approved guest their RSVP and portal links
+// over channel ("email" or "sms").
+func (s *Service) SendApproval(g *models.Guest, channel string) error {
+	var err error
+	switch channel {
+	case "email":
+		_, err = s.SendEmail(g, notify.TemplateApproval, notify.Data{})
+	case "sms":
+		_, err = s.SendSMS(g, notify.TemplateApproval, notify.Data{})
+	default:
+		err = fmt.Errorf("unknown channel %q", channel)
+	}
+	return err
+}
+
+// SendRSVPConfirmation confirms a guest's response, listing the events they
+// said they'll attend.
+func (s *Service) SendRSVPConfirmation(g *models.Guest, response string, events []string) error {
+	_, err := s.SendEmail(g, notify.TemplateRSVPConfirmation, notify.Data{
+		Response:  response,
+		PartySize: g.PartySize,
+		Events:    events,
+	})
+	return err
+}
+
+// SendReminder nudges a guest to RSVP over channel ("email" or "sms").
+// Empty subject or body fall back to the template defaults.
+func (s *Service) SendReminder(g *models.Guest, channel, subject, body string) (*models.Delivery, error) {
+	data := notify.Data{Subject: subject, Body: body}
+	switch channel {
+	case "email":
+		return s.SendEmail(g, notify.TemplateReminder, data)
+	case "sms":
+		return s.SendSMS(g, notify.TemplateReminder, data)
+	default:
+		return nil, fmt.Errorf("unknown channel %q", channel)
+	}
+}
+
+// SendEmail renders the named template for g and sends it, recording the
+// outcome as a delivery. The guest's name and links are filled in on data.
+// The delivery is returned even when sending failed.
+func (s *Service) SendEmail(g *models.Guest, template string, data notify.Data) (*models.Delivery, error) {
+	if g.Email == "" {
+		return nil, fmt.Errorf("guest %d has no email address", g.ID)
+	}
+
+	data.FirstName = g.FirstName
+	data.RSVPURL = s.RSVPURL(g)
+	data.PortalURL = s.PortalURL(g)
+
+	msg, err := s.templates.Render(template, g.Email, data)
+	if err != nil {
+		return nil, err
+	}
+
+	guestID := g.ID
+	delivery := &models.Delivery{
+		GuestID:   &guestID,
+		Channel:   "email",
+		Template:  template,
+		Recipient: g.Email,
+		Subject:   msg.Subject,
+	}
+
+	messageID, sendErr := s.mailer.Send(msg)
+	s.record(delivery, messageID, sendErr)
+
+	if sendErr != nil {
+		return delivery, fmt.Errorf("failed to send %s email: %w", template, sendErr)
+	}
+
+	s.logger.Info("Email sent", "guest", g.ID, "template", template, "messageId", messageID)
+	return delivery, nil
+}
+
+// SendUserEmail renders the named template for an admin user, or someone
+// being invited to become one, and sends it to the address to. It is
+// recorded as a delivery with no guest.
+func (s *Service) SendUserEmail(to, template string, data notify.Data) error {
+	msg, err := s.templates.Render(template, to, data)
+	if err != nil {
+		return err
+	}
+
+	delivery := &models.Delivery{
+		Channel:   "email",
+		Template:  template,
+		Recipient: to,
+		Subject:   msg.Subject,
+	}
+
+	messageID, sendErr := s.mailer.Send(msg)
+	s.record(delivery, messageID, sendErr)
+
+	if sendErr != nil {
+		return fmt.Errorf("failed to send %s email: %w", template, sendErr)
+	}
+
+	s.logger.Info("Email sent", "recipient", to, "template", template, "messageId", messageID)
+	return nil
+}
+
+// SendSMS renders the named template as a text message for g and sends it,
+// recording the outcome as a delivery. Guests who haven't opted in are
+// skipped with ErrSMSNotAllowed. If the provider reports the number as
+// unsubscribed, the guest is opted out.
+func (s *Service) SendSMS(g *models.Guest, template string, data notify.Data) (*models.Delivery, error) {
+	if g.Phone == "" || !g.SMSOptIn {
+		return nil, ErrSMSNotAllowed
+	}
+
+	data.FirstName = g.FirstName
+	data.RSVPURL = s.RSVPURL(g)
+	data.PortalURL = s.PortalURL(g)
+
+	msg, err := s.templates.RenderSMS(template, g.Phone, data)
+	if err != nil {
+		return nil, err
+	}
+
+	guestID := g.ID
+	delivery := &models.Delivery{
+		GuestID:   &guestID,
+		Channel:   "sms",
+		Template:  template,
+		Recipient: g.Phone,
+	}
+
+	messageID, sendErr := s.sms.SendSMS(msg)
+	s.record(delivery, messageID, sendErr)
+
+	if errors.Is(sendErr, notify.ErrUnsubscribed) {
+		err := s.db.Model(&models.Guest{}).Where("id = ?", g.ID).Update("sms_opt_in", false).Error
+		if err != nil {
+			s.logger.Error("Failed to opt out unsubscribed guest", "error", err, "guest", g.ID)
+		}
+		g.SMSOptIn = false
+	}
+	if sendErr != nil {
+		return delivery, fmt.Errorf("failed to send %s SMS: %w", template, sendErr)
+	}
+
+	s.logger.Info("SMS sent", "guest", g.ID, "template", template, "messageId", messageID)
+	return delivery, nil
+}
+
+// record stores the outcome of a send. Permanent rejections count as
+// bounces; anything else that failed may succeed on a retry.
+func (s *Service) record(delivery *models.Delivery, providerID string, sendErr error) {
+	delivery.ProviderID = providerID
+	now := time.Now()
+	switch {
+	case sendErr == nil:
+		delivery.Status = "sent"
+		delivery.SentAt = &now
+	case errors.Is(sendErr, notify.ErrRejected):
+		delivery.Status = "bounced"
+		delivery.BouncedAt = &now
+		delivery.Error = sendErr.Error()
+	default:
+		delivery.Status = "failed"
+		delivery.Error = sendErr.Error()
+	}
+
+	if err := s.db.Create(delivery).Error; err != nil {
+		s.logger.Error("Failed to record delivery", "error", err, "channel", delivery.Channel, "template", delivery.Template)
+	}
+}
+
+// SetSMSOptIn records whether the guest agrees to receive text messages.
+func (s *Service) SetSMSOptIn(guestID uint, optIn bool) error {
+	result := s.db.Model(&models.Guest{}).Where("id = ?", guestID).Update("sms_opt_in", optIn)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	s.logger.Info("SMS preference updated", "guest", guestID, "optIn", optIn)
+	return nil
+}
+
+// DeliveryListOptions controls sorting and search on the delivery log.
+var DeliveryListOptions = pagination.Options{
+	Sortable: map[string]string{
+		"createdAt": "deliveries.created_at",
+		"status":    "deliveries.status",
+		"template":  "deliveries.template",
+		"recipient": "deliveries.recipient",
+	},
+	DefaultSort: "-createdAt",
+	Searchable:  []string{"deliveries.recipient", "deliveries.subject", "deliveries.error"},
+	IDColumn:    "deliveries.id",
+}
+
+type DeliveryFilter struct {
+	Channel string
+	Status  string
+	GuestID uint
+}
+
+// ListDeliveries returns one page of the delivery log, newest first by
+// default.
+func (s *Service) ListDeliveries(params *pagination.Params, filter DeliveryFilter) (*pagination.Page[models.Delivery], error) {
+	query := s.db.Model(&models.Delivery{})
+	if filter.Channel != "" {
+		query = query.Where("deliveries.channel = ?", filter.Channel)
+	}
+	if filter.Status != "" {
+		query = query.Where("deliveries.status = ?", filter.Status)
+	}
+	if filter.GuestID != 0 {
+		query = query.Where("deliveries.guest_id = ?", filter.GuestID)
+	}
+
+	var page pagination.Page[models.Delivery]
+	err := pagination.Find(query, params, &page, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Guest", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped().Select("id", "first_name", "last_name", "email", "phone")
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// MarkBounced records a bounce reported after the message was accepted,
+// matched by Message-ID or, failing that, the most recent delivery to the
+// recipient.
+func (s *Service) MarkBounced(messageID, recipient, reason string) (*models.Delivery, error) {
+	var delivery models.Delivery
+	var err error
+	switch {
+	case messageID != "":
+		err = s.db.Where("provider_id = ?", messageID).First(&delivery).Error
+	case recipient != "":
+		err = s.db.Where("LOWER(recipient) = LOWER(?)", recipient).Order("created_at DESC").First(&delivery).Error
+	default:
+		return nil, fmt.Errorf("messageId or recipient is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery.Status = "bounced"
+	delivery.BouncedAt = &now
+	if reason != "" {
+		delivery.Error = reason
+	}
+	if err := s.db.Save(&delivery).Error; err != nil {
+		return nil, err
+	}
+
+	s.logger.Warn("Email bounced", "delivery", delivery.ID, "recipient", delivery.Recipient, "reason", reason)
+	return &delivery, nil
+}
@@ -0,0 +1,501 @@
+package photo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"wedding-app/internal/models"
+)
+
+// ErrAlbumNotVisible is returned when a guest asks for a private album they
+// haven't been given access to. Handlers report it as not found.
+var ErrAlbumNotVisible = errors.New("album not found")
+
+// ErrInvalidPortalToken is returned for a portal token that matches no guest.
+var ErrInvalidPortalToken = errors.New("invalid guest portal token")
+
+type AlbumInput struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Visibility  string  `json:"visibility"`
+	// Access lists for private albums; nil leaves them unchanged
+	GuestIDs []uint `json:"guestIds"`
+	TagIDs   []uint `json:"tagIds"`
+}
+
+func (s *Service) GetAlbums() ([]Album, error) {
+	var albums []Album
+	err := s.db.Preload("CoverPhoto").Order("created_at DESC").Find(&albums).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range albums {
+		if albums[i].CoverPhoto != nil {
+			s.signURLs(albums[i].CoverPhoto)
+		}
+	}
+	return albums, nil
+}
+
+// GetAlbum returns an album with every photo in it, whatever its status,
+// and its access lists.
+func (s *Service) GetAlbum(id uint) (*Album, error) {
+	var album Album
+	err := s.db.
+		Preload("Photos", func(db *gorm.DB) *gorm.DB {
+			return db.Order("album_position ASC, id ASC")
+		}).
+		Preload("Guests", func(db *gorm.DB) *gorm.DB {
+			return db.Select("guests.id", "guests.first_name", "guests.last_name")
+		}).
+		Preload("Tags").
+		First(&album, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range album.Photos {
+		s.signURLs(&album.Photos[i])
+	}
+	return &album, nil
+}
+
+func (s *Service) CreateAlbum(input AlbumInput) (*Album, error) {
+	album := Album{Visibility: "public"}
+	if err := applyAlbumInput(&album, input); err != nil {
+		return nil, err
+	}
+	if album.Title == "" {
+		return nil, fmt.Errorf("album title is required")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Guests", "Tags").Create(&album).Error; err != nil {
+			return err
+		}
+		return setAlbumAccess(tx, &album, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetAlbum(album.ID)
+}
+
+func (s *Service) UpdateAlbum(id uint, input AlbumInput) (*Album, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var album Album
+		if err := tx.First(&album, id).Error; err != nil {
+			return err
+		}
+		if err := applyAlbumInput(&album, input); err != nil {
+			return err
+		}
+		if album.Title == "" {
+			return fmt.Errorf("album title is required")
+		}
+
+		err := tx.Model(&album).Select("title", "description", "visibility").Updates(&album).Error
+		if err != nil {
+			return err
+		}
+		return setAlbumAccess(tx, &album, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetAlbum(id)
+}
+
+// DeleteAlbum removes an album. Its photos stay in the gallery, just no
+// longer in the album.
+func (s *Service) DeleteAlbum(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var album Album
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&album, id).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&Photo{}).Where("album_id = ?", id).
+			Updates(map[string]interface{}{"album_id": nil, "album_position": 0}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&album).Association("Guests").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(&album).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&album).Error
+	})
+}
+
+func applyAlbumInput(album *Album, input AlbumInput) error {
+	if input.Title != nil {
+		album.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Description != nil {
+		album.Description = strings.TrimSpace(*input.Description)
+	}
+	if input.Visibility != "" {
+		if input.Visibility != "public" && input.Visibility != "private" {
+			return fmt.Errorf("visibility must be public or private")
+		}
+		album.Visibility = input.Visibility
+	}
+	return nil
+}
+
+// setAlbumAccess replaces the guests and tags that may see a private album.
+func setAlbumAccess(tx *gorm.DB, album *Album, input AlbumInput) error {
+	if input.GuestIDs != nil {
+		var guests []models.Guest
+		if len(input.GuestIDs) > 0 {
+			if err := tx.Where("id IN ?", input.GuestIDs).Find(&guests).Error; err != nil {
+				return err
+			}
+			if len(guests) != len(uniqueIDs(input.GuestIDs)) {
+				return fmt.Errorf("one or more guests not found")
+			}
+		}
+		if err := tx.Model(album).Association("Guests").Replace(guests); err != nil {
+			return err
+		}
+	}
+
+	if input.TagIDs != nil {
+		var tags []models.GuestTag
+		if len(input.TagIDs) > 0 {
+			if err := tx.Where("id IN ?", input.TagIDs).Find(&tags).Error; err != nil {
+				return err
+			}
+			if len(tags) != len(uniqueIDs(input.TagIDs)) {
+				return fmt.Errorf("one or more tags not found")
+			}
+		}
+		if err := tx.Model(album).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddPhotos moves photos into an album, after the photos already there.
+// Photos taken from another album leave it.
+func (s *Service) AddPhotos(albumID uint, photoIDs []uint) (*Album, error) {
+	photoIDs = uniqueIDs(photoIDs)
+	if len(photoIDs) == 0 {
+		return nil, fmt.Errorf("photoIds is required")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockAlbum(tx, albumID); err != nil {
+			return err
+		}
+
+		var photos []Photo
+		if err := tx.Where("id IN ? AND status <> ?", photoIDs, "uploading").Find(&photos).Error; err != nil {
+			return err
+		}
+		if len(photos) != len(photoIDs) {
+			return fmt.Errorf("one or more photos not found")
+		}
+
+		var next int
+		err := tx.Model(&Photo{}).Where("album_id = ?", albumID).
+			Select("COALESCE(MAX(album_position), -1) + 1").Scan(&next).Error
+		if err != nil {
+			return err
+		}
+
+		affected := []uint{albumID}
+		byID := make(map[uint]*Photo, len(photos))
+		for i := range photos {
+			byID[photos[i].ID] = &photos[i]
+		}
+		// Keep the order the photos were given in
+		for _, id := range photoIDs {
+			p := byID[id]
+			if p.AlbumID != nil && *p.AlbumID == albumID {
+				continue
+			}
+			if p.AlbumID != nil {
+				affected = append(affected, *p.AlbumID)
+			}
+			err := tx.Model(p).Updates(map[string]interface{}{"album_id": albumID, "album_position": next}).Error
+			if err != nil {
+				return err
+			}
+			next++
+		}
+
+		// Albums the photos left can't keep them as their cover
+		err = tx.Model(&Album{}).Where("id <> ? AND cover_photo_id IN ?", albumID, photoIDs).
+			Update("cover_photo_id", nil).Error
+		if err != nil {
+			return err
+		}
+		return recountAlbums(tx, affected...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetAlbum(albumID)
+}
+
+// RemovePhotos takes photos out of an album. They stay in the gallery.
+func (s *Service) RemovePhotos(albumID uint, photoIDs []uint) (*Album, error) {
+	if len(photoIDs) == 0 {
+		return nil, fmt.Errorf("photoIds is required")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		album, err := lockAlbum(tx, albumID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&Photo{}).Where("album_id = ? AND id IN ?", albumID, photoIDs).
+			Updates(map[string]interface{}{"album_id": nil, "album_position": 0}).Error
+		if err != nil {
+			return err
+		}
+
+		if album.CoverPhotoID != nil && containsID(photoIDs, *album.CoverPhotoID) {
+			if err := tx.Model(album).Update("cover_photo_id", nil).Error; err != nil {
+				return err
+			}
+		}
+		return recountAlbums(tx, albumID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetAlbum(albumID)
+}
+
+// ReorderPhotos puts the given photos first, in the given order, followed
+// by any others in the album in their existing order.
+func (s *Service) ReorderPhotos(albumID uint, photoIDs []uint) (*Album, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockAlbum(tx, albumID); err != nil {
+			return err
+		}
+
+		var current []uint
+		err := tx.Model(&Photo{}).Where("album_id = ?", albumID).
+			Order("album_position ASC, id ASC").Pluck("id", &current).Error
+		if err != nil {
+			return err
+		}
+
+		order := make([]uint, 0, len(current))
+		for _, id := range uniqueIDs(photoIDs) {
+			if !containsID(current, id) {
+				return fmt.Errorf("photo %d is not in this album", id)
+			}
+			order = append(order, id)
+		}
+		for _, id := range current {
+			if !containsID(order, id) {
+				order = append(order, id)
+			}
+		}
+
+		for position, id := range order {
+			if err := tx.Model(&Photo{}).Where("id = ?", id).Update("album_position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetAlbum(albumID)
+}
+
+// SetCover picks the album's cover from its own photos; nil clears it.
+func (s *Service) SetCover(albumID uint, photoID *uint) (*Album, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		album, err := lockAlbum(tx, albumID)
+		if err != nil {
+			return err
+		}
+
+		if photoID != nil {
+			var count int64
+			err := tx.Model(&Photo{}).Where("id = ? AND album_id = ?", *photoID, albumID).Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return fmt.Errorf("photo %d is not in this album", *photoID)
+			}
+		}
+		return tx.Model(album).Update("cover_photo_id", photoID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetAlbum(albumID)
+}
+
+// GetVisibleAlbums lists the albums a visitor may see: every public album,
+// plus private ones shared with the guest whose portal token is given.
+func (s *Service) GetVisibleAlbums(portalToken string) ([]Album, error) {
+	query := s.db.Preload("CoverPhoto", "status = ?", "approved").Order("created_at DESC")
+
+	guestID, tagIDs, err := s.viewer(portalToken)
+	if err != nil {
+		return nil, err
+	}
+	if guestID == 0 {
+		query = query.Where("visibility = ?", "public")
+	} else {
+		query = query.Where(s.visibleTo(guestID, tagIDs))
+	}
+
+	var albums []Album
+	if err := query.Find(&albums).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range albums {
+		if albums[i].CoverPhoto != nil {
+			s.signURLs(albums[i].CoverPhoto)
+		}
+	}
+	return albums, nil
+}
+
+// GetPublicAlbum returns an album's approved photos for the gallery,
+// checking the visitor may see it.
+func (s *Service) GetPublicAlbum(id uint, portalToken string) (*Album, error) {
+	guestID, tagIDs, err := s.viewer(portalToken)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.Where("visibility = ?", "public")
+	if guestID != 0 {
+		query = s.db.Where(s.visibleTo(guestID, tagIDs))
+	}
+
+	var album Album
+	err = query.
+		Preload("Photos", func(db *gorm.DB) *gorm.DB {
+			return db.Where("status = ?", "approved").Order("album_position ASC, id ASC")
+		}).
+		Preload("CoverPhoto", "status = ?", "approved").
+		First(&album, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAlbumNotVisible
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range album.Photos {
+		s.signURLs(&album.Photos[i])
+	}
+	if album.CoverPhoto != nil {
+		s.signURLs(album.CoverPhoto)
+	}
+	return &album, nil
+}
+
+// viewer resolves a guest portal token to the guest and their tag IDs. An
+// empty token is an anonymous visitor; an unknown one is an error.
+func (s *Service) viewer(portalToken string) (uint, []uint, error) {
+	if portalToken == "" {
+		return 0, nil, nil
+	}
+
+	var guest models.Guest
+	err := s.db.Select("id").Where("guest_portal_token = ?", portalToken).First(&guest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil, ErrInvalidPortalToken
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var tagIDs []uint
+	err = s.db.Table("guest_tag_maps").Where("guest_id = ?", guest.ID).Pluck("tag_id", &tagIDs).Error
+	if err != nil {
+		return 0, nil, err
+	}
+	return guest.ID, tagIDs, nil
+}
+
+// visibleTo matches albums a guest may see: public ones, and private ones
+// shared with them directly or through a tag.
+func (s *Service) visibleTo(guestID uint, tagIDs []uint) *gorm.DB {
+	condition := s.db.Where("albums.visibility = ?", "public").
+		Or("albums.id IN (?)", s.db.Table("album_guests").Select("album_id").Where("guest_id = ?", guestID))
+	if len(tagIDs) > 0 {
+		condition = condition.Or("albums.id IN (?)", s.db.Table("album_tags").Select("album_id").Where("tag_id IN ?", tagIDs))
+	}
+	return condition
+}
+
+// visiblePhotos matches gallery photos a visitor may see: those in no
+// album, and those in an album visible to them. A guestID of 0 is an
+// anonymous visitor, who only sees public albums.
+func (s *Service) visiblePhotos(guestID uint, tagIDs []uint) *gorm.DB {
+	albums := s.db.Model(&Album{}).Select("albums.id")
+	if guestID == 0 {
+		albums = albums.Where("albums.visibility = ?", "public")
+	} else {
+		albums = albums.Where(s.visibleTo(guestID, tagIDs))
+	}
+	return s.db.Where("photos.album_id IS NULL").Or("photos.album_id IN (?)", albums)
+}
+
+func lockAlbum(tx *gorm.DB, id uint) (*Album, error) {
+	var album Album
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&album, id).Error; err != nil {
+		return nil, err
+	}
+	return &album, nil
+}
+
+// recountAlbums sets PhotoCount from the photos table. Callers run it in
+// the same transaction as the change that affected the count.
+func recountAlbums(tx *gorm.DB, albumIDs ...uint) error {
+	for _, id := range uniqueIDs(albumIDs) {
+		err := tx.Model(&Album{}).Where("id = ?", id).Update("photo_count",
+			tx.Model(&Photo{}).Select("COUNT(*)").Where("album_id = ? AND status = ?", id, "approved"),
+		).Error
+		if err != nil {
+			return fmt.Errorf("failed to update album photo count: %w", err)
+		}
+	}
+	return nil
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,465 @@
+package photo
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+	"wedding-app/internal/jobs"
+	"wedding-app/pkg/storage"
+)
+
+// JobBuildArchive builds a ZIP of photos too large to stream in one request.
+const JobBuildArchive = "photo.archive"
+
+// Sets within both limits are streamed straight to the client; larger
+// ones are built by a background job.
+const (
+	streamMaxPhotos = 200
+	streamMaxBytes  = 1 << 30
+)
+
+// maxArchiveSelection caps how many photos an admin may pick by ID.
+const maxArchiveSelection = 5000
+
+// A built archive can be downloaded for archiveRetention; each download
+// link is valid for archiveLinkExpiry.
+const (
+	archiveRetention  = 24 * time.Hour
+	archiveLinkExpiry = time.Hour
+)
+
+var (
+	ErrNoPhotos       = errors.New("there are no photos to download")
+	ErrArchiveExpired = errors.New("this archive has expired; request a new one")
+)
+
+type BuildArchiveJob struct {
+	ArchiveID uint `json:"archiveId"`
+}
+
+// ArchiveRequest picks the photos for a ZIP: the whole gallery, one album,
+// or one uploader's photos by guest name. PhotoIDs selects photos directly
+// and is only honoured for admins.
+type ArchiveRequest struct {
+	AlbumID  *uint  `json:"albumId"`
+	Uploader string `json:"uploader"`
+	PhotoIDs []uint `json:"photoIds"`
+	// Guest portal token, needed for private albums
+	Token string `json:"token"`
+}
+
+// ArchiveSet is the photos an archive request resolved to, in the order
+// they go into the ZIP.
+type ArchiveSet struct {
+	Scope  string
+	Name   string // ZIP file name without the extension
+	Photos []Photo
+	Size   int64
+}
+
+// Streamable reports whether the set is small enough to send in a single
+// response.
+func (a *ArchiveSet) Streamable() bool {
+	return len(a.Photos) <= streamMaxPhotos && a.Size <= streamMaxBytes
+}
+
+// ResolveArchive works out which photos an archive request covers. Guests
+// only get approved photos, and only from albums they may see; admins may
+// also pick photos in any state by ID.
+func (s *Service) ResolveArchive(req ArchiveRequest, admin bool) (*ArchiveSet, error) {
+	set := &ArchiveSet{Scope: "gallery", Name: "wedding-photos"}
+	query := s.db.Where("status = ?", "approved").Order("COALESCE(uploaded_at, created_at) ASC, id ASC")
+
+	switch {
+	case len(req.PhotoIDs) > 0:
+		if !admin {
+			return nil, fmt.Errorf("photoIds can only be used by admins")
+		}
+		ids := uniqueIDs(req.PhotoIDs)
+		if len(ids) > maxArchiveSelection {
+			return nil, fmt.Errorf("at most %d photos can be selected at once", maxArchiveSelection)
+		}
+		set.Scope = "selection"
+		query = s.db.Where("id IN ? AND status <> ?", ids, "uploading").
+			Order("COALESCE(uploaded_at, created_at) ASC, id ASC")
+	case req.AlbumID != nil:
+		album, err := s.archiveAlbum(*req.AlbumID, req.Token, admin)
+		if err != nil {
+			return nil, err
+		}
+		set.Scope = "album"
+		set.Name = fileSlug(album.Title, "album")
+		query = s.db.Where("status = ? AND album_id = ?", "approved", album.ID).
+			Order("album_position ASC, id ASC")
+	case req.Uploader != "":
+		set.Scope = "uploader"
+		set.Name = "photos-by-" + fileSlug(req.Uploader, "guest")
+		query = query.Where("guest_name = ?", req.Uploader)
+	}
+	if !admin && (set.Scope == "gallery" || set.Scope == "uploader") {
+		guestID, tagIDs, err := s.viewer(req.Token)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(s.visiblePhotos(guestID, tagIDs))
+	}
+
+	if err := query.Find(&set.Photos).Error; err != nil {
+		return nil, err
+	}
+	if len(set.Photos) == 0 {
+		return nil, ErrNoPhotos
+	}
+	for _, p := range set.Photos {
+		set.Size += p.FileSize
+	}
+	return set, nil
+}
+
+// archiveAlbum loads an album for download, checking a guest may see it.
+func (s *Service) archiveAlbum(id uint, portalToken string, admin bool) (*Album, error) {
+	var album Album
+	if admin {
+		if err := s.db.First(&album, id).Error; err != nil {
+			return nil, err
+		}
+		return &album, nil
+	}
+
+	guestID, tagIDs, err := s.viewer(portalToken)
+	if err != nil {
+		return nil, err
+	}
+	query := s.db.Where("visibility = ?", "public")
+	if guestID != 0 {
+		query = s.db.Where(s.visibleTo(guestID, tagIDs))
+	}
+	err = query.First(&album, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAlbumNotVisible
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &album, nil
+}
+
+// StreamArchive writes the set as a ZIP to w, reading each photo from
+// storage as it goes so only one is in flight at a time.
+func (s *Service) StreamArchive(ctx context.Context, w io.Writer, set *ArchiveSet) error {
+	_, err := s.writeArchive(ctx, w, set.Photos)
+	return err
+}
+
+// RequestArchive queues a background build of the set, or returns an
+// archive of the same photos that is already built or on its way.
+func (s *Service) RequestArchive(set *ArchiveSet, userID uint) (*PhotoArchive, error) {
+	ids := make([]uint, len(set.Photos))
+	for i, p := range set.Photos {
+		ids[i] = p.ID
+	}
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(append([]byte(set.Scope+":"), encoded...))
+	fingerprint := hex.EncodeToString(sum[:])
+
+	// Reuse an archive if it will outlive the link we hand out
+	var existing PhotoArchive
+	err = s.db.Where("fingerprint = ? AND (status = ? OR (status = ? AND expires_at > ?))",
+		fingerprint, "pending", "ready", time.Now().Add(archiveLinkExpiry)).
+		Order("id DESC").First(&existing).Error
+	if err == nil {
+		s.signArchive(&existing)
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	token, err := newArchiveToken()
+	if err != nil {
+		return nil, err
+	}
+	archive := PhotoArchive{
+		Token:       token,
+		Scope:       set.Scope,
+		PhotoIDs:    string(encoded),
+		Fingerprint: fingerprint,
+		Status:      "pending",
+		PhotoCount:  len(set.Photos),
+		Size:        set.Size,
+		StorageKey:  fmt.Sprintf("archives/%s/%s.zip", token, set.Name),
+	}
+	if set.Scope == "album" {
+		archive.AlbumID = set.Photos[0].AlbumID
+	}
+	if set.Scope == "uploader" {
+		archive.Uploader = set.Photos[0].GuestName
+	}
+	if userID != 0 {
+		archive.RequestedBy = &userID
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&archive).Error; err != nil {
+			return err
+		}
+		_, err := s.queue.EnqueueTx(tx, JobBuildArchive, BuildArchiveJob{ArchiveID: archive.ID})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Photo archive requested", "id", archive.ID, "scope", archive.Scope, "photos", archive.PhotoCount, "size", archive.Size)
+	return &archive, nil
+}
+
+// GetArchive returns an archive by its token, with a signed download link
+// once it is ready.
+func (s *Service) GetArchive(token string) (*PhotoArchive, error) {
+	var archive PhotoArchive
+	if err := s.db.Where("token = ?", token).First(&archive).Error; err != nil {
+		return nil, err
+	}
+	if archive.ExpiresAt != nil && archive.ExpiresAt.Before(time.Now()) {
+		return nil, ErrArchiveExpired
+	}
+
+	s.signArchive(&archive)
+	return &archive, nil
+}
+
+func (s *Service) signArchive(archive *PhotoArchive) {
+	if archive.Status == "ready" {
+		archive.DownloadURL = s.storage.GeneratePresignedURL(archive.StorageKey, archiveLinkExpiry)
+	}
+}
+
+// buildArchive is the background job that writes a requested archive to a
+// temporary file and uploads it to storage.
+func (s *Service) buildArchive(ctx context.Context, job BuildArchiveJob) (err error) {
+	var archive PhotoArchive
+	if err := s.db.First(&archive, job.ArchiveID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	if archive.Status == "ready" {
+		return nil
+	}
+
+	defer func() {
+		if err != nil && jobs.LastAttempt(ctx) {
+			s.db.Model(&archive).Updates(map[string]interface{}{"status": "failed", "error": "Failed to build the archive"})
+		}
+	}()
+
+	var ids []uint
+	if err := json.Unmarshal([]byte(archive.PhotoIDs), &ids); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	// Photos deleted or unapproved since the request are left out
+	query := s.db.Where("id IN ?", ids)
+	if archive.Scope == "selection" {
+		query = query.Where("status <> ?", "uploading")
+	} else {
+		query = query.Where("status = ?", "approved")
+	}
+	var photos []Photo
+	if err := query.Find(&photos).Error; err != nil {
+		return err
+	}
+	order := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		order[id] = i
+	}
+	sort.Slice(photos, func(i, j int) bool { return order[photos[i].ID] < order[photos[j].ID] })
+
+	file, err := os.CreateTemp("", "photo-archive-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	count, err := s.writeArchive(ctx, file, photos)
+	if err != nil {
+		return err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := s.storage.PutObject(archive.StorageKey, file, "application/zip"); err != nil {
+		return err
+	}
+
+	expires := time.Now().Add(archiveRetention)
+	err = s.db.Model(&archive).Updates(map[string]interface{}{
+		"status":      "ready",
+		"photo_count": count,
+		"size":        size,
+		"error":       "",
+		"expires_at":  expires,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Photo archive ready", "id", archive.ID, "photos", count, "size", size)
+	return nil
+}
+
+// writeArchive writes photos to w as a ZIP and returns how many went in.
+// Photos whose original has gone missing from storage are skipped.
+func (s *Service) writeArchive(ctx context.Context, w io.Writer, photos []Photo) (int, error) {
+	zw := zip.NewWriter(w)
+	names := archiveNames(photos)
+
+	written := 0
+	for i := range photos {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		body, err := s.storage.GetObject(photos[i].S3Key)
+		if errors.Is(err, storage.ErrNotFound) {
+			s.logger.Warn("Photo missing from storage, leaving it out of archive", "id", photos[i].ID, "key", photos[i].S3Key)
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+
+		// Photos are already compressed, so store them as they are
+		entry, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     names[i],
+			Method:   zip.Store,
+			Modified: uploadTime(&photos[i]),
+		})
+		if err == nil {
+			_, err = io.Copy(entry, body)
+		}
+		body.Close()
+		if err != nil {
+			return written, fmt.Errorf("failed to add photo %d to archive: %w", photos[i].ID, err)
+		}
+		written++
+	}
+
+	return written, zw.Close()
+}
+
+// archiveNames names each photo in a ZIP after the guest who uploaded it
+// and when, e.g. "Jane-Smith_2024-06-15_18-30-05.jpg", numbering clashes.
+func archiveNames(photos []Photo) []string {
+	names := make([]string, len(photos))
+	used := make(map[string]bool, len(photos))
+	for i := range photos {
+		p := &photos[i]
+		base := fileSlug(p.GuestName, "guest") + "_" + uploadTime(p).Format("2006-01-02_15-04-05")
+
+		ext := filepath.Ext(p.FileName)
+		if ext == "" {
+			ext = filepath.Ext(p.S3Key)
+		}
+		ext = "." + strings.ToLower(fileSlug(strings.TrimPrefix(ext, "."), "jpg"))
+
+		name := base + ext
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s-%d%s", base, n, ext)
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+func uploadTime(p *Photo) time.Time {
+	if p.UploadedAt.IsZero() {
+		return p.CreatedAt
+	}
+	return p.UploadedAt
+}
+
+// fileSlug reduces free text to letters and digits joined by dashes, safe
+// to use in a file name.
+func fileSlug(text, fallback string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range text {
+		if b.Len() >= 60 {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if b.Len() > 0 && !dash {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	if slug := strings.Trim(b.String(), "-"); slug != "" {
+		return slug
+	}
+	return fallback
+}
+
+// ExpireArchives deletes archives past their retention, and failed ones
+// once they have been visible for as long.
+func (s *Service) ExpireArchives() (int, error) {
+	now := time.Now()
+	var expired []PhotoArchive
+	err := s.db.Where("expires_at < ? OR (status = ? AND updated_at < ?)", now, "failed", now.Add(-archiveRetention)).
+		Find(&expired).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range expired {
+		if expired[i].Status == "ready" {
+			if err := s.storage.DeleteObject(expired[i].StorageKey); err != nil {
+				s.logger.Error("Failed to delete expired archive", "error", err, "key", expired[i].StorageKey)
+				continue
+			}
+		}
+		if err := s.db.Delete(&expired[i]).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	if len(expired) > 0 {
+		s.logger.Info("Expired photo archives", "count", len(expired))
+	}
+	return len(expired), nil
+}
+
+func newArchiveToken() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate archive token: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,278 @@
+package photo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+
+	"gorm.io/gorm"
+	"wedding-app/internal/jobs"
+	"wedding-app/pkg/imaging"
+)
+
+// JobHashPhoto fingerprints a photo processed before duplicate detection
+// existed, then checks it for duplicates.
+const JobHashPhoto = "photo.hash"
+
+// similarDistance is the largest perceptual hash distance, in bits out of
+// 64, at which two photos count as near duplicates.
+const similarDistance = 6
+
+// preferOriginal orders duplicate candidates so a photo is linked to the
+// copy a moderator most likely wants to keep: one that isn't itself a
+// duplicate, approved before pending, then the earliest upload.
+const preferOriginal = "(duplicate_of_id IS NOT NULL) ASC, " +
+	"CASE status WHEN 'approved' THEN 0 WHEN 'pending' THEN 1 ELSE 2 END ASC, id ASC"
+
+var (
+	ErrNotDuplicate    = errors.New("photo is not flagged as a duplicate")
+	ErrOriginalDeleted = errors.New("the photo this duplicates has been deleted")
+)
+
+type HashPhotoJob struct {
+	PhotoID uint `json:"photoId"`
+}
+
+// perceptualHash returns the perceptual hash of an upright image.
+func perceptualHash(upright image.Image) string {
+	return fmt.Sprintf("%016x", imaging.DHash(upright))
+}
+
+// hashPhoto is the background job behind ScanForDuplicates.
+func (s *Service) hashPhoto(ctx context.Context, job HashPhotoJob) error {
+	var photo Photo
+	if err := s.db.First(&photo, job.PhotoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+
+	if photo.ContentHash == "" {
+		src, err := s.readStill(ctx, &photo)
+		if err != nil {
+			return err
+		}
+
+		// The hash only looks at 9x8 pixels, so a small copy will do
+		upright := imaging.Orient(imaging.Fit(src.img, mediumSize), src.exif.Orientation)
+		photo.ContentHash, photo.PerceptualHash = src.contentHash, perceptualHash(upright)
+		err = s.db.Model(&photo).Select("content_hash", "perceptual_hash").Updates(&photo).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.checkDuplicate(&photo)
+}
+
+// checkDuplicate looks for another upload of the same picture and flags
+// photo as its duplicate: "exact" when the files are identical, "similar"
+// when their perceptual hashes are close. With auto-reject on, pending
+// exact duplicates are rejected straight away.
+func (s *Service) checkDuplicate(photo *Photo) error {
+	if photo.ContentHash == "" || photo.DuplicateOfID != nil {
+		return nil
+	}
+
+	kind := "exact"
+	var originalID uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Checks run one at a time, so two copies processed at once can't
+		// each see the other unflagged and end up linked to each other
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('photo.duplicates'))").Error; err != nil {
+			return err
+		}
+
+		// Never link two photos to each other
+		candidates := tx.Model(&Photo{}).
+			Where("id <> ? AND status <> ?", photo.ID, "uploading").
+			Where("duplicate_of_id IS NULL OR duplicate_of_id <> ?", photo.ID)
+
+		var exact []uint
+		err := candidates.Session(&gorm.Session{}).
+			Where("content_hash = ?", photo.ContentHash).
+			Order(preferOriginal).Limit(1).Pluck("id", &exact).Error
+		if err != nil {
+			return err
+		}
+		if len(exact) > 0 {
+			originalID = exact[0]
+		} else {
+			kind = "similar"
+			originalID, err = s.findSimilar(candidates.Session(&gorm.Session{}), photo)
+			if err != nil || originalID == 0 {
+				return err
+			}
+		}
+
+		photo.DuplicateOfID = &originalID
+		photo.DuplicateKind = kind
+		return tx.Model(photo).Select("duplicate_of_id", "duplicate_kind").Updates(photo).Error
+	})
+	if err != nil || originalID == 0 {
+		return err
+	}
+	s.logger.Info("Duplicate photo found", "id", photo.ID, "duplicateOf", originalID, "kind", kind)
+
+	if kind == "exact" && s.autoRejectDuplicates && photo.Status == "pending" {
+		reason := fmt.Sprintf("Exact duplicate of photo %d", originalID)
+		if _, err := s.Moderate(ActionReject, []uint{photo.ID}, reason, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findSimilar returns the closest candidate within similarDistance of
+// photo's perceptual hash, or 0.
+func (s *Service) findSimilar(candidates *gorm.DB, photo *Photo) (uint, error) {
+	hash, err := strconv.ParseUint(photo.PerceptualHash, 16, 64)
+	if err != nil {
+		return 0, nil
+	}
+
+	var rows []Photo
+	err = candidates.Where("perceptual_hash <> ''").
+		Order(preferOriginal).
+		Select("id", "perceptual_hash").Find(&rows).Error
+	if err != nil {
+		return 0, err
+	}
+
+	// Rows are in preference order, so the first at the best distance wins
+	var bestID uint
+	bestDistance := similarDistance + 1
+	for _, row := range rows {
+		other, err := strconv.ParseUint(row.PerceptualHash, 16, 64)
+		if err != nil {
+			continue
+		}
+		if d := imaging.HashDistance(hash, other); d < bestDistance {
+			bestID, bestDistance = row.ID, d
+		}
+	}
+	return bestID, nil
+}
+
+// MergeDuplicate folds a flagged duplicate into the photo it duplicates.
+// The kept photo takes the duplicate's album and camera details where it
+// has none, photos flagged as copies of the duplicate are relinked to it,
+// and the duplicate is deleted like any moderator delete, so it can be
+// undone within the undo window.
+func (s *Service) MergeDuplicate(photoID, userID uint) (*ModerationResult, error) {
+	var duplicate Photo
+	if err := s.db.First(&duplicate, photoID).Error; err != nil {
+		return nil, err
+	}
+	if duplicate.DuplicateOfID == nil {
+		return nil, ErrNotDuplicate
+	}
+	var kept Photo
+	if err := s.db.First(&kept, *duplicate.DuplicateOfID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOriginalDeleted
+		}
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{}
+		if kept.AlbumID == nil && duplicate.AlbumID != nil {
+			updates["album_id"] = *duplicate.AlbumID
+			updates["album_position"] = duplicate.AlbumPosition
+		}
+		if kept.CapturedAt == nil && duplicate.CapturedAt != nil {
+			updates["captured_at"] = *duplicate.CapturedAt
+		}
+		if kept.CameraMake == "" && kept.CameraModel == "" && (duplicate.CameraMake != "" || duplicate.CameraModel != "") {
+			updates["camera_make"] = duplicate.CameraMake
+			updates["camera_model"] = duplicate.CameraModel
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&kept).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		err := tx.Model(&Photo{}).
+			Where("duplicate_of_id = ? AND id <> ?", duplicate.ID, kept.ID).
+			Update("duplicate_of_id", kept.ID).Error
+		if err != nil {
+			return err
+		}
+
+		if _, moved := updates["album_id"]; moved {
+			return recountAlbums(tx, *duplicate.AlbumID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Moderate(ActionDelete, []uint{duplicate.ID}, fmt.Sprintf("Merged into photo %d", kept.ID), userID)
+}
+
+// DismissDuplicate clears a photo's duplicate flag after a moderator has
+// decided it is a different shot.
+func (s *Service) DismissDuplicate(photoID uint) error {
+	result := s.db.Model(&Photo{}).Where("id = ?", photoID).
+		Updates(map[string]interface{}{"duplicate_of_id": nil, "duplicate_kind": ""})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RejectDuplicates rejects every pending photo flagged as a duplicate,
+// optionally only those of one kind ("exact" or "similar").
+func (s *Service) RejectDuplicates(kind string, userID uint) (*ModerationResult, error) {
+	query := s.db.Model(&Photo{}).Where("status = ? AND duplicate_of_id IS NOT NULL", "pending")
+	switch kind {
+	case "":
+	case "exact", "similar":
+		query = query.Where("duplicate_kind = ?", kind)
+	default:
+		return nil, fmt.Errorf("kind must be exact or similar")
+	}
+
+	var ids []uint
+	if err := query.Order("id").Limit(maxModerationBatch).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return &ModerationResult{Action: ActionReject, PhotoIDs: []uint{}, Skipped: []uint{}}, nil
+	}
+	return s.Moderate(ActionReject, ids, "Duplicate of another photo", userID)
+}
+
+// ScanForDuplicates queues hashing for processed photos that have no
+// hashes yet, so uploads from before duplicate detection are checked too.
+func (s *Service) ScanForDuplicates() (int, error) {
+	var ids []uint
+	err := s.db.Model(&Photo{}).
+		Where("(content_hash IS NULL OR content_hash = '') AND status <> ? AND processed_at IS NOT NULL", "uploading").
+		Order("id").Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			if _, err := s.queue.EnqueueTx(tx, JobHashPhoto, HashPhotoJob{PhotoID: id}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
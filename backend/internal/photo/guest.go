@@ -0,0 +1,95 @@
+package photo
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"wedding-app/internal/models"
+)
+
+// ErrAlreadyApproved is returned when a guest tries to delete a photo a
+// moderator has already approved; only moderators can remove those.
+var ErrAlreadyApproved = errors.New("photo has already been approved")
+
+// portalGuest resolves a guest portal token to its guest.
+func (s *Service) portalGuest(portalToken string) (*models.Guest, error) {
+	if portalToken == "" {
+		return nil, ErrInvalidPortalToken
+	}
+
+	var guest models.Guest
+	err := s.db.Select("id", "first_name", "last_name", "guest_portal_token").
+		Where("guest_portal_token = ?", portalToken).First(&guest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidPortalToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &guest, nil
+}
+
+func guestName(guest *models.Guest) string {
+	return strings.TrimSpace(guest.FirstName + " " + guest.LastName)
+}
+
+// GetGuestPhotos lists the uploads of the guest whose portal token is
+// given, in every moderation state, newest first.
+func (s *Service) GetGuestPhotos(portalToken string) ([]Photo, error) {
+	guest, err := s.portalGuest(portalToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var photos []Photo
+	err = s.db.Where("guest_id = ? AND status <> ?", guest.ID, "uploading").
+		Order("created_at DESC").Find(&photos).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range photos {
+		s.signURLs(&photos[i])
+	}
+	return photos, nil
+}
+
+// DeleteGuestPhoto lets a guest remove one of their own uploads until a
+// moderator approves it. Unlike a moderator delete it can't be undone.
+func (s *Service) DeleteGuestPhoto(portalToken string, photoID uint) error {
+	guest, err := s.portalGuest(portalToken)
+	if err != nil {
+		return err
+	}
+
+	var photo Photo
+	if err := s.db.Where("id = ? AND guest_id = ?", photoID, guest.ID).First(&photo).Error; err != nil {
+		return err
+	}
+	if photo.Status == "approved" {
+		return ErrAlreadyApproved
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// A moderator may approve it in the meantime
+		result := tx.Where("status <> ?", "approved").Delete(&photo)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlreadyApproved
+		}
+		// Album counts only include approved photos, so only the cover
+		// needs fixing
+		return tx.Model(&Album{}).Where("cover_photo_id = ?", photo.ID).Update("cover_photo_id", nil).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Guest deleted photo", "id", photo.ID, "guestId", guest.ID)
+	s.abortUpload(photo.S3Key, photo.UploadID)
+	s.deleteObjects(&photo)
+	return nil
+}
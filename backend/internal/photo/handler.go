@@ -1,10 +1,17 @@
 package photo
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"wedding-app/pkg/pagination"
+	"wedding-app/pkg/storage"
 )
 
 type Handler struct {
@@ -17,9 +24,15 @@ func NewHandler(service *Service) *Handler {
 	}
 }
 
+// GetPhotos is the public gallery. Guests pass their portal token as
+// ?token= to include photos in private albums shared with them.
 func (h *Handler) GetPhotos(c *gin.Context) {
-	photos, err := h.service.GetApprovedPhotos()
+	photos, err := h.service.GetApprovedPhotos(c.Query("token"))
 	if err != nil {
+		if errors.Is(err, ErrInvalidPortalToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch photos"})
 		return
 	}
@@ -30,9 +43,8 @@ func (h *Handler) GetPhotos(c *gin.Context) {
 type UploadURLRequest struct {
 	FileName   string `json:"fileName" binding:"required"`
 	FileType   string `json:"fileType" binding:"required"`
-	FileSize   int64  `json:"fileSize" binding:"required"`
-	GuestToken string `json:"guestToken"`
-	GuestName  string `json:"guestName"`
+	FileSize   int64  `json:"fileSize" binding:"required,gt=0"`
+	GuestToken string `json:"guestToken" binding:"required"` // guest portal token
 }
 
 func (h *Handler) GetUploadURL(c *gin.Context) {
@@ -43,32 +55,42 @@ func (h *Handler) GetUploadURL(c *gin.Context) {
 	}
 
 	// Validate file type
-	if !h.service.IsValidImageType(req.FileType) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only images are allowed."})
+	mediaType := h.service.MediaTypeOf(req.FileType)
+	if mediaType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only images and MP4 or MOV videos are allowed."})
 		return
 	}
 
-	// Validate file size (10MB limit)
-	if req.FileSize > 10*1024*1024 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File size too large. Maximum 10MB allowed."})
+	// Validate file size (10MB for photos, 200MB for videos); the stored
+	// file is checked again when the upload completes
+	if limit := MaxUploadSize(mediaType); req.FileSize > limit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File size too large. Maximum %dMB allowed for %ss.", limit>>20, mediaType)})
 		return
 	}
 
-	uploadURL, photoID, err := h.service.GenerateUploadURL(req.FileName, req.FileType, req.FileSize, req.GuestToken, req.GuestName)
+	ticket, err := h.service.GenerateUploadURL(req.FileName, req.FileType, req.FileSize, req.GuestToken)
 	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidPortalToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid guest portal link"})
+			return
+		case errors.Is(err, ErrVideoUnsupported), errors.Is(err, ErrInvalidFileSize):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate upload URL"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"uploadUrl": uploadURL,
-		"photoId":   photoID,
-	})
+	c.JSON(http.StatusOK, ticket)
 }
 
 type CompleteUploadRequest struct {
-	PhotoID  uint   `json:"photoId" binding:"required"`
-	FileName string `json:"fileName" binding:"required"`
+	PhotoID    uint   `json:"photoId" binding:"required"`
+	FileName   string `json:"fileName" binding:"required"`
+	GuestToken string `json:"guestToken" binding:"required"` // guest portal token
+	// Parts of a video upload, with the ETag each part URL returned
+	Parts []storage.CompletedPart `json:"parts" binding:"omitempty,dive"`
 }
 
 func (h *Handler) CompleteUpload(c *gin.Context) {
@@ -78,25 +100,87 @@ func (h *Handler) CompleteUpload(c *gin.Context) {
 		return
 	}
 
-	photo, err := h.service.CompleteUpload(req.PhotoID, req.FileName)
+	photo, err := h.service.CompleteUpload(req.PhotoID, req.GuestToken, req.Parts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, ErrInvalidPortalToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid guest portal link"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+		case errors.Is(err, ErrNotUploading), errors.Is(err, ErrUploadMissing):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrUploadRejected):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrPartsRequired), errors.Is(err, ErrVideoUnsupported):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, photo)
 }
 
+// GetGuestPhotos lists a guest's own uploads, with their moderation
+// status, for their portal.
+func (h *Handler) GetGuestPhotos(c *gin.Context) {
+	photos, err := h.service.GetGuestPhotos(c.Param("token"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidPortalToken) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid guest portal link"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch photos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, photos)
+}
+
+// DeleteGuestPhoto lets a guest delete one of their uploads from their
+// portal until it has been approved.
+func (h *Handler) DeleteGuestPhoto(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid photo ID"})
+		return
+	}
+
+	err = h.service.DeleteGuestPhoto(c.Param("token"), uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidPortalToken):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid guest portal link"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+		case errors.Is(err, ErrAlreadyApproved):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete photo"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Photo deleted successfully"})
+}
+
 // Admin endpoints
 
 func (h *Handler) GetAdminPhotos(c *gin.Context) {
-	photos, err := h.service.GetAllPhotos()
+	params, err := pagination.Parse(c, PhotoListOptions)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch photos"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, photos)
+	page, err := h.service.ListPhotos(params, c.Query("status"), c.Query("duplicate"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
 }
 
 func (h *Handler) GetPendingPhotos(c *gin.Context) {
@@ -116,13 +200,17 @@ func (h *Handler) ApprovePhoto(c *gin.Context) {
 		return
 	}
 
-	err = h.service.ApprovePhoto(uint(id))
+	result, err := h.service.ApprovePhoto(uint(id), c.GetUint("userID"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondModerationError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Photo approved successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Photo approved successfully", "result": result})
+}
+
+type RejectPhotoRequest struct {
+	Reason string `json:"reason"`
 }
 
 func (h *Handler) RejectPhoto(c *gin.Context) {
@@ -132,15 +220,24 @@ func (h *Handler) RejectPhoto(c *gin.Context) {
 		return
 	}
 
-	err = h.service.RejectPhoto(uint(id))
+	// The reason is optional, as is the body
+	var req RejectPhotoRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.RejectPhoto(uint(id), req.Reason, c.GetUint("userID"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondModerationError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Photo rejected successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Photo rejected successfully", "result": result})
 }
 
+// DeletePhoto hides a photo straight away; its files are removed once the
+// undo window has passed.
 func (h *Handler) DeletePhoto(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -148,11 +245,499 @@ func (h *Handler) DeletePhoto(c *gin.Context) {
 		return
 	}
 
-	err = h.service.DeletePhoto(uint(id))
+	result, err := h.service.moderateOne(ActionDelete, uint(id), "", c.GetUint("userID"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondModerationError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Photo deleted successfully"})
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, gin.H{"message": "Photo deleted successfully", "result": result})
+}
+
+type BulkModerationRequest struct {
+	PhotoIDs []uint `json:"photoIds" binding:"required"`
+	Reason   string `json:"reason"`
+}
+
+func (h *Handler) BulkApprovePhotos(c *gin.Context) {
+	h.bulkModerate(c, ActionApprove)
+}
+
+func (h *Handler) BulkRejectPhotos(c *gin.Context) {
+	h.bulkModerate(c, ActionReject)
+}
+
+func (h *Handler) BulkDeletePhotos(c *gin.Context) {
+	h.bulkModerate(c, ActionDelete)
+}
+
+func (h *Handler) bulkModerate(c *gin.Context, action string) {
+	var req BulkModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.Moderate(action, req.PhotoIDs, req.Reason, c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// UndoModeration reverts a moderation batch within the undo window.
+func (h *Handler) UndoModeration(c *gin.Context) {
+	result, err := h.service.Undo(c.Param("batchId"), c.GetUint("userID"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNothingToUndo):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrUndoExpired):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to undo moderation"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetModerationHistory lists moderation decisions, filtered by ?photoId=,
+// ?userId=, ?action= and ?batchId=.
+func (h *Handler) GetModerationHistory(c *gin.Context) {
+	var filter ModerationFilter
+	if photoID := c.Query("photoId"); photoID != "" {
+		id, err := strconv.ParseUint(photoID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid photo ID"})
+			return
+		}
+		filter.PhotoID = uint(id)
+	}
+	h.listModerations(c, filter)
+}
+
+func (h *Handler) GetPhotoModerationHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid photo ID"})
+		return
+	}
+	h.listModerations(c, ModerationFilter{PhotoID: uint(id)})
+}
+
+func (h *Handler) listModerations(c *gin.Context, filter ModerationFilter) {
+	params, err := pagination.Parse(c, ModerationListOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if userID := c.Query("userId"); userID != "" {
+		id, err := strconv.ParseUint(userID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		filter.UserID = uint(id)
+	}
+	filter.Action = c.Query("action")
+	filter.BatchID = c.Query("batchId")
+
+	page, err := h.service.ListModerations(params, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderation history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func respondModerationError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// MergeDuplicate keeps the photo a duplicate copies and deletes the
+// duplicate.
+func (h *Handler) MergeDuplicate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid photo ID"})
+		return
+	}
+
+	result, err := h.service.MergeDuplicate(uint(id), c.GetUint("userID"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotDuplicate), errors.Is(err, ErrOriginalDeleted):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			respondModerationError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DismissDuplicate marks a flagged photo as not a duplicate after all.
+func (h *Handler) DismissDuplicate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid photo ID"})
+		return
+	}
+
+	if err := h.service.DismissDuplicate(uint(id)); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Duplicate flag cleared"})
+}
+
+type RejectDuplicatesRequest struct {
+	Kind string `json:"kind"` // exact or similar; empty for both
+}
+
+// RejectDuplicates rejects every pending photo flagged as a duplicate.
+func (h *Handler) RejectDuplicates(c *gin.Context) {
+	var req RejectDuplicatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.RejectDuplicates(req.Kind, c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ScanForDuplicates queues duplicate checks for photos uploaded before
+// they were checked on upload.
+func (h *Handler) ScanForDuplicates(c *gin.Context) {
+	queued, err := h.service.ScanForDuplicates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue duplicate scan"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"queued": queued})
+}
+
+// DownloadArchive sends a ZIP of approved photos: the gallery, an album
+// (?token= for private ones) or one uploader's. Large sets are built in the
+// background instead; the response is then 202 with the archive to poll.
+func (h *Handler) DownloadArchive(c *gin.Context) {
+	h.downloadArchive(c, false)
+}
+
+// DownloadAdminArchive is DownloadArchive for admins, who may also select
+// photos in any state by ID.
+func (h *Handler) DownloadAdminArchive(c *gin.Context) {
+	h.downloadArchive(c, true)
+}
+
+func (h *Handler) downloadArchive(c *gin.Context, admin bool) {
+	// An empty body asks for the whole gallery
+	var req ArchiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	set, err := h.service.ResolveArchive(req, admin)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidPortalToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrAlbumNotVisible), errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		case errors.Is(err, ErrNoPhotos):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if !set.Streamable() {
+		archive, err := h.service.RequestArchive(set, c.GetUint("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request archive"})
+			return
+		}
+		c.JSON(http.StatusAccepted, archive)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": set.Name + ".zip"}))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	// Once the body has started the status can't change; a failure leaves
+	// the client with a truncated ZIP, which it will reject
+	if err := h.service.StreamArchive(c.Request.Context(), c.Writer, set); err != nil {
+		h.service.logger.Error("Failed to stream photo archive", "error", err, "scope", set.Scope)
+	}
+}
+
+// GetArchive reports a background archive's progress, with a signed
+// download link once it is ready.
+func (h *Handler) GetArchive(c *gin.Context) {
+	archive, err := h.service.GetArchive(c.Param("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Archive not found"})
+		case errors.Is(err, ErrArchiveExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch archive"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, archive)
+}
+
+// GetStorageDrift reports differences between the photos table and
+// storage without changing anything.
+func (h *Handler) GetStorageDrift(c *gin.Context) {
+	report, err := h.service.Reconcile(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile photo storage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RepairStorageDrift reports and fixes differences between the photos
+// table and storage.
+func (h *Handler) RepairStorageDrift(c *gin.Context) {
+	report, err := h.service.Reconcile(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile photo storage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// Albums
+
+// GetAlbums lists the albums a visitor may see. Guests pass their portal
+// token as ?token= to include private albums shared with them.
+func (h *Handler) GetAlbums(c *gin.Context) {
+	albums, err := h.service.GetVisibleAlbums(c.Query("token"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidPortalToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch albums"})
+		return
+	}
+
+	c.JSON(http.StatusOK, albums)
+}
+
+// GetAlbum is the public gallery view of one album. Private albums need
+// the portal token of a guest they are shared with as ?token=.
+func (h *Handler) GetAlbum(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	album, err := h.service.GetPublicAlbum(uint(id), c.Query("token"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidPortalToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrAlbumNotVisible) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch album"})
+		return
+	}
+
+	c.JSON(http.StatusOK, album)
+}
+
+func (h *Handler) GetAdminAlbums(c *gin.Context) {
+	albums, err := h.service.GetAlbums()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch albums"})
+		return
+	}
+
+	c.JSON(http.StatusOK, albums)
+}
+
+func (h *Handler) GetAdminAlbum(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	album, err := h.service.GetAlbum(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch album"})
+		return
+	}
+
+	c.JSON(http.StatusOK, album)
+}
+
+func (h *Handler) CreateAlbum(c *gin.Context) {
+	var req AlbumInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	album, err := h.service.CreateAlbum(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, album)
+}
+
+func (h *Handler) UpdateAlbum(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	var req AlbumInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	album, err := h.service.UpdateAlbum(uint(id), req)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, album)
+}
+
+func (h *Handler) DeleteAlbum(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	if err := h.service.DeleteAlbum(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete album"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Album deleted successfully"})
+}
+
+type AlbumPhotosRequest struct {
+	PhotoIDs []uint `json:"photoIds"`
+}
+
+func (h *Handler) AddAlbumPhotos(c *gin.Context) {
+	h.changeAlbumPhotos(c, h.service.AddPhotos)
+}
+
+func (h *Handler) RemoveAlbumPhotos(c *gin.Context) {
+	h.changeAlbumPhotos(c, h.service.RemovePhotos)
+}
+
+// ReorderAlbumPhotos takes photo IDs in their new order. Photos left out
+// keep their relative order after the listed ones.
+func (h *Handler) ReorderAlbumPhotos(c *gin.Context) {
+	h.changeAlbumPhotos(c, h.service.ReorderPhotos)
+}
+
+func (h *Handler) changeAlbumPhotos(c *gin.Context, change func(albumID uint, photoIDs []uint) (*Album, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	var req AlbumPhotosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	album, err := change(uint(id), req.PhotoIDs)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, album)
+}
+
+type AlbumCoverRequest struct {
+	PhotoID *uint `json:"photoId"`
+}
+
+func (h *Handler) SetAlbumCover(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	var req AlbumCoverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	album, err := h.service.SetCover(uint(id), req.PhotoID)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, album)
+}
+
+func respondAlbumError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
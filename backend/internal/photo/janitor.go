@@ -0,0 +1,290 @@
+package photo
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"wedding-app/pkg/storage"
+)
+
+// keyPrefix is where every photo object and its resized copies live.
+const keyPrefix = "photos/"
+
+// staleUploadAfter is how old an "uploading" record must be before it is
+// considered abandoned: the longest presign window, a video's, plus time
+// for a slow upload to finish and be completed.
+const staleUploadAfter = videoUploadURLExpiry + 45*time.Minute
+
+// driftGrace keeps very recent changes out of the report: objects written
+// within it aren't orphans yet, as their photo may still be being saved,
+// and photos changed within it aren't missing files, as the listing may
+// have passed their keys before the files were written.
+const driftGrace = time.Hour
+
+type PhotoRef struct {
+	ID     uint      `json:"id"`
+	Key    string    `json:"key"`
+	Status string    `json:"status"`
+	Since  time.Time `json:"since"`
+
+	uploadID string
+}
+
+type ObjectRef struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// DriftReport compares the photos table with the storage bucket.
+type DriftReport struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	Repaired  bool      `json:"repaired"`
+	// Uploads started but never completed
+	StaleUploads []PhotoRef `json:"staleUploads"`
+	// Objects under the photo prefix that no photo refers to
+	OrphanedObjects []ObjectRef `json:"orphanedObjects"`
+	// Photos whose original is gone from storage
+	MissingOriginals []PhotoRef `json:"missingOriginals"`
+	// Processed photos missing one or more resized copies
+	MissingVariants []PhotoRef `json:"missingVariants"`
+}
+
+// Reconcile reports drift between the database and storage. With repair it
+// also fixes it: stale uploads and photos whose original is gone are
+// deleted, orphaned objects are removed, and photos missing resized copies
+// are queued for processing again.
+func (s *Service) Reconcile(repair bool) (*DriftReport, error) {
+	report := &DriftReport{
+		CheckedAt:        time.Now(),
+		Repaired:         repair,
+		StaleUploads:     []PhotoRef{},
+		OrphanedObjects:  []ObjectRef{},
+		MissingOriginals: []PhotoRef{},
+		MissingVariants:  []PhotoRef{},
+	}
+
+	// Anything written after the listing starts may be absent from it
+	settledBefore := report.CheckedAt.Add(-driftGrace)
+	objects := map[string]storage.ObjectInfo{}
+	err := s.storage.ListObjects(keyPrefix, func(obj storage.ObjectInfo) error {
+		objects[obj.Key] = obj
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Photos deleted by a moderator keep their files until the undo window
+	// has passed
+	var photos []Photo
+	err = s.db.Unscoped().
+		Where("deleted_at IS NULL OR deleted_at > ?", report.CheckedAt.Add(-undoWindow)).
+		Find(&photos).Error
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	staleBefore := report.CheckedAt.Add(-staleUploadAfter)
+	for i := range photos {
+		p := &photos[i]
+		ref := PhotoRef{ID: p.ID, Key: p.S3Key, Status: p.Status, Since: p.CreatedAt, uploadID: p.UploadID}
+
+		if p.DeletedAt.Valid {
+			for _, key := range []string{p.S3Key, p.ThumbnailKey, p.MediumKey, p.FullKey} {
+				if key != "" {
+					known[key] = true
+				}
+			}
+			continue
+		}
+
+		if p.Status == "uploading" {
+			if p.CreatedAt.Before(staleBefore) {
+				report.StaleUploads = append(report.StaleUploads, ref)
+				continue // its object, if any, is reported as orphaned
+			}
+			known[p.S3Key] = true
+			continue
+		}
+
+		for _, key := range []string{p.S3Key, p.ThumbnailKey, p.MediumKey, p.FullKey} {
+			if key != "" {
+				known[key] = true
+			}
+		}
+
+		// Completed or processed while the bucket was being listed, or
+		// just before
+		if p.UpdatedAt.After(settledBefore) {
+			continue
+		}
+
+		if _, ok := objects[p.S3Key]; !ok {
+			report.MissingOriginals = append(report.MissingOriginals, ref)
+			continue
+		}
+		if p.ProcessedAt != nil {
+			for _, key := range []string{p.ThumbnailKey, p.MediumKey, p.FullKey} {
+				if _, ok := objects[key]; key != "" && !ok {
+					report.MissingVariants = append(report.MissingVariants, ref)
+					break
+				}
+			}
+		}
+	}
+
+	for key, obj := range objects {
+		if !known[key] && obj.LastModified.Before(settledBefore) {
+			report.OrphanedObjects = append(report.OrphanedObjects, ObjectRef{Key: key, Size: obj.Size, LastModified: obj.LastModified})
+		}
+	}
+
+	s.logger.Info("Photo storage reconciled",
+		"staleUploads", len(report.StaleUploads),
+		"orphanedObjects", len(report.OrphanedObjects),
+		"missingOriginals", len(report.MissingOriginals),
+		"missingVariants", len(report.MissingVariants),
+		"repair", repair)
+
+	if repair {
+		s.repairDrift(report)
+	}
+	return report, nil
+}
+
+func (s *Service) repairDrift(report *DriftReport) {
+	for _, ref := range report.StaleUploads {
+		// Only expire it if it's still uploading
+		result := s.db.Where("id = ? AND status = ?", ref.ID, "uploading").Delete(&Photo{})
+		if result.Error != nil {
+			s.logger.Error("Failed to expire stale upload", "error", result.Error, "id", ref.ID)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		s.abortUpload(ref.Key, ref.uploadID)
+		if err := s.storage.DeleteObject(ref.Key); err != nil {
+			s.logger.Error("Failed to delete abandoned upload", "error", err, "key", ref.Key)
+		}
+	}
+
+	for _, obj := range report.OrphanedObjects {
+		if err := s.storage.DeleteObject(obj.Key); err != nil {
+			s.logger.Error("Failed to delete orphaned object", "error", err, "key", obj.Key)
+		}
+	}
+
+	for _, ref := range report.MissingOriginals {
+		if err := s.DeletePhoto(ref.ID); err != nil {
+			s.logger.Error("Failed to delete photo with missing original", "error", err, "id", ref.ID)
+		}
+	}
+
+	for _, ref := range report.MissingVariants {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&Photo{}).Where("id = ?", ref.ID).Update("processed_at", nil).Error; err != nil {
+				return err
+			}
+			_, err := s.queue.EnqueueTx(tx, JobProcessPhoto, ProcessPhotoJob{PhotoID: ref.ID})
+			return err
+		})
+		if err != nil {
+			s.logger.Error("Failed to requeue photo processing", "error", err, "id", ref.ID)
+		}
+	}
+}
+
+// ExpireStaleUploads deletes abandoned "uploading" records and any object
+// the guest managed to upload for them.
+func (s *Service) ExpireStaleUploads() (int, error) {
+	var stale []Photo
+	err := s.db.Where("status = ? AND created_at < ?", "uploading", time.Now().Add(-staleUploadAfter)).Find(&stale).Error
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for i := range stale {
+		result := s.db.Where("status = ?", "uploading").Delete(&stale[i])
+		if result.Error != nil {
+			s.logger.Error("Failed to expire stale upload", "error", result.Error, "id", stale[i].ID)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue // completed in the meantime
+		}
+		s.abortUpload(stale[i].S3Key, stale[i].UploadID)
+		if err := s.storage.DeleteObject(stale[i].S3Key); err != nil {
+			s.logger.Error("Failed to delete abandoned upload", "error", err, "key", stale[i].S3Key)
+		}
+		expired++
+	}
+
+	if expired > 0 {
+		s.logger.Info("Expired stale uploads", "count", expired)
+	}
+	return expired, nil
+}
+
+// Janitor periodically expires abandoned uploads and old archives, and
+// logs any other drift between the database and storage for an admin to
+// review.
+type Janitor struct {
+	service  *Service
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewJanitor(service *Service, interval time.Duration) *Janitor {
+	return &Janitor{
+		service:  service,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (j *Janitor) Start() {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+				j.run()
+			}
+		}
+	}()
+}
+
+func (j *Janitor) Stop() {
+	close(j.stop)
+	j.wg.Wait()
+}
+
+func (j *Janitor) run() {
+	if _, err := j.service.ExpireStaleUploads(); err != nil {
+		j.service.logger.Error("Failed to expire stale uploads", "error", err)
+	}
+	if _, err := j.service.ExpireArchives(); err != nil {
+		j.service.logger.Error("Failed to expire photo archives", "error", err)
+	}
+
+	report, err := j.service.Reconcile(false)
+	if err != nil {
+		j.service.logger.Error("Failed to reconcile photo storage", "error", err)
+		return
+	}
+	if n := len(report.OrphanedObjects) + len(report.MissingOriginals) + len(report.MissingVariants); n > 0 {
+		j.service.logger.Warn("Photo storage drift found; review it under /api/admin/photos/reconcile", "issues", n)
+	}
+}
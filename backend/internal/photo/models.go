@@ -5,4 +5,6 @@ import (
 )
 
 type Photo = models.Photo
-type Album = models.Album
\ No newline at end of file
+type Album = models.Album
+type PhotoModeration = models.PhotoModeration
+type PhotoArchive = models.PhotoArchive
\ No newline at end of file
@@ -0,0 +1,419 @@
+package photo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"wedding-app/internal/jobs"
+	"wedding-app/pkg/pagination"
+)
+
+// Moderation actions
+const (
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+	ActionDelete  = "delete"
+)
+
+// JobPurgePhotos removes the files of photos deleted by a moderator once
+// the delete can no longer be undone.
+const JobPurgePhotos = "photo.purge"
+
+// undoWindow is how long a moderation decision can be undone. Deleted
+// photos keep their files in storage until it has passed.
+const undoWindow = 10 * time.Minute
+
+// maxModerationBatch caps how many photos one request may moderate.
+const maxModerationBatch = 1000
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo for this batch")
+	ErrUndoExpired   = errors.New("the undo window for this batch has passed")
+)
+
+type PurgePhotosJob struct {
+	BatchID string `json:"batchId"`
+}
+
+// ModerationResult reports what a moderation request changed. Photos that
+// don't exist, are still uploading or are already in the requested state
+// are listed as skipped.
+type ModerationResult struct {
+	BatchID   string     `json:"batchId,omitempty"`
+	Action    string     `json:"action"`
+	PhotoIDs  []uint     `json:"photoIds"`
+	Skipped   []uint     `json:"skipped"`
+	UndoUntil *time.Time `json:"undoUntil,omitempty"`
+}
+
+// Moderate approves, rejects or deletes photos in one transaction, recording
+// each decision with the moderator and reason. Deleted photos are hidden
+// at once but their files are only removed after the undo window. The
+// result's batch ID can be passed to Undo.
+func (s *Service) Moderate(action string, photoIDs []uint, reason string, userID uint) (*ModerationResult, error) {
+	var toStatus string
+	switch action {
+	case ActionApprove:
+		toStatus = "approved"
+	case ActionReject:
+		toStatus = "rejected"
+	case ActionDelete:
+		toStatus = "deleted" // recorded in history only; the row is soft-deleted
+	default:
+		return nil, fmt.Errorf("unknown moderation action %q", action)
+	}
+
+	photoIDs = uniqueIDs(photoIDs)
+	if len(photoIDs) == 0 {
+		return nil, fmt.Errorf("photoIds is required")
+	}
+	if len(photoIDs) > maxModerationBatch {
+		return nil, fmt.Errorf("at most %d photos can be moderated at once", maxModerationBatch)
+	}
+
+	batchID, err := newBatchID()
+	if err != nil {
+		return nil, err
+	}
+	var moderator *uint
+	if userID != 0 {
+		moderator = &userID
+	}
+
+	now := time.Now()
+	result := &ModerationResult{
+		BatchID:  batchID,
+		Action:   action,
+		PhotoIDs: []uint{},
+		Skipped:  []uint{},
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var photos []Photo
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND status <> ?", photoIDs, "uploading").
+			Order("id").Find(&photos).Error
+		if err != nil {
+			return err
+		}
+
+		found := make(map[uint]bool, len(photos))
+		var entries []PhotoModeration
+		var albumIDs []uint
+		for i := range photos {
+			p := &photos[i]
+			found[p.ID] = true
+			if p.Status == toStatus {
+				result.Skipped = append(result.Skipped, p.ID)
+				continue
+			}
+
+			if action == ActionDelete {
+				err = tx.Delete(p).Error
+			} else {
+				rejectionReason := ""
+				if action == ActionReject {
+					rejectionReason = reason
+				}
+				err = tx.Model(p).Updates(map[string]interface{}{
+					"status":           toStatus,
+					"moderated_at":     now,
+					"moderated_by":     moderator,
+					"rejection_reason": rejectionReason,
+				}).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			entries = append(entries, PhotoModeration{
+				BatchID:    batchID,
+				PhotoID:    p.ID,
+				Action:     action,
+				FromStatus: p.Status,
+				ToStatus:   toStatus,
+				Reason:     reason,
+				UserID:     moderator,
+			})
+			result.PhotoIDs = append(result.PhotoIDs, p.ID)
+			if p.AlbumID != nil {
+				albumIDs = append(albumIDs, *p.AlbumID)
+			}
+		}
+		for _, id := range photoIDs {
+			if !found[id] {
+				result.Skipped = append(result.Skipped, id)
+			}
+		}
+
+		if len(entries) == 0 {
+			return nil
+		}
+
+		if action == ActionDelete {
+			// Remember the covers being cleared so Undo can put them back
+			var covers []uint
+			err := tx.Model(&Album{}).Where("cover_photo_id IN ?", result.PhotoIDs).Pluck("cover_photo_id", &covers).Error
+			if err != nil {
+				return err
+			}
+			for i := range entries {
+				entries[i].WasCover = containsID(covers, entries[i].PhotoID)
+			}
+		}
+		if err := tx.Create(&entries).Error; err != nil {
+			return err
+		}
+
+		if action == ActionDelete {
+			err := tx.Model(&Album{}).Where("cover_photo_id IN ?", result.PhotoIDs).Update("cover_photo_id", nil).Error
+			if err != nil {
+				return err
+			}
+			// A minute's slack so an undo right at the deadline isn't raced
+			_, err = s.queue.EnqueueTx(tx, JobPurgePhotos, PurgePhotosJob{BatchID: batchID}, jobs.Delay(undoWindow+time.Minute))
+			if err != nil {
+				return err
+			}
+		}
+		return recountAlbums(tx, albumIDs...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.PhotoIDs) == 0 {
+		result.BatchID = ""
+	} else {
+		until := now.Add(undoWindow)
+		result.UndoUntil = &until
+		s.logger.Info("Photos moderated", "action", action, "batch", batchID, "count", len(result.PhotoIDs), "user", userID)
+	}
+	return result, nil
+}
+
+// moderateOne moderates a single photo, reporting a missing one as not
+// found rather than skipped.
+func (s *Service) moderateOne(action string, photoID uint, reason string, userID uint) (*ModerationResult, error) {
+	var count int64
+	err := s.db.Model(&Photo{}).Where("id = ? AND status <> ?", photoID, "uploading").Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return s.Moderate(action, []uint{photoID}, reason, userID)
+}
+
+// Undo reverts a moderation batch within the undo window. Photos that have
+// been moderated again since are left alone and reported as skipped.
+// Restored photos that were their album's cover become it again if the
+// album hasn't been given another.
+func (s *Service) Undo(batchID string, userID uint) (*ModerationResult, error) {
+	var undoneBy *uint
+	if userID != 0 {
+		undoneBy = &userID
+	}
+
+	now := time.Now()
+	result := &ModerationResult{
+		BatchID:  batchID,
+		PhotoIDs: []uint{},
+		Skipped:  []uint{},
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var entries []PhotoModeration
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("batch_id = ? AND undone_at IS NULL", batchID).
+			Order("id").Find(&entries).Error
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return ErrNothingToUndo
+		}
+		if entries[0].CreatedAt.Before(now.Add(-undoWindow)) {
+			return ErrUndoExpired
+		}
+		result.Action = entries[0].Action
+
+		var albumIDs []uint
+		for _, entry := range entries {
+			var photo Photo
+			err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&photo, entry.PhotoID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				result.Skipped = append(result.Skipped, entry.PhotoID)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			var later int64
+			err = tx.Model(&PhotoModeration{}).
+				Where("photo_id = ? AND id > ? AND undone_at IS NULL", entry.PhotoID, entry.ID).
+				Count(&later).Error
+			if err != nil {
+				return err
+			}
+
+			if entry.Action == ActionDelete {
+				if later > 0 || !photo.DeletedAt.Valid {
+					result.Skipped = append(result.Skipped, entry.PhotoID)
+					continue
+				}
+				err = tx.Unscoped().Model(&photo).Update("deleted_at", nil).Error
+				if err == nil && entry.WasCover && photo.AlbumID != nil {
+					// Unless the album has been given another cover since
+					err = tx.Model(&Album{}).Where("id = ? AND cover_photo_id IS NULL", *photo.AlbumID).
+						Update("cover_photo_id", photo.ID).Error
+				}
+			} else {
+				if later > 0 || photo.DeletedAt.Valid || photo.Status != entry.ToStatus {
+					result.Skipped = append(result.Skipped, entry.PhotoID)
+					continue
+				}
+				err = s.restoreModeration(tx, &photo, entry)
+			}
+			if err != nil {
+				return err
+			}
+
+			err = tx.Model(&PhotoModeration{}).Where("id = ?", entry.ID).
+				Updates(map[string]interface{}{"undone_at": now, "undone_by": undoneBy}).Error
+			if err != nil {
+				return err
+			}
+			result.PhotoIDs = append(result.PhotoIDs, entry.PhotoID)
+			if photo.AlbumID != nil {
+				albumIDs = append(albumIDs, *photo.AlbumID)
+			}
+		}
+		return recountAlbums(tx, albumIDs...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Photo moderation undone", "batch", batchID, "count", len(result.PhotoIDs), "user", userID)
+	return result, nil
+}
+
+// restoreModeration puts a photo back to its status before entry, along
+// with the moderator and reason of the decision that set that status.
+func (s *Service) restoreModeration(tx *gorm.DB, photo *Photo, entry PhotoModeration) error {
+	updates := map[string]interface{}{
+		"status":           entry.FromStatus,
+		"moderated_at":     nil,
+		"moderated_by":     nil,
+		"rejection_reason": "",
+	}
+
+	var previous PhotoModeration
+	err := tx.Where("photo_id = ? AND id < ? AND undone_at IS NULL AND action <> ?", photo.ID, entry.ID, ActionDelete).
+		Order("id DESC").First(&previous).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil && previous.ToStatus == entry.FromStatus {
+		updates["moderated_at"] = previous.CreatedAt
+		updates["moderated_by"] = previous.UserID
+		if previous.Action == ActionReject {
+			updates["rejection_reason"] = previous.Reason
+		}
+	}
+
+	return tx.Model(photo).Updates(updates).Error
+}
+
+// purgePhotos is the background job that removes the files of a delete
+// batch once it can no longer be undone.
+func (s *Service) purgePhotos(ctx context.Context, job PurgePhotosJob) error {
+	deleted := s.db.Model(&PhotoModeration{}).Select("photo_id").
+		Where("batch_id = ? AND action = ? AND undone_at IS NULL", job.BatchID, ActionDelete)
+
+	// A photo restored and deleted again belongs to the later batch's window
+	var photos []Photo
+	err := s.db.Unscoped().
+		Where("id IN (?) AND deleted_at IS NOT NULL AND deleted_at <= ?", deleted, time.Now().Add(-undoWindow)).
+		Find(&photos).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range photos {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.deleteObjects(&photos[i])
+	}
+
+	s.logger.Info("Purged deleted photos", "batch", job.BatchID, "count", len(photos))
+	return nil
+}
+
+// ModerationListOptions controls sorting on moderation history.
+var ModerationListOptions = pagination.Options{
+	Sortable: map[string]string{
+		"createdAt": "photo_moderations.created_at",
+		"action":    "photo_moderations.action",
+	},
+	DefaultSort: "-createdAt",
+	Searchable: []string{
+		"photo_moderations.reason",
+		"users.email",
+	},
+	IDColumn: "photo_moderations.id",
+}
+
+// ModerationFilter narrows moderation history. Zero values match anything.
+type ModerationFilter struct {
+	PhotoID uint
+	UserID  uint
+	Action  string
+	BatchID string
+}
+
+// ListModerations returns one page of moderation history, newest first by
+// default, with each moderator's email.
+func (s *Service) ListModerations(params *pagination.Params, filter ModerationFilter) (*pagination.Page[PhotoModeration], error) {
+	query := s.db.Model(&PhotoModeration{}).
+		Joins("LEFT JOIN users ON users.id = photo_moderations.user_id")
+	if filter.PhotoID != 0 {
+		query = query.Where("photo_moderations.photo_id = ?", filter.PhotoID)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("photo_moderations.user_id = ?", filter.UserID)
+	}
+	if filter.Action != "" {
+		query = query.Where("photo_moderations.action = ?", filter.Action)
+	}
+	if filter.BatchID != "" {
+		query = query.Where("photo_moderations.batch_id = ?", filter.BatchID)
+	}
+
+	var page pagination.Page[PhotoModeration]
+	err := pagination.Find(query, params, &page, func(db *gorm.DB) *gorm.DB {
+		return db.Select("photo_moderations.*, users.email AS user_email")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func newBatchID() (string, error) {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate batch ID: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
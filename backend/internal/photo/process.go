@@ -0,0 +1,171 @@
+package photo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+	"wedding-app/internal/jobs"
+	"wedding-app/pkg/imaging"
+)
+
+// Longest side in pixels of each resized copy. Full is what the gallery
+// shows in the lightbox; the original is kept for downloads.
+const (
+	thumbnailSize = 400
+	mediumSize    = 1280
+	fullSize      = 2560
+)
+
+// maxProcessBytes is the largest original the worker will load into memory.
+const maxProcessBytes = 64 << 20
+
+// processPhoto is the background job run after an upload completes. It
+// reads the original from storage, or a video's poster frame, writes
+// upright JPEG copies at each size,
+// records the image dimensions and camera metadata, and flags the photo if
+// it duplicates another upload.
+func (s *Service) processPhoto(ctx context.Context, job ProcessPhotoJob) error {
+	var photo Photo
+	if err := s.db.First(&photo, job.PhotoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Deleted before it was processed
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	if photo.ProcessedAt != nil {
+		return nil
+	}
+
+	s.logger.Info("Processing photo", "id", photo.ID, "key", photo.S3Key)
+
+	src, err := s.readStill(ctx, &photo)
+	if err != nil {
+		return err
+	}
+	img, exif := src.img, src.exif
+
+	// Resize before rotating; the bounding box is square so the result is
+	// the same and rotating the smaller image is much cheaper
+	full := imaging.Orient(imaging.Fit(img, fullSize), exif.Orientation)
+	variants := []struct {
+		key     string
+		img     image.Image
+		quality int
+	}{
+		{photo.FullKey, full, 88},
+		{photo.MediumKey, imaging.Fit(full, mediumSize), 85},
+		{photo.ThumbnailKey, imaging.Fit(full, thumbnailSize), 80},
+	}
+	for _, v := range variants {
+		if v.key == "" {
+			continue
+		}
+		encoded, err := imaging.EncodeJPEG(v.img, v.quality)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", v.key, err)
+		}
+		if err := s.storage.UploadFile(v.key, encoded, "image/jpeg"); err != nil {
+			return err
+		}
+	}
+
+	bounds := img.Bounds()
+	photo.Width, photo.Height = bounds.Dx(), bounds.Dy()
+	if imaging.SwapsAxes(exif.Orientation) {
+		photo.Width, photo.Height = photo.Height, photo.Width
+	}
+	photo.ContentHash, photo.PerceptualHash = src.contentHash, perceptualHash(full)
+	photo.CapturedAt = exif.CapturedAt
+	photo.CameraMake = exif.CameraMake
+	photo.CameraModel = exif.CameraModel
+	now := time.Now()
+	photo.ProcessedAt = &now
+
+	err = s.db.Model(&photo).
+		Select("width", "height", "captured_at", "camera_make", "camera_model", "content_hash", "perceptual_hash", "processed_at").
+		Updates(&photo).Error
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Photo processing complete", "id", photo.ID, "format", src.format, "width", photo.Width, "height", photo.Height)
+
+	// The photo is usable either way; a missed duplicate can be found by a
+	// later scan
+	if err := s.checkDuplicate(&photo); err != nil {
+		s.logger.Error("Failed to check photo for duplicates", "error", err, "id", photo.ID)
+	}
+	return nil
+}
+
+// still is the image processing works from: the photo itself, or a
+// video's poster frame.
+type still struct {
+	img         image.Image
+	format      string
+	exif        *imaging.EXIF
+	contentHash string // SHA-256 of the original file
+}
+
+func (s *Service) readStill(ctx context.Context, photo *Photo) (*still, error) {
+	if photo.MediaType == MediaVideo {
+		frame, contentHash, err := s.readVideoPoster(ctx, photo.S3Key)
+		if err != nil {
+			return nil, err
+		}
+		img, _, err := imaging.Decode(frame)
+		if err != nil {
+			return nil, jobs.Permanent(err)
+		}
+		// ffmpeg has already turned the frame upright
+		return &still{img: img, format: photo.ContentType, exif: &imaging.EXIF{}, contentHash: contentHash}, nil
+	}
+
+	data, err := s.readOriginal(photo.S3Key)
+	if err != nil {
+		return nil, err
+	}
+
+	exif, err := imaging.ReadEXIF(data)
+	if err != nil {
+		// Broken metadata shouldn't stop the photo being shown
+		s.logger.Warn("Failed to read photo EXIF", "error", err, "id", photo.ID)
+		exif = &imaging.EXIF{}
+	}
+
+	img, format, err := imaging.Decode(data)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	return &still{img: img, format: format, exif: exif, contentHash: hex.EncodeToString(sum[:])}, nil
+}
+
+func (s *Service) readOriginal(key string) ([]byte, error) {
+	body, err := s.storage.GetObject(key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, maxProcessBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read original: %w", err)
+	}
+	if len(data) > maxProcessBytes {
+		return nil, jobs.Permanent(fmt.Errorf("original is larger than %d MB", maxProcessBytes>>20))
+	}
+	return data, nil
+}
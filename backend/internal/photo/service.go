@@ -4,75 +4,142 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"wedding-app/internal/jobs"
 	"wedding-app/pkg/logger"
+	"wedding-app/pkg/pagination"
 	"wedding-app/pkg/storage"
 )
 
+// JobProcessPhoto is the background job run for each completed upload.
+const JobProcessPhoto = "photo.process"
+
+// uploadURLExpiry is how long a guest has to upload once they're given a
+// presigned URL.
+const uploadURLExpiry = 15 * time.Minute
+
+type ProcessPhotoJob struct {
+	PhotoID uint `json:"photoId"`
+}
+
 type Service struct {
 	db      *gorm.DB
 	logger  logger.Logger
 	storage storage.Client
+	queue   *jobs.Queue
+
+	// autoRejectDuplicates rejects pending uploads identical to another
+	// photo as soon as they are processed
+	autoRejectDuplicates bool
 }
 
-func NewService(db *gorm.DB, logger logger.Logger) *Service {
-	// Initialize S3 client
-	storageClient := storage.NewS3Client()
-	
-	return &Service{
+func NewService(db *gorm.DB, logger logger.Logger, storageClient storage.Client, queue *jobs.Queue) *Service {
+	s := &Service{
 		db:      db,
 		logger:  logger,
 		storage: storageClient,
+		queue:   queue,
 	}
+	s.autoRejectDuplicates, _ = strconv.ParseBool(os.Getenv("PHOTO_AUTO_REJECT_DUPLICATES"))
+	jobs.Register(queue, JobProcessPhoto, s.processPhoto)
+	jobs.Register(queue, JobPurgePhotos, s.purgePhotos)
+	jobs.Register(queue, JobBuildArchive, s.buildArchive)
+	jobs.Register(queue, JobHashPhoto, s.hashPhoto)
+	return s
 }
 
-func (s *Service) GetApprovedPhotos() ([]Photo, error) {
+// GetApprovedPhotos returns the gallery: approved photos outside private
+// albums, plus those in private albums shared with the guest whose portal
+// token is given.
+func (s *Service) GetApprovedPhotos(portalToken string) ([]Photo, error) {
+	guestID, tagIDs, err := s.viewer(portalToken)
+	if err != nil {
+		return nil, err
+	}
+
 	var photos []Photo
-	err := s.db.Where("status = ?", "approved").Order("created_at DESC").Find(&photos).Error
+	err = s.db.Where("status = ?", "approved").Where(s.visiblePhotos(guestID, tagIDs)).
+		Order("created_at DESC").Find(&photos).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Generate signed URLs
 	for i := range photos {
-		photos[i].ThumbnailURL = s.storage.GeneratePresignedURL(photos[i].ThumbnailKey, 24*time.Hour)
-		photos[i].FullURL = s.storage.GeneratePresignedURL(photos[i].S3Key, 24*time.Hour)
+		s.signURLs(&photos[i])
 	}
 
 	return photos, nil
 }
 
-func (s *Service) GetAllPhotos() ([]Photo, error) {
-	var photos []Photo
-	err := s.db.Order("created_at DESC").Find(&photos).Error
-	if err != nil {
+// PhotoListOptions controls sorting and search on the admin photo list.
+var PhotoListOptions = pagination.Options{
+	Sortable: map[string]string{
+		"createdAt":  "photos.created_at",
+		"uploadedAt": "COALESCE(photos.uploaded_at, photos.created_at)",
+		"fileSize":   "photos.file_size",
+		"guestName":  "COALESCE(photos.guest_name, '')",
+		"status":     "COALESCE(photos.status, '')",
+		"capturedAt": "COALESCE(photos.captured_at, photos.uploaded_at, photos.created_at)",
+		"camera":     "(COALESCE(photos.camera_make, '') || ' ' || COALESCE(photos.camera_model, ''))",
+	},
+	DefaultSort: "-createdAt",
+	Searchable: []string{
+		"photos.file_name",
+		"photos.guest_name",
+		"photos.uploaded_by",
+		"photos.camera_model",
+	},
+	IDColumn: "photos.id",
+}
+
+// ListPhotos returns one page of photos in any state, optionally limited to
+// a single status. duplicate limits it to photos flagged as duplicates:
+// "exact", "similar" or "any".
+func (s *Service) ListPhotos(params *pagination.Params, status, duplicate string) (*pagination.Page[Photo], error) {
+	query := s.db.Model(&Photo{})
+	if status != "" {
+		query = query.Where("photos.status = ?", status)
+	}
+	switch duplicate {
+	case "":
+	case "any":
+		query = query.Where("photos.duplicate_of_id IS NOT NULL")
+	case "exact", "similar":
+		query = query.Where("photos.duplicate_kind = ? AND photos.duplicate_of_id IS NOT NULL", duplicate)
+	default:
+		return nil, fmt.Errorf("duplicate must be exact, similar or any")
+	}
+
+	var page pagination.Page[Photo]
+	if err := pagination.Find(query, params, &page, preloadDuplicateOf); err != nil {
 		return nil, err
 	}
 
 	// Generate signed URLs
-	for i := range photos {
-		photos[i].ThumbnailURL = s.storage.GeneratePresignedURL(photos[i].ThumbnailKey, 24*time.Hour)
-		photos[i].FullURL = s.storage.GeneratePresignedURL(photos[i].S3Key, 24*time.Hour)
+	for i := range page.Items {
+		s.signURLs(&page.Items[i])
 	}
 
-	return photos, nil
+	return &page, nil
 }
 
 func (s *Service) GetPendingPhotos() ([]Photo, error) {
 	var photos []Photo
-	err := s.db.Where("status = ?", "pending").Order("created_at ASC").Find(&photos).Error
+	err := s.db.Scopes(preloadDuplicateOf).Where("status = ?", "pending").Order("created_at ASC").Find(&photos).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Generate signed URLs
 	for i := range photos {
-		photos[i].ThumbnailURL = s.storage.GeneratePresignedURL(photos[i].ThumbnailKey, 24*time.Hour)
-		photos[i].FullURL = s.storage.GeneratePresignedURL(photos[i].S3Key, 24*time.Hour)
+		s.signURLs(&photos[i])
 	}
 
 	return photos, nil
@@ -95,11 +162,50 @@ func (s *Service) IsValidImageType(contentType string) bool {
 	return false
 }
 
-func (s *Service) GenerateUploadURL(fileName, fileType string, fileSize int64, guestToken, guestName string) (string, uint, error) {
+func (s *Service) IsValidVideoType(contentType string) bool {
+	return contentType == "video/mp4" || contentType == "video/quicktime"
+}
+
+// MediaTypeOf returns the media type of an upload content type, or "" if
+// guests can't upload files of that type.
+func (s *Service) MediaTypeOf(contentType string) string {
+	switch {
+	case s.IsValidImageType(contentType):
+		return MediaImage
+	case s.IsValidVideoType(contentType):
+		return MediaVideo
+	}
+	return ""
+}
+
+// UploadTicket tells the client where to send a file. A photo goes to
+// UploadURL in one request; a video is split into PartSize pieces, each
+// sent to its part URL, and the ETag returned for each part is passed to
+// CompleteUpload.
+type UploadTicket struct {
+	PhotoID   uint      `json:"photoId"`
+	MediaType string    `json:"mediaType"`
+	UploadURL string    `json:"uploadUrl,omitempty"`
+	PartSize  int64     `json:"partSize,omitempty"`
+	PartURLs  []PartURL `json:"partUrls,omitempty"`
+}
+
+// GenerateUploadURL starts an upload for the guest whose portal token is
+// given; the photo is recorded as theirs.
+func (s *Service) GenerateUploadURL(fileName, fileType string, fileSize int64, portalToken string) (*UploadTicket, error) {
+	if err := checkFileSize(s.MediaTypeOf(fileType), fileSize); err != nil {
+		return nil, err
+	}
+
+	guest, err := s.portalGuest(portalToken)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate unique key
 	key, err := s.generatePhotoKey(fileName)
 	if err != nil {
-		return "", 0, err
+		return nil, err
 	}
 
 	// Create photo record
@@ -107,109 +213,158 @@ func (s *Service) GenerateUploadURL(fileName, fileType string, fileSize int64, g
 		FileName:    fileName,
 		S3Key:       key,
 		ContentType: fileType,
+		MediaType:   s.MediaTypeOf(fileType),
 		FileSize:    fileSize,
 		Status:      "uploading",
-		GuestToken:  guestToken,
-		GuestName:   guestName,
+		GuestID:     &guest.ID,
+		GuestToken:  guest.GuestPortalToken,
+		GuestName:   guestName(guest),
+	}
+	ticket := &UploadTicket{MediaType: photo.MediaType}
+
+	if photo.MediaType == MediaVideo {
+		photo.UploadID, ticket.PartURLs, err = s.startVideoUpload(key, fileType, fileSize)
+		if err != nil {
+			return nil, err
+		}
+		ticket.PartSize = videoPartSize
 	}
 
 	err = s.db.Create(&photo).Error
 	if err != nil {
-		return "", 0, err
+		s.abortUpload(key, photo.UploadID)
+		return nil, err
+	}
+	ticket.PhotoID = photo.ID
+
+	if photo.MediaType == MediaVideo {
+		return ticket, nil
 	}
 
 	// Generate presigned upload URL
-	uploadURL, err := s.storage.GeneratePresignedUploadURL(key, fileType, 15*time.Minute)
+	ticket.UploadURL, err = s.storage.GeneratePresignedUploadURL(key, fileType, uploadURLExpiry)
 	if err != nil {
-		return "", 0, err
+		return nil, err
 	}
 
-	return uploadURL, photo.ID, nil
+	return ticket, nil
 }
 
-func (s *Service) CompleteUpload(photoID uint, fileName string) (*Photo, error) {
+// CompleteUpload checks the uploaded file and queues it for processing.
+// Only the guest who started the upload can complete it. Videos also need
+// the parts they were uploaded in, to be joined first.
+func (s *Service) CompleteUpload(photoID uint, portalToken string, parts []storage.CompletedPart) (*Photo, error) {
+	guest, err := s.portalGuest(portalToken)
+	if err != nil {
+		return nil, err
+	}
+
 	var photo Photo
-	err := s.db.First(&photo, photoID).Error
+	err = s.db.Where("guest_id = ?", guest.ID).First(&photo, photoID).Error
 	if err != nil {
 		return nil, err
 	}
 
 	if photo.Status != "uploading" {
-		return nil, fmt.Errorf("photo is not in uploading state")
+		return nil, ErrNotUploading
+	}
+
+	if err := s.completeVideoUpload(&photo, parts); err != nil {
+		return nil, err
+	}
+	if err := s.verifyUpload(&photo); err != nil {
+		return nil, err
 	}
 
 	// Update photo status
 	photo.Status = "pending" // Requires moderation
 	photo.UploadedAt = time.Now()
+	photo.UploadID = ""
 
-	// Generate thumbnail key
+	// Generate keys for the resized copies
 	photo.ThumbnailKey = s.generateThumbnailKey(photo.S3Key)
-
-	err = s.db.Save(&photo).Error
+	photo.MediumKey = variantKey(photo.S3Key, "medium")
+	photo.FullKey = variantKey(photo.S3Key, "full")
+
+	// Queue background processing (thumbnail generation, moderation) with
+	// the status change so a completed upload is never left unprocessed
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&photo).Where("status = ?", "uploading").
+			Select("status", "uploaded_at", "upload_id", "thumbnail_key", "medium_key", "full_key").
+			Updates(&photo)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Completed by a concurrent request
+			return ErrNotUploading
+		}
+		_, err := s.queue.EnqueueTx(tx, JobProcessPhoto, ProcessPhotoJob{PhotoID: photo.ID})
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Trigger background processing (thumbnail generation, moderation)
-	go s.processPhoto(&photo)
-
 	// Generate URLs for response
-	photo.ThumbnailURL = s.storage.GeneratePresignedURL(photo.ThumbnailKey, 24*time.Hour)
-	photo.FullURL = s.storage.GeneratePresignedURL(photo.S3Key, 24*time.Hour)
+	s.signURLs(&photo)
 
 	return &photo, nil
 }
 
-func (s *Service) ApprovePhoto(photoID uint) error {
-	var photo Photo
-	err := s.db.First(&photo, photoID).Error
-	if err != nil {
-		return err
-	}
-
-	photo.Status = "approved"
-	photo.ModeratedAt = &time.Time{}
-	*photo.ModeratedAt = time.Now()
+// ApprovePhoto approves a single photo. See Moderate.
+func (s *Service) ApprovePhoto(photoID, userID uint) (*ModerationResult, error) {
+	return s.moderateOne(ActionApprove, photoID, "", userID)
+}
 
-	return s.db.Save(&photo).Error
+// RejectPhoto rejects a single photo, recording why. See Moderate.
+func (s *Service) RejectPhoto(photoID uint, reason string, userID uint) (*ModerationResult, error) {
+	return s.moderateOne(ActionReject, photoID, reason, userID)
 }
 
-func (s *Service) RejectPhoto(photoID uint) error {
+// DeletePhoto removes a photo and its files straight away. Moderators go
+// through Moderate instead so the delete can be undone.
+func (s *Service) DeletePhoto(photoID uint) error {
 	var photo Photo
 	err := s.db.First(&photo, photoID).Error
 	if err != nil {
 		return err
 	}
 
-	photo.Status = "rejected"
-	photo.ModeratedAt = &time.Time{}
-	*photo.ModeratedAt = time.Now()
+	s.deleteObjects(&photo)
 
-	return s.db.Save(&photo).Error
+	// Delete from database
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&photo).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Album{}).Where("cover_photo_id = ?", photo.ID).Update("cover_photo_id", nil).Error; err != nil {
+			return err
+		}
+		if photo.AlbumID != nil {
+			return recountAlbums(tx, *photo.AlbumID)
+		}
+		return nil
+	})
 }
 
-func (s *Service) DeletePhoto(photoID uint) error {
-	var photo Photo
-	err := s.db.First(&photo, photoID).Error
-	if err != nil {
-		return err
-	}
-
-	// Delete from S3
-	err = s.storage.DeleteObject(photo.S3Key)
+// deleteObjects removes a photo's original and resized copies from
+// storage. Failures are logged; the janitor picks up anything left behind.
+func (s *Service) deleteObjects(photo *Photo) {
+	err := s.storage.DeleteObject(photo.S3Key)
 	if err != nil {
 		s.logger.Error("Failed to delete photo from S3", "error", err, "key", photo.S3Key)
 	}
 
-	if photo.ThumbnailKey != "" {
-		err = s.storage.DeleteObject(photo.ThumbnailKey)
+	for _, key := range []string{photo.ThumbnailKey, photo.MediumKey, photo.FullKey} {
+		if key == "" {
+			continue
+		}
+		err = s.storage.DeleteObject(key)
 		if err != nil {
-			s.logger.Error("Failed to delete thumbnail from S3", "error", err, "key", photo.ThumbnailKey)
+			s.logger.Error("Failed to delete resized copy from S3", "error", err, "key", key)
 		}
 	}
-
-	// Delete from database
-	return s.db.Delete(&photo).Error
 }
 
 func (s *Service) generatePhotoKey(fileName string) (string, error) {
@@ -232,21 +387,49 @@ func (s *Service) generatePhotoKey(fileName string) (string, error) {
 }
 
 func (s *Service) generateThumbnailKey(originalKey string) string {
-	// Replace the file extension with _thumb.jpg
+	return variantKey(originalKey, "thumb")
+}
+
+// variantKey names a resized copy of the original, e.g.
+// photos/2024/01/15/abc123_medium.jpg
+func variantKey(originalKey, size string) string {
+	// Replace the file extension with _<size>.jpg
 	extension := filepath.Ext(originalKey)
 	baseKey := strings.TrimSuffix(originalKey, extension)
-	return baseKey + "_thumb.jpg"
+	return baseKey + "_" + size + ".jpg"
+}
+
+// preloadDuplicateOf loads the photo each flagged duplicate copies, so
+// moderators can compare the two.
+func preloadDuplicateOf(db *gorm.DB) *gorm.DB {
+	return db.Preload("DuplicateOf")
 }
 
-func (s *Service) processPhoto(photo *Photo) {
-	s.logger.Info("Processing photo", "id", photo.ID, "key", photo.S3Key)
+// signURLs fills in the photo's download links. Until processing has made
+// the resized copies, every size falls back to the original. A video's
+// sizes are stills of its poster frame, so they stay empty until then.
+func (s *Service) signURLs(photo *Photo) {
+	if photo.DuplicateOf != nil {
+		s.signURLs(photo.DuplicateOf)
+	}
+	photo.FullURL = s.storage.GeneratePresignedURL(photo.S3Key, 24*time.Hour)
+	if photo.MediaType == MediaVideo {
+		photo.VideoURL, photo.FullURL = photo.FullURL, ""
+	}
+	photo.MediumURL = photo.FullURL
+	photo.ThumbnailURL = photo.FullURL
+	if photo.ProcessedAt == nil {
+		return
+	}
+
+	if photo.FullKey != "" {
+		photo.FullURL = s.storage.GeneratePresignedURL(photo.FullKey, 24*time.Hour)
+	}
+	if photo.MediumKey != "" {
+		photo.MediumURL = s.storage.GeneratePresignedURL(photo.MediumKey, 24*time.Hour)
+	}
+	if photo.ThumbnailKey != "" {
+		photo.ThumbnailURL = s.storage.GeneratePresignedURL(photo.ThumbnailKey, 24*time.Hour)
+	}
+}
 
-	// TODO: Implement background processing
-	// 1. Generate thumbnail
-	// 2. Run content moderation (AWS Rekognition, Google Vision API, etc.)
-	// 3. Extract EXIF data
-	// 4. Virus scan
-	
-	// For now, just log
-	s.logger.Info("Photo processing complete", "id", photo.ID)
-}
\ No newline at end of file
@@ -0,0 +1,115 @@
+package photo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"wedding-app/pkg/imaging"
+	"wedding-app/pkg/storage"
+)
+
+// Largest file a guest may upload of each media type.
+const (
+	MaxPhotoSize = 10 * 1024 * 1024
+	MaxVideoSize = 200 * 1024 * 1024
+)
+
+// Media types a photo record can hold.
+const (
+	MediaImage = "image"
+	MediaVideo = "video"
+)
+
+// MaxUploadSize is the size limit for uploads of a media type.
+func MaxUploadSize(mediaType string) int64 {
+	if mediaType == MediaVideo {
+		return MaxVideoSize
+	}
+	return MaxPhotoSize
+}
+
+// checkFileSize rejects a declared upload size that is empty, negative or
+// over the limit for mediaType.
+func checkFileSize(mediaType string, size int64) error {
+	if limit := MaxUploadSize(mediaType); size <= 0 || size > limit {
+		return fmt.Errorf("%w: must be between 1 byte and %d MB for %ss", ErrInvalidFileSize, limit>>20, mediaType)
+	}
+	return nil
+}
+
+var (
+	ErrNotUploading = errors.New("photo is not in uploading state")
+	// ErrInvalidFileSize means the declared size is zero, negative or over
+	// the limit for the media type.
+	ErrInvalidFileSize = errors.New("invalid file size")
+	// ErrUploadMissing means the file hasn't reached storage; the client may
+	// still be uploading it.
+	ErrUploadMissing = errors.New("uploaded file not found")
+	// ErrUploadRejected means the stored file failed verification. The
+	// object and its photo record have been deleted.
+	ErrUploadRejected = errors.New("upload rejected")
+)
+
+// verifyUpload checks that what the guest actually put in storage is the
+// file they said they would upload: present, within the size limit, the
+// declared size, and of the declared type judging by its leading bytes.
+func (s *Service) verifyUpload(photo *Photo) error {
+	info, err := s.storage.Stat(photo.S3Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrUploadMissing
+	}
+	if err != nil {
+		return err
+	}
+
+	if limit := MaxUploadSize(photo.MediaType); info.Size > limit {
+		return s.rejectUpload(photo, fmt.Sprintf("file is %d bytes, over the %d MB limit", info.Size, limit>>20))
+	}
+	if info.Size != photo.FileSize {
+		return s.rejectUpload(photo, fmt.Sprintf("file is %d bytes but %d were declared", info.Size, photo.FileSize))
+	}
+
+	header, err := s.readHeader(photo.S3Key)
+	if err != nil {
+		return err
+	}
+	sniffed := imaging.Sniff(header)
+	if sniffed == "" {
+		return s.rejectUpload(photo, "file is not a supported image or video")
+	}
+	if declared := imaging.NormalizeType(photo.ContentType); sniffed != declared {
+		return s.rejectUpload(photo, fmt.Sprintf("file is %s but was declared as %s", sniffed, declared))
+	}
+
+	return nil
+}
+
+func (s *Service) readHeader(key string) ([]byte, error) {
+	body, err := s.storage.GetRange(key, 0, imaging.SniffLength)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	header, err := io.ReadAll(io.LimitReader(body, imaging.SniffLength))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	return header, nil
+}
+
+// rejectUpload deletes a failed upload's object and record so neither is
+// left orphaned.
+func (s *Service) rejectUpload(photo *Photo, reason string) error {
+	s.logger.Warn("Upload rejected", "id", photo.ID, "key", photo.S3Key, "reason", reason)
+
+	if err := s.storage.DeleteObject(photo.S3Key); err != nil {
+		s.logger.Error("Failed to delete rejected upload", "error", err, "key", photo.S3Key)
+	}
+	if err := s.db.Delete(photo).Error; err != nil {
+		s.logger.Error("Failed to delete rejected photo record", "error", err, "id", photo.ID)
+	}
+
+	return fmt.Errorf("%w: %s", ErrUploadRejected, reason)
+}
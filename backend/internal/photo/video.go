@@ -0,0 +1,144 @@
+package photo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"wedding-app/internal/jobs"
+	"wedding-app/pkg/imaging"
+	"wedding-app/pkg/storage"
+)
+
+// videoPartSize is the size of each part of a video upload. S3 needs every
+// part but the last to be at least 5 MB.
+const videoPartSize = 8 * 1024 * 1024
+
+// videoUploadURLExpiry is how long a guest has to upload a video's parts;
+// longer than for photos since clips are bigger and often sent over mobile
+// data.
+const videoUploadURLExpiry = time.Hour
+
+var (
+	// ErrVideoUnsupported means the storage backend can't take multipart
+	// uploads, so videos can't be uploaded at all.
+	ErrVideoUnsupported = errors.New("video uploads are not supported by this storage backend")
+	ErrPartsRequired    = errors.New("the uploaded parts are required to complete a video upload")
+)
+
+// PartURL is where the client uploads one part of a video.
+type PartURL struct {
+	PartNumber int32  `json:"partNumber"`
+	URL        string `json:"url"`
+}
+
+// startVideoUpload opens a multipart upload for the video at key and signs
+// a URL for each of its parts. The size is checked first so a bad one
+// can't leave an upload behind.
+func (s *Service) startVideoUpload(key, contentType string, size int64) (string, []PartURL, error) {
+	mp, ok := s.storage.(storage.MultipartClient)
+	if !ok {
+		return "", nil, ErrVideoUnsupported
+	}
+	if err := checkFileSize(MediaVideo, size); err != nil {
+		return "", nil, err
+	}
+
+	uploadID, err := mp.CreateMultipartUpload(key, contentType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	count := int32((size + videoPartSize - 1) / videoPartSize)
+	parts := make([]PartURL, 0, count)
+	for n := int32(1); n <= count; n++ {
+		url, err := mp.GeneratePresignedPartURL(key, uploadID, n, videoUploadURLExpiry)
+		if err != nil {
+			s.abortUpload(key, uploadID)
+			return "", nil, err
+		}
+		parts = append(parts, PartURL{PartNumber: n, URL: url})
+	}
+	return uploadID, parts, nil
+}
+
+// completeVideoUpload joins the uploaded parts of a video into its object.
+// If the upload is already gone, a concurrent request may have completed
+// it; verifyUpload decides whether the object is there.
+func (s *Service) completeVideoUpload(photo *Photo, parts []storage.CompletedPart) error {
+	if photo.UploadID == "" {
+		return nil
+	}
+	if len(parts) == 0 {
+		return ErrPartsRequired
+	}
+	mp, ok := s.storage.(storage.MultipartClient)
+	if !ok {
+		return ErrVideoUnsupported
+	}
+
+	err := mp.CompleteMultipartUpload(photo.S3Key, photo.UploadID, parts)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		// Usually a part that hasn't finished uploading; the client can
+		// retry once it has
+		s.logger.Warn("Failed to complete video upload", "error", err, "id", photo.ID)
+		return ErrUploadMissing
+	}
+	return nil
+}
+
+// abortUpload discards the parts of an unfinished video upload.
+func (s *Service) abortUpload(key, uploadID string) {
+	mp, ok := s.storage.(storage.MultipartClient)
+	if !ok || uploadID == "" {
+		return
+	}
+	if err := mp.AbortMultipartUpload(key, uploadID); err != nil {
+		s.logger.Error("Failed to abort multipart upload", "error", err, "key", key)
+	}
+}
+
+// readVideoPoster copies a video to a temporary file, hashing it on the
+// way, and extracts its poster frame. Videos are too big to hold in memory
+// and ffmpeg needs to seek.
+func (s *Service) readVideoPoster(ctx context.Context, key string) ([]byte, string, error) {
+	body, err := s.storage.GetObject(key)
+	if err != nil {
+		return nil, "", err
+	}
+	defer body.Close()
+
+	file, err := os.CreateTemp("", "video-*"+filepath.Ext(key))
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(body, MaxVideoSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read original: %w", err)
+	}
+	if n > MaxVideoSize {
+		return nil, "", jobs.Permanent(fmt.Errorf("original is larger than %d MB", MaxVideoSize>>20))
+	}
+
+	frame, err := imaging.PosterFrame(ctx, file.Name())
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+		// Neither a broken clip nor a missing ffmpeg fixes itself; once
+		// ffmpeg is installed the job can be retried from the dead letter
+		// queue
+		return nil, "", jobs.Permanent(err)
+	}
+	return frame, hex.EncodeToString(hash.Sum(nil)), nil
+}
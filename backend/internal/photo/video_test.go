@@ -0,0 +1,150 @@
+package photo
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"wedding-app/pkg/storage"
+)
+
+type discardLogger struct{}
+
+func (discardLogger) Debug(string, ...interface{}) {}
+func (discardLogger) Info(string, ...interface{})  {}
+func (discardLogger) Warn(string, ...interface{})  {}
+func (discardLogger) Error(string, ...interface{}) {}
+
+func newVideoService() (*Service, *storage.MemoryClient) {
+	mem := storage.NewMemoryClient()
+	return &Service{storage: mem, logger: discardLogger{}}, mem
+}
+
+func TestStartVideoUploadParts(t *testing.T) {
+	tests := []struct {
+		size  int64
+		parts int
+	}{
+		{1, 1},
+		{videoPartSize - 1, 1},
+		{videoPartSize, 1},
+		{videoPartSize + 1, 2},
+		{3 * videoPartSize, 3},
+		{MaxVideoSize, 25},
+	}
+	for _, tt := range tests {
+		s, mem := newVideoService()
+		uploadID, parts, err := s.startVideoUpload("videos/clip.mp4", "video/mp4", tt.size)
+		if err != nil {
+			t.Errorf("size %d: %v", tt.size, err)
+			continue
+		}
+		if len(parts) != tt.parts {
+			t.Errorf("size %d: %d parts, want %d", tt.size, len(parts), tt.parts)
+		}
+		for i, part := range parts {
+			if part.PartNumber != int32(i+1) || part.URL == "" {
+				t.Errorf("size %d: part %d = %+v", tt.size, i, part)
+			}
+		}
+		if ids := mem.Uploads(); len(ids) != 1 || ids[0] != uploadID {
+			t.Errorf("size %d: open uploads = %v, want [%s]", tt.size, ids, uploadID)
+		}
+	}
+}
+
+func TestStartVideoUploadRejectsSizes(t *testing.T) {
+	for _, size := range []int64{0, -1, -videoPartSize, -1 << 62, MaxVideoSize + 1, 1 << 62} {
+		s, mem := newVideoService()
+		_, parts, err := s.startVideoUpload("videos/clip.mp4", "video/mp4", size)
+		if !errors.Is(err, ErrInvalidFileSize) {
+			t.Errorf("size %d: got %v, want ErrInvalidFileSize", size, err)
+		}
+		if parts != nil {
+			t.Errorf("size %d: got %d parts", size, len(parts))
+		}
+		if ids := mem.Uploads(); len(ids) != 0 {
+			t.Errorf("size %d: upload left open: %v", size, ids)
+		}
+	}
+}
+
+func TestStartVideoUploadUnsupported(t *testing.T) {
+	// Hide the memory client's multipart methods
+	s := &Service{storage: struct{ storage.Client }{storage.NewMemoryClient()}, logger: discardLogger{}}
+	if _, _, err := s.startVideoUpload("videos/clip.mp4", "video/mp4", 1); !errors.Is(err, ErrVideoUnsupported) {
+		t.Errorf("got %v, want ErrVideoUnsupported", err)
+	}
+}
+
+func TestCompleteVideoUpload(t *testing.T) {
+	s, mem := newVideoService()
+	content := strings.Repeat("a", videoPartSize) + "tail"
+	uploadID, partURLs, err := s.startVideoUpload("videos/clip.mp4", "video/mp4", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partURLs) != 2 {
+		t.Fatalf("%d parts, want 2", len(partURLs))
+	}
+
+	var parts []storage.CompletedPart
+	for i, part := range partURLs {
+		chunk := content[i*videoPartSize : min((i+1)*videoPartSize, len(content))]
+		etag, err := mem.UploadPart("videos/clip.mp4", uploadID, part.PartNumber, []byte(chunk))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, storage.CompletedPart{PartNumber: part.PartNumber, ETag: etag})
+	}
+
+	photo := &Photo{S3Key: "videos/clip.mp4", UploadID: uploadID}
+	if err := s.completeVideoUpload(photo, nil); !errors.Is(err, ErrPartsRequired) {
+		t.Errorf("without parts: got %v, want ErrPartsRequired", err)
+	}
+	bad := []storage.CompletedPart{{PartNumber: 1, ETag: "0000"}, parts[1]}
+	if err := s.completeVideoUpload(photo, bad); !errors.Is(err, ErrUploadMissing) {
+		t.Errorf("with a bad ETag: got %v, want ErrUploadMissing", err)
+	}
+
+	if err := s.completeVideoUpload(photo, parts); err != nil {
+		t.Fatal(err)
+	}
+	body, err := mem.GetObject("videos/clip.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if got, _ := io.ReadAll(body); string(got) != content {
+		t.Errorf("assembled video is %d bytes, want %d", len(got), len(content))
+	}
+
+	// A second completion finds the upload gone and leaves the decision
+	// to verifyUpload
+	if err := s.completeVideoUpload(photo, parts); err != nil {
+		t.Errorf("completing twice: %v", err)
+	}
+}
+
+func TestCheckFileSize(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		size      int64
+		ok        bool
+	}{
+		{MediaImage, 1, true},
+		{MediaImage, MaxPhotoSize, true},
+		{MediaImage, MaxPhotoSize + 1, false},
+		{MediaImage, 0, false},
+		{MediaImage, -1, false},
+		{MediaVideo, MaxPhotoSize + 1, true},
+		{MediaVideo, MaxVideoSize, true},
+		{MediaVideo, MaxVideoSize + 1, false},
+	}
+	for _, tt := range tests {
+		if err := checkFileSize(tt.mediaType, tt.size); (err == nil) != tt.ok {
+			t.Errorf("checkFileSize(%s, %d) = %v, want ok %v", tt.mediaType, tt.size, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package questionnaire
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"wedding-app/internal/models"
+)
+
+// maxTextAnswer caps free-text answers so a form can't store arbitrarily
+// large blobs on the guest.
+const maxTextAnswer = 2000
+
+// ValidationError reports an answer that doesn't fit its question.
+type ValidationError struct {
+	Key     string `json:"key"`
+	Member  string `json:"member,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	if e.Member != "" {
+		return fmt.Sprintf("%s (%s): %s", e.Key, e.Member, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+// Form is the questionnaire as seen at one stage. Questions lists what the
+// stage asks; answers to questions from other stages are still accepted so a
+// guest can revise their registration answers when they RSVP.
+type Form struct {
+	Stage     string
+	Questions []Question
+
+	all map[string]Question
+}
+
+func LoadForm(db *gorm.DB, stage string) (*Form, error) {
+	questions, err := ListQuestions(db, "")
+	if err != nil {
+		return nil, err
+	}
+
+	form := &Form{Stage: stage, Questions: []Question{}, all: make(map[string]Question, len(questions))}
+	for _, q := range questions {
+		form.all[q.Key] = q
+		if q.Stage == stage || q.Stage == StageBoth {
+			form.Questions = append(form.Questions, q)
+		}
+	}
+	return form, nil
+}
+
+// Apply validates answers and merges them over existing, returning the new
+// answer map. Empty answers clear the stored value. Per-member answers are
+// read from and normalized in members. When enforceRequired is set, every
+// required question at this stage must be answered once merged, and
+// required per-member questions must be answered for each party member.
+func (f *Form) Apply(existing, answers Answers, members models.PartyMembers, enforceRequired bool) (Answers, error) {
+	merged := Answers{}
+	for key, value := range existing {
+		merged[key] = value
+	}
+	if err := f.merge(merged, answers, ""); err != nil {
+		return nil, err
+	}
+
+	for i := range members {
+		m := &members[i]
+		name := strings.TrimSpace(m.FirstName + " " + m.LastName)
+		if name == "" {
+			name = fmt.Sprintf("party member %d", i+1)
+		}
+
+		memberAnswers := Answers{}
+		if err := f.merge(memberAnswers, m.Answers, name); err != nil {
+			return nil, err
+		}
+		m.Answers = nil
+		if len(memberAnswers) > 0 {
+			m.Answers = memberAnswers
+		}
+	}
+
+	if enforceRequired {
+		for _, q := range f.Questions {
+			if !q.Required {
+				continue
+			}
+			if _, ok := merged[q.Key]; !ok {
+				return nil, &ValidationError{Key: q.Key, Message: "an answer is required"}
+			}
+			if !q.PerMember {
+				continue
+			}
+			for i, m := range members {
+				if _, ok := m.Answers[q.Key]; !ok {
+					name := strings.TrimSpace(m.FirstName + " " + m.LastName)
+					if name == "" {
+						name = fmt.Sprintf("party member %d", i+1)
+					}
+					return nil, &ValidationError{Key: q.Key, Member: name, Message: "an answer is required"}
+				}
+			}
+		}
+	}
+
+	if len(merged) == 0 {
+		return nil, nil
+	}
+	return merged, nil
+}
+
+// merge validates each answer and writes the normalized value into dst.
+// member is set when the answers belong to a party member, in which case
+// only per-member questions may be answered.
+func (f *Form) merge(dst, answers Answers, member string) error {
+	for key, value := range answers {
+		q, ok := f.all[key]
+		if !ok {
+			return &ValidationError{Key: key, Member: member, Message: "unknown question"}
+		}
+		if member != "" && !q.PerMember {
+			return &ValidationError{Key: key, Member: member, Message: "question is not asked for party members"}
+		}
+
+		normalized, err := normalize(q, value)
+		if err != nil {
+			return &ValidationError{Key: key, Member: member, Message: err.Error()}
+		}
+		if normalized == nil {
+			delete(dst, key)
+			continue
+		}
+		dst[key] = normalized
+	}
+	return nil
+}
+
+// normalize checks value against the question type and returns it in its
+// stored form, or nil when the value counts as unanswered.
+func normalize(q Question, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch q.Type {
+	case "text":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected text")
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil, nil
+		}
+		if len(s) > maxTextAnswer {
+			return nil, fmt.Errorf("answer is longer than %d characters", maxTextAnswer)
+		}
+		return s, nil
+
+	case "single_choice":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected one of the options")
+		}
+		if s == "" {
+			return nil, nil
+		}
+		if !contains(q.Options, s) {
+			return nil, fmt.Errorf("%q is not one of the options", s)
+		}
+		return s, nil
+
+	case "multi_choice":
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a list of options")
+		}
+		var choices []string
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok || !contains(q.Options, s) {
+				return nil, fmt.Errorf("%v is not one of the options", item)
+			}
+			if !contains(choices, s) {
+				choices = append(choices, s)
+			}
+		}
+		if len(choices) == 0 {
+			return nil, nil
+		}
+		return choices, nil
+
+	case "boolean":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected true or false")
+		}
+		return b, nil
+
+	case "number":
+		var n float64
+		switch v := value.(type) {
+		case float64:
+			n = v
+		case string:
+			if strings.TrimSpace(v) == "" {
+				return nil, nil
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("expected a number")
+			}
+			n = parsed
+		default:
+			return nil, fmt.Errorf("expected a number")
+		}
+		if q.Min != nil && n < *q.Min {
+			return nil, fmt.Errorf("must be at least %s", FormatAnswer(*q.Min))
+		}
+		if q.Max != nil && n > *q.Max {
+			return nil, fmt.Errorf("must be at most %s", FormatAnswer(*q.Max))
+		}
+		return n, nil
+	}
+
+	return nil, fmt.Errorf("unsupported question type %q", q.Type)
+}
+
+// FormatAnswer renders a stored answer for exports.
+func FormatAnswer(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		if v {
+			return "Yes"
+		}
+		return "No"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, FormatAnswer(item))
+		}
+		return strings.Join(parts, ", ")
+	case []string:
+		return strings.Join(v, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
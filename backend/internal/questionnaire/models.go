@@ -0,0 +1,8 @@
+package questionnaire
+
+import (
+	"wedding-app/internal/models"
+)
+
+type Question = models.Question
+type Answers = models.Answers
@@ -0,0 +1,236 @@
+package questionnaire
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"wedding-app/internal/models"
+	"wedding-app/pkg/logger"
+)
+
+const (
+	StageRegistration = "registration"
+	StageRSVP         = "rsvp"
+	StageBoth         = "both"
+)
+
+var questionTypes = []string{"text", "single_choice", "multi_choice", "boolean", "number"}
+
+var keyPattern = regexp.MustCompile(`^[a-z0-9]+(?:[-_][a-z0-9]+)*$`)
+
+type Service struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+func NewService(db *gorm.DB, logger logger.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetQuestions returns the questions asked at stage, or every question when
+// stage is empty.
+func (s *Service) GetQuestions(stage string) ([]Question, error) {
+	if stage != "" && stage != StageRegistration && stage != StageRSVP {
+		return nil, fmt.Errorf("stage must be registration or rsvp")
+	}
+	return ListQuestions(s.db, stage)
+}
+
+func (s *Service) GetQuestionByID(id uint) (*Question, error) {
+	var question Question
+	if err := s.db.First(&question, id).Error; err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+type QuestionInput struct {
+	Key       string   `json:"key"`
+	Label     string   `json:"label"`
+	Type      string   `json:"type"`
+	Options   []string `json:"options"`
+	HelpText  *string  `json:"helpText"`
+	Required  *bool    `json:"required"`
+	PerMember *bool    `json:"perMember"`
+	Stage     string   `json:"stage"`
+	Min       *float64 `json:"min"`
+	Max       *float64 `json:"max"`
+	SortOrder *int     `json:"sortOrder"`
+}
+
+func (s *Service) CreateQuestion(input QuestionInput) (*Question, error) {
+	question := Question{Stage: StageBoth}
+	if err := applyInput(&question, input); err != nil {
+		return nil, err
+	}
+	if question.Key == "" || question.Label == "" || question.Type == "" {
+		return nil, fmt.Errorf("key, label and type are required")
+	}
+	if err := checkQuestion(&question); err != nil {
+		return nil, err
+	}
+	if err := s.ensureKeyFree(question.Key, 0); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&question).Error; err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Question created", "question", question.Key)
+	return &question, nil
+}
+
+// UpdateQuestion changes a question's definition. Answers already given are
+// kept as they are, even if they no longer fit the new definition.
+func (s *Service) UpdateQuestion(id uint, input QuestionInput) (*Question, error) {
+	question, err := s.GetQuestionByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyInput(question, input); err != nil {
+		return nil, err
+	}
+	if err := checkQuestion(question); err != nil {
+		return nil, err
+	}
+	if err := s.ensureKeyFree(question.Key, id); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(question).Error; err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+// DeleteQuestion removes the question from the forms and the export. Guests'
+// answers stay in their answer maps.
+func (s *Service) DeleteQuestion(id uint) error {
+	result := s.db.Delete(&Question{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *Service) ensureKeyFree(key string, exceptID uint) error {
+	var count int64
+	err := s.db.Model(&Question{}).Where("key = ? AND id <> ?", key, exceptID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("a question with key %q already exists", key)
+	}
+	return nil
+}
+
+func applyInput(question *Question, input QuestionInput) error {
+	if key := strings.ToLower(strings.TrimSpace(input.Key)); key != "" {
+		if !keyPattern.MatchString(key) {
+			return fmt.Errorf("key may only contain lowercase letters, numbers, dashes and underscores")
+		}
+		question.Key = key
+	}
+	if label := strings.TrimSpace(input.Label); label != "" {
+		question.Label = label
+	}
+	if input.Type != "" {
+		if !contains(questionTypes, input.Type) {
+			return fmt.Errorf("type must be one of %s", strings.Join(questionTypes, ", "))
+		}
+		question.Type = input.Type
+	}
+	if input.Options != nil {
+		var options models.StringList
+		for _, option := range input.Options {
+			option = strings.TrimSpace(option)
+			if option == "" {
+				continue
+			}
+			if contains(options, option) {
+				return fmt.Errorf("option %q is listed twice", option)
+			}
+			options = append(options, option)
+		}
+		question.Options = options
+	}
+	if input.HelpText != nil {
+		question.HelpText = strings.TrimSpace(*input.HelpText)
+	}
+	if input.Required != nil {
+		question.Required = *input.Required
+	}
+	if input.PerMember != nil {
+		question.PerMember = *input.PerMember
+	}
+	if input.Stage != "" {
+		if input.Stage != StageRegistration && input.Stage != StageRSVP && input.Stage != StageBoth {
+			return fmt.Errorf("stage must be registration, rsvp or both")
+		}
+		question.Stage = input.Stage
+	}
+	if input.Min != nil {
+		question.Min = input.Min
+	}
+	if input.Max != nil {
+		question.Max = input.Max
+	}
+	if input.SortOrder != nil {
+		question.SortOrder = *input.SortOrder
+	}
+	return nil
+}
+
+// checkQuestion validates settings that depend on the question type.
+func checkQuestion(question *Question) error {
+	switch question.Type {
+	case "single_choice", "multi_choice":
+		if len(question.Options) == 0 {
+			return fmt.Errorf("choice questions need at least one option")
+		}
+	default:
+		question.Options = nil
+	}
+
+	if question.Type == "number" {
+		if question.Min != nil && question.Max != nil && *question.Min > *question.Max {
+			return fmt.Errorf("min cannot be greater than max")
+		}
+	} else {
+		question.Min, question.Max = nil, nil
+	}
+	return nil
+}
+
+// ListQuestions returns the questions asked at stage in form order, or every
+// question when stage is empty.
+func ListQuestions(db *gorm.DB, stage string) ([]Question, error) {
+	query := db.Order("sort_order ASC, id ASC")
+	if stage != "" {
+		query = query.Where("stage IN ?", []string{stage, StageBoth})
+	}
+
+	var questions []Question
+	err := query.Find(&questions).Error
+	return questions, err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
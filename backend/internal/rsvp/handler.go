@@ -5,6 +5,9 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"wedding-app/internal/guest"
+	"wedding-app/internal/models"
+	"wedding-app/pkg/pagination"
 )
 
 type Handler struct {
@@ -24,44 +27,94 @@ func (h *Handler) GetRSVP(c *gin.Context) {
 		return
 	}
 
-	guest, err := h.service.GetGuestByToken(token)
+	invitation, err := h.service.GetInvitationByToken(token)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid RSVP link"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"firstName":                   guest.FirstName,
-		"lastName":                    guest.LastName,
-		"maxPartySize":                guest.MaxPartySize,
-		"hasRSVP":                     guest.RSVPStatus != "pending",
-		"partySize":                   guest.PartySize,
-		"partyMembers":                guest.PartyMembers,
-		"mainPersonDietaryPreference": guest.MainPersonDietaryPreference,
-		"dec24Attendance":             guest.Dec24Attendance,
-		"dec25Attendance":             guest.Dec25Attendance,
-		"accommodationDec23":          guest.AccommodationDec23,
-		"accommodationDec24":          guest.AccommodationDec24,
-		"accommodationDec25":          guest.AccommodationDec25,
-		"concerns":                    guest.Concerns,
-	})
+	events, err := h.service.GetEvents()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load events"})
+		return
+	}
+
+	form, err := h.service.GetQuestionnaire()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load questionnaire"})
+		return
+	}
+
+	if invitation.Household != nil {
+		members := make([]gin.H, 0, len(invitation.Guests))
+		hasRSVP := true
+		for _, g := range invitation.Guests {
+			member := guestRSVPDetails(&g)
+			member["guestId"] = g.ID
+			members = append(members, member)
+			if g.RSVPStatus == "pending" {
+				hasRSVP = false
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"household": gin.H{
+				"id":   invitation.Household.ID,
+				"name": invitation.Household.Name,
+			},
+			"hasRSVP":         hasRSVP,
+			"members":         members,
+			"availableEvents": events,
+			"questions":       form.Questions,
+		})
+		return
+	}
+
+	details := guestRSVPDetails(&invitation.Guests[0])
+	details["availableEvents"] = events
+	details["questions"] = form.Questions
+	c.JSON(http.StatusOK, details)
+}
+
+// guestRSVPDetails is the RSVP form payload for a single guest.
+func guestRSVPDetails(g *guest.Guest) gin.H {
+	return gin.H{
+		"firstName":                   g.FirstName,
+		"lastName":                    g.LastName,
+		"maxPartySize":                g.MaxPartySize,
+		"hasRSVP":                     g.RSVPStatus != "pending",
+		"rsvpStatus":                  g.RSVPStatus,
+		"partySize":                   g.PartySize,
+		"partyMembers":                g.PartyMembers,
+		"mainPersonDietaryPreference": g.MainPersonDietaryPreference,
+		"events":                      g.Events,
+		"answers":                     g.Answers,
+		"concerns":                    g.Concerns,
+	}
 }
 
 type SubmitRSVPRequest struct {
 	Response        string                `json:"response" binding:"required,oneof=yes no"`
 	Message         string                `json:"message"`
 	UpdatedDetails  *UpdatedDetailsStruct `json:"updatedDetails"`
+
+	// Members carries per-guest answers when a household token is used.
+	// Household members without an entry take Response.
+	Members []MemberResponse `json:"members" binding:"dive"`
+}
+
+type MemberResponse struct {
+	GuestID        uint                  `json:"guestId" binding:"required"`
+	Response       string                `json:"response" binding:"omitempty,oneof=yes no"`
+	UpdatedDetails *UpdatedDetailsStruct `json:"updatedDetails"`
 }
 
 type UpdatedDetailsStruct struct {
 	PartySize                   int                        `json:"partySize"`
 	PartyMembers                interface{}                `json:"partyMembers"` // Keep as interface{} for JSONB compatibility
 	MainPersonDietaryPreference string                     `json:"mainPersonDietaryPreference"`
-	Dec24Attendance             bool                       `json:"dec24Attendance"`
-	Dec25Attendance             bool                       `json:"dec25Attendance"`
-	AccommodationDec23          bool                       `json:"accommodationDec23"`
-	AccommodationDec24          bool                       `json:"accommodationDec24"`
-	AccommodationDec25          bool                       `json:"accommodationDec25"`
+	Events                      map[string]bool            `json:"events"` // event slug -> attending
+	Answers                     models.Answers             `json:"answers"` // question key -> answer
 	Concerns                    string                     `json:"concerns"`
 }
 
@@ -82,7 +135,7 @@ func (h *Handler) SubmitRSVP(c *gin.Context) {
 	fmt.Printf("Received RSVP request: %+v\n", req)
 	fmt.Printf("Updated details: %+v\n", req.UpdatedDetails)
 
-	err := h.service.SubmitRSVP(token, req.Response, req.Message, req.UpdatedDetails)
+	err := h.service.SubmitRSVP(token, req)
 	if err != nil {
 		fmt.Printf("Service error: %v\n", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -93,15 +146,29 @@ func (h *Handler) SubmitRSVP(c *gin.Context) {
 }
 
 func (h *Handler) GetRSVPs(c *gin.Context) {
-	rsvps, stats, err := h.service.GetAllRSVPs()
+	params, err := pagination.Parse(c, RSVPListOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := c.Query("response")
+	if response != "" && response != "yes" && response != "no" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "response must be yes or no"})
+		return
+	}
+
+	page, stats, err := h.service.ListRSVPs(params, response)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch RSVPs"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"rsvps": rsvps,
-		"stats": stats,
+		"items":      page.Items,
+		"nextCursor": page.NextCursor,
+		"total":      page.Total,
+		"stats":      stats,
 	})
 }
 
@@ -2,29 +2,63 @@ package rsvp
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"wedding-app/internal/event"
 	"wedding-app/internal/guest"
+	"wedding-app/internal/jobs"
 	"wedding-app/internal/models"
+	"wedding-app/internal/notification"
+	"wedding-app/internal/questionnaire"
 	"wedding-app/pkg/logger"
+	"wedding-app/pkg/notify"
+	"wedding-app/pkg/pagination"
 )
 
+// JobSendConfirmation is the background job that emails a guest their RSVP
+// confirmation.
+const JobSendConfirmation = "rsvp.confirmation"
+
+type ConfirmationJob struct {
+	GuestID uint `json:"guestId"`
+	RSVPID  uint `json:"rsvpId"`
+}
+
 type Service struct {
-	db     *gorm.DB
-	logger logger.Logger
+	db       *gorm.DB
+	logger   logger.Logger
+	notifier *notification.Service
+	queue    *jobs.Queue
 }
 
 
-func NewService(db *gorm.DB, logger logger.Logger) *Service {
-	return &Service{
-		db:     db,
-		logger: logger,
+func NewService(db *gorm.DB, logger logger.Logger, notifier *notification.Service, queue *jobs.Queue) *Service {
+	s := &Service{
+		db:       db,
+		logger:   logger,
+		notifier: notifier,
+		queue:    queue,
 	}
+	jobs.Register(queue, JobSendConfirmation, s.sendConfirmationEmail)
+	return s
+}
+
+// GetEvents returns the configured events so the RSVP form can render them.
+func (s *Service) GetEvents() ([]event.Event, error) {
+	return event.ListEvents(s.db)
+}
+
+// GetQuestionnaire returns the custom questions asked when guests RSVP.
+func (s *Service) GetQuestionnaire() (*questionnaire.Form, error) {
+	return questionnaire.LoadForm(s.db, questionnaire.StageRSVP)
 }
 
 func (s *Service) GetGuestByToken(token string) (*guest.Guest, error) {
@@ -33,101 +67,242 @@ func (s *Service) GetGuestByToken(token string) (*guest.Guest, error) {
 	return &g, err
 }
 
-func (s *Service) SubmitRSVP(token, response, message string, updatedDetails *UpdatedDetailsStruct) error {
-	// Find guest by token
+// Invitation is whoever an RSVP token answers for: a single guest, or every
+// member of a household when the household invite token is used.
+type Invitation struct {
+	Household *guest.Household
+	Guests    []guest.Guest
+}
+
+func (s *Service) GetInvitationByToken(token string) (*Invitation, error) {
 	var g guest.Guest
-	err := s.db.Where("invite_token = ?", token).First(&g).Error
+	err := s.db.Preload("Attendance.Event").Where("invite_token = ?", token).First(&g).Error
+	if err == nil {
+		return &Invitation{Guests: []guest.Guest{g}}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var household guest.Household
+	err = s.db.Preload("Guests", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Preload("Guests.Attendance.Event").Where("invite_token = ?", token).First(&household).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(household.Guests) == 0 {
+		return nil, fmt.Errorf("household has no members")
+	}
+
+	guests := household.Guests
+	household.Guests = nil
+	return &Invitation{Household: &household, Guests: guests}, nil
+}
+
+func (s *Service) SubmitRSVP(token string, req SubmitRSVPRequest) error {
+	// Find the guest, or every household member, by token
+	invitation, err := s.GetInvitationByToken(token)
 	if err != nil {
 		return fmt.Errorf("invalid RSVP token")
 	}
 
-	// Update guest RSVP status
-	g.RSVPStatus = response
+	memberResponses := make(map[uint]MemberResponse, len(req.Members))
+	if invitation.Household != nil {
+		for _, m := range req.Members {
+			memberResponses[m.GuestID] = m
+		}
+		for _, m := range req.Members {
+			if !invitation.includes(m.GuestID) {
+				return fmt.Errorf("guest %d is not part of this invitation", m.GuestID)
+			}
+		}
+	}
+
+	form, err := s.GetQuestionnaire()
+	if err != nil {
+		return fmt.Errorf("failed to load questionnaire: %w", err)
+	}
 
-	// If updated details are provided, update the guest questionnaire data
-	if updatedDetails != nil {
-		// Allow party size increase during RSVP if reasonable (up to 10 people)
-		if updatedDetails.PartySize > 10 {
-			return fmt.Errorf("party size exceeds maximum allowed (10)")
+	guests := invitation.Guests
+	rsvps := make([]RSVP, len(guests))
+	attendance := make([]map[string]bool, len(guests))
+	for i := range guests {
+		response, details := req.Response, req.UpdatedDetails
+		if invitation.Household != nil {
+			// Shared details only make sense for a single guest; household
+			// members update their own details through Members.
+			details = nil
+			if m, ok := memberResponses[guests[i].ID]; ok {
+				if m.Response != "" {
+					response = m.Response
+				}
+				details = m.UpdatedDetails
+			}
 		}
-		
-		// Update MaxPartySize if needed
-		if updatedDetails.PartySize > g.MaxPartySize {
-			g.MaxPartySize = updatedDetails.PartySize
+
+		if err := applyRSVP(&guests[i], response, details, form); err != nil {
+			return err
+		}
+		if details != nil {
+			attendance[i] = details.Events
 		}
 
-		g.PartySize = updatedDetails.PartySize
-		g.MainPersonDietaryPreference = updatedDetails.MainPersonDietaryPreference
-		g.Dec24Attendance = updatedDetails.Dec24Attendance
-		g.Dec25Attendance = updatedDetails.Dec25Attendance
-		g.AccommodationDec23 = updatedDetails.AccommodationDec23
-		g.AccommodationDec24 = updatedDetails.AccommodationDec24
-		g.AccommodationDec25 = updatedDetails.AccommodationDec25
-		g.Concerns = updatedDetails.Concerns
-
-		// Handle party members - convert interface{} to proper type
-		if updatedDetails.PartyMembers != nil {
-			// Convert the interface{} to JSON and then parse as PartyMembers
-			partyMembersJSON, err := json.Marshal(updatedDetails.PartyMembers)
-			if err != nil {
-				return fmt.Errorf("failed to process party members: %w", err)
+		rsvps[i] = RSVP{
+			GuestID:     guests[i].ID,
+			Response:    response,
+			PartySize:   guests[i].PartySize,
+			Message:     req.Message,
+			RespondedAt: time.Now(),
+		}
+	}
+
+	// Use transaction to ensure all updates succeed
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range guests {
+			if err := tx.Omit("Attendance").Save(&guests[i]).Error; err != nil {
+				return fmt.Errorf("failed to update guest: %w", err)
+			}
+			if err := event.SetAttendance(tx, guests[i].ID, attendance[i]); err != nil {
+				return err
+			}
+			if err := tx.Create(&rsvps[i]).Error; err != nil {
+				return fmt.Errorf("failed to create RSVP: %w", err)
 			}
-			
-			var partyMembers models.PartyMembers
-			err = json.Unmarshal(partyMembersJSON, &partyMembers)
-			if err != nil {
-				return fmt.Errorf("failed to parse party members: %w", err)
+			if guests[i].Email != "" {
+				job := ConfirmationJob{GuestID: guests[i].ID, RSVPID: rsvps[i].ID}
+				if _, err := s.queue.EnqueueTx(tx, JobSendConfirmation, job); err != nil {
+					return err
+				}
 			}
-			
-			g.PartyMembers = partyMembers
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// Create RSVP record
-	rsvp := RSVP{
-		GuestID:             g.ID,
-		Response:            response,
-		PartySize:           g.PartySize,
-		Message:             message,
-		RespondedAt:         time.Now(),
+	for i := range guests {
+		g := &guests[i]
+		s.logger.Info("RSVP submitted", "guest", g.FirstName+" "+g.LastName, "response", rsvps[i].Response, "partySize", g.PartySize)
 	}
 
-	// Use transaction to ensure both updates succeed
-	tx := s.db.Begin()
+	return nil
+}
 
-	if err := tx.Save(&g).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update guest: %w", err)
+func (inv *Invitation) includes(guestID uint) bool {
+	for _, g := range inv.Guests {
+		if g.ID == guestID {
+			return true
+		}
 	}
+	return false
+}
+
+// applyRSVP records a response on the guest and, if provided, the updated
+// questionnaire details. Required custom questions must be answered when a
+// guest accepts, whether or not they send details with the response.
+func applyRSVP(g *guest.Guest, response string, updatedDetails *UpdatedDetailsStruct, form *questionnaire.Form) error {
+	// Update guest RSVP status
+	g.RSVPStatus = response
 
-	if err := tx.Create(&rsvp).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to create RSVP: %w", err)
+	if updatedDetails == nil {
+		// Nothing to merge, but an acceptance still needs the required
+		// answers already on file.
+		_, err := form.Apply(g.Answers, nil, nil, response == "yes")
+		return err
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	// Allow party size increase during RSVP if reasonable (up to 10 people)
+	if updatedDetails.PartySize > 10 {
+		return fmt.Errorf("party size exceeds maximum allowed (10)")
 	}
 
-	s.logger.Info("RSVP submitted", "guest", g.FirstName+" "+g.LastName, "response", response, "partySize", g.PartySize)
+	// Update MaxPartySize if needed
+	if updatedDetails.PartySize > g.MaxPartySize {
+		g.MaxPartySize = updatedDetails.PartySize
+	}
+
+	g.PartySize = updatedDetails.PartySize
+	g.MainPersonDietaryPreference = updatedDetails.MainPersonDietaryPreference
+	g.Concerns = updatedDetails.Concerns
+
+	// Handle party members - convert interface{} to proper type. Only
+	// resubmitted members have their answers checked again.
+	var members models.PartyMembers
+	if updatedDetails.PartyMembers != nil {
+		// Convert the interface{} to JSON and then parse as PartyMembers
+		partyMembersJSON, err := json.Marshal(updatedDetails.PartyMembers)
+		if err != nil {
+			return fmt.Errorf("failed to process party members: %w", err)
+		}
 
-	// TODO: Send confirmation email
-	go s.sendConfirmationEmail(&g, &rsvp)
+		var partyMembers models.PartyMembers
+		err = json.Unmarshal(partyMembersJSON, &partyMembers)
+		if err != nil {
+			return fmt.Errorf("failed to parse party members: %w", err)
+		}
+
+		g.PartyMembers = partyMembers
+		members = g.PartyMembers
+	}
+
+	answers, err := form.Apply(g.Answers, updatedDetails.Answers, members, response == "yes")
+	if err != nil {
+		return err
+	}
+	g.Answers = answers
 
 	return nil
 }
 
-func (s *Service) GetAllRSVPs() ([]RSVPWithGuest, *RSVPStats, error) {
-	var rsvps []RSVP
-	err := s.db.Preload("Guest").Order("responded_at DESC").Find(&rsvps).Error
+// RSVPListOptions controls sorting and search on the admin RSVP list. Guest
+// columns are available because the list query joins guests.
+var RSVPListOptions = pagination.Options{
+	Sortable: map[string]string{
+		"respondedAt": "rsvps.responded_at",
+		"response":    "rsvps.response",
+		"partySize":   "COALESCE(rsvps.party_size, 0)",
+		"firstName":   "guests.first_name",
+		"lastName":    "guests.last_name",
+	},
+	DefaultSort: "-respondedAt",
+	Searchable: []string{
+		"guests.first_name",
+		"guests.last_name",
+		"guests.email",
+		"guests.phone",
+		"guests.concerns",
+		"rsvps.message",
+	},
+	IDColumn: "rsvps.id",
+}
+
+// ListRSVPs returns one page of RSVPs, optionally limited to one response
+// value, along with the overall RSVP stats.
+func (s *Service) ListRSVPs(params *pagination.Params, response string) (*pagination.Page[RSVPWithGuest], *RSVPStats, error) {
+	query := s.db.Model(&RSVP{}).
+		Joins("JOIN guests ON guests.id = rsvps.guest_id AND guests.deleted_at IS NULL")
+	if response != "" {
+		query = query.Where("rsvps.response = ?", response)
+	}
+
+	var rsvps pagination.Page[RSVP]
+	err := pagination.Find(query, params, &rsvps, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Guest").Preload("Guest.Attendance.Event")
+	})
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Convert to response format
-	var result []RSVPWithGuest
-	for _, rsvp := range rsvps {
-		result = append(result, RSVPWithGuest{
+	result := &pagination.Page[RSVPWithGuest]{
+		Items:      make([]RSVPWithGuest, 0, len(rsvps.Items)),
+		NextCursor: rsvps.NextCursor,
+		Total:      rsvps.Total,
+	}
+	for _, rsvp := range rsvps.Items {
+		result.Items = append(result.Items, RSVPWithGuest{
 			ID:                  rsvp.ID,
 			Response:            rsvp.Response,
 			PartySize:           rsvp.PartySize,
@@ -181,12 +356,81 @@ func (s *Service) calculateStats() (*RSVPStats, error) {
 	}
 	stats.TotalAttending = totalAttending
 
+	households, err := s.calculateHouseholdStats()
+	if err != nil {
+		return nil, err
+	}
+	stats.Households = *households
+
+	stats.Events, err = event.Stats(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// calculateHouseholdStats rolls member RSVP statuses up to their household.
+// A household counts as responded once every member has answered; one with
+// no members is counted as empty rather than pending.
+func (s *Service) calculateHouseholdStats() (*models.HouseholdStats, error) {
+	var stats models.HouseholdStats
+
+	err := s.db.Model(&guest.Household{}).Count(&stats.Total).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var rollups []struct {
+		HouseholdID uint
+		Members     int64
+		Yes         int64
+		No          int64
+	}
+	err = s.db.Model(&guest.Guest{}).
+		Select("guests.household_id, COUNT(*) AS members, " +
+			"SUM(CASE WHEN guests.rsvp_status = 'yes' THEN 1 ELSE 0 END) AS yes, " +
+			"SUM(CASE WHEN guests.rsvp_status = 'no' THEN 1 ELSE 0 END) AS no").
+		Joins("JOIN households ON households.id = guests.household_id AND households.deleted_at IS NULL").
+		Group("guests.household_id").
+		Scan(&rollups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rollups {
+		if r.Yes+r.No < r.Members {
+			stats.Pending++
+			continue
+		}
+		stats.Responded++
+		switch r.Members {
+		case r.Yes:
+			stats.Attending++
+		case r.No:
+			stats.Declined++
+		default:
+			stats.Partial++
+		}
+	}
+	stats.Empty = stats.Total - int64(len(rollups))
+
 	return &stats, nil
 }
 
 func (s *Service) ExportRSVPsToCSV() (string, error) {
 	var guests []guest.Guest
-	err := s.db.Preload("RSVPs").Find(&guests).Error
+	err := s.db.Preload("RSVPs").Preload("Attendance.Event").Find(&guests).Error
+	if err != nil {
+		return "", err
+	}
+
+	events, err := event.ListEvents(s.db)
+	if err != nil {
+		return "", err
+	}
+
+	questions, err := questionnaire.ListQuestions(s.db, "")
 	if err != nil {
 		return "", err
 	}
@@ -194,15 +438,24 @@ func (s *Service) ExportRSVPsToCSV() (string, error) {
 	var buffer bytes.Buffer
 	writer := csv.NewWriter(&buffer)
 
-	// Write header with comprehensive questionnaire fields
+	// Write header with comprehensive questionnaire fields, one column per
+	// configured event
 	header := []string{
 		"First Name", "Last Name", "Email", "Phone",
 		"RSVP Status", "Party Size", "Main Person Dietary",
 		"Party Members", "Party Member Dietaries",
-		"Dec 24 Attendance", "Dec 25 Attendance",
-		"Accommodation Dec 23", "Accommodation Dec 24", "Accommodation Dec 25",
-		"Special Concerns", "RSVP Message", "Responded At",
 	}
+	for _, e := range events {
+		if e.Type == "accommodation" {
+			header = append(header, e.Name)
+		} else {
+			header = append(header, e.Name+" Attendance")
+		}
+	}
+	for _, q := range questions {
+		header = append(header, q.Label)
+	}
+	header = append(header, "Special Concerns", "RSVP Message", "Responded At")
 	writer.Write(header)
 
 	// Write data
@@ -228,32 +481,6 @@ func (s *Service) ExportRSVPsToCSV() (string, error) {
 			partyDietariesStr = fmt.Sprintf("\"%s\"", fmt.Sprintf("%v", memberDietaries))
 		}
 
-		// Convert boolean values to readable strings
-		dec24Attendance := "No"
-		if g.Dec24Attendance {
-			dec24Attendance = "Yes"
-		}
-		
-		dec25Attendance := "No"
-		if g.Dec25Attendance {
-			dec25Attendance = "Yes"
-		}
-		
-		accommodationDec23 := "No"
-		if g.AccommodationDec23 {
-			accommodationDec23 = "Yes"
-		}
-		
-		accommodationDec24 := "No"
-		if g.AccommodationDec24 {
-			accommodationDec24 = "Yes"
-		}
-		
-		accommodationDec25 := "No"
-		if g.AccommodationDec25 {
-			accommodationDec25 = "Yes"
-		}
-
 		row := []string{
 			g.FirstName,
 			g.LastName,
@@ -264,15 +491,22 @@ func (s *Service) ExportRSVPsToCSV() (string, error) {
 			g.MainPersonDietaryPreference,
 			partyMembersStr,
 			partyDietariesStr,
-			dec24Attendance,
-			dec25Attendance,
-			accommodationDec23,
-			accommodationDec24,
-			accommodationDec25,
-			g.Concerns,
-			message,
-			respondedAt,
 		}
+
+		// Convert attendance to readable strings
+		for _, e := range events {
+			attending := "No"
+			if g.Events[e.Slug] {
+				attending = "Yes"
+			}
+			row = append(row, attending)
+		}
+
+		for _, q := range questions {
+			row = append(row, exportAnswer(&g, q))
+		}
+
+		row = append(row, g.Concerns, message, respondedAt)
 		writer.Write(row)
 	}
 
@@ -280,7 +514,65 @@ func (s *Service) ExportRSVPsToCSV() (string, error) {
 	return buffer.String(), nil
 }
 
-func (s *Service) sendConfirmationEmail(g *guest.Guest, rsvp *RSVP) {
-	// TODO: Implement email confirmation sending
-	s.logger.Info("Sending RSVP confirmation email", "guest", g.Email, "response", rsvp.Response)
-}
\ No newline at end of file
+// exportAnswer renders the guest's answer to q. Per-member questions list
+// each party member's answer after their name.
+func exportAnswer(g *guest.Guest, q questionnaire.Question) string {
+	answer := questionnaire.FormatAnswer(g.Answers[q.Key])
+	if !q.PerMember || len(g.PartyMembers) == 0 {
+		return answer
+	}
+
+	parts := []string{g.FirstName + " " + g.LastName + ": " + answer}
+	for _, m := range g.PartyMembers {
+		parts = append(parts, m.FirstName+" "+m.LastName+": "+questionnaire.FormatAnswer(m.Answers[q.Key]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// sendConfirmationEmail runs as a background job after an RSVP is
+// submitted. Addresses the mail server refuses outright aren't retried.
+func (s *Service) sendConfirmationEmail(ctx context.Context, job ConfirmationJob) error {
+	var g guest.Guest
+	if err := s.db.First(&g, job.GuestID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	var rsvp RSVP
+	if err := s.db.First(&rsvp, job.RSVPID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	if g.Email == "" {
+		return nil
+	}
+
+	var attending []string
+	if rsvp.Response == "yes" {
+		events, err := event.ListEvents(s.db)
+		if err != nil {
+			return fmt.Errorf("failed to load events: %w", err)
+		}
+		var attendance []event.EventAttendance
+		err = s.db.Where("guest_id = ? AND attending", g.ID).Find(&attendance).Error
+		if err != nil {
+			return fmt.Errorf("failed to load attendance: %w", err)
+		}
+		for _, e := range events {
+			for _, a := range attendance {
+				if a.EventID == e.ID {
+					attending = append(attending, e.Name)
+				}
+			}
+		}
+	}
+
+	err := s.notifier.SendRSVPConfirmation(&g, rsvp.Response, attending)
+	if errors.Is(err, notify.ErrRejected) {
+		return jobs.Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,172 @@
+package rsvp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"wedding-app/internal/models"
+	"wedding-app/internal/testdb"
+)
+
+type discardLogger struct{}
+
+func (discardLogger) Debug(string, ...interface{}) {}
+func (discardLogger) Info(string, ...interface{})  {}
+func (discardLogger) Warn(string, ...interface{})  {}
+func (discardLogger) Error(string, ...interface{}) {}
+
+// newTestService returns a service on a stand-in database.
+func newTestService(t *testing.T) (*Service, *testdb.DB) {
+	t.Helper()
+	db, fake := testdb.New(t)
+	return &Service{db: db, logger: discardLogger{}}, fake
+}
+
+func TestCalculateHouseholdStats(t *testing.T) {
+	s, db := newTestService(t)
+	db.OnQuery(`SELECT count(*) FROM "households"`, []string{"count"}, []interface{}{int64(5)})
+	// Household 5 has no members, so the rollup has no row for it
+	db.OnQuery("JOIN households ON households.id = guests.household_id", []string{"household_id", "members", "yes", "no"},
+		[]interface{}{int64(1), int64(2), int64(2), int64(0)}, // everyone said yes
+		[]interface{}{int64(2), int64(2), int64(0), int64(2)}, // everyone said no
+		[]interface{}{int64(3), int64(3), int64(1), int64(1)}, // one still to answer
+		[]interface{}{int64(4), int64(2), int64(1), int64(1)}, // split answers
+	)
+
+	stats, err := s.calculateHouseholdStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.HouseholdStats{
+		Total:     5,
+		Responded: 3,
+		Pending:   1,
+		Empty:     1,
+		Attending: 1,
+		Partial:   1,
+		Declined:  1,
+	}
+	if *stats != want {
+		t.Errorf("stats = %+v, want %+v", *stats, want)
+	}
+
+	rollup := db.Find("JOIN households ON households.id = guests.household_id")
+	if len(rollup) != 1 || !strings.Contains(rollup[0].SQL, "households.deleted_at IS NULL") {
+		t.Errorf("rollup counts members of deleted households: %+v", rollup)
+	}
+}
+
+func TestCalculateHouseholdStatsNoMembers(t *testing.T) {
+	s, db := newTestService(t)
+	db.OnQuery(`SELECT count(*) FROM "households"`, []string{"count"}, []interface{}{int64(2)})
+
+	stats, err := s.calculateHouseholdStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (models.HouseholdStats{Total: 2, Empty: 2}); *stats != want {
+		t.Errorf("stats = %+v, want %+v", *stats, want)
+	}
+}
+
+// newHouseholdService returns a service where "household-token" belongs to
+// a household of guests 1 and 2, and no guest has that token themselves.
+func newHouseholdService(t *testing.T) (*Service, *testdb.DB) {
+	s, db := newTestService(t)
+	db.OnQuery(`FROM "households" WHERE invite_token = $1`, []string{"id", "name", "invite_token"},
+		[]interface{}{int64(7), "The Lees", "household-token"})
+	db.OnQuery(`FROM "guests" WHERE "guests"."household_id" = $1`,
+		[]string{"id", "first_name", "last_name", "party_size", "household_id"},
+		[]interface{}{int64(1), "Ann", "Lee", int64(1), int64(7)},
+		[]interface{}{int64(2), "Bo", "Lee", int64(2), int64(7)},
+	)
+	return s, db
+}
+
+// responses returns the guest ID and response of every RSVP inserted.
+func responses(db *testdb.DB) map[interface{}]interface{} {
+	got := make(map[interface{}]interface{})
+	for _, insert := range db.Find(`INSERT INTO "rsvps"`) {
+		// guest_id and response are the first two columns
+		got[insert.Args[0]] = insert.Args[1]
+	}
+	return got
+}
+
+func TestSubmitRSVPHousehold(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []MemberResponse
+		want    map[interface{}]interface{}
+	}{
+		{
+			name: "one member answers for everyone",
+			want: map[interface{}]interface{}{uint(1): "yes", uint(2): "yes"},
+		},
+		{
+			name:    "members answer for themselves",
+			members: []MemberResponse{{GuestID: 2, Response: "no"}},
+			want:    map[interface{}]interface{}{uint(1): "yes", uint(2): "no"},
+		},
+		{
+			name:    "member entry without a response",
+			members: []MemberResponse{{GuestID: 1}},
+			want:    map[interface{}]interface{}{uint(1): "yes", uint(2): "yes"},
+		},
+	}
+	for _, tt := range tests {
+		s, db := newHouseholdService(t)
+		err := s.SubmitRSVP("household-token", SubmitRSVPRequest{Response: "yes", Members: tt.members})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if got := responses(db); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: responses = %v, want %v", tt.name, got, tt.want)
+		}
+		if updates := db.Find(`UPDATE "guests"`); len(updates) != 2 {
+			t.Errorf("%s: %d guests updated, want 2", tt.name, len(updates))
+		}
+
+		// Every member's answer is saved together
+		var steps []string
+		for _, statement := range db.SQL() {
+			switch {
+			case statement == "BEGIN", statement == "COMMIT", statement == "ROLLBACK":
+				steps = append(steps, statement)
+			case strings.HasPrefix(statement, `INSERT INTO "rsvps"`):
+				steps = append(steps, "rsvp")
+			}
+		}
+		if want := []string{"BEGIN", "rsvp", "rsvp", "COMMIT"}; !reflect.DeepEqual(steps, want) {
+			t.Errorf("%s: steps = %q, want %q", tt.name, steps, want)
+		}
+	}
+}
+
+func TestSubmitRSVPHouseholdRejects(t *testing.T) {
+	s, db := newHouseholdService(t)
+	err := s.SubmitRSVP("household-token", SubmitRSVPRequest{
+		Response: "yes",
+		Members:  []MemberResponse{{GuestID: 1, Response: "no"}, {GuestID: 3, Response: "yes"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "guest 3 is not part of this invitation") {
+		t.Errorf("outside guest: err = %v", err)
+	}
+	if writes := append(db.Find("UPDATE"), db.Find("INSERT")...); len(writes) != 0 {
+		t.Errorf("outside guest: wrote %+v", writes)
+	}
+
+	// A household nobody has been added to yet has nobody to answer for
+	s, db = newTestService(t)
+	db.OnQuery(`FROM "households" WHERE invite_token = $1`, []string{"id", "name", "invite_token"},
+		[]interface{}{int64(8), "Empty", "empty-token"})
+	err = s.SubmitRSVP("empty-token", SubmitRSVPRequest{Response: "yes"})
+	if err == nil || err.Error() != "invalid RSVP token" {
+		t.Errorf("empty household: err = %v", err)
+	}
+	if writes := append(db.Find("UPDATE"), db.Find("INSERT")...); len(writes) != 0 {
+		t.Errorf("empty household: wrote %+v", writes)
+	}
+}
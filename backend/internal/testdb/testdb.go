@@ -0,0 +1,212 @@
+// Package testdb provides a stand-in database for service tests. It records
+// every statement it is sent and answers queries from canned results, so
+// code built on GORM can be tested without a Postgres server.
+package testdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Statement is one statement the database was sent.
+type Statement struct {
+	SQL  string
+	Args []interface{}
+}
+
+// DB is the recording side of a stand-in database.
+type DB struct {
+	mu         sync.Mutex
+	statements []Statement
+	results    []result
+	execHook   func(query string, args []interface{}) error
+}
+
+type result struct {
+	match   string
+	columns []string
+	rows    [][]interface{}
+}
+
+// New returns a GORM session backed by a new stand-in database.
+func New(t *testing.T) (*gorm.DB, *DB) {
+	t.Helper()
+	fake := &DB{}
+	conn := sql.OpenDB(connector{fake})
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		Logger:                 logger.Discard,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, fake
+}
+
+// OnQuery answers queries containing match with rows of the given columns.
+// Results are checked in the order they were added; queries matching none
+// return no rows.
+func (d *DB) OnQuery(match string, columns []string, rows ...[]interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.results = append(d.results, result{match: match, columns: columns, rows: rows})
+}
+
+// OnExec sets a function that sees every statement run with Exec before it
+// is recorded. An error it returns fails the statement.
+func (d *DB) OnExec(hook func(query string, args []interface{}) error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.execHook = hook
+}
+
+// Statements returns every statement sent so far, including BEGIN, COMMIT
+// and ROLLBACK.
+func (d *DB) Statements() []Statement {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Statement(nil), d.statements...)
+}
+
+// SQL returns the text of every statement sent so far.
+func (d *DB) SQL() []string {
+	var out []string
+	for _, s := range d.Statements() {
+		out = append(out, s.SQL)
+	}
+	return out
+}
+
+// Find returns the statements whose text contains match.
+func (d *DB) Find(match string) []Statement {
+	var out []Statement
+	for _, s := range d.Statements() {
+		if strings.Contains(s.SQL, match) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (d *DB) record(query string, args []interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, Statement{SQL: query, Args: args})
+}
+
+func values(args []driver.NamedValue) []interface{} {
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		out[i] = arg.Value
+	}
+	return out
+}
+
+type connector struct {
+	db *DB
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return &conn{db: c.db}, nil }
+func (c connector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("testdb: open through a connector")
+}
+
+type conn struct {
+	db *DB
+}
+
+func (c *conn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("testdb: prepared statements are not supported")
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.db.record("BEGIN", nil)
+	return tx{c.db}, nil
+}
+
+// CheckNamedValue accepts arguments as they are, so tests see what GORM
+// passed.
+func (c *conn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	hook := c.db.execHook
+	c.db.mu.Unlock()
+	if hook != nil {
+		if err := hook(query, values(args)); err != nil {
+			return nil, err
+		}
+	}
+	c.db.record(query, values(args))
+	return driver.RowsAffected(1), nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.record(query, values(args))
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	for _, r := range c.db.results {
+		if strings.Contains(query, r.match) {
+			return &rows{columns: r.columns, values: r.rows}, nil
+		}
+	}
+	return &rows{}, nil
+}
+
+type tx struct {
+	db *DB
+}
+
+func (t tx) Commit() error {
+	t.db.record("COMMIT", nil)
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.db.record("ROLLBACK", nil)
+	return nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]interface{}
+	next    int
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	for i, v := range r.values[r.next] {
+		dest[i] = v
+	}
+	r.next++
+	return nil
+}
@@ -0,0 +1,9 @@
+-- Reverse the changes from the up migration
+DROP INDEX IF EXISTS idx_guests_household_id;
+DROP INDEX IF EXISTS idx_households_deleted_at;
+DROP INDEX IF EXISTS idx_households_invite_token;
+
+ALTER TABLE guests
+DROP COLUMN IF EXISTS household_id;
+
+DROP TABLE IF EXISTS households;
@@ -0,0 +1,17 @@
+-- Households group guests who share a single invitation and RSVP
+CREATE TABLE IF NOT EXISTS households (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    invite_token VARCHAR(255) UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+ALTER TABLE guests
+ADD COLUMN household_id INTEGER REFERENCES households(id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+-- Add indexes for faster lookups
+CREATE INDEX IF NOT EXISTS idx_households_invite_token ON households(invite_token);
+CREATE INDEX IF NOT EXISTS idx_households_deleted_at ON households(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_guests_household_id ON guests(household_id);
@@ -0,0 +1,13 @@
+-- Reverse the changes from the up migration
+DROP INDEX IF EXISTS idx_guest_segments_deleted_at;
+DROP INDEX IF EXISTS idx_guest_tag_maps_tag_id;
+DROP INDEX IF EXISTS idx_guest_tags_deleted_at;
+DROP INDEX IF EXISTS idx_guest_tags_name;
+DROP INDEX IF EXISTS idx_guests_side;
+
+DROP TABLE IF EXISTS guest_segments;
+DROP TABLE IF EXISTS guest_tag_maps;
+DROP TABLE IF EXISTS guest_tags;
+
+ALTER TABLE guests
+DROP COLUMN IF EXISTS side;
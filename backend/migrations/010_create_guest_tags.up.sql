@@ -0,0 +1,37 @@
+-- Add side column so guests can be split between the couple
+ALTER TABLE guests
+ADD COLUMN side VARCHAR(20);
+
+CREATE TABLE IF NOT EXISTS guest_tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    color VARCHAR(50) DEFAULT 'blue',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS guest_tag_maps (
+    guest_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (guest_id, tag_id),
+    FOREIGN KEY (guest_id) REFERENCES guests(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES guest_tags(id) ON DELETE CASCADE
+);
+
+-- Saved segment queries for filtering and bulk actions
+CREATE TABLE IF NOT EXISTS guest_segments (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    query TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Add indexes for faster lookups
+CREATE INDEX IF NOT EXISTS idx_guests_side ON guests(side);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_guest_tags_name ON guest_tags(LOWER(name)) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_guest_tags_deleted_at ON guest_tags(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_guest_tag_maps_tag_id ON guest_tag_maps(tag_id);
+CREATE INDEX IF NOT EXISTS idx_guest_segments_deleted_at ON guest_segments(deleted_at);
@@ -0,0 +1,21 @@
+-- Restore the hard-coded attendance columns from the event rows
+ALTER TABLE guests
+ADD COLUMN IF NOT EXISTS dec24_attendance BOOLEAN DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS dec25_attendance BOOLEAN DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS accommodation_dec23 BOOLEAN DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS accommodation_dec24 BOOLEAN DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS accommodation_dec25 BOOLEAN DEFAULT FALSE;
+
+UPDATE guests g SET
+    dec24_attendance = COALESCE((SELECT a.attending FROM event_attendances a JOIN events e ON e.id = a.event_id WHERE a.guest_id = g.id AND e.slug = 'dec24'), false),
+    dec25_attendance = COALESCE((SELECT a.attending FROM event_attendances a JOIN events e ON e.id = a.event_id WHERE a.guest_id = g.id AND e.slug = 'dec25'), false),
+    accommodation_dec23 = COALESCE((SELECT a.attending FROM event_attendances a JOIN events e ON e.id = a.event_id WHERE a.guest_id = g.id AND e.slug = 'accommodation-dec23'), false),
+    accommodation_dec24 = COALESCE((SELECT a.attending FROM event_attendances a JOIN events e ON e.id = a.event_id WHERE a.guest_id = g.id AND e.slug = 'accommodation-dec24'), false),
+    accommodation_dec25 = COALESCE((SELECT a.attending FROM event_attendances a JOIN events e ON e.id = a.event_id WHERE a.guest_id = g.id AND e.slug = 'accommodation-dec25'), false);
+
+DROP INDEX IF EXISTS idx_events_deleted_at;
+DROP INDEX IF EXISTS idx_event_attendances_event_id;
+DROP INDEX IF EXISTS idx_event_attendances_guest_event;
+
+DROP TABLE IF EXISTS event_attendances;
+DROP TABLE IF EXISTS events;
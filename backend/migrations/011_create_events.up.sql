@@ -0,0 +1,63 @@
+-- Configurable event schedule replacing the hard-coded Dec 23-25 guest columns
+CREATE TABLE IF NOT EXISTS events (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(100) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(50) DEFAULT 'event',
+    description TEXT,
+    location VARCHAR(255),
+    starts_at TIMESTAMP WITH TIME ZONE,
+    sort_order INTEGER DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS event_attendances (
+    id SERIAL PRIMARY KEY,
+    guest_id INTEGER NOT NULL,
+    event_id INTEGER NOT NULL,
+    attending BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (guest_id) REFERENCES guests(id) ON DELETE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE
+);
+
+-- Add indexes for faster lookups
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_attendances_guest_event ON event_attendances(guest_id, event_id);
+CREATE INDEX IF NOT EXISTS idx_event_attendances_event_id ON event_attendances(event_id);
+CREATE INDEX IF NOT EXISTS idx_events_deleted_at ON events(deleted_at);
+
+-- Move existing answers into attendance rows, then drop the old columns
+DO $$
+DECLARE
+    legacy RECORD;
+    new_event_id INTEGER;
+BEGIN
+    FOR legacy IN
+        SELECT * FROM (VALUES
+            ('dec24_attendance', 'dec24', 'Raaga Riti', 'event', 1),
+            ('dec25_attendance', 'dec25', 'Wedding', 'event', 2),
+            ('accommodation_dec23', 'accommodation-dec23', 'Accommodation Dec 23', 'accommodation', 3),
+            ('accommodation_dec24', 'accommodation-dec24', 'Accommodation Dec 24', 'accommodation', 4),
+            ('accommodation_dec25', 'accommodation-dec25', 'Accommodation Dec 25', 'accommodation', 5)
+        ) AS t(column_name, slug, name, type, sort_order)
+    LOOP
+        IF EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_name = 'guests' AND column_name = legacy.column_name) THEN
+            INSERT INTO events (slug, name, type, sort_order)
+            VALUES (legacy.slug, legacy.name, legacy.type, legacy.sort_order)
+            ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug
+            RETURNING id INTO new_event_id;
+
+            EXECUTE format(
+                'INSERT INTO event_attendances (guest_id, event_id, attending)
+                 SELECT id, %s, COALESCE(%I, false) FROM guests
+                 ON CONFLICT (guest_id, event_id) DO NOTHING',
+                new_event_id, legacy.column_name);
+
+            EXECUTE format('ALTER TABLE guests DROP COLUMN %I', legacy.column_name);
+        END IF;
+    END LOOP;
+END $$;
@@ -0,0 +1,5 @@
+ALTER TABLE guests
+DROP COLUMN IF EXISTS answers;
+
+DROP INDEX IF EXISTS idx_questions_deleted_at;
+DROP TABLE IF EXISTS questions;
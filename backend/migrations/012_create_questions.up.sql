@@ -0,0 +1,24 @@
+-- Admin-defined registration and RSVP questions
+CREATE TABLE IF NOT EXISTS questions (
+    id SERIAL PRIMARY KEY,
+    key VARCHAR(100) UNIQUE NOT NULL,
+    label VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    options JSONB,
+    help_text TEXT,
+    required BOOLEAN DEFAULT FALSE,
+    per_member BOOLEAN DEFAULT FALSE,
+    stage VARCHAR(20) DEFAULT 'both',
+    min NUMERIC,
+    max NUMERIC,
+    sort_order INTEGER DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_questions_deleted_at ON questions(deleted_at);
+
+-- Answers keyed by question key; per-member answers live in party_members
+ALTER TABLE guests
+ADD COLUMN answers JSONB;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_deliveries_provider_id;
+DROP INDEX IF EXISTS idx_deliveries_status;
+DROP INDEX IF EXISTS idx_deliveries_guest_id;
+
+DROP TABLE IF EXISTS deliveries;
@@ -0,0 +1,22 @@
+-- Log of notifications sent to guests, with bounce tracking
+CREATE TABLE IF NOT EXISTS deliveries (
+    id SERIAL PRIMARY KEY,
+    guest_id INTEGER,
+    channel VARCHAR(20) NOT NULL DEFAULT 'email',
+    template VARCHAR(100) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255),
+    status VARCHAR(20) NOT NULL,
+    provider_id VARCHAR(255),
+    error TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    bounced_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (guest_id) REFERENCES guests(id) ON DELETE SET NULL
+);
+
+-- Add indexes for the admin log and bounce lookups
+CREATE INDEX IF NOT EXISTS idx_deliveries_guest_id ON deliveries(guest_id);
+CREATE INDEX IF NOT EXISTS idx_deliveries_status ON deliveries(status);
+CREATE INDEX IF NOT EXISTS idx_deliveries_provider_id ON deliveries(provider_id);
@@ -0,0 +1,2 @@
+ALTER TABLE guests
+DROP COLUMN IF EXISTS sms_opt_in;
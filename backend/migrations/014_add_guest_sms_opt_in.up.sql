@@ -0,0 +1,3 @@
+-- Guests must opt in before they are sent text messages
+ALTER TABLE guests
+ADD COLUMN sms_opt_in BOOLEAN DEFAULT FALSE;
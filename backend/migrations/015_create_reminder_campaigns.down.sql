@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_campaign_sends_guest_id;
+DROP INDEX IF EXISTS idx_campaign_sends_campaign_guest;
+DROP INDEX IF EXISTS idx_reminder_campaigns_deleted_at;
+DROP INDEX IF EXISTS idx_reminder_campaigns_status;
+DROP INDEX IF EXISTS idx_reminder_campaigns_send_at;
+
+DROP TABLE IF EXISTS campaign_sends;
+DROP TABLE IF EXISTS reminder_campaigns;
@@ -0,0 +1,40 @@
+-- Scheduled reminders to guests who haven't RSVP'd
+CREATE TABLE IF NOT EXISTS reminder_campaigns (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    segment TEXT,
+    channel VARCHAR(20) NOT NULL DEFAULT 'email',
+    subject VARCHAR(255),
+    body TEXT,
+    send_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    started_at TIMESTAMP WITH TIME ZONE,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    sent INTEGER DEFAULT 0,
+    skipped INTEGER DEFAULT 0,
+    failed INTEGER DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- What each campaign did for each guest; one row per guest per campaign
+CREATE TABLE IF NOT EXISTS campaign_sends (
+    id SERIAL PRIMARY KEY,
+    campaign_id INTEGER NOT NULL,
+    guest_id INTEGER NOT NULL,
+    delivery_id INTEGER,
+    status VARCHAR(20) NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (campaign_id) REFERENCES reminder_campaigns(id) ON DELETE CASCADE,
+    FOREIGN KEY (guest_id) REFERENCES guests(id) ON DELETE CASCADE,
+    FOREIGN KEY (delivery_id) REFERENCES deliveries(id) ON DELETE SET NULL
+);
+
+-- Add indexes for the scheduler and history lookups
+CREATE INDEX IF NOT EXISTS idx_reminder_campaigns_send_at ON reminder_campaigns(send_at);
+CREATE INDEX IF NOT EXISTS idx_reminder_campaigns_status ON reminder_campaigns(status);
+CREATE INDEX IF NOT EXISTS idx_reminder_campaigns_deleted_at ON reminder_campaigns(deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_campaign_sends_campaign_guest ON campaign_sends(campaign_id, guest_id);
+CREATE INDEX IF NOT EXISTS idx_campaign_sends_guest_id ON campaign_sends(guest_id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_photos_captured_at;
+
+ALTER TABLE photos DROP COLUMN IF EXISTS processed_at;
+ALTER TABLE photos DROP COLUMN IF EXISTS camera_model;
+ALTER TABLE photos DROP COLUMN IF EXISTS camera_make;
+ALTER TABLE photos DROP COLUMN IF EXISTS captured_at;
+ALTER TABLE photos DROP COLUMN IF EXISTS full_key;
+ALTER TABLE photos DROP COLUMN IF EXISTS medium_key;
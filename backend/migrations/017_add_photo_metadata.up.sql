@@ -0,0 +1,9 @@
+-- Resized copies and camera metadata filled in by background processing
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS medium_key VARCHAR(500);
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS full_key VARCHAR(500);
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS captured_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS camera_make VARCHAR(255);
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS camera_model VARCHAR(255);
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS processed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_photos_captured_at ON photos(captured_at);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_album_tags_tag_id;
+DROP INDEX IF EXISTS idx_album_guests_guest_id;
+DROP INDEX IF EXISTS idx_photos_album_position;
+
+DROP TABLE IF EXISTS album_tags;
+DROP TABLE IF EXISTS album_guests;
+
+ALTER TABLE photos DROP COLUMN IF EXISTS album_position;
@@ -0,0 +1,23 @@
+-- Manual ordering within an album
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS album_position INTEGER DEFAULT 0;
+
+-- Guests and tags a private album is shared with
+CREATE TABLE IF NOT EXISTS album_guests (
+    album_id INTEGER NOT NULL,
+    guest_id INTEGER NOT NULL,
+    PRIMARY KEY (album_id, guest_id),
+    FOREIGN KEY (album_id) REFERENCES albums(id) ON DELETE CASCADE,
+    FOREIGN KEY (guest_id) REFERENCES guests(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS album_tags (
+    album_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (album_id, tag_id),
+    FOREIGN KEY (album_id) REFERENCES albums(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES guest_tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_photos_album_position ON photos(album_id, album_position);
+CREATE INDEX IF NOT EXISTS idx_album_guests_guest_id ON album_guests(guest_id);
+CREATE INDEX IF NOT EXISTS idx_album_tags_tag_id ON album_tags(tag_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS photo_moderations;
+
+ALTER TABLE photos DROP COLUMN IF EXISTS rejection_reason;
+ALTER TABLE photos DROP COLUMN IF EXISTS moderated_by;
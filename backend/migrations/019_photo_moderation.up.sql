@@ -0,0 +1,22 @@
+-- Who moderated a photo and why it was rejected
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS moderated_by INTEGER;
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
+
+-- History of moderation decisions; a batch is undone together
+CREATE TABLE IF NOT EXISTS photo_moderations (
+    id SERIAL PRIMARY KEY,
+    batch_id VARCHAR(255) NOT NULL,
+    photo_id INTEGER NOT NULL,
+    action VARCHAR(255) NOT NULL,
+    from_status VARCHAR(255),
+    to_status VARCHAR(255),
+    reason TEXT,
+    user_id INTEGER,
+    undone_at TIMESTAMP WITH TIME ZONE,
+    undone_by INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_photo_moderations_batch_id ON photo_moderations(batch_id);
+CREATE INDEX IF NOT EXISTS idx_photo_moderations_photo_id ON photo_moderations(photo_id);
+CREATE INDEX IF NOT EXISTS idx_photo_moderations_user_id ON photo_moderations(user_id);
@@ -0,0 +1,21 @@
+-- ZIP archives of photos built in the background for large downloads
+CREATE TABLE IF NOT EXISTS photo_archives (
+    id SERIAL PRIMARY KEY,
+    token VARCHAR(255) UNIQUE NOT NULL,
+    scope VARCHAR(255) NOT NULL,
+    album_id INTEGER,
+    uploader VARCHAR(255),
+    photo_ids TEXT,
+    fingerprint VARCHAR(255),
+    status VARCHAR(255) DEFAULT 'pending',
+    photo_count INTEGER DEFAULT 0,
+    size BIGINT DEFAULT 0,
+    storage_key VARCHAR(500),
+    error TEXT,
+    requested_by INTEGER,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_photo_archives_fingerprint ON photo_archives(fingerprint);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_photos_duplicate_of_id;
+DROP INDEX IF EXISTS idx_photos_content_hash;
+
+ALTER TABLE photos DROP COLUMN IF EXISTS duplicate_kind;
+ALTER TABLE photos DROP COLUMN IF EXISTS duplicate_of_id;
+ALTER TABLE photos DROP COLUMN IF EXISTS perceptual_hash;
+ALTER TABLE photos DROP COLUMN IF EXISTS content_hash;
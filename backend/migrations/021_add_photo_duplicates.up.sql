@@ -0,0 +1,8 @@
+-- Content and perceptual hashes for duplicate detection
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS perceptual_hash VARCHAR(16);
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS duplicate_of_id INTEGER REFERENCES photos(id) ON UPDATE CASCADE ON DELETE SET NULL;
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS duplicate_kind VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_photos_content_hash ON photos(content_hash);
+CREATE INDEX IF NOT EXISTS idx_photos_duplicate_of_id ON photos(duplicate_of_id);
@@ -0,0 +1,2 @@
+ALTER TABLE photos DROP COLUMN IF EXISTS upload_id;
+ALTER TABLE photos DROP COLUMN IF EXISTS media_type;
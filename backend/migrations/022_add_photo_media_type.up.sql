@@ -0,0 +1,5 @@
+-- Video uploads: media type and the multipart upload in progress
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS media_type VARCHAR(255) DEFAULT 'image';
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS upload_id TEXT;
+
+UPDATE photos SET media_type = 'image' WHERE media_type IS NULL;
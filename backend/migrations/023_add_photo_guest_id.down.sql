@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_photos_guest_id;
+
+ALTER TABLE photos DROP COLUMN IF EXISTS guest_id;
@@ -0,0 +1,9 @@
+-- Bind uploads to the guest whose portal they came from
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS guest_id INTEGER REFERENCES guests(id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_photos_guest_id ON photos(guest_id);
+
+-- Earlier uploads recorded only the token the client sent
+UPDATE photos SET guest_id = guests.id
+FROM guests
+WHERE photos.guest_id IS NULL AND photos.guest_token = guests.guest_portal_token;
@@ -0,0 +1,3 @@
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'admin';
+
+UPDATE users SET role = 'admin';
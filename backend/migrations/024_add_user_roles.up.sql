@@ -0,0 +1,10 @@
+-- Role-based access: existing admins keep full access as owners; new
+-- users get the least privileged role. AutoMigrate does the same at
+-- startup (migrateUserRoles) and promotes an owner if none is left.
+UPDATE users SET role = 'owner' WHERE role IS NULL OR role IN ('', 'admin');
+
+UPDATE users SET role = 'owner'
+WHERE id = (SELECT id FROM users WHERE status = 'active' AND deleted_at IS NULL ORDER BY created_at, id LIMIT 1)
+  AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'owner' AND status = 'active' AND deleted_at IS NULL);
+
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'viewer';
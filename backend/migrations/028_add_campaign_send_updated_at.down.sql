@@ -0,0 +1 @@
+ALTER TABLE campaign_sends DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,3 @@
+-- Send outcomes can be updated after they are first recorded
+ALTER TABLE campaign_sends ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+UPDATE campaign_sends SET updated_at = created_at WHERE updated_at IS NULL OR updated_at > created_at;
@@ -0,0 +1,2 @@
+ALTER TABLE reminder_campaigns DROP COLUMN IF EXISTS heartbeat_at;
+ALTER TABLE reminder_campaigns DROP COLUMN IF EXISTS locked_by;
@@ -0,0 +1,3 @@
+-- The runner sending a campaign holds it by keeping heartbeat_at fresh
+ALTER TABLE reminder_campaigns ADD COLUMN IF NOT EXISTS locked_by VARCHAR(255);
+ALTER TABLE reminder_campaigns ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMP WITH TIME ZONE;
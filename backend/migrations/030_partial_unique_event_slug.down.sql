@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_events_slug;
+ALTER TABLE events ADD CONSTRAINT events_slug_key UNIQUE (slug);
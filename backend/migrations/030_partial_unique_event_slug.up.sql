@@ -0,0 +1,4 @@
+-- Deleted events keep their row, so only live events need unique slugs
+ALTER TABLE events DROP CONSTRAINT IF EXISTS events_slug_key;
+DROP INDEX IF EXISTS idx_events_slug;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_slug ON events(slug) WHERE deleted_at IS NULL;
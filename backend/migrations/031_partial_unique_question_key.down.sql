@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_questions_key;
+ALTER TABLE questions ADD CONSTRAINT questions_key_key UNIQUE (key);
@@ -0,0 +1,4 @@
+-- Deleted questions keep their row, so only live questions need unique keys
+ALTER TABLE questions DROP CONSTRAINT IF EXISTS questions_key_key;
+DROP INDEX IF EXISTS idx_questions_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_questions_key ON questions(key) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE photo_moderations
+DROP COLUMN IF EXISTS was_cover;
@@ -0,0 +1,4 @@
+-- Whether a delete cleared the photo as its album's cover, so undoing it
+-- can restore the cover
+ALTER TABLE photo_moderations
+ADD COLUMN IF NOT EXISTS was_cover BOOLEAN DEFAULT FALSE;
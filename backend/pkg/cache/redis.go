@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -15,6 +16,17 @@ type Client interface {
 	Delete(key string) error
 	Exists(key string) (bool, error)
 	SetNX(key string, value interface{}, expiration time.Duration) (bool, error)
+	Expire(key string, expiration time.Duration) error
+	// Incr adds one to a counter, starting its expiry when it is created
+	Incr(key string, expiration time.Duration) (int64, error)
+	// SetIfMatch replaces a JSON object only if it still exists and its
+	// field still holds expected, and reports whether it did
+	SetIfMatch(key, field, expected string, value interface{}, expiration time.Duration) (bool, error)
+
+	// Sets of strings, for indexes such as a user's sessions
+	SAdd(key string, members ...string) error
+	SRem(key string, members ...string) error
+	SMembers(key string) ([]string, error)
 }
 
 type RedisClient struct {
@@ -34,6 +46,9 @@ func NewRedisClient() *RedisClient {
 	db := 0
 	if dbStr != "" {
 		// Parse db number if provided
+		if n, err := strconv.Atoi(dbStr); err == nil {
+			db = n
+		}
 	}
 
 	rdb := redis.NewClient(&redis.Options{
@@ -87,6 +102,65 @@ func (r *RedisClient) SetNX(key string, value interface{}, expiration time.Durat
 	return r.client.SetNX(r.ctx, key, jsonData, expiration).Result()
 }
 
+func (r *RedisClient) Expire(key string, expiration time.Duration) error {
+	return r.client.Expire(r.ctx, key, expiration).Err()
+}
+
+func (r *RedisClient) Incr(key string, expiration time.Duration) (int64, error) {
+	count, err := r.client.Incr(r.ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		err = r.client.Expire(r.ctx, key, expiration).Err()
+	}
+	return count, err
+}
+
+// setIfMatchScript does SetIfMatch's check and write in one step, so a
+// concurrent change or delete can't slip in between them.
+var setIfMatchScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if not current then
+	return 0
+end
+if cjson.decode(current)[ARGV[1]] ~= ARGV[2] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[3], "PX", ARGV[4])
+return 1
+`)
+
+func (r *RedisClient) SetIfMatch(key, field, expected string, value interface{}, expiration time.Duration) (bool, error) {
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	set, err := setIfMatchScript.Run(r.ctx, r.client, []string{key}, field, expected, jsonData, expiration.Milliseconds()).Int()
+	return set == 1, err
+}
+
+func (r *RedisClient) SAdd(key string, members ...string) error {
+	return r.client.SAdd(r.ctx, key, toInterfaces(members)...).Err()
+}
+
+func (r *RedisClient) SRem(key string, members ...string) error {
+	return r.client.SRem(r.ctx, key, toInterfaces(members)...).Err()
+}
+
+func (r *RedisClient) SMembers(key string) ([]string, error) {
+	return r.client.SMembers(r.ctx, key).Result()
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
 func (r *RedisClient) Close() error {
 	return r.client.Close()
 }
\ No newline at end of file
@@ -7,6 +7,7 @@ import (
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 
 	"wedding-app/internal/auth"
@@ -74,6 +75,14 @@ func AutoMigrate(db *gorm.DB) error {
 		return fmt.Errorf("failed to create Album table: %w", err)
 	}
 	
+	if err := db.Migrator().CreateTable(&models.Household{}); err != nil && !db.Migrator().HasTable(&models.Household{}) {
+		return fmt.Errorf("failed to create Household table: %w", err)
+	}
+	
+	if err := db.Migrator().CreateTable(&models.GuestTag{}); err != nil && !db.Migrator().HasTable(&models.GuestTag{}) {
+		return fmt.Errorf("failed to create GuestTag table: %w", err)
+	}
+	
 	if err := db.Migrator().CreateTable(&models.Guest{}); err != nil && !db.Migrator().HasTable(&models.Guest{}) {
 		return fmt.Errorf("failed to create Guest table: %w", err)
 	}
@@ -87,14 +96,168 @@ func AutoMigrate(db *gorm.DB) error {
 	}
 	
 	// Now run full AutoMigrate to add constraints and relationships
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&auth.User{},
+		&auth.Invitation{},
+		&auth.PasswordReset{},
+		&auth.RecoveryCode{},
+		&auth.Settings{},
 		&models.Photo{},
 		&models.Album{},
+		&models.PhotoModeration{},
+		&models.PhotoArchive{},
+		&models.Household{},
+		&models.GuestTag{},
+		&models.GuestSegment{},
 		&models.Guest{},
 		&models.RSVP{},
 		&models.Message{},
+		&models.Event{},
+		&models.EventAttendance{},
+		&models.Question{},
+		&models.Delivery{},
+		&models.ReminderCampaign{},
+		&models.CampaignSend{},
+		&models.Job{},
 	)
+	if err != nil {
+		return err
+	}
+
+	if err := migrateLiveUniqueIndexes(db); err != nil {
+		return err
+	}
+	if err := seedAuthSettings(db); err != nil {
+		return err
+	}
+	if err := migrateUserRoles(db); err != nil {
+		return err
+	}
+	return migrateLegacyAttendance(db)
+}
+
+// liveUniqueIndexes are unique columns on soft-deleted tables that only have
+// to be unique among rows that are not deleted, so deleting an event or a
+// question frees its slug or key for reuse. Older databases enforced them
+// across every row, under a column constraint or an index of the same name.
+var liveUniqueIndexes = []struct {
+	table, column, constraint, index string
+}{
+	{"events", "slug", "events_slug_key", "idx_events_slug"},
+	{"questions", "key", "questions_key_key", "idx_questions_key"},
+}
+
+// migrateLiveUniqueIndexes replaces the old table-wide unique constraints
+// with partial indexes. Once an index has its WHERE clause it does nothing.
+func migrateLiveUniqueIndexes(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, u := range liveUniqueIndexes {
+			if err := tx.Exec(`ALTER TABLE ` + u.table + ` DROP CONSTRAINT IF EXISTS ` + u.constraint).Error; err != nil {
+				return fmt.Errorf("failed to drop %s: %w", u.constraint, err)
+			}
+
+			var partial int64
+			err := tx.Raw(`SELECT COUNT(*) FROM pg_indexes WHERE tablename = ? AND indexname = ? AND indexdef LIKE '%WHERE%'`,
+				u.table, u.index).Scan(&partial).Error
+			if err != nil {
+				return fmt.Errorf("failed to inspect %s: %w", u.index, err)
+			}
+			if partial > 0 {
+				continue
+			}
+
+			if err := tx.Exec(`DROP INDEX IF EXISTS ` + u.index).Error; err != nil {
+				return fmt.Errorf("failed to drop %s: %w", u.index, err)
+			}
+			err = tx.Exec(`CREATE UNIQUE INDEX ` + u.index + ` ON ` + u.table + ` (` + u.column + `) WHERE deleted_at IS NULL`).Error
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", u.index, err)
+			}
+		}
+		return nil
+	})
+}
+
+// seedAuthSettings creates the single security settings row, so reading it
+// never has to.
+func seedAuthSettings(db *gorm.DB) error {
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&auth.Settings{ID: 1}).Error
+	if err != nil {
+		return fmt.Errorf("failed to seed security settings: %w", err)
+	}
+	return nil
+}
+
+// migrateUserRoles makes the admins from before roles existed owners, so
+// they keep full access, and makes sure there is always an owner who can
+// manage the others: if none is left, the oldest active user becomes one.
+func migrateUserRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&auth.User{}).Where("role IS NULL OR role IN ?", []string{"", "admin"}).
+			Update("role", auth.RoleOwner).Error
+		if err != nil {
+			return fmt.Errorf("failed to migrate admin roles: %w", err)
+		}
+
+		var owners int64
+		err = tx.Model(&auth.User{}).Where("role = ? AND status = ?", auth.RoleOwner, auth.StatusActive).
+			Count(&owners).Error
+		if err != nil || owners > 0 {
+			return err
+		}
+
+		var oldest auth.User
+		err = tx.Where("status = ?", auth.StatusActive).Order("created_at ASC, id ASC").Limit(1).Find(&oldest).Error
+		if err != nil || oldest.ID == 0 {
+			return err
+		}
+		if err := tx.Model(&oldest).Update("role", auth.RoleOwner).Error; err != nil {
+			return fmt.Errorf("failed to promote an owner: %w", err)
+		}
+		return nil
+	})
+}
+
+// legacyAttendanceColumns are the hard-coded guest columns that predate
+// configurable events, with the event each one becomes.
+var legacyAttendanceColumns = []struct {
+	column string
+	event  models.Event
+}{
+	{"dec24_attendance", models.Event{Slug: "dec24", Name: "Raaga Riti", Type: "event", SortOrder: 1}},
+	{"dec25_attendance", models.Event{Slug: "dec25", Name: "Wedding", Type: "event", SortOrder: 2}},
+	{"accommodation_dec23", models.Event{Slug: "accommodation-dec23", Name: "Accommodation Dec 23", Type: "accommodation", SortOrder: 3}},
+	{"accommodation_dec24", models.Event{Slug: "accommodation-dec24", Name: "Accommodation Dec 24", Type: "accommodation", SortOrder: 4}},
+	{"accommodation_dec25", models.Event{Slug: "accommodation-dec25", Name: "Accommodation Dec 25", Type: "accommodation", SortOrder: 5}},
+}
+
+// migrateLegacyAttendance copies the old per-date guest columns into event
+// attendance rows and drops them. Once the columns are gone it does nothing.
+func migrateLegacyAttendance(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, legacy := range legacyAttendanceColumns {
+			if !tx.Migrator().HasColumn(&models.Guest{}, legacy.column) {
+				continue
+			}
+
+			event := legacy.event
+			if err := tx.Where("slug = ?", event.Slug).FirstOrCreate(&event).Error; err != nil {
+				return fmt.Errorf("failed to create event %s: %w", event.Slug, err)
+			}
+
+			err := tx.Exec(`INSERT INTO event_attendances (guest_id, event_id, attending, created_at, updated_at)
+				SELECT id, ?, COALESCE(`+legacy.column+`, false), NOW(), NOW() FROM guests
+				ON CONFLICT (guest_id, event_id) DO NOTHING`, event.ID).Error
+			if err != nil {
+				return fmt.Errorf("failed to migrate %s: %w", legacy.column, err)
+			}
+
+			if err := tx.Migrator().DropColumn(&models.Guest{}, legacy.column); err != nil {
+				return fmt.Errorf("failed to drop %s: %w", legacy.column, err)
+			}
+		}
+		return nil
+	})
 }
 
 func getEnv(key, defaultValue string) string {
@@ -0,0 +1,239 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+// EXIF is the subset of photo metadata the gallery uses.
+type EXIF struct {
+	Orientation int // 1-8 as defined by the TIFF spec; 0 when absent
+	CapturedAt  *time.Time
+	CameraMake  string
+	CameraModel string
+}
+
+var errNoEXIF = errors.New("no EXIF data")
+
+// ReadEXIF extracts EXIF metadata from a JPEG, PNG or WebP file. Images
+// without EXIF return an empty EXIF and no error.
+func ReadEXIF(data []byte) (*EXIF, error) {
+	var tiff []byte
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		tiff, err = jpegEXIF(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		tiff, err = pngEXIF(data)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		tiff, err = webpEXIF(data)
+	default:
+		err = errNoEXIF
+	}
+	if errors.Is(err, errNoEXIF) {
+		return &EXIF{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseTIFF(tiff)
+}
+
+// jpegEXIF finds the APP1 Exif segment, which must come before the image
+// data.
+func jpegEXIF(data []byte) ([]byte, error) {
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil, errors.New("malformed JPEG marker")
+		}
+		marker := data[pos+1]
+		if marker == 0xD8 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0xFF {
+			pos++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			break // start of scan: no metadata beyond here
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return nil, errors.New("truncated JPEG segment")
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:], nil
+		}
+		pos += 2 + length
+	}
+	return nil, errNoEXIF
+}
+
+func pngEXIF(data []byte) ([]byte, error) {
+	pos := 8
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		kind := string(data[pos+4 : pos+8])
+		if length < 0 || pos+12+length > len(data) {
+			return nil, errors.New("truncated PNG chunk")
+		}
+		if kind == "eXIf" {
+			return data[pos+8 : pos+8+length], nil
+		}
+		if kind == "IDAT" || kind == "IEND" {
+			break
+		}
+		pos += 12 + length
+	}
+	return nil, errNoEXIF
+}
+
+func webpEXIF(data []byte) ([]byte, error) {
+	pos := 12
+	for pos+8 <= len(data) {
+		kind := string(data[pos : pos+4])
+		length := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		if length < 0 || pos+8+length > len(data) {
+			return nil, errors.New("truncated WebP chunk")
+		}
+		if kind == "EXIF" {
+			// Some encoders keep the JPEG-style prefix
+			return bytes.TrimPrefix(data[pos+8:pos+8+length], []byte("Exif\x00\x00")), nil
+		}
+		pos += 8 + length + length%2
+	}
+	return nil, errNoEXIF
+}
+
+const (
+	tagMake               = 0x010F
+	tagModel              = 0x0110
+	tagOrientation        = 0x0112
+	tagDateTime           = 0x0132
+	tagExifIFD            = 0x8769
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+)
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// parseTIFF reads the tags we need from IFD0 and the Exif sub-IFD.
+func parseTIFF(data []byte) (*EXIF, error) {
+	if len(data) < 8 {
+		return nil, errors.New("truncated EXIF header")
+	}
+
+	r := &tiffReader{data: data}
+	switch string(data[0:2]) {
+	case "II":
+		r.order = binary.LittleEndian
+	case "MM":
+		r.order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid EXIF byte order")
+	}
+
+	tags := map[uint16][]byte{}
+	if err := r.readIFD(r.order.Uint32(data[4:]), tags); err != nil {
+		return nil, err
+	}
+	if ptr, ok := tags[tagExifIFD]; ok && len(ptr) >= 4 {
+		if err := r.readIFD(r.order.Uint32(ptr), tags); err != nil {
+			return nil, err
+		}
+	}
+
+	exif := &EXIF{
+		CameraMake:  asciiValue(tags[tagMake]),
+		CameraModel: asciiValue(tags[tagModel]),
+	}
+	if v, ok := tags[tagOrientation]; ok && len(v) >= 2 {
+		if o := int(r.order.Uint16(v)); o >= 1 && o <= 8 {
+			exif.Orientation = o
+		}
+	}
+
+	taken := asciiValue(tags[tagDateTimeOriginal])
+	if taken == "" {
+		taken = asciiValue(tags[tagDateTime])
+	}
+	if taken != "" {
+		exif.CapturedAt = parseEXIFTime(taken, asciiValue(tags[tagOffsetTimeOriginal]))
+	}
+
+	return exif, nil
+}
+
+// tiffTypeSizes are the byte sizes of the TIFF field types we may meet.
+var tiffTypeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+
+// readIFD copies the raw value of every entry in the IFD at offset into
+// tags, resolving values stored out of line.
+func (r *tiffReader) readIFD(offset uint32, tags map[uint16][]byte) error {
+	start := int(offset)
+	if start < 8 || start+2 > len(r.data) {
+		return errors.New("invalid EXIF IFD offset")
+	}
+
+	count := int(r.order.Uint16(r.data[start:]))
+	if start+2+count*12 > len(r.data) {
+		return errors.New("truncated EXIF IFD")
+	}
+
+	for i := 0; i < count; i++ {
+		entry := r.data[start+2+i*12:]
+		tag := r.order.Uint16(entry[0:])
+		size, ok := tiffTypeSizes[r.order.Uint16(entry[2:])]
+		if !ok {
+			continue
+		}
+
+		n := int(r.order.Uint32(entry[4:])) * size
+		if n < 0 || n > len(r.data) {
+			continue
+		}
+		value := entry[8:12]
+		if n > 4 {
+			at := int(r.order.Uint32(entry[8:]))
+			if at < 0 || at+n > len(r.data) {
+				continue
+			}
+			value = r.data[at : at+n]
+		} else {
+			value = value[:n]
+		}
+		tags[tag] = value
+	}
+	return nil
+}
+
+func asciiValue(v []byte) string {
+	if i := bytes.IndexByte(v, 0); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(string(v))
+}
+
+// parseEXIFTime reads an EXIF timestamp. Cameras record local time; without
+// an offset tag the wall-clock time is kept as if it were UTC.
+func parseEXIFTime(value, offset string) *time.Time {
+	location := time.UTC
+	if offset != "" {
+		if t, err := time.Parse("-07:00", offset); err == nil {
+			_, seconds := t.Zone()
+			location = time.FixedZone(offset, seconds)
+		}
+	}
+
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", value, location)
+	if err != nil || t.Year() < 1900 {
+		return nil
+	}
+	return &t
+}
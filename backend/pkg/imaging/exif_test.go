@@ -0,0 +1,274 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+)
+
+type tiffEntry struct {
+	tag   uint16
+	kind  uint16 // 2 ASCII, 3 SHORT, 4 LONG
+	value []byte
+}
+
+func ascii(s string) []byte { return append([]byte(s), 0) }
+
+// buildTIFF lays out IFD0 and, if exif is non-empty, an Exif sub-IFD that
+// IFD0 points to. Values longer than four bytes are stored after the IFDs.
+func buildTIFF(order binary.ByteOrder, ifd0, exif []tiffEntry) []byte {
+	header := "II*\x00"
+	if order == binary.BigEndian {
+		header = "MM\x00*"
+	}
+
+	ifdSize := func(n int) int { return 2 + n*12 + 4 }
+	n0 := len(ifd0)
+	if len(exif) > 0 {
+		n0++
+	}
+	exifOffset := 8 + ifdSize(n0)
+	dataOffset := exifOffset
+	if len(exif) > 0 {
+		dataOffset += ifdSize(len(exif))
+	}
+
+	var data []byte
+	writeIFD := func(entries []tiffEntry, link *uint32) []byte {
+		out := make([]byte, 2, ifdSize(len(entries)))
+		order.PutUint16(out, uint16(len(entries)+btoi(link != nil)))
+		put := func(tag, kind uint16, count uint32, value []byte) {
+			entry := make([]byte, 12)
+			order.PutUint16(entry[0:], tag)
+			order.PutUint16(entry[2:], kind)
+			order.PutUint32(entry[4:], count)
+			if len(value) <= 4 {
+				copy(entry[8:], value)
+			} else {
+				order.PutUint32(entry[8:], uint32(dataOffset+len(data)))
+				data = append(data, value...)
+			}
+			out = append(out, entry...)
+		}
+		for _, e := range entries {
+			count := uint32(len(e.value))
+			if e.kind == 3 {
+				count /= 2
+			}
+			put(e.tag, e.kind, count, e.value)
+		}
+		if link != nil {
+			value := make([]byte, 4)
+			order.PutUint32(value, *link)
+			put(tagExifIFD, 4, 1, value)
+		}
+		return append(out, 0, 0, 0, 0)
+	}
+
+	var link *uint32
+	if len(exif) > 0 {
+		offset := uint32(exifOffset)
+		link = &offset
+	}
+	out := append([]byte(header), make([]byte, 4)...)
+	order.PutUint32(out[4:], 8)
+	out = append(out, writeIFD(ifd0, link)...)
+	if len(exif) > 0 {
+		out = append(out, writeIFD(exif, nil)...)
+	}
+	return append(out, data...)
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func short(order binary.ByteOrder, v uint16) []byte {
+	b := make([]byte, 2)
+	order.PutUint16(b, v)
+	return b
+}
+
+func sampleTIFF(order binary.ByteOrder) []byte {
+	return buildTIFF(order,
+		[]tiffEntry{
+			{tag: tagMake, kind: 2, value: ascii("Canon")},
+			{tag: tagModel, kind: 2, value: ascii("EOS R6")},
+			{tag: tagOrientation, kind: 3, value: short(order, 6)},
+			{tag: tagDateTime, kind: 2, value: ascii("2024:12:26 09:00:00")},
+		},
+		[]tiffEntry{
+			{tag: tagDateTimeOriginal, kind: 2, value: ascii("2024:12:24 18:30:15")},
+			{tag: tagOffsetTimeOriginal, kind: 2, value: ascii("+05:30")},
+		},
+	)
+}
+
+func jpegWithEXIF(tiff []byte) []byte {
+	out := []byte{0xFF, 0xD8}
+	// A JFIF segment first, as most cameras write
+	out = append(out, 0xFF, 0xE0, 0x00, 0x07, 'J', 'F', 'I', 'F', 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	out = append(out, 0xFF, 0xE1)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, 0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9)
+}
+
+func pngChunk(kind string, data []byte) []byte {
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	out = append(out, kind...)
+	out = append(out, data...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(append([]byte(kind), data...)))
+}
+
+func webpChunk(kind string, data []byte) []byte {
+	out := append([]byte(kind), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+	out = append(out, data...)
+	if len(data)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+func webpFile(chunks ...[]byte) []byte {
+	body := []byte("WEBP")
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	return append(append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...), body...)
+}
+
+func checkSample(t *testing.T, name string, exif *EXIF, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if exif.CameraMake != "Canon" || exif.CameraModel != "EOS R6" || exif.Orientation != 6 {
+		t.Errorf("%s: EXIF = %+v", name, exif)
+	}
+	want := time.Date(2024, 12, 24, 18, 30, 15, 0, time.FixedZone("+05:30", 5*3600+1800))
+	if exif.CapturedAt == nil || !exif.CapturedAt.Equal(want) {
+		t.Errorf("%s: CapturedAt = %v, want %v", name, exif.CapturedAt, want)
+	}
+}
+
+func TestReadEXIF(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		tiff := sampleTIFF(order)
+
+		exif, err := ReadEXIF(jpegWithEXIF(tiff))
+		checkSample(t, "jpeg "+order.String(), exif, err)
+
+		var pngFile []byte
+		pngFile = append(pngFile, "\x89PNG\r\n\x1a\n"...)
+		pngFile = append(pngFile, pngChunk("IHDR", make([]byte, 13))...)
+		pngFile = append(pngFile, pngChunk("eXIf", tiff)...)
+		pngFile = append(pngFile, pngChunk("IEND", nil)...)
+		exif, err = ReadEXIF(pngFile)
+		checkSample(t, "png "+order.String(), exif, err)
+
+		exif, err = ReadEXIF(webpFile(webpChunk("VP8X", make([]byte, 10)), webpChunk("EXIF", tiff)))
+		checkSample(t, "webp "+order.String(), exif, err)
+
+		// Some WebP encoders keep the JPEG-style prefix
+		exif, err = ReadEXIF(webpFile(webpChunk("EXIF", append([]byte("Exif\x00\x00"), tiff...))))
+		checkSample(t, "webp with prefix "+order.String(), exif, err)
+	}
+}
+
+func TestReadEXIFFallbacks(t *testing.T) {
+	order := binary.LittleEndian
+
+	// Without DateTimeOriginal the IFD0 DateTime is used, and without an
+	// offset the wall-clock time is kept as UTC
+	tiff := buildTIFF(order, []tiffEntry{
+		{tag: tagDateTime, kind: 2, value: ascii("2024:12:26 09:00:00")},
+		{tag: tagOrientation, kind: 3, value: short(order, 9)},
+	}, nil)
+	exif, err := ReadEXIF(jpegWithEXIF(tiff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 12, 26, 9, 0, 0, 0, time.UTC); exif.CapturedAt == nil || !exif.CapturedAt.Equal(want) {
+		t.Errorf("CapturedAt = %v, want %v", exif.CapturedAt, want)
+	}
+	if exif.Orientation != 0 {
+		t.Errorf("out of range orientation kept: %d", exif.Orientation)
+	}
+
+	// Cameras with an unset clock write zeros
+	tiff = buildTIFF(order, []tiffEntry{
+		{tag: tagDateTime, kind: 2, value: ascii("0000:00:00 00:00:00")},
+	}, nil)
+	exif, err = ReadEXIF(jpegWithEXIF(tiff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exif.CapturedAt != nil {
+		t.Errorf("CapturedAt = %v for a zero timestamp", exif.CapturedAt)
+	}
+}
+
+func TestReadEXIFWithoutMetadata(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	jpegData, err := EncodeJPEG(img, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{
+		"jpeg":    jpegData,
+		"png":     pngData.Bytes(),
+		"webp":    webpFile(webpChunk("VP8 ", make([]byte, 10))),
+		"gif":     []byte("GIF89a\x01\x00\x01\x00"),
+		"unknown": []byte("not an image"),
+	} {
+		exif, err := ReadEXIF(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if *exif != (EXIF{}) {
+			t.Errorf("%s: EXIF = %+v, want empty", name, exif)
+		}
+	}
+}
+
+func TestReadEXIFMalformed(t *testing.T) {
+	tiff := sampleTIFF(binary.LittleEndian)
+	tests := map[string][]byte{
+		"bad byte order":      jpegWithEXIF(append([]byte("XX"), tiff[2:]...)),
+		"short TIFF header":   jpegWithEXIF(tiff[:6]),
+		"IFD offset past end": jpegWithEXIF(append(append([]byte("II*\x00"), 0xFF, 0, 0, 0), make([]byte, 8)...)),
+		"truncated IFD":       jpegWithEXIF(tiff[:20]),
+		"truncated segment":   jpegWithEXIF(tiff)[:30],
+		"bad JPEG marker":     []byte{0xFF, 0xD8, 0x00, 0x00, 0x00, 0x00},
+		"truncated PNG chunk": append([]byte("\x89PNG\r\n\x1a\n"), pngChunk("eXIf", tiff)[:20]...),
+		"truncated WebP":      webpFile(webpChunk("EXIF", tiff))[:40],
+	}
+	for name, data := range tests {
+		if _, err := ReadEXIF(data); err == nil {
+			t.Errorf("%s: ReadEXIF succeeded", name)
+		}
+	}
+
+	// Entries pointing outside the data are skipped, not fatal
+	bad := buildTIFF(binary.LittleEndian, []tiffEntry{{tag: tagMake, kind: 2, value: ascii("Canon")}}, nil)
+	binary.LittleEndian.PutUint32(bad[8+2+8:], 1<<30)
+	exif, err := ReadEXIF(jpegWithEXIF(bad))
+	if err != nil || exif.CameraMake != "" {
+		t.Errorf("out of bounds value: EXIF = %+v, err = %v", exif, err)
+	}
+}
@@ -0,0 +1,33 @@
+package imaging
+
+import (
+	"image"
+	"math/bits"
+
+	"golang.org/x/image/draw"
+)
+
+// DHash is a 64-bit perceptual "difference hash": the image is shrunk to
+// 9x8 greyscale and each bit records whether a pixel is brighter than its
+// right-hand neighbour. Resized or recompressed copies of a picture hash
+// to values a few bits apart.
+func DHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.BiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HashDistance is the number of bits two perceptual hashes differ by.
+func HashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
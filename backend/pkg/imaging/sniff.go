@@ -0,0 +1,42 @@
+package imaging
+
+import (
+	"bytes"
+)
+
+// SniffLength is how many leading bytes Sniff needs.
+const SniffLength = 16
+
+// Sniff identifies an image or video from its leading bytes, returning its
+// MIME type or "" if it isn't a format we accept.
+func Sniff(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(header, []byte("GIF87a")), bytes.HasPrefix(header, []byte("GIF89a")):
+		return "image/gif"
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP")):
+		return "image/webp"
+	case len(header) >= 12 && bytes.Equal(header[4:8], []byte("ftyp")):
+		// ISO base media file; the major brand tells QuickTime from MP4, and
+		// from HEIC and AVIF stills, which share the container
+		switch string(header[8:12]) {
+		case "qt  ":
+			return "video/quicktime"
+		case "heic", "heix", "mif1", "msf1", "avif":
+			return ""
+		}
+		return "video/mp4"
+	}
+	return ""
+}
+
+// NormalizeType maps MIME aliases browsers send onto the canonical type.
+func NormalizeType(contentType string) string {
+	if contentType == "image/jpg" || contentType == "image/pjpeg" {
+		return "image/jpeg"
+	}
+	return contentType
+}
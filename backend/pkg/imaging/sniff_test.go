@@ -0,0 +1,72 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"jpeg", "\xFF\xD8\xFF\xE0\x00\x10JFIF", "image/jpeg"},
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR", "image/png"},
+		{"gif87a", "GIF87a\x01\x00", "image/gif"},
+		{"gif89a", "GIF89a\x01\x00", "image/gif"},
+		{"webp", "RIFF\x24\x00\x00\x00WEBPVP8 ", "image/webp"},
+		{"mp4", "\x00\x00\x00\x18ftypisom\x00\x00\x02\x00", "video/mp4"},
+		{"m4v", "\x00\x00\x00\x1cftypM4V \x00\x00\x00\x01", "video/mp4"},
+		{"quicktime", "\x00\x00\x00\x14ftypqt  \x00\x00\x02\x00", "video/quicktime"},
+		{"heic", "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00", ""},
+		{"avif", "\x00\x00\x00\x1cftypavif\x00\x00\x00\x00", ""},
+		{"riff that isn't webp", "RIFF\x24\x00\x00\x00WAVEfmt ", ""},
+		{"html", "<!DOCTYPE html><html>", ""},
+		{"svg", "<svg xmlns=\"http", ""},
+		{"pdf", "%PDF-1.7\n", ""},
+		{"truncated jpeg", "\xFF\xD8", ""},
+		{"truncated ftyp", "\x00\x00\x00\x18ftyp", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		if got := Sniff([]byte(tt.header)); got != tt.want {
+			t.Errorf("%s: Sniff = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSniffEncodedImage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	if got := Sniff(buf.Bytes()[:SniffLength]); got != "image/png" {
+		t.Errorf("Sniff of an encoded PNG = %q", got)
+	}
+
+	data, err := EncodeJPEG(image.NewRGBA(image.Rect(0, 0, 2, 2)), 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Sniff(data[:SniffLength]); got != "image/jpeg" {
+		t.Errorf("Sniff of an encoded JPEG = %q", got)
+	}
+}
+
+func TestNormalizeType(t *testing.T) {
+	tests := map[string]string{
+		"image/jpg":   "image/jpeg",
+		"image/pjpeg": "image/jpeg",
+		"image/jpeg":  "image/jpeg",
+		"image/png":   "image/png",
+		"":            "",
+	}
+	for in, want := range tests {
+		if got := NormalizeType(in); got != want {
+			t.Errorf("NormalizeType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
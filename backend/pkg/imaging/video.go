@@ -0,0 +1,47 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ErrNoFFmpeg is returned when ffmpeg, which poster frames need, isn't
+// installed.
+var ErrNoFFmpeg = errors.New("ffmpeg is not available")
+
+// PosterFrame grabs one frame of the video file at path as a PNG: about a
+// second in so it isn't a fade from black, or the first frame of shorter
+// clips. It runs the ffmpeg named by FFMPEG_PATH (default "ffmpeg" on the
+// PATH), which also applies any rotation the phone recorded.
+func PosterFrame(ctx context.Context, path string) ([]byte, error) {
+	name := os.Getenv("FFMPEG_PATH")
+	if name == "" {
+		name = "ffmpeg"
+	}
+	ffmpeg, err := exec.LookPath(name)
+	if err != nil {
+		return nil, ErrNoFFmpeg
+	}
+
+	for _, offset := range []string{"1", "0"} {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, ffmpeg,
+			"-hide_banner", "-loglevel", "error", "-nostdin",
+			"-ss", offset, "-i", path,
+			"-frames:v", "1", "-f", "image2pipe", "-c:v", "png", "pipe:1")
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		if stdout.Len() > 0 {
+			return stdout.Bytes(), nil
+		}
+	}
+	return nil, fmt.Errorf("video has no frames")
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer writes each message into a maildir (tmp/, new/, cur/) instead
+// of sending it, for local development and tests. Any mail client that reads
+// maildirs can open the result.
+type FileMailer struct {
+	dir     string
+	from    string
+	counter uint64
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create maildir: %w", err)
+		}
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(msg *Message) (string, error) {
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return "", fmt.Errorf("%w: invalid address %q", ErrRejected, msg.To)
+	}
+
+	messageID, err := newMessageID(m.from)
+	if err != nil {
+		return "", err
+	}
+	data, err := buildMIME(m.from, messageID, msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to build message: %w", err)
+	}
+
+	// Maildir delivery: write under tmp/ then rename into new/ so readers
+	// never see a partial file
+	hostname, _ := os.Hostname()
+	name := fmt.Sprintf("%d.%d_%d.%s", time.Now().UnixNano(), os.Getpid(),
+		atomic.AddUint64(&m.counter, 1), strings.ReplaceAll(hostname, "/", "_"))
+
+	tmpPath := filepath.Join(m.dir, "tmp", name)
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(m.dir, "new", name)); err != nil {
+		return "", fmt.Errorf("failed to deliver message: %w", err)
+	}
+
+	return messageID, nil
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// NormalizePhone converts a phone number as typed by a guest into E.164
+// (+<country code><number>). Numbers written without an international
+// prefix get defaultCountryCode, dropping a leading trunk "0". Spaces,
+// dashes, dots and parentheses are ignored. An empty input returns "".
+func NormalizePhone(raw, defaultCountryCode string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+
+	var digits strings.Builder
+	international := false
+	for i, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			international = true
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+
+	number := digits.String()
+	switch {
+	case international:
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	default:
+		if defaultCountryCode == "" {
+			return "", ErrInvalidPhone
+		}
+		number = strings.TrimPrefix(defaultCountryCode, "+") + strings.TrimPrefix(number, "0")
+	}
+
+	// E.164 allows at most 15 digits; anything under 8 can't be a full
+	// international number
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	return "+" + number, nil
+}
+
+// DefaultCountryCode is the calling code assumed for numbers entered
+// without one, from PHONE_DEFAULT_COUNTRY_CODE. It defaults to India, where
+// the wedding is held.
+func DefaultCountryCode() string {
+	return getEnv("PHONE_DEFAULT_COUNTRY_CODE", "91")
+}
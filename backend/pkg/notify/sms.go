@@ -0,0 +1,177 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMS is a single text message. To must be an E.164 number.
+type SMS struct {
+	To   string
+	Body string
+}
+
+type SMSSender interface {
+	// SendSMS delivers msg and returns the provider's message ID.
+	SendSMS(msg *SMS) (string, error)
+}
+
+// ErrUnsubscribed is returned when the provider refuses to text a number
+// that replied STOP. Callers should record the guest as opted out.
+var ErrUnsubscribed = fmt.Errorf("%w: recipient unsubscribed", ErrRejected)
+
+// NewSMSSenderFromEnv picks the SMS backend from SMS_BACKEND: "twilio" for
+// any Twilio-compatible API, or "fake" (the default) which keeps messages in
+// memory and sends nothing.
+func NewSMSSenderFromEnv() (SMSSender, error) {
+	switch backend := getEnv("SMS_BACKEND", "fake"); backend {
+	case "twilio":
+		config := TwilioConfig{
+			BaseURL:             getEnv("SMS_API_URL", "https://api.twilio.com"),
+			AccountSID:          os.Getenv("TWILIO_ACCOUNT_SID"),
+			AuthToken:           os.Getenv("TWILIO_AUTH_TOKEN"),
+			From:                os.Getenv("SMS_FROM"),
+			MessagingServiceSID: os.Getenv("TWILIO_MESSAGING_SERVICE_SID"),
+		}
+		if config.AccountSID == "" || config.AuthToken == "" {
+			return nil, fmt.Errorf("TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN are required")
+		}
+		if config.From == "" && config.MessagingServiceSID == "" {
+			return nil, fmt.Errorf("SMS_FROM or TWILIO_MESSAGING_SERVICE_SID is required")
+		}
+		return NewTwilioSender(config), nil
+	case "fake":
+		return NewFakeSMSSender(), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS_BACKEND %q", backend)
+	}
+}
+
+type TwilioConfig struct {
+	BaseURL             string
+	AccountSID          string
+	AuthToken           string
+	From                string
+	MessagingServiceSID string
+}
+
+// TwilioSender sends through the Twilio Messages API, or any service that
+// implements the same endpoint.
+type TwilioSender struct {
+	config TwilioConfig
+	client *http.Client
+}
+
+func NewTwilioSender(config TwilioConfig) *TwilioSender {
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	return &TwilioSender{
+		config: config,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Twilio error codes that mean the number will never accept a message.
+var twilioRejections = map[int]bool{
+	21211: true, // invalid 'To' number
+	21214: true, // 'To' number cannot be reached
+	21408: true, // region not enabled
+	21612: true, // cannot route to this number
+	21614: true, // not a mobile number
+}
+
+const twilioUnsubscribed = 21610
+
+func (t *TwilioSender) SendSMS(msg *SMS) (string, error) {
+	form := url.Values{}
+	form.Set("To", msg.To)
+	form.Set("Body", msg.Body)
+	if t.config.MessagingServiceSID != "" {
+		form.Set("MessagingServiceSid", t.config.MessagingServiceSID)
+	} else {
+		form.Set("From", t.config.From)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", t.config.BaseURL, url.PathEscape(t.config.AccountSID))
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(t.config.AccountSID, t.config.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("SMS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read SMS response: %w", err)
+	}
+
+	var result struct {
+		SID     string `json:"sid"`
+		Status  string `json:"status"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil && resp.StatusCode < 300 {
+		return "", fmt.Errorf("invalid SMS response: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		switch {
+		case result.Code == twilioUnsubscribed:
+			return "", fmt.Errorf("%w (%s)", ErrUnsubscribed, result.Message)
+		case twilioRejections[result.Code]:
+			return "", fmt.Errorf("%w: %s (code %d)", ErrRejected, result.Message, result.Code)
+		default:
+			return "", fmt.Errorf("SMS provider returned %d: %s", resp.StatusCode, result.Message)
+		}
+	}
+
+	return result.SID, nil
+}
+
+// FakeSMSSender records messages in memory instead of sending them. Set
+// Fail to make sends to particular numbers return an error.
+type FakeSMSSender struct {
+	mu   sync.Mutex
+	sent []SMS
+	seq  int
+
+	Fail func(msg *SMS) error
+}
+
+func NewFakeSMSSender() *FakeSMSSender {
+	return &FakeSMSSender{}
+}
+
+func (f *FakeSMSSender) SendSMS(msg *SMS) (string, error) {
+	if f.Fail != nil {
+		if err := f.Fail(msg); err != nil {
+			return "", err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	f.sent = append(f.sent, *msg)
+	return fmt.Sprintf("fake-%d", f.seq), nil
+}
+
+// Sent returns a copy of every message sent so far.
+func (f *FakeSMSSender) Sent() []SMS {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]SMS(nil), f.sent...)
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPMailer sends through an SMTP relay, upgrading to TLS with STARTTLS
+// when the server offers it.
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{config: config}
+}
+
+func (m *SMTPMailer) Send(msg *Message) (string, error) {
+	from, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return "", fmt.Errorf("invalid MAIL_FROM address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid address %q", ErrRejected, msg.To)
+	}
+
+	messageID, err := newMessageID(from.Address)
+	if err != nil {
+		return "", err
+	}
+	data, err := buildMIME(m.config.From, messageID, msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to build message: %w", err)
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	addr := net.JoinHostPort(m.config.Host, m.config.Port)
+	if err := smtp.SendMail(addr, auth, from.Address, []string{to.Address}, data); err != nil {
+		// 5xx replies are permanent: the server will never accept this
+		// recipient, so report it as a bounce rather than a retryable error
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+			return messageID, fmt.Errorf("%w: %v", ErrRejected, err)
+		}
+		return messageID, err
+	}
+
+	return messageID, nil
+}
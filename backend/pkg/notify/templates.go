@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.txt templates/*.html templates/*.sms
+var templateFS embed.FS
+
+const (
+	TemplateApproval         = "approval"
+	TemplateRSVPConfirmation = "rsvp_confirmation"
+	TemplateReminder         = "reminder"
+	TemplateInvitation       = "invitation"
+	TemplatePasswordReset    = "password_reset"
+)
+
+// Templates renders the built-in emails and text messages. Each email is a
+// pair of files, <name>.txt and <name>.html; the text file also defines the
+// subject in a {{define "subject"}} block. <name>.sms is the SMS version,
+// for the emails guests can also get by text.
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+	sms  map[string]*texttemplate.Template
+}
+
+func LoadTemplates() (*Templates, error) {
+	t := &Templates{
+		text: map[string]*texttemplate.Template{},
+		html: map[string]*htmltemplate.Template{},
+		sms:  map[string]*texttemplate.Template{},
+	}
+
+	for _, name := range []string{TemplateApproval, TemplateRSVPConfirmation, TemplateReminder, TemplateInvitation, TemplatePasswordReset} {
+		text, err := texttemplate.ParseFS(templateFS, "templates/"+name+".txt")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s text template: %w", name, err)
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("%s text template has no subject", name)
+		}
+
+		html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s HTML template: %w", name, err)
+		}
+
+		t.text[name] = text
+		t.html[name] = html
+
+		if _, err := fs.Stat(templateFS, "templates/"+name+".sms"); errors.Is(err, fs.ErrNotExist) {
+			continue // email only
+		}
+		sms, err := texttemplate.ParseFS(templateFS, "templates/"+name+".sms")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s SMS template: %w", name, err)
+		}
+		t.sms[name] = sms
+	}
+
+	return t, nil
+}
+
+// Render fills in the named email for the recipient address to.
+func (t *Templates) Render(name, to string, data interface{}) (*Message, error) {
+	text, ok := t.text[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, body, html bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	if err := text.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s text: %w", name, err)
+	}
+	if err := t.html[name].ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s HTML: %w", name, err)
+	}
+
+	return &Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// RenderSMS fills in the named text message for the phone number to.
+func (t *Templates) RenderSMS(name, to string, data interface{}) (*SMS, error) {
+	sms, ok := t.sms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown SMS template %q", name)
+	}
+
+	var body bytes.Buffer
+	if err := sms.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s SMS: %w", name, err)
+	}
+
+	return &SMS{To: to, Body: strings.TrimSpace(body.String())}, nil
+}
+
+// Data is what the built-in templates can refer to. Fields a template
+// doesn't use may be left empty.
+type Data struct {
+	FirstName string
+	RSVPURL   string
+	PortalURL string
+
+	// RSVP confirmation
+	Response  string
+	PartySize int
+	Events    []string
+
+	// Reminder overrides for the default subject and body
+	Subject string
+	Body    string
+
+	// Admin user invitation
+	InviteURL string
+	Role      string
+	InvitedBy string
+
+	// Admin user password reset
+	ResetURL string
+}
@@ -0,0 +1,285 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// Page is the response envelope shared by every paginated list endpoint.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor"`
+	Total      int64  `json:"total"`
+}
+
+// Options describes what a list endpoint lets clients sort and search on.
+// Sortable maps the public field name used in ?sort= to a SQL expression.
+// Sort expressions must never be NULL (wrap nullable columns in COALESCE) or
+// keyset comparisons will skip rows.
+type Options struct {
+	Sortable    map[string]string
+	DefaultSort string
+	Searchable  []string
+	IDColumn    string
+}
+
+type SortField struct {
+	Field string
+	Expr  string
+	Desc  bool
+}
+
+// Params are the parsed ?limit=, ?cursor=, ?sort= and ?search= parameters.
+type Params struct {
+	Limit  int
+	Sort   []SortField
+	Search string
+
+	cursor []*string
+	opts   Options
+}
+
+// Parse reads pagination parameters from the request. Sort is a
+// comma-separated field list where a leading "-" means descending, e.g.
+// "lastName,-createdAt".
+func Parse(c *gin.Context, opts Options) (*Params, error) {
+	p := &Params{
+		Limit:  DefaultLimit,
+		Search: strings.TrimSpace(c.Query("search")),
+		opts:   opts,
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("limit must be a positive number")
+		}
+		if limit > MaxLimit {
+			limit = MaxLimit
+		}
+		p.Limit = limit
+	}
+
+	sort := c.DefaultQuery("sort", opts.DefaultSort)
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+		expr, ok := opts.Sortable[field]
+		if !ok {
+			return nil, fmt.Errorf("cannot sort by %q", field)
+		}
+		p.Sort = append(p.Sort, SortField{Field: field, Expr: expr, Desc: desc})
+	}
+
+	// The ID column breaks ties so every row has a unique position
+	tieDesc := len(p.Sort) > 0 && p.Sort[len(p.Sort)-1].Desc
+	p.Sort = append(p.Sort, SortField{Field: "id", Expr: opts.IDColumn, Desc: tieDesc})
+
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := decodeCursor(v)
+		if err != nil || len(cursor) != len(p.Sort) {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+		p.cursor = cursor
+	}
+
+	return p, nil
+}
+
+// Find fills page from query. The query should already carry the endpoint's
+// filters; scopes are applied only when loading items (e.g. preloads), so
+// they don't affect the total count.
+func Find[T any](query *gorm.DB, p *Params, page *Page[T], scopes ...func(*gorm.DB) *gorm.DB) error {
+	filtered := query.Scopes(p.searchScope)
+
+	if err := filtered.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return err
+	}
+
+	items := make([]T, 0, p.Limit+1)
+	err := filtered.Session(&gorm.Session{}).
+		Scopes(p.cursorScope, p.orderScope).
+		Scopes(scopes...).
+		Limit(p.Limit + 1).
+		Find(&items).Error
+	if err != nil {
+		return err
+	}
+
+	page.NextCursor = ""
+	if len(items) > p.Limit {
+		items = items[:p.Limit]
+		cursor, err := p.cursorAfter(filtered, idOf(items[len(items)-1]))
+		if err != nil {
+			return err
+		}
+		page.NextCursor = cursor
+	}
+	page.Items = items
+
+	return nil
+}
+
+func (p *Params) orderScope(db *gorm.DB) *gorm.DB {
+	for _, f := range p.Sort {
+		if f.Desc {
+			db = db.Order(f.Expr + " DESC")
+		} else {
+			db = db.Order(f.Expr + " ASC")
+		}
+	}
+	return db
+}
+
+// cursorScope restricts the query to rows after the cursor position:
+// (a > x) OR (a = x AND b > y) OR ..., flipping the comparison for
+// descending fields.
+func (p *Params) cursorScope(db *gorm.DB) *gorm.DB {
+	if p.cursor == nil {
+		return db
+	}
+
+	var clauses []string
+	var args []interface{}
+	for i, f := range p.Sort {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, p.Sort[j].Expr+" = ?")
+			args = append(args, p.cursor[j])
+		}
+		op := ">"
+		if f.Desc {
+			op = "<"
+		}
+		parts = append(parts, f.Expr+" "+op+" ?")
+		args = append(args, p.cursor[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return db.Where("("+strings.Join(clauses, " OR ")+")", args...)
+}
+
+// searchScope requires every whitespace-separated word of the search to
+// appear in at least one searchable column.
+func (p *Params) searchScope(db *gorm.DB) *gorm.DB {
+	if p.Search == "" || len(p.opts.Searchable) == 0 {
+		return db
+	}
+
+	for _, word := range strings.Fields(p.Search) {
+		pattern := "%" + escapeLike(word) + "%"
+		var parts []string
+		var args []interface{}
+		for _, column := range p.opts.Searchable {
+			parts = append(parts, column+" ILIKE ?")
+			args = append(args, pattern)
+		}
+		db = db.Where("("+strings.Join(parts, " OR ")+")", args...)
+	}
+	return db
+}
+
+// cursorAfter reads the sort values of the row with the given ID and encodes
+// them as the cursor for the next page.
+func (p *Params) cursorAfter(query *gorm.DB, id uint) (string, error) {
+	exprs := make([]string, len(p.Sort))
+	for i, f := range p.Sort {
+		exprs[i] = f.Expr
+	}
+
+	rows, err := query.Session(&gorm.Session{}).
+		Select(strings.Join(exprs, ", ")).
+		Where(p.opts.IDColumn+" = ?", id).
+		Limit(1).
+		Rows()
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return "", fmt.Errorf("failed to build cursor for row %d", id)
+	}
+
+	values := make([]interface{}, len(exprs))
+	ptrs := make([]interface{}, len(exprs))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return "", err
+	}
+
+	cursor := make([]*string, len(values))
+	for i, v := range values {
+		cursor[i] = cursorValue(v)
+	}
+	return encodeCursor(cursor)
+}
+
+func cursorValue(v interface{}) *string {
+	var s string
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		s = val.Format(time.RFC3339Nano)
+	case []byte:
+		s = string(val)
+	default:
+		s = fmt.Sprint(val)
+	}
+	return &s
+}
+
+func encodeCursor(values []*string) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor string) ([]*string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var values []*string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// idOf reads the ID field every model in this app has.
+func idOf(item interface{}) uint {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	field := v.FieldByName("ID")
+	if !field.IsValid() {
+		return 0
+	}
+	return uint(field.Uint())
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
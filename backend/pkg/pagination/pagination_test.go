@@ -0,0 +1,234 @@
+package pagination
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var testOptions = Options{
+	Sortable: map[string]string{
+		"name":      "guests.last_name",
+		"createdAt": "guests.created_at",
+	},
+	DefaultSort: "name",
+	Searchable:  []string{"guests.first_name", "guests.email"},
+	IDColumn:    "guests.id",
+}
+
+func parse(t *testing.T, query string) (*Params, error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/items?"+query, nil)
+	return Parse(c, testOptions)
+}
+
+// dryRun returns a session that builds SQL without a database.
+func dryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := sql.Open("pgx", "host=unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestParseDefaults(t *testing.T) {
+	p, err := parse(t, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Limit != DefaultLimit || p.Search != "" {
+		t.Errorf("Limit = %d, Search = %q", p.Limit, p.Search)
+	}
+	want := []SortField{
+		{Field: "name", Expr: "guests.last_name"},
+		{Field: "id", Expr: "guests.id"},
+	}
+	if !reflect.DeepEqual(p.Sort, want) {
+		t.Errorf("Sort = %+v, want %+v", p.Sort, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	p, err := parse(t, "limit=500&sort=-createdAt,name&search=+ann+")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Limit != MaxLimit {
+		t.Errorf("Limit = %d, want it capped at %d", p.Limit, MaxLimit)
+	}
+	if p.Search != "ann" {
+		t.Errorf("Search = %q", p.Search)
+	}
+	want := []SortField{
+		{Field: "createdAt", Expr: "guests.created_at", Desc: true},
+		{Field: "name", Expr: "guests.last_name"},
+		{Field: "id", Expr: "guests.id"},
+	}
+	if !reflect.DeepEqual(p.Sort, want) {
+		t.Errorf("Sort = %+v, want %+v", p.Sort, want)
+	}
+
+	// The ID tiebreak follows the direction of the last sort field
+	p, err = parse(t, "sort=-name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := p.Sort[len(p.Sort)-1]; last.Field != "id" || !last.Desc {
+		t.Errorf("tiebreak = %+v, want id descending", last)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	valid, _ := encodeCursor([]*string{ptr("Smith"), ptr("4")})
+	tests := map[string]string{
+		"zero limit":        "limit=0",
+		"negative limit":    "limit=-5",
+		"non-numeric limit": "limit=ten",
+		"unknown sort":      "sort=password",
+		"garbage cursor":    "cursor=!!!",
+		"cursor not JSON":   "cursor=bm90LWpzb24",
+		"cursor mismatch":   "sort=name,createdAt&cursor=" + valid,
+	}
+	for name, query := range tests {
+		if _, err := parse(t, query); err == nil {
+			t.Errorf("%s: Parse(%q) succeeded", name, query)
+		}
+	}
+
+	if _, err := parse(t, "cursor="+valid); err != nil {
+		t.Errorf("a cursor matching the sort was rejected: %v", err)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	values := []*string{ptr("O'Brien"), nil, ptr("42")}
+	encoded, err := encodeCursor(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.ContainsAny(encoded, "+/=") {
+		t.Errorf("cursor %q is not URL safe", encoded)
+	}
+	decoded, err := decodeCursor(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, values) {
+		t.Errorf("decoded %v, want %v", decoded, values)
+	}
+}
+
+func TestCursorValue(t *testing.T) {
+	at := time.Date(2024, 12, 24, 18, 30, 0, 123, time.UTC)
+	tests := []struct {
+		in   interface{}
+		want *string
+	}{
+		{nil, nil},
+		{"text", ptr("text")},
+		{[]byte("bytes"), ptr("bytes")},
+		{int64(7), ptr("7")},
+		{at, ptr("2024-12-24T18:30:00.000000123Z")},
+	}
+	for _, tt := range tests {
+		if got := cursorValue(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cursorValue(%v) = %v, want %v", tt.in, deref(got), deref(tt.want))
+		}
+	}
+}
+
+func TestOrderAndCursorScopes(t *testing.T) {
+	cursor, _ := encodeCursor([]*string{ptr("2024-01-01T00:00:00Z"), ptr("Smith"), ptr("9")})
+	p, err := parse(t, "sort=-createdAt,name&cursor="+cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []map[string]interface{}
+	stmt := dryRun(t).Table("guests").Scopes(p.cursorScope, p.orderScope).Find(&rows).Statement
+	query := stmt.SQL.String()
+
+	wantWhere := "((guests.created_at < $1) OR (guests.created_at = $2 AND guests.last_name > $3) OR " +
+		"(guests.created_at = $4 AND guests.last_name = $5 AND guests.id > $6))"
+	if !strings.Contains(query, wantWhere) {
+		t.Errorf("SQL %q\nis missing the keyset condition %q", query, wantWhere)
+	}
+	wantOrder := "ORDER BY guests.created_at DESC,guests.last_name ASC,guests.id ASC"
+	if !strings.Contains(query, wantOrder) {
+		t.Errorf("SQL %q\nis missing %q", query, wantOrder)
+	}
+
+	var args []string
+	for _, v := range stmt.Vars {
+		args = append(args, deref(v.(*string)))
+	}
+	wantArgs := "2024-01-01T00:00:00Z 2024-01-01T00:00:00Z Smith 2024-01-01T00:00:00Z Smith 9"
+	if strings.Join(args, " ") != wantArgs {
+		t.Errorf("args = %v, want %s", args, wantArgs)
+	}
+}
+
+func TestSearchScope(t *testing.T) {
+	p, err := parse(t, "search=ann+50%25_off")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []map[string]interface{}
+	stmt := dryRun(t).Table("guests").Scopes(p.searchScope).Find(&rows).Statement
+	query := stmt.SQL.String()
+
+	want := "((guests.first_name ILIKE $1 OR guests.email ILIKE $2)) AND ((guests.first_name ILIKE $3 OR guests.email ILIKE $4))"
+	if !strings.Contains(query, want) {
+		t.Errorf("SQL %q\nis missing %q", query, want)
+	}
+	wantVars := []interface{}{"%ann%", "%ann%", `%50\%\_off%`, `%50\%\_off%`}
+	if !reflect.DeepEqual(stmt.Vars, wantVars) {
+		t.Errorf("args = %v, want %v", stmt.Vars, wantVars)
+	}
+}
+
+func TestIDOf(t *testing.T) {
+	type row struct {
+		ID   uint
+		Name string
+	}
+	if got := idOf(row{ID: 12}); got != 12 {
+		t.Errorf("idOf(value) = %d", got)
+	}
+	if got := idOf(&row{ID: 13}); got != 13 {
+		t.Errorf("idOf(pointer) = %d", got)
+	}
+	if got := idOf(struct{ Name string }{}); got != 0 {
+		t.Errorf("idOf without an ID field = %d", got)
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func deref(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
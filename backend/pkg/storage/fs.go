@@ -0,0 +1,416 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxFSUploadBytes caps a single upload through the filesystem backend.
+const MaxFSUploadBytes = 512 << 20
+
+type FSConfig struct {
+	Root      string
+	PublicURL string // where the API is reachable, e.g. http://localhost:8080
+	// SigningSecret signs upload and download URLs. If empty a random secret
+	// is used, so links stop working when the server restarts.
+	SigningSecret string
+}
+
+// FSClient stores objects as files under a root directory. Upload and
+// download URLs point back at the API (see FileHandler) and carry an HMAC
+// signature and expiry, mirroring S3 presigned URLs.
+type FSClient struct {
+	root      string
+	publicURL string
+	secret    []byte
+}
+
+func NewFSClient(config FSConfig) (*FSClient, error) {
+	root, err := filepath.Abs(config.Root)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	secret := []byte(config.SigningSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+
+	return &FSClient{
+		root:      root,
+		publicURL: strings.TrimRight(config.PublicURL, "/"),
+		secret:    secret,
+	}, nil
+}
+
+func (c *FSClient) GeneratePresignedUploadURL(key, contentType string, duration time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return c.signedURL("PUT", key, contentType, duration), nil
+}
+
+func (c *FSClient) GeneratePresignedURL(key string, duration time.Duration) string {
+	key, err := cleanKey(key)
+	if err != nil {
+		return ""
+	}
+	return c.signedURL("GET", key, "", duration)
+}
+
+func (c *FSClient) signedURL(method, key, contentType string, duration time.Duration) string {
+	expires := strconv.FormatInt(time.Now().Add(duration).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	if contentType != "" {
+		query.Set("contentType", contentType)
+	}
+	query.Set("signature", c.sign(method, key, contentType, expires))
+
+	segments := strings.Split(key, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return fmt.Sprintf("%s/api/files/%s?%s", c.publicURL, strings.Join(segments, "/"), query.Encode())
+}
+
+func (c *FSClient) sign(method, key, contentType, expires string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s", method, key, contentType, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ErrInvalidSignature is returned for tampered or expired file URLs.
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// Verify checks a signed URL's parameters for method and key.
+func (c *FSClient) Verify(method, key, contentType, expires, signature string) error {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return ErrInvalidSignature
+	}
+	expected := c.sign(method, key, contentType, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// path maps a key to its file, refusing keys that leave the root.
+func (c *FSClient) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.root, filepath.FromSlash(key)), nil
+}
+
+func (c *FSClient) DeleteObject(key string) error {
+	path, err := c.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+func (c *FSClient) GetObject(key string) (io.ReadCloser, error) {
+	file, err := c.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (c *FSClient) GetRange(key string, offset, length int64) (io.ReadCloser, error) {
+	file, err := c.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, nil
+}
+
+// Stat reports the file's size. The content type is inferred from the
+// key's extension, as files carry no metadata of their own.
+func (c *FSClient) Stat(key string) (*ObjectInfo, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	path, err := c.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		ContentType:  mime.TypeByExtension(filepath.Ext(key)),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+// ListObjects walks the directory tree under prefix, skipping uploads
+// that are still being written or assembled from parts.
+func (c *FSClient) ListObjects(prefix string, fn func(ObjectInfo) error) error {
+	// Walk from the deepest directory the prefix names, then filter on the
+	// full prefix
+	dir := c.root
+	if i := strings.LastIndex(prefix, "/"); i > 0 {
+		sub, err := c.path(prefix[:i])
+		if err != nil {
+			return err
+		}
+		dir = sub
+	}
+
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(c.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return fn(ObjectInfo{
+			Key:          key,
+			Size:         info.Size(),
+			ContentType:  mime.TypeByExtension(filepath.Ext(key)),
+			LastModified: info.ModTime(),
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	return nil
+}
+
+// Open returns the file behind key for serving with range support.
+func (c *FSClient) Open(key string) (*os.File, error) {
+	path, err := c.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return file, err
+}
+
+func (c *FSClient) UploadFile(key string, data []byte, contentType string) error {
+	_, err := c.Write(key, bytes.NewReader(data))
+	return err
+}
+
+func (c *FSClient) PutObject(key string, body io.ReadSeeker, contentType string) error {
+	_, err := c.Write(key, body)
+	return err
+}
+
+// Write stores r under key, replacing any existing object only once the
+// whole body has been written. It returns the number of bytes stored.
+func (c *FSClient) Write(key string, r io.Reader) (int64, error) {
+	path, err := c.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return n, fmt.Errorf("failed to store file: %w", err)
+	}
+	return n, nil
+}
+
+// multipartDir holds the parts of unfinished multipart uploads, one
+// directory per upload.
+const multipartDir = ".multipart"
+
+func (c *FSClient) CreateMultipartUpload(key, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(id)
+
+	// Remember which object the parts are for
+	if _, err := c.Write(multipartDir+"/"+uploadID+"/key", strings.NewReader(key)); err != nil {
+		return "", fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	return uploadID, nil
+}
+
+func (c *FSClient) GeneratePresignedPartURL(key, uploadID string, partNumber int32, duration time.Duration) (string, error) {
+	if err := c.checkUpload(key, uploadID); err != nil {
+		return "", err
+	}
+	if partNumber < 1 {
+		return "", fmt.Errorf("invalid part number %d", partNumber)
+	}
+	// Parts are sent without a declared type
+	return c.signedURL("PUT", partKey(uploadID, partNumber), "", duration), nil
+}
+
+// CompleteMultipartUpload checks each part against the ETag the client
+// was given for it, then joins them into the object.
+func (c *FSClient) CompleteMultipartUpload(key, uploadID string, parts []CompletedPart) error {
+	if err := c.checkUpload(key, uploadID); err != nil {
+		return err
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("multipart upload has no parts")
+	}
+
+	parts = append([]CompletedPart(nil), parts...)
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	readers := make([]io.Reader, 0, len(parts))
+	for i, part := range parts {
+		if i > 0 && part.PartNumber == parts[i-1].PartNumber {
+			return fmt.Errorf("part %d listed twice", part.PartNumber)
+		}
+		file, err := c.Open(partKey(uploadID, part.PartNumber))
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+
+		hash := md5.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			return fmt.Errorf("failed to read part %d: %w", part.PartNumber, err)
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != strings.Trim(part.ETag, `"`) {
+			return fmt.Errorf("part %d does not match its ETag", part.PartNumber)
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		readers = append(readers, file)
+	}
+
+	if _, err := c.Write(key, io.MultiReader(readers...)); err != nil {
+		return err
+	}
+	return c.AbortMultipartUpload(key, uploadID)
+}
+
+// AbortMultipartUpload discards an upload's parts.
+func (c *FSClient) AbortMultipartUpload(key, uploadID string) error {
+	dir, err := c.path(multipartDir + "/" + uploadID)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove multipart upload: %w", err)
+	}
+	return nil
+}
+
+// checkUpload confirms uploadID is an upload in progress for key.
+func (c *FSClient) checkUpload(key, uploadID string) error {
+	file, err := c.Open(multipartDir + "/" + uploadID + "/key")
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("%w: upload %s", ErrNotFound, uploadID)
+		}
+		return err
+	}
+	defer file.Close()
+
+	stored, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	if string(stored) != strings.TrimPrefix(key, "/") {
+		return fmt.Errorf("%w: upload %s", ErrNotFound, uploadID)
+	}
+	return nil
+}
+
+func partKey(uploadID string, partNumber int32) string {
+	return fmt.Sprintf("%s/%s/part-%05d", multipartDir, uploadID, partNumber)
+}
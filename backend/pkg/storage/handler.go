@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FileHandler serves the signed upload and download URLs issued by an
+// FSClient. Requests are authorised by the URL signature alone.
+type FileHandler struct {
+	client *FSClient
+}
+
+func NewFileHandler(client *FSClient) *FileHandler {
+	return &FileHandler{
+		client: client,
+	}
+}
+
+// Download handles GET /api/files/*key.
+func (h *FileHandler) Download(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	err := h.client.Verify("GET", key, "", c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := h.client.Open(key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+		return
+	}
+
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		c.Header("Content-Type", contentType)
+	}
+	c.Header("Cache-Control", "private, max-age=3600")
+	http.ServeContent(c.Writer, c.Request, path.Base(key), info.ModTime(), file)
+}
+
+// Upload handles PUT /api/files/*key. Like S3, the Content-Type header
+// must match the one the URL was signed for, if it was signed for one.
+func (h *FileHandler) Upload(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	contentType := c.Query("contentType")
+	err := h.client.Verify("PUT", key, contentType, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if contentType != "" && c.GetHeader("Content-Type") != contentType {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Content-Type does not match the signed upload"})
+		return
+	}
+
+	// Like S3, report the body's MD5 as the ETag; multipart uploads need it
+	// to complete
+	hash := md5.New()
+	body := io.TeeReader(http.MaxBytesReader(c.Writer, c.Request.Body, MaxFSUploadBytes), hash)
+	if _, err := h.client.Write(key, body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+
+	c.Header("ETag", `"`+hex.EncodeToString(hash.Sum(nil))+`"`)
+	c.Status(http.StatusOK)
+}
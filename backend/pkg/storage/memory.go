@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryClient keeps objects in memory. It is meant for tests and demos;
+// its URLs (memory://...) can't be fetched over HTTP.
+type MemoryClient struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+	uploads map[string]*memoryUpload // multipart uploads by ID
+}
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+	modified    time.Time
+}
+
+type memoryUpload struct {
+	key         string
+	contentType string
+	parts       map[int32][]byte
+}
+
+func NewMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		objects: make(map[string]memoryObject),
+		uploads: make(map[string]*memoryUpload),
+	}
+}
+
+func (c *MemoryClient) GeneratePresignedUploadURL(key, contentType string, duration time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return memoryURL(key, duration), nil
+}
+
+func (c *MemoryClient) GeneratePresignedURL(key string, duration time.Duration) string {
+	key, err := cleanKey(key)
+	if err != nil {
+		return ""
+	}
+	return memoryURL(key, duration)
+}
+
+func memoryURL(key string, duration time.Duration) string {
+	return fmt.Sprintf("memory://objects/%s?expires=%d", (&url.URL{Path: key}).EscapedPath(), time.Now().Add(duration).Unix())
+}
+
+func (c *MemoryClient) DeleteObject(key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.objects, key)
+	return nil
+}
+
+// object looks up key, normalized the same way UploadFile stores it.
+func (c *MemoryClient) object(key string) (string, memoryObject, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", memoryObject{}, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	obj, ok := c.objects[key]
+	if !ok {
+		return "", memoryObject{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return key, obj, nil
+}
+
+func (c *MemoryClient) GetObject(key string) (io.ReadCloser, error) {
+	_, obj, err := c.object(key)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+func (c *MemoryClient) GetRange(key string, offset, length int64) (io.ReadCloser, error) {
+	_, obj, err := c.object(key)
+	if err != nil {
+		return nil, err
+	}
+	data := obj.data[min(offset, int64(len(obj.data))):]
+	data = data[:min(length, int64(len(data)))]
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (c *MemoryClient) Stat(key string) (*ObjectInfo, error) {
+	key, obj, err := c.object(key)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{
+		Key:          key,
+		Size:         int64(len(obj.data)),
+		ContentType:  obj.contentType,
+		LastModified: obj.modified,
+	}, nil
+}
+
+func (c *MemoryClient) ListObjects(prefix string, fn func(ObjectInfo) error) error {
+	for _, key := range c.Keys() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		info, err := c.Stat(key)
+		if err != nil {
+			continue // deleted since Keys
+		}
+		if err := fn(*info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *MemoryClient) UploadFile(key string, data []byte, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.objects[key] = memoryObject{
+		data:        append([]byte(nil), data...),
+		contentType: contentType,
+		modified:    time.Now(),
+	}
+	return nil
+}
+
+func (c *MemoryClient) PutObject(key string, body io.ReadSeeker, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	return c.UploadFile(key, data, contentType)
+}
+
+// Keys returns every stored key in order.
+func (c *MemoryClient) Keys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make([]string, 0, len(c.objects))
+	for key := range c.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *MemoryClient) CreateMultipartUpload(key, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(id)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.uploads[uploadID] = &memoryUpload{key: key, contentType: contentType, parts: map[int32][]byte{}}
+	return uploadID, nil
+}
+
+// upload returns the upload in progress for key with uploadID. The caller
+// must hold c.mu.
+func (c *MemoryClient) upload(key, uploadID string) (*memoryUpload, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	upload, ok := c.uploads[uploadID]
+	if !ok || upload.key != key {
+		return nil, fmt.Errorf("%w: upload %s", ErrNotFound, uploadID)
+	}
+	return upload, nil
+}
+
+func (c *MemoryClient) GeneratePresignedPartURL(key, uploadID string, partNumber int32, duration time.Duration) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	upload, err := c.upload(key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	if partNumber < 1 {
+		return "", fmt.Errorf("invalid part number %d", partNumber)
+	}
+	return fmt.Sprintf("%s&uploadId=%s&partNumber=%d", memoryURL(upload.key, duration), uploadID, partNumber), nil
+}
+
+// UploadPart stores one part of a multipart upload, standing in for the
+// PUT a browser would send to the part's URL. It returns the part's ETag.
+func (c *MemoryClient) UploadPart(key, uploadID string, partNumber int32, data []byte) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	upload, err := c.upload(key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	upload.parts[partNumber] = append([]byte(nil), data...)
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`, nil
+}
+
+// CompleteMultipartUpload checks each part against its ETag, then joins
+// them into the object.
+func (c *MemoryClient) CompleteMultipartUpload(key, uploadID string, parts []CompletedPart) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("multipart upload has no parts")
+	}
+	parts = append([]CompletedPart(nil), parts...)
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	upload, err := c.upload(key, uploadID)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	for i, part := range parts {
+		if i > 0 && part.PartNumber == parts[i-1].PartNumber {
+			return fmt.Errorf("part %d listed twice", part.PartNumber)
+		}
+		chunk, ok := upload.parts[part.PartNumber]
+		if !ok {
+			return fmt.Errorf("%w: part %d", ErrNotFound, part.PartNumber)
+		}
+		sum := md5.Sum(chunk)
+		if hex.EncodeToString(sum[:]) != strings.Trim(part.ETag, `"`) {
+			return fmt.Errorf("part %d does not match its ETag", part.PartNumber)
+		}
+		data = append(data, chunk...)
+	}
+
+	c.objects[upload.key] = memoryObject{data: data, contentType: upload.contentType, modified: time.Now()}
+	delete(c.uploads, uploadID)
+	return nil
+}
+
+func (c *MemoryClient) AbortMultipartUpload(key, uploadID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.uploads, uploadID)
+	return nil
+}
+
+// Uploads returns the IDs of the multipart uploads still in progress.
+func (c *MemoryClient) Uploads() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ids := make([]string, 0, len(c.uploads))
+	for id := range c.uploads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
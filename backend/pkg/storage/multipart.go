@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"time"
+)
+
+// CompletedPart identifies one uploaded part of a multipart upload. The
+// ETag is the one the storage backend returned when the part was uploaded.
+type CompletedPart struct {
+	PartNumber int32  `json:"partNumber" binding:"required,min=1"`
+	ETag       string `json:"etag" binding:"required"`
+}
+
+// MultipartClient is implemented by backends that take large uploads in
+// parts, each sent by the browser straight to its own presigned URL.
+type MultipartClient interface {
+	CreateMultipartUpload(key, contentType string) (string, error)
+	GeneratePresignedPartURL(key, uploadID string, partNumber int32, duration time.Duration) (string, error)
+	// CompleteMultipartUpload joins the parts, in part number order, into
+	// the object at key.
+	CompleteMultipartUpload(key, uploadID string, parts []CompletedPart) error
+	AbortMultipartUpload(key, uploadID string) error
+}
@@ -3,19 +3,37 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 type Client interface {
 	GeneratePresignedUploadURL(key, contentType string, duration time.Duration) (string, error)
 	GeneratePresignedURL(key string, duration time.Duration) string
 	DeleteObject(key string) error
+	GetObject(key string) (io.ReadCloser, error)
+	// GetRange reads length bytes starting at offset; a short object
+	// returns what there is.
+	GetRange(key string, offset, length int64) (io.ReadCloser, error)
+	// Stat returns an object's size and type without fetching it.
+	Stat(key string) (*ObjectInfo, error)
+	// ListObjects calls fn for every object whose key starts with prefix,
+	// stopping at the first error fn returns.
+	ListObjects(prefix string, fn func(ObjectInfo) error) error
+	UploadFile(key string, data []byte, contentType string) error
+	// PutObject uploads body from its current position to the end, for
+	// objects too large to hold in memory. There is no size limit; S3
+	// takes large bodies as a multipart upload.
+	PutObject(key string, body io.ReadSeeker, contentType string) error
 }
 
 type S3Client struct {
@@ -24,10 +42,10 @@ type S3Client struct {
 	region string
 }
 
-func NewS3Client() *S3Client {
+func NewS3Client() (*S3Client, error) {
 	cfg, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
-		panic("failed to load AWS config: " + err.Error())
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
 	bucket := os.Getenv("S3_BUCKET")
@@ -44,7 +62,7 @@ func NewS3Client() *S3Client {
 		client: s3.NewFromConfig(cfg),
 		bucket: bucket,
 		region: region,
-	}
+	}, nil
 }
 
 func (c *S3Client) GeneratePresignedUploadURL(key, contentType string, duration time.Duration) (string, error) {
@@ -95,6 +113,89 @@ func (c *S3Client) DeleteObject(key string) error {
 	return nil
 }
 
+func (c *S3Client) GetObject(key string) (io.ReadCloser, error) {
+	output, err := c.client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, fmt.Errorf("failed to get object from S3: %w", err)
+	}
+
+	return output.Body, nil
+}
+
+func (c *S3Client) GetRange(key string, offset, length int64) (io.ReadCloser, error) {
+	output, err := c.client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, fmt.Errorf("failed to get object range from S3: %w", err)
+	}
+
+	return output.Body, nil
+}
+
+func (c *S3Client) Stat(key string) (*ObjectInfo, error) {
+	output, err := c.client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, fmt.Errorf("failed to stat object in S3: %w", err)
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(output.ContentLength),
+		ContentType:  aws.ToString(output.ContentType),
+		LastModified: aws.ToTime(output.LastModified),
+	}, nil
+}
+
+func (c *S3Client) ListObjects(prefix string, fn func(ObjectInfo) error) error {
+	paginator := s3.NewListObjectsV2Paginator(c.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(c.bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return fmt.Errorf("failed to list objects in S3: %w", err)
+		}
+		for _, obj := range page.Contents {
+			err := fn(ObjectInfo{
+				Key:          aws.ToString(obj.Key),
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (c *S3Client) UploadFile(key string, data []byte, contentType string) error {
 	_, err := c.client.PutObject(context.TODO(), &s3.PutObjectInput{
 		Bucket:      aws.String(c.bucket),
@@ -110,6 +211,184 @@ func (c *S3Client) UploadFile(key string, data []byte, contentType string) error
 	return nil
 }
 
+func (c *S3Client) PutObject(key string, body io.ReadSeeker, contentType string) error {
+	start, err := body.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	end, err := body.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := body.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	if end-start > multipartThreshold {
+		return c.putMultipart(key, body, start, end-start, contentType)
+	}
+
+	_, err = c.client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:        aws.String(c.bucket),
+		Key:           aws.String(key),
+		Body:          body,
+		ContentLength: aws.Int64(end - start),
+		ContentType:   aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload file to S3: %w", err)
+	}
+	return nil
+}
+
+// S3 refuses single PUTs over 5 GB, so PutObject sends anything over
+// multipartThreshold in parts of at least minUploadPartSize. S3 allows at
+// most maxUploadParts parts.
+const (
+	multipartThreshold = 256 << 20
+	minUploadPartSize  = 64 << 20
+	maxUploadParts     = 10000
+)
+
+// putMultipart uploads size bytes of body, starting at offset, as a
+// multipart upload, aborting it if any part fails.
+func (c *S3Client) putMultipart(key string, body io.ReadSeeker, offset, size int64, contentType string) error {
+	partSize := int64(minUploadPartSize)
+	if size/partSize >= maxUploadParts {
+		partSize = size/maxUploadParts + 1
+	}
+
+	uploadID, err := c.CreateMultipartUpload(key, contentType)
+	if err != nil {
+		return err
+	}
+
+	var parts []CompletedPart
+	for done, number := int64(0), int32(1); done < size; number++ {
+		length := partSize
+		if size-done < length {
+			length = size - done
+		}
+		part, err := c.uploadPart(key, uploadID, number, body, offset+done, length)
+		if err != nil {
+			c.AbortMultipartUpload(key, uploadID)
+			return err
+		}
+		parts = append(parts, part)
+		done += length
+	}
+
+	if err := c.CompleteMultipartUpload(key, uploadID, parts); err != nil {
+		c.AbortMultipartUpload(key, uploadID)
+		return err
+	}
+	return nil
+}
+
+// uploadPart sends one part. Files are read in place; other bodies are
+// buffered a part at a time so the request can be signed and retried.
+func (c *S3Client) uploadPart(key, uploadID string, number int32, body io.ReadSeeker, offset, length int64) (CompletedPart, error) {
+	var section io.ReadSeeker
+	if readerAt, ok := body.(io.ReaderAt); ok {
+		section = io.NewSectionReader(readerAt, offset, length)
+	} else {
+		if _, err := body.Seek(offset, io.SeekStart); err != nil {
+			return CompletedPart{}, err
+		}
+		buf := make([]byte, length)
+		if _, err := io.ReadFull(body, buf); err != nil {
+			return CompletedPart{}, err
+		}
+		section = bytes.NewReader(buf)
+	}
+
+	output, err := c.client.UploadPart(context.TODO(), &s3.UploadPartInput{
+		Bucket:        aws.String(c.bucket),
+		Key:           aws.String(key),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(number),
+		Body:          section,
+		ContentLength: aws.Int64(length),
+	})
+	if err != nil {
+		return CompletedPart{}, fmt.Errorf("failed to upload part %d to S3: %w", number, err)
+	}
+	return CompletedPart{PartNumber: number, ETag: aws.ToString(output.ETag)}, nil
+}
+
+func (c *S3Client) CreateMultipartUpload(key, contentType string) (string, error) {
+	output, err := c.client.CreateMultipartUpload(context.TODO(), &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(c.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	return aws.ToString(output.UploadId), nil
+}
+
+func (c *S3Client) GeneratePresignedPartURL(key, uploadID string, partNumber int32, duration time.Duration) (string, error) {
+	presignClient := s3.NewPresignClient(c.client)
+
+	request, err := presignClient.PresignUploadPart(context.TODO(), &s3.UploadPartInput{
+		Bucket:     aws.String(c.bucket),
+		Key:        aws.String(key),
+		UploadId:   aws.String(uploadID),
+		PartNumber: aws.Int32(partNumber),
+	}, func(opts *s3.PresignOptions) {
+		opts.Expires = duration
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned part URL: %w", err)
+	}
+
+	return request.URL, nil
+}
+
+func (c *S3Client) CompleteMultipartUpload(key, uploadID string, parts []CompletedPart) error {
+	completed := make([]types.CompletedPart, len(parts))
+	for i, part := range parts {
+		completed[i] = types.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int32(part.PartNumber),
+		}
+	}
+	sort.Slice(completed, func(i, j int) bool {
+		return aws.ToInt32(completed[i].PartNumber) < aws.ToInt32(completed[j].PartNumber)
+	})
+
+	_, err := c.client.CompleteMultipartUpload(context.TODO(), &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(c.bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		var noSuchUpload *types.NoSuchUpload
+		if errors.As(err, &noSuchUpload) {
+			return fmt.Errorf("%w: upload %s", ErrNotFound, uploadID)
+		}
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+func (c *S3Client) AbortMultipartUpload(key, uploadID string) error {
+	_, err := c.client.AbortMultipartUpload(context.TODO(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(c.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		var noSuchUpload *types.NoSuchUpload
+		if errors.As(err, &noSuchUpload) {
+			return nil
+		}
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+	return nil
+}
+
 func (c *S3Client) GetObjectURL(key string) string {
 	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", c.bucket, c.region, key)
 }
\ No newline at end of file
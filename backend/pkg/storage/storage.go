@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a key has no object.
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// NewClientFromEnv picks the storage backend from STORAGE_BACKEND: "s3"
+// (the default), or for local development "filesystem" which keeps objects
+// under STORAGE_DIR and serves them through the API at STORAGE_PUBLIC_URL,
+// or "memory" which keeps them in memory and is lost on restart.
+func NewClientFromEnv() (Client, error) {
+	switch backend := getEnv("STORAGE_BACKEND", "s3"); backend {
+	case "s3":
+		return NewS3Client()
+	case "filesystem":
+		return NewFSClient(FSConfig{
+			Root:          getEnv("STORAGE_DIR", "./uploads"),
+			PublicURL:     getEnv("STORAGE_PUBLIC_URL", "http://localhost:8080"),
+			SigningSecret: os.Getenv("STORAGE_SIGNING_SECRET"),
+		})
+	case "memory":
+		return NewMemoryClient(), nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
+
+// cleanKey rejects keys that are empty, absolute or would escape the
+// storage root.
+func cleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if key == "" || strings.Contains(key, "\\") || strings.Contains(key, "\x00") {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("invalid object key %q", key)
+		}
+	}
+	return key, nil
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,337 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clients returns a fresh instance of every backend that runs without
+// network access.
+func clients(t *testing.T) map[string]Client {
+	t.Helper()
+	fs, err := NewFSClient(FSConfig{Root: t.TempDir(), PublicURL: "http://api.test/", SigningSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Client{
+		"memory":     NewMemoryClient(),
+		"filesystem": fs,
+	}
+}
+
+func readAll(t *testing.T, r io.ReadCloser, err error) string {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	for name, c := range clients(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := c.UploadFile("photos/a.jpg", []byte("hello world"), "image/jpeg"); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := c.GetObject("photos/a.jpg")
+			if got := readAll(t, r, err); got != "hello world" {
+				t.Errorf("GetObject = %q", got)
+			}
+
+			r, err = c.GetRange("photos/a.jpg", 6, 100)
+			if got := readAll(t, r, err); got != "world" {
+				t.Errorf("GetRange past the end = %q", got)
+			}
+			r, err = c.GetRange("photos/a.jpg", 2, 3)
+			if got := readAll(t, r, err); got != "llo" {
+				t.Errorf("GetRange = %q", got)
+			}
+
+			info, err := c.Stat("photos/a.jpg")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Key != "photos/a.jpg" || info.Size != 11 || info.ContentType != "image/jpeg" {
+				t.Errorf("Stat = %+v", info)
+			}
+
+			if err := c.DeleteObject("photos/a.jpg"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Stat("photos/a.jpg"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Stat after delete: got %v, want ErrNotFound", err)
+			}
+			if _, err := c.GetObject("photos/a.jpg"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetObject after delete: got %v, want ErrNotFound", err)
+			}
+			if err := c.DeleteObject("photos/a.jpg"); err != nil {
+				t.Errorf("deleting a missing object: %v", err)
+			}
+		})
+	}
+}
+
+func TestClientNormalizesKeys(t *testing.T) {
+	for name, c := range clients(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := c.UploadFile("/photos/b.png", []byte("png"), "image/png"); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, key := range []string{"photos/b.png", "/photos/b.png"} {
+				r, err := c.GetObject(key)
+				if got := readAll(t, r, err); got != "png" {
+					t.Errorf("GetObject(%q) = %q", key, got)
+				}
+				r, err = c.GetRange(key, 0, 2)
+				if got := readAll(t, r, err); got != "pn" {
+					t.Errorf("GetRange(%q) = %q", key, got)
+				}
+				info, err := c.Stat(key)
+				if err != nil {
+					t.Fatalf("Stat(%q): %v", key, err)
+				}
+				if info.Key != "photos/b.png" {
+					t.Errorf("Stat(%q).Key = %q", key, info.Key)
+				}
+			}
+
+			if err := c.DeleteObject("/photos/b.png"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Stat("photos/b.png"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("deleting with a leading slash left the object: %v", err)
+			}
+		})
+	}
+}
+
+func TestClientRejectsInvalidKeys(t *testing.T) {
+	keys := []string{"", "/", "a//b", "../etc/passwd", "a/../../b", "a/./b", `a\b`, "a\x00b"}
+	for name, c := range clients(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, key := range keys {
+				if err := c.UploadFile(key, []byte("x"), "text/plain"); err == nil {
+					t.Errorf("UploadFile(%q) succeeded", key)
+				}
+				if _, err := c.GetObject(key); err == nil || errors.Is(err, ErrNotFound) {
+					t.Errorf("GetObject(%q): got %v, want an invalid key error", key, err)
+				}
+				if _, err := c.Stat(key); err == nil || errors.Is(err, ErrNotFound) {
+					t.Errorf("Stat(%q): got %v, want an invalid key error", key, err)
+				}
+				if err := c.DeleteObject(key); err == nil {
+					t.Errorf("DeleteObject(%q) succeeded", key)
+				}
+				if _, err := c.GeneratePresignedUploadURL(key, "text/plain", time.Minute); err == nil {
+					t.Errorf("GeneratePresignedUploadURL(%q) succeeded", key)
+				}
+			}
+		})
+	}
+}
+
+func TestClientListObjects(t *testing.T) {
+	for name, c := range clients(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, key := range []string{"photos/1.jpg", "photos/2.jpg", "photos-old/3.jpg", "videos/4.mp4"} {
+				if err := c.PutObject(key, strings.NewReader(key), "application/octet-stream"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var keys []string
+			err := c.ListObjects("photos/", func(info ObjectInfo) error {
+				keys = append(keys, info.Key)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(keys, " ") != "photos/1.jpg photos/2.jpg" {
+				t.Errorf("ListObjects(photos/) = %v", keys)
+			}
+
+			stop := errors.New("stop")
+			calls := 0
+			err = c.ListObjects("", func(ObjectInfo) error {
+				calls++
+				return stop
+			})
+			if !errors.Is(err, stop) || calls != 1 {
+				t.Errorf("ListObjects did not stop at the first error: err=%v calls=%d", err, calls)
+			}
+		})
+	}
+}
+
+func TestFSSignedURLs(t *testing.T) {
+	c, err := NewFSClient(FSConfig{Root: t.TempDir(), PublicURL: "http://api.test/", SigningSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := c.GeneratePresignedUploadURL("/photos/my photo.jpg", "image/jpeg", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "api.test" || u.Path != "/api/files/photos/my photo.jpg" {
+		t.Errorf("upload URL = %s", raw)
+	}
+
+	q := u.Query()
+	if err := c.Verify("PUT", "photos/my photo.jpg", "image/jpeg", q.Get("expires"), q.Get("signature")); err != nil {
+		t.Errorf("Verify rejected a fresh URL: %v", err)
+	}
+	if err := c.Verify("GET", "photos/my photo.jpg", "image/jpeg", q.Get("expires"), q.Get("signature")); err == nil {
+		t.Error("Verify accepted the upload signature for a download")
+	}
+	if err := c.Verify("PUT", "photos/other.jpg", "image/jpeg", q.Get("expires"), q.Get("signature")); err == nil {
+		t.Error("Verify accepted the signature for another key")
+	}
+	if err := c.Verify("PUT", "photos/my photo.jpg", "text/html", q.Get("expires"), q.Get("signature")); err == nil {
+		t.Error("Verify accepted the signature for another content type")
+	}
+
+	expired := c.signedURL("GET", "photos/a.jpg", "", -time.Minute)
+	u, _ = url.Parse(expired)
+	if err := c.Verify("GET", "photos/a.jpg", "", u.Query().Get("expires"), u.Query().Get("signature")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify of an expired URL: got %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestFSMultipartUpload(t *testing.T) {
+	c, err := NewFSClient(FSConfig{Root: t.TempDir(), SigningSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uploadID, err := c.CreateMultipartUpload("videos/clip.mp4", "video/mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GeneratePresignedPartURL("videos/other.mp4", uploadID, 1, time.Minute); !errors.Is(err, ErrNotFound) {
+		t.Errorf("part URL for another key: got %v, want ErrNotFound", err)
+	}
+
+	chunks := []string{"first-", "second-", "third"}
+	var parts []CompletedPart
+	for i := len(chunks) - 1; i >= 0; i-- { // out of order on purpose
+		n := int32(i + 1)
+		if _, err := c.Write(partKey(uploadID, n), strings.NewReader(chunks[i])); err != nil {
+			t.Fatal(err)
+		}
+		sum := md5.Sum([]byte(chunks[i]))
+		parts = append(parts, CompletedPart{PartNumber: n, ETag: `"` + hex.EncodeToString(sum[:]) + `"`})
+	}
+
+	bad := append([]CompletedPart(nil), parts...)
+	bad[0].ETag = "0000"
+	if err := c.CompleteMultipartUpload("videos/clip.mp4", uploadID, bad); err == nil {
+		t.Error("CompleteMultipartUpload accepted a part that doesn't match its ETag")
+	}
+
+	if err := c.CompleteMultipartUpload("videos/clip.mp4", uploadID, parts); err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.GetObject("videos/clip.mp4")
+	if got := readAll(t, r, err); got != "first-second-third" {
+		t.Errorf("assembled object = %q", got)
+	}
+	if _, err := c.GeneratePresignedPartURL("videos/clip.mp4", uploadID, 1, time.Minute); !errors.Is(err, ErrNotFound) {
+		t.Errorf("upload still open after completing: %v", err)
+	}
+}
+
+func TestMemoryMultipartUpload(t *testing.T) {
+	c := NewMemoryClient()
+	var _ MultipartClient = c
+
+	uploadID, err := c.CreateMultipartUpload("/videos/clip.mp4", "video/mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GeneratePresignedPartURL("videos/other.mp4", uploadID, 1, time.Minute); !errors.Is(err, ErrNotFound) {
+		t.Errorf("part URL for another key: got %v, want ErrNotFound", err)
+	}
+	if _, err := c.GeneratePresignedPartURL("videos/clip.mp4", uploadID, 0, time.Minute); err == nil {
+		t.Error("GeneratePresignedPartURL accepted part 0")
+	}
+
+	chunks := []string{"first-", "second-", "third"}
+	var parts []CompletedPart
+	for i := len(chunks) - 1; i >= 0; i-- { // out of order on purpose
+		n := int32(i + 1)
+		etag, err := c.UploadPart("videos/clip.mp4", uploadID, n, []byte(chunks[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, CompletedPart{PartNumber: n, ETag: etag})
+	}
+
+	bad := append([]CompletedPart(nil), parts...)
+	bad[0].ETag = "0000"
+	if err := c.CompleteMultipartUpload("videos/clip.mp4", uploadID, bad); err == nil {
+		t.Error("CompleteMultipartUpload accepted a part that doesn't match its ETag")
+	}
+	if err := c.CompleteMultipartUpload("videos/clip.mp4", uploadID, append(parts, parts[0])); err == nil {
+		t.Error("CompleteMultipartUpload accepted a part listed twice")
+	}
+
+	if err := c.CompleteMultipartUpload("videos/clip.mp4", uploadID, parts); err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.GetObject("videos/clip.mp4")
+	if got := readAll(t, r, err); got != "first-second-third" {
+		t.Errorf("assembled object = %q", got)
+	}
+	if info, err := c.Stat("videos/clip.mp4"); err != nil || info.ContentType != "video/mp4" {
+		t.Errorf("Stat = %+v, %v", info, err)
+	}
+	if ids := c.Uploads(); len(ids) != 0 {
+		t.Errorf("uploads still open after completing: %v", ids)
+	}
+
+	// Aborting discards the parts
+	uploadID, err = c.CreateMultipartUpload("videos/clip2.mp4", "video/mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AbortMultipartUpload("videos/clip2.mp4", uploadID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UploadPart("videos/clip2.mp4", uploadID, 1, []byte("x")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UploadPart after abort: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryCopiesData(t *testing.T) {
+	c := NewMemoryClient()
+	data := []byte("original")
+	if err := c.UploadFile("a", data, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	copy(data, "modified")
+
+	r, err := c.GetObject("a")
+	if got := readAll(t, r, err); !bytes.Equal([]byte(got), []byte("original")) {
+		t.Errorf("stored object changed with the caller's buffer: %q", got)
+	}
+}
@@ -1,11 +1,11 @@
 import Link from 'next/link';
 import { useRouter } from 'next/router';
-import { useState } from 'react';
+import { useState, useEffect } from 'react';
 import { useAuth } from '../lib/auth';
 import { Button } from '@/components/ui/button';
 import { ThemeToggle } from '@/components/ui/theme-toggle';
 import { Card } from '@/components/ui/card';
-import { BarChart3, FileText, Users, Mail, Camera, MessageSquare, ExternalLink, LogOut, Menu, X } from 'lucide-react';
+import { BarChart3, FileText, Users, Mail, Camera, MessageSquare, ExternalLink, LogOut, Menu, Shield, X } from 'lucide-react';
 
 export default function AdminLayout({ children }) {
   const router = useRouter();
@@ -19,8 +19,16 @@ export default function AdminLayout({ children }) {
     { name: 'RSVPs', href: '/admin/rsvps', icon: Mail },
     { name: 'Photos', href: '/admin/photos', icon: Camera },
     { name: 'Messages', href: '/admin/messages', icon: MessageSquare },
+    { name: 'Security', href: '/admin/security', icon: Shield },
   ];
 
+  // Until required two-factor setup is done, the API refuses everything else
+  useEffect(() => {
+    if (user?.twoFactorSetupRequired && router.pathname !== '/admin/security') {
+      router.replace('/admin/security');
+    }
+  }, [user, router]);
+
   const handleLogout = async () => {
     await logout();
     router.push('/admin/login');
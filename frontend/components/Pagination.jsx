@@ -0,0 +1,36 @@
+export default function Pagination({ list, noun = 'items' }) {
+  const { page, pageSize, pageCount, total, items, hasPrev, hasNext, prev, next, loading } = list;
+  if (total === 0) {
+    return null;
+  }
+
+  const first = page * pageSize + 1;
+  const last = page * pageSize + items.length;
+
+  return (
+    <div className="flex items-center justify-between px-4 py-3 border-t text-sm text-gray-600">
+      <div>
+        Showing {first}–{last} of {total} {noun}
+      </div>
+      <div className="flex items-center space-x-3">
+        <span>
+          Page {page + 1} of {pageCount}
+        </span>
+        <button
+          onClick={prev}
+          disabled={!hasPrev || loading}
+          className="px-3 py-1 border rounded hover:bg-gray-50 disabled:opacity-50 disabled:cursor-not-allowed"
+        >
+          Previous
+        </button>
+        <button
+          onClick={next}
+          disabled={!hasNext || loading}
+          className="px-3 py-1 border rounded hover:bg-gray-50 disabled:opacity-50 disabled:cursor-not-allowed"
+        >
+          Next
+        </button>
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,32 @@
+import { useMemo } from 'react';
+import { encodeQR } from '../lib/qrcode';
+
+// QRCode draws text as an SVG QR code with the standard four-module quiet
+// zone, so it scans on light and dark themes alike.
+export default function QRCode({ value, size = 192, className = '' }) {
+  const { path, dimension } = useMemo(() => {
+    const modules = encodeQR(value);
+    let d = '';
+    modules.forEach((row, r) => {
+      row.forEach((dark, c) => {
+        if (dark) d += `M${c + 4} ${r + 4}h1v1h-1z`;
+      });
+    });
+    return { path: d, dimension: modules.length + 8 };
+  }, [value]);
+
+  return (
+    <svg
+      width={size}
+      height={size}
+      viewBox={`0 0 ${dimension} ${dimension}`}
+      shapeRendering="crispEdges"
+      role="img"
+      aria-label="QR code"
+      className={className}
+    >
+      <rect width={dimension} height={dimension} fill="#fff" />
+      <path d={path} fill="#000" />
+    </svg>
+  );
+}
@@ -0,0 +1,91 @@
+// Renders one admin-defined questionnaire question. `value` is the current
+// answer and `onChange` receives the new one in the shape the API expects.
+export default function QuestionField({ question, value, onChange, name }) {
+  const inputName = name || `question-${question.key}`;
+
+  const renderInput = () => {
+    switch (question.type) {
+      case 'boolean':
+        return (
+          <div className="flex gap-4">
+            {[true, false].map(option => (
+              <label key={String(option)} className="flex items-center">
+                <input
+                  type="radio"
+                  name={inputName}
+                  checked={value === option}
+                  onChange={() => onChange(option)}
+                  className="mr-2"
+                />
+                <span className="text-sm">{option ? 'Yes' : 'No'}</span>
+              </label>
+            ))}
+          </div>
+        );
+      case 'single_choice':
+        return (
+          <select
+            value={value || ''}
+            onChange={(e) => onChange(e.target.value || null)}
+            className="border border-gray-300 rounded px-2 py-1 text-sm w-full"
+          >
+            <option value="">Select...</option>
+            {(question.options || []).map(option => (
+              <option key={option} value={option}>{option}</option>
+            ))}
+          </select>
+        );
+      case 'multi_choice': {
+        const selected = Array.isArray(value) ? value : [];
+        return (
+          <div className="space-y-1">
+            {(question.options || []).map(option => (
+              <label key={option} className="flex items-center">
+                <input
+                  type="checkbox"
+                  checked={selected.includes(option)}
+                  onChange={(e) =>
+                    onChange(e.target.checked ? [...selected, option] : selected.filter(o => o !== option))
+                  }
+                  className="mr-2"
+                />
+                <span className="text-sm">{option}</span>
+              </label>
+            ))}
+          </div>
+        );
+      }
+      case 'number':
+        return (
+          <input
+            type="number"
+            value={value ?? ''}
+            min={question.min ?? undefined}
+            max={question.max ?? undefined}
+            onChange={(e) => onChange(e.target.value === '' ? null : Number(e.target.value))}
+            className="border border-gray-300 rounded px-2 py-1 text-sm w-full"
+          />
+        );
+      default:
+        return (
+          <input
+            type="text"
+            value={value || ''}
+            onChange={(e) => onChange(e.target.value)}
+            className="border border-gray-300 rounded px-2 py-1 text-sm w-full"
+          />
+        );
+    }
+  };
+
+  return (
+    <div>
+      <label className="block text-sm font-medium text-gray-700 mb-1">
+        {question.label}
+        {question.required && <span className="text-red-500"> *</span>}
+      </label>
+      {question.helpText && <p className="text-xs text-gray-500 mb-1">{question.helpText}</p>}
+      {renderInput()}
+    </div>
+  );
+}
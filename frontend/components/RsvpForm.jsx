@@ -1,5 +1,6 @@
 import { useState, useEffect } from 'react';
 import { apiClient } from '../lib/api';
+import QuestionField from './QuestionField';
 
 export default function RsvpForm({ token, guestData, onSubmitSuccess }) {
   const [formData, setFormData] = useState({
@@ -13,14 +14,17 @@ export default function RsvpForm({ token, guestData, onSubmitSuccess }) {
     partySize: 1,
     partyMembers: [],
     mainPersonDietaryPreference: '',
-    dec24Attendance: false,
-    dec25Attendance: false,
-    accommodationDec23: false,
-    accommodationDec24: false,
-    accommodationDec25: false,
+    events: {},
+    answers: {},
     concerns: ''
   });
 
+  const availableEvents = guestData?.availableEvents || [];
+  const attendanceEvents = availableEvents.filter(event => event.type !== 'accommodation');
+  const accommodationEvents = availableEvents.filter(event => event.type === 'accommodation');
+  const questions = guestData?.questions || [];
+  const memberQuestions = questions.filter(question => question.perMember);
+
   // Initialize updatedDetails when guestData is available
   useEffect(() => {
     if (guestData) {
@@ -29,11 +33,8 @@ export default function RsvpForm({ token, guestData, onSubmitSuccess }) {
         partySize: guestData.partySize || 1,
         partyMembers: Array.isArray(guestData.partyMembers) ? guestData.partyMembers : [],
         mainPersonDietaryPreference: guestData.mainPersonDietaryPreference || '',
-        dec24Attendance: guestData.dec24Attendance || false,
-        dec25Attendance: guestData.dec25Attendance || false,
-        accommodationDec23: guestData.accommodationDec23 || false,
-        accommodationDec24: guestData.accommodationDec24 || false,
-        accommodationDec25: guestData.accommodationDec25 || false,
+        events: { ...(guestData.events || {}) },
+        answers: { ...(guestData.answers || {}) },
         concerns: guestData.concerns || ''
       });
     }
@@ -74,6 +75,31 @@ export default function RsvpForm({ token, guestData, onSubmitSuccess }) {
     }));
   };
 
+  const handleEventChange = (slug, value) => {
+    setUpdatedDetails(prev => ({
+      ...prev,
+      events: { ...prev.events, [slug]: value }
+    }));
+  };
+
+  const handleAnswerChange = (key, value) => {
+    setUpdatedDetails(prev => ({
+      ...prev,
+      answers: { ...prev.answers, [key]: value }
+    }));
+  };
+
+  const handleMemberAnswerChange = (index, key, value) => {
+    setUpdatedDetails(prev => {
+      const newMembers = [...prev.partyMembers];
+      newMembers[index] = {
+        ...newMembers[index],
+        answers: { ...(newMembers[index].answers || {}), [key]: value }
+      };
+      return { ...prev, partyMembers: newMembers };
+    });
+  };
+
   const handlePartyMemberChange = (index, field, value) => {
     setUpdatedDetails(prev => {
       const newMembers = [...prev.partyMembers];
@@ -155,25 +181,28 @@ export default function RsvpForm({ token, guestData, onSubmitSuccess }) {
               <div className="space-y-1">
                 <div className="font-medium">Event Attendance:</div>
                 <div className="ml-4">
-                  <div>Dec 24 (Raaga Riti): {guestData.dec24Attendance ? '✓ Attending' : '✗ Not Attending'}</div>
-                  <div>Dec 25 (Wedding): {guestData.dec25Attendance ? '✓ Attending' : '✗ Not Attending'}</div>
+                  {attendanceEvents.map(event => (
+                    <div key={event.slug}>
+                      {event.name}: {guestData.events?.[event.slug] ? '✓ Attending' : '✗ Not Attending'}
+                    </div>
+                  ))}
                 </div>
               </div>
               
-              <div>
-                <span className="font-medium">Accommodation Needed:</span>
-                <div className="ml-4">
-                  {(guestData.accommodationDec23 || guestData.accommodationDec24 || guestData.accommodationDec25) ? (
-                    <>
-                      {guestData.accommodationDec23 && <div>✓ Dec 23rd</div>}
-                      {guestData.accommodationDec24 && <div>✓ Dec 24th</div>}
-                      {guestData.accommodationDec25 && <div>✓ Dec 25th</div>}
-                    </>
-                  ) : (
-                    <div className="text-gray-500">No accommodation needed</div>
-                  )}
+              {accommodationEvents.length > 0 && (
+                <div>
+                  <span className="font-medium">Accommodation Needed:</span>
+                  <div className="ml-4">
+                    {accommodationEvents.some(event => guestData.events?.[event.slug]) ? (
+                      accommodationEvents
+                        .filter(event => guestData.events?.[event.slug])
+                        .map(event => <div key={event.slug}>✓ {event.name}</div>)
+                    ) : (
+                      <div className="text-gray-500">No accommodation needed</div>
+                    )}
+                  </div>
                 </div>
-              </div>
+              )}
               
               {guestData.concerns && (
                 <div>
@@ -272,6 +301,19 @@ export default function RsvpForm({ token, guestData, onSubmitSuccess }) {
                           <span className="text-sm">Non Veg</span>
                         </label>
                       </div>
+                      {memberQuestions.length > 0 && (
+                        <div className="space-y-2 mt-2">
+                          {memberQuestions.map(question => (
+                            <QuestionField
+                              key={question.key}
+                              question={question}
+                              name={`member-${index}-${question.key}`}
+                              value={member.answers?.[question.key]}
+                              onChange={(value) => handleMemberAnswerChange(index, question.key, value)}
+                            />
+                          ))}
+                        </div>
+                      )}
                     </div>
                   ))}
                 </div>
@@ -281,60 +323,53 @@ export default function RsvpForm({ token, guestData, onSubmitSuccess }) {
               <div>
                 <label className="block text-sm font-medium text-gray-700 mb-2">Event Attendance</label>
                 <div className="space-y-2">
-                  <label className="flex items-center">
-                    <input
-                      type="checkbox"
-                      checked={updatedDetails.dec24Attendance}
-                      onChange={(e) => handleDetailChange('dec24Attendance', e.target.checked)}
-                      className="mr-2"
-                    />
-                    Dec 24 (Raaga Riti)
-                  </label>
-                  <label className="flex items-center">
-                    <input
-                      type="checkbox"
-                      checked={updatedDetails.dec25Attendance}
-                      onChange={(e) => handleDetailChange('dec25Attendance', e.target.checked)}
-                      className="mr-2"
-                    />
-                    Dec 25 (Wedding)
-                  </label>
+                  {attendanceEvents.map(event => (
+                    <label key={event.slug} className="flex items-center">
+                      <input
+                        type="checkbox"
+                        checked={!!updatedDetails.events[event.slug]}
+                        onChange={(e) => handleEventChange(event.slug, e.target.checked)}
+                        className="mr-2"
+                      />
+                      {event.name}
+                    </label>
+                  ))}
                 </div>
               </div>
 
               {/* Accommodation */}
-              <div>
-                <label className="block text-sm font-medium text-gray-700 mb-2">Accommodation Needed</label>
-                <div className="space-y-2">
-                  <label className="flex items-center">
-                    <input
-                      type="checkbox"
-                      checked={updatedDetails.accommodationDec23}
-                      onChange={(e) => handleDetailChange('accommodationDec23', e.target.checked)}
-                      className="mr-2"
-                    />
-                    Dec 23rd
-                  </label>
-                  <label className="flex items-center">
-                    <input
-                      type="checkbox"
-                      checked={updatedDetails.accommodationDec24}
-                      onChange={(e) => handleDetailChange('accommodationDec24', e.target.checked)}
-                      className="mr-2"
-                    />
-                    Dec 24th
-                  </label>
-                  <label className="flex items-center">
-                    <input
-                      type="checkbox"
-                      checked={updatedDetails.accommodationDec25}
-                      onChange={(e) => handleDetailChange('accommodationDec25', e.target.checked)}
-                      className="mr-2"
+              {accommodationEvents.length > 0 && (
+                <div>
+                  <label className="block text-sm font-medium text-gray-700 mb-2">Accommodation Needed</label>
+                  <div className="space-y-2">
+                    {accommodationEvents.map(event => (
+                      <label key={event.slug} className="flex items-center">
+                        <input
+                          type="checkbox"
+                          checked={!!updatedDetails.events[event.slug]}
+                          onChange={(e) => handleEventChange(event.slug, e.target.checked)}
+                          className="mr-2"
+                        />
+                        {event.name}
+                      </label>
+                    ))}
+                  </div>
+                </div>
+              )}
+
+              {/* Custom questions */}
+              {questions.length > 0 && (
+                <div className="space-y-3">
+                  {questions.map(question => (
+                    <QuestionField
+                      key={question.key}
+                      question={question}
+                      value={updatedDetails.answers[question.key]}
+                      onChange={(value) => handleAnswerChange(question.key, value)}
                     />
-                    Dec 25th
-                  </label>
+                  ))}
                 </div>
-              </div>
+              )}
 
               {/* Concerns */}
               <div>
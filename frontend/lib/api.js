@@ -28,16 +28,54 @@ apiClient.interceptors.request.use(
   }
 );
 
+// Store the tokens from a login or refresh
+export const saveTokens = ({ token, refreshToken }) => {
+  localStorage.setItem('authToken', token);
+  localStorage.setItem('refreshToken', refreshToken);
+};
+
+export const clearTokens = () => {
+  localStorage.removeItem('authToken');
+  localStorage.removeItem('refreshToken');
+};
+
+// Concurrent 401s share one refresh, since each refresh token works once
+let refreshing = null;
+
+const refreshTokens = () => {
+  if (!refreshing) {
+    const refreshToken = localStorage.getItem('refreshToken');
+    refreshing = axios
+      .post(`${API_BASE_URL}/api/auth/refresh`, { refreshToken })
+      .then((response) => saveTokens(response.data))
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+  return refreshing;
+};
+
 // Response interceptor for error handling
 apiClient.interceptors.response.use(
   (response) => response,
-  (error) => {
-    if (error.response?.status === 401) {
-      // Redirect to login if unauthorized
-      if (typeof window !== 'undefined') {
-        localStorage.removeItem('authToken');
-        window.location.href = '/admin/login';
+  async (error) => {
+    const { config, response } = error;
+    if (response?.status === 401 && typeof window !== 'undefined') {
+      // Access tokens are short-lived; try once to refresh before giving up
+      if (!config._retried && localStorage.getItem('refreshToken') &&
+          !['/api/auth/login', '/api/auth/login/2fa', '/api/auth/logout'].includes(config.url)) {
+        config._retried = true;
+        try {
+          await refreshTokens();
+          return apiClient(config);
+        } catch (refreshError) {
+          // Fall through to the login page
+        }
       }
+
+      // Redirect to login if unauthorized
+      clearTokens();
+      window.location.href = '/admin/login';
     }
     
     return Promise.reject(error);
@@ -48,8 +86,30 @@ apiClient.interceptors.response.use(
 export const api = {
   // Authentication
   login: (credentials) => apiClient.post('/api/auth/login', credentials),
-  logout: () => apiClient.post('/api/auth/logout'),
+  logout: () => apiClient.post('/api/auth/logout', {
+    refreshToken: localStorage.getItem('refreshToken'),
+  }),
   me: () => apiClient.get('/api/auth/me'),
+  changePassword: (data) => apiClient.put('/api/auth/password', data),
+  requestPasswordReset: (email) => apiClient.post('/api/auth/password-resets', { email }),
+  getPasswordReset: (token) => apiClient.get(`/api/auth/password-resets/${token}`),
+  resetPassword: (token, password) => apiClient.post(`/api/auth/password-resets/${token}/complete`, { password }),
+  getInvitation: (token) => apiClient.get(`/api/auth/invitations/${token}`),
+  getTwoFactorStatus: () => apiClient.get('/api/auth/2fa'),
+  startTwoFactorSetup: () => apiClient.post('/api/auth/2fa/setup'),
+  enableTwoFactor: (code) => apiClient.post('/api/auth/2fa/enable', { code }),
+  disableTwoFactor: (password, code) => apiClient.post('/api/auth/2fa/disable', { password, code }),
+  regenerateRecoveryCodes: (code) => apiClient.post('/api/auth/2fa/recovery-codes', { code }),
+  getUsers: () => apiClient.get('/api/users'),
+  resetUserTwoFactor: (userId) => apiClient.post(`/api/users/${userId}/2fa/reset`),
+  getSecuritySettings: () => apiClient.get('/api/settings/security'),
+  updateSecuritySettings: (settings) => apiClient.put('/api/settings/security', settings),
+  getSessions: () => apiClient.get('/api/auth/sessions'),
+  revokeSession: (id) => apiClient.delete(`/api/auth/sessions/${id}`),
+  revokeOtherSessions: () => apiClient.delete('/api/auth/sessions'),
+  getAllSessions: () => apiClient.get('/api/sessions'),
+  revokeAnySession: (id) => apiClient.delete(`/api/sessions/${id}`),
+  revokeUserSessions: (userId) => apiClient.delete(`/api/users/${userId}/sessions`),
 
   // Guests
   getGuests: () => apiClient.get('/api/guests'),
@@ -1,5 +1,5 @@
 import { useState, useEffect, useContext, createContext } from 'react';
-import { apiClient } from './api';
+import { apiClient, saveTokens, clearTokens } from './api';
 
 const AuthContext = createContext();
 
@@ -19,7 +19,7 @@ export function AuthProvider({ children }) {
         setUser(response.data);
       }
     } catch (error) {
-      localStorage.removeItem('authToken');
+      clearTokens();
     } finally {
       setLoading(false);
     }
@@ -28,9 +28,14 @@ export function AuthProvider({ children }) {
   const login = async (credentials) => {
     try {
       const response = await apiClient.post('/api/auth/login', credentials);
-      const { token, user } = response.data;
+      const { user, twoFactor } = response.data;
+
+      // Accounts with two-factor authentication need a code next
+      if (twoFactor) {
+        return { success: false, twoFactor };
+      }
       
-      localStorage.setItem('authToken', token);
+      saveTokens(response.data);
       setUser(user);
       
       return { success: true };
@@ -42,13 +47,47 @@ export function AuthProvider({ children }) {
     }
   };
 
+  const verifyTwoFactor = async (challengeToken, code) => {
+    try {
+      const response = await apiClient.post('/api/auth/login/2fa', { challengeToken, code });
+
+      saveTokens(response.data);
+      setUser(response.data.user);
+
+      return { success: true };
+    } catch (error) {
+      return {
+        success: false,
+        error: error.response?.data?.error || 'Verification failed'
+      };
+    }
+  };
+
+  const acceptInvitation = async (token, password) => {
+    try {
+      const response = await apiClient.post(`/api/auth/invitations/${token}/accept`, { password });
+
+      saveTokens(response.data);
+      setUser(response.data.user);
+
+      return { success: true };
+    } catch (error) {
+      return {
+        success: false,
+        error: error.response?.data?.error || 'Failed to create account'
+      };
+    }
+  };
+
   const logout = async () => {
     try {
-      await apiClient.post('/api/auth/logout');
+      await apiClient.post('/api/auth/logout', {
+        refreshToken: localStorage.getItem('refreshToken'),
+      });
     } catch (error) {
       // Ignore logout errors
     } finally {
-      localStorage.removeItem('authToken');
+      clearTokens();
       setUser(null);
     }
   };
@@ -56,7 +95,10 @@ export function AuthProvider({ children }) {
   const value = {
     user,
     login,
+    verifyTwoFactor,
+    acceptInvitation,
     logout,
+    refreshUser: checkAuth,
     loading,
     isAuthenticated: !!user,
   };
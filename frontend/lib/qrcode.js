@@ -0,0 +1,302 @@
+// A small QR code encoder (ISO/IEC 18004) for the authenticator setup
+// screen. It only does what that needs: byte mode, error correction level
+// M, and versions 1-10, which hold up to 213 bytes - plenty for an
+// otpauth:// URI.
+
+// Error correction per version at level M: codewords per block, then
+// [block count, data codewords per block] for each block group.
+const BLOCKS = [
+  null,
+  [10, [1, 16]],
+  [16, [1, 28]],
+  [26, [1, 44]],
+  [18, [2, 32]],
+  [24, [2, 43]],
+  [16, [4, 27]],
+  [18, [4, 31]],
+  [22, [2, 38], [2, 39]],
+  [22, [3, 36], [2, 37]],
+  [26, [4, 43], [1, 44]],
+];
+
+const ALIGNMENT = [
+  null, [], [6, 18], [6, 22], [6, 26], [6, 30], [6, 34],
+  [6, 22, 38], [6, 24, 42], [6, 26, 46], [6, 28, 50],
+];
+
+const MAX_VERSION = BLOCKS.length - 1;
+
+// Galois field GF(256) arithmetic for Reed-Solomon, with the QR polynomial
+// x^8 + x^4 + x^3 + x^2 + 1
+const EXP = new Array(512);
+const LOG = new Array(256);
+for (let i = 0, x = 1; i < 255; i++) {
+  EXP[i] = x;
+  LOG[x] = i;
+  x <<= 1;
+  if (x & 0x100) x ^= 0x11d;
+}
+for (let i = 255; i < 512; i++) EXP[i] = EXP[i - 255];
+
+const multiply = (a, b) => (a && b ? EXP[LOG[a] + LOG[b]] : 0);
+
+function generator(degree) {
+  let poly = [1];
+  for (let i = 0; i < degree; i++) {
+    const next = new Array(poly.length + 1).fill(0);
+    poly.forEach((c, j) => {
+      next[j] ^= c;
+      next[j + 1] ^= multiply(c, EXP[i]);
+    });
+    poly = next;
+  }
+  return poly;
+}
+
+function errorCorrection(data, degree) {
+  const gen = generator(degree);
+  const rem = new Array(degree).fill(0);
+  data.forEach((byte) => {
+    const factor = byte ^ rem.shift();
+    rem.push(0);
+    for (let i = 0; i < degree; i++) rem[i] ^= multiply(gen[i + 1], factor);
+  });
+  return rem;
+}
+
+const dataCapacity = (version) =>
+  BLOCKS[version].slice(1).reduce((sum, [count, size]) => sum + count * size, 0);
+
+// codewords encodes the bytes as one byte-mode segment, pads it to the
+// version's capacity and interleaves the blocks with their error correction.
+function codewords(bytes, version) {
+  const bits = [];
+  const push = (value, length) => {
+    for (let i = length - 1; i >= 0; i--) bits.push((value >>> i) & 1);
+  };
+
+  const capacity = dataCapacity(version) * 8;
+  push(0b0100, 4);
+  push(bytes.length, version < 10 ? 8 : 16);
+  bytes.forEach((b) => push(b, 8));
+  push(0, Math.min(4, capacity - bits.length));
+  push(0, (8 - (bits.length % 8)) % 8);
+  for (let pad = 0xec; bits.length < capacity; pad ^= 0xec ^ 0x11) push(pad, 8);
+
+  const data = [];
+  for (let i = 0; i < bits.length; i += 8) {
+    data.push(bits.slice(i, i + 8).reduce((byte, bit) => (byte << 1) | bit, 0));
+  }
+
+  const [ecLength, ...groups] = BLOCKS[version];
+  const blocks = [];
+  let offset = 0;
+  groups.forEach(([count, size]) => {
+    for (let i = 0; i < count; i++) {
+      const block = data.slice(offset, offset + size);
+      blocks.push({ data: block, ec: errorCorrection(block, ecLength) });
+      offset += size;
+    }
+  });
+
+  const result = [];
+  const longest = Math.max(...blocks.map((b) => b.data.length));
+  for (let i = 0; i < longest; i++) {
+    blocks.forEach((b) => i < b.data.length && result.push(b.data[i]));
+  }
+  for (let i = 0; i < ecLength; i++) {
+    blocks.forEach((b) => result.push(b.ec[i]));
+  }
+  return result;
+}
+
+// bch appends the BCH error correction bits of value, as used by the
+// format and version information.
+function bch(value, poly, length) {
+  const degree = Math.floor(Math.log2(poly));
+  let rem = value << degree;
+  for (let i = length - 1; i >= degree; i--) {
+    if ((rem >>> i) & 1) rem ^= poly << (i - degree);
+  }
+  return (value << degree) | rem;
+}
+
+const MASKS = [
+  (r, c) => (r + c) % 2 === 0,
+  (r) => r % 2 === 0,
+  (r, c) => c % 3 === 0,
+  (r, c) => (r + c) % 3 === 0,
+  (r, c) => (Math.floor(r / 2) + Math.floor(c / 3)) % 2 === 0,
+  (r, c) => ((r * c) % 2) + ((r * c) % 3) === 0,
+  (r, c) => (((r * c) % 2) + ((r * c) % 3)) % 2 === 0,
+  (r, c) => (((r + c) % 2) + ((r * c) % 3)) % 2 === 0,
+];
+
+function build(version, data, mask) {
+  const size = version * 4 + 17;
+  const modules = Array.from({ length: size }, () => new Array(size).fill(false));
+  const reserved = Array.from({ length: size }, () => new Array(size).fill(false));
+  const set = (r, c, dark) => {
+    modules[r][c] = dark;
+    reserved[r][c] = true;
+  };
+
+  // Finder patterns with their separators
+  [[0, 0], [0, size - 7], [size - 7, 0]].forEach(([top, left]) => {
+    for (let r = -1; r <= 7; r++) {
+      for (let c = -1; c <= 7; c++) {
+        const row = top + r;
+        const col = left + c;
+        if (row < 0 || row >= size || col < 0 || col >= size) continue;
+        const ring = Math.max(Math.abs(r - 3), Math.abs(c - 3));
+        set(row, col, ring !== 2 && ring !== 4);
+      }
+    }
+  });
+
+  // Alignment patterns, except where they would overlap a finder
+  const centers = ALIGNMENT[version];
+  centers.forEach((row) => {
+    centers.forEach((col) => {
+      if (reserved[row][col]) return;
+      for (let r = -2; r <= 2; r++) {
+        for (let c = -2; c <= 2; c++) {
+          set(row + r, col + c, Math.max(Math.abs(r), Math.abs(c)) !== 1);
+        }
+      }
+    });
+  });
+
+  // Timing patterns
+  for (let i = 8; i < size - 8; i++) {
+    set(6, i, i % 2 === 0);
+    set(i, 6, i % 2 === 0);
+  }
+
+  // Format information, with the dark module beside it
+  const format = bch((0b00 << 3) | mask, 0x537, 15) ^ 0x5412;
+  for (let i = 0; i < 15; i++) {
+    const dark = ((format >>> i) & 1) === 1;
+    if (i < 6) set(i, 8, dark);
+    else if (i < 8) set(i + 1, 8, dark);
+    else set(size - 15 + i, 8, dark);
+
+    if (i < 8) set(8, size - 1 - i, dark);
+    else if (i < 9) set(8, 15 - i, dark);
+    else set(8, 14 - i, dark);
+  }
+  set(size - 8, 8, true);
+
+  if (version >= 7) {
+    const info = bch(version, 0x1f25, 18);
+    for (let i = 0; i < 18; i++) {
+      const dark = ((info >>> i) & 1) === 1;
+      const a = Math.floor(i / 3);
+      const b = (i % 3) + size - 11;
+      set(a, b, dark);
+      set(b, a, dark);
+    }
+  }
+
+  // Data, in two-module columns zigzagging up and down from the bottom
+  // right, skipping the vertical timing pattern
+  let bit = 0;
+  let upward = true;
+  for (let right = size - 1; right >= 1; right -= 2) {
+    if (right === 6) right = 5;
+    for (let i = 0; i < size; i++) {
+      const row = upward ? size - 1 - i : i;
+      for (let col = right; col > right - 2; col--) {
+        if (reserved[row][col]) continue;
+        const byte = data[bit >>> 3];
+        const dark = byte !== undefined && ((byte >>> (7 - (bit & 7))) & 1) === 1;
+        modules[row][col] = dark !== MASKS[mask](row, col);
+        bit++;
+      }
+    }
+    upward = !upward;
+  }
+
+  return modules;
+}
+
+// penalty scores a masked symbol; the mask with the lowest score is used.
+function penalty(modules) {
+  const size = modules.length;
+  let score = 0;
+
+  const lines = [];
+  for (let i = 0; i < size; i++) {
+    lines.push(modules[i]);
+    lines.push(modules.map((row) => row[i]));
+  }
+
+  lines.forEach((line) => {
+    // Runs of five or more modules of one colour
+    let run = 1;
+    for (let i = 1; i <= size; i++) {
+      if (i < size && line[i] === line[i - 1]) {
+        run++;
+      } else {
+        if (run >= 5) score += run - 2;
+        run = 1;
+      }
+    }
+
+    // Patterns that look like a finder: 1:1:3:1:1 with four light
+    // modules on one side
+    const text = line.map((dark) => (dark ? '1' : '0')).join('');
+    for (const pattern of ['10111010000', '00001011101']) {
+      for (let i = text.indexOf(pattern); i !== -1; i = text.indexOf(pattern, i + 1)) {
+        score += 40;
+      }
+    }
+  });
+
+  // 2x2 blocks of one colour
+  for (let r = 0; r < size - 1; r++) {
+    for (let c = 0; c < size - 1; c++) {
+      const dark = modules[r][c];
+      if (modules[r][c + 1] === dark && modules[r + 1][c] === dark && modules[r + 1][c + 1] === dark) {
+        score += 3;
+      }
+    }
+  }
+
+  // Imbalance between dark and light
+  const dark = modules.reduce((sum, row) => sum + row.filter(Boolean).length, 0);
+  score += Math.floor(Math.abs((dark * 20) / (size * size) - 10)) * 10;
+
+  return score;
+}
+
+// encodeQR returns the QR code for text as rows of booleans, true for a
+// dark module. The quiet zone around it is left to the caller.
+export function encodeQR(text, mask) {
+  const bytes = Array.from(new TextEncoder().encode(text));
+
+  let version = 1;
+  while (version <= MAX_VERSION && bytes.length + (version < 10 ? 2 : 3) > dataCapacity(version)) {
+    version++;
+  }
+  if (version > MAX_VERSION) {
+    throw new Error('Text is too long for a QR code');
+  }
+
+  const data = codewords(bytes, version);
+  if (mask !== undefined) {
+    return build(version, data, mask);
+  }
+
+  let best = null;
+  let bestScore = Infinity;
+  MASKS.forEach((_, m) => {
+    const modules = build(version, data, m);
+    const score = penalty(modules);
+    if (score < bestScore) {
+      best = modules;
+      bestScore = score;
+    }
+  });
+  return best;
+}
@@ -0,0 +1,86 @@
+import { useState, useEffect, useCallback } from 'react';
+import { apiClient } from './api';
+
+export const PAGE_SIZE = 50;
+
+// Load a paginated list endpoint one page at a time. The server only hands
+// out a cursor for the next page, so the cursors of pages already visited
+// are kept to step back through them.
+export function usePagedList(url, params = {}, pageSize = PAGE_SIZE) {
+  const [cursors, setCursors] = useState(['']);
+  const [page, setPage] = useState(0);
+  const [data, setData] = useState({ items: [], total: 0, nextCursor: '' });
+  const [loading, setLoading] = useState(true);
+  const [reloads, setReloads] = useState(0);
+  const paramsKey = JSON.stringify(params);
+
+  // Different filters mean different pages; start again from the first
+  useEffect(() => {
+    setCursors(['']);
+    setPage(0);
+  }, [url, paramsKey]);
+
+  useEffect(() => {
+    let cancelled = false;
+    const cursor = cursors[page];
+
+    const load = async () => {
+      setLoading(true);
+      try {
+        const response = await apiClient.get(url, {
+          params: { ...params, limit: pageSize, ...(cursor ? { cursor } : {}) },
+        });
+        if (cancelled) return;
+        setData(response.data);
+        setCursors((prev) => {
+          const next = prev.slice(0, page + 1);
+          if (response.data.nextCursor) {
+            next.push(response.data.nextCursor);
+          }
+          return next;
+        });
+      } catch (error) {
+        if (!cancelled) {
+          console.error(`Error fetching ${url}:`, error);
+          setData({ items: [], total: 0, nextCursor: '' });
+        }
+      }
+      if (!cancelled) {
+        setLoading(false);
+      }
+    };
+
+    load();
+    return () => {
+      cancelled = true;
+    };
+    // cursors is left out on purpose: it is updated from the response
+  }, [url, paramsKey, page, pageSize, reloads]);
+
+  const next = useCallback(() => {
+    if (data.nextCursor) {
+      setPage((p) => p + 1);
+    }
+  }, [data.nextCursor]);
+
+  const prev = useCallback(() => {
+    setPage((p) => Math.max(0, p - 1));
+  }, []);
+
+  const reload = useCallback(() => setReloads((n) => n + 1), []);
+
+  return {
+    data,
+    items: data.items || [],
+    total: data.total || 0,
+    page,
+    pageSize,
+    pageCount: Math.max(1, Math.ceil((data.total || 0) / pageSize)),
+    hasPrev: page > 0,
+    hasNext: !!data.nextCursor,
+    next,
+    prev,
+    reload,
+    loading,
+  };
+}
@@ -2,31 +2,73 @@ import React, { useState, useEffect } from 'react';
 import Head from 'next/head';
 import AdminLayout from '../../components/AdminLayout';
 import ProtectedRoute from '../../components/ProtectedRoute';
+import Pagination from '../../components/Pagination';
 import { apiClient } from '../../lib/api';
+import { usePagedList } from '../../lib/usePagedList';
+
+// Only show approved guests
+const approvedOnly = { q: 'status:approved' };
 
 export default function AdminGuests() {
-  const [guests, setGuests] = useState([]);
-  const [loading, setLoading] = useState(true);
+  const list = usePagedList('/api/guests', approvedOnly);
+  const { items: guests, loading } = list;
   const [selectedGuests, setSelectedGuests] = useState(new Set());
   const [expandedGuests, setExpandedGuests] = useState(new Set());
+  const [events, setEvents] = useState([]);
+  const [stats, setStats] = useState({
+    total: 0,
+    rsvpYes: 0,
+    rsvpNo: 0,
+    pending: 0,
+    totalAttending: 0
+  });
 
   useEffect(() => {
-    fetchGuests();
+    fetchEvents();
+    fetchStats();
   }, []);
 
-  const fetchGuests = async () => {
+  const fetchEvents = async () => {
+    try {
+      const response = await apiClient.get('/api/events');
+      setEvents(response.data);
+    } catch (error) {
+      console.error('Error fetching events:', error);
+    }
+  };
+
+  // Totals come from the server, since only one page of guests is loaded
+  const countGuests = async (q) => {
+    const response = await apiClient.get('/api/guests', { params: { limit: 1, q } });
+    return response.data.total;
+  };
+
+  const fetchStats = async () => {
     try {
-      const response = await apiClient.get('/api/guests');
-      // Filter to only show approved guests
-      const approvedGuests = response.data.filter(guest => guest.registrationStatus === 'approved');
-      setGuests(approvedGuests);
-      setLoading(false);
+      const [total, rsvpYes, rsvpNo, pending, rsvps] = await Promise.all([
+        countGuests('status:approved'),
+        countGuests('status:approved rsvp:yes'),
+        countGuests('status:approved rsvp:no'),
+        countGuests('status:approved rsvp:pending'),
+        apiClient.get('/api/rsvps', { params: { limit: 1 } }),
+      ]);
+      setStats({
+        total,
+        rsvpYes,
+        rsvpNo,
+        pending,
+        totalAttending: rsvps.data.stats?.totalAttending || 0
+      });
     } catch (error) {
-      console.error('Error fetching guests:', error);
-      setLoading(false);
+      console.error('Error fetching guest stats:', error);
     }
   };
 
+  const fetchGuests = () => {
+    list.reload();
+    fetchStats();
+  };
+
   const handleSelectGuest = (guestId, checked) => {
     setSelectedGuests(prev => {
       const newSet = new Set(prev);
@@ -99,14 +141,6 @@ This action CANNOT be undone.`;
     }
   };
 
-  const stats = {
-    total: guests.length,
-    rsvpYes: guests.filter(g => g.rsvpStatus === 'yes').length,
-    rsvpNo: guests.filter(g => g.rsvpStatus === 'no').length,
-    pending: guests.filter(g => g.rsvpStatus === 'pending').length,
-    totalAttending: guests.reduce((sum, g) => sum + (g.rsvpStatus === 'yes' ? g.partySize || 1 : 0), 0)
-  };
-
   return (
     <ProtectedRoute>
       <AdminLayout>
@@ -162,7 +196,7 @@ This action CANNOT be undone.`;
           <div className="bg-white rounded-lg shadow">
             <div className="p-4 border-b">
               <h2 className="text-lg font-semibold">
-                Guest List ({guests.length} approved guests)
+                Guest List ({list.total} approved guests)
               </h2>
               <p className="text-sm text-gray-600 mt-1">
                 These guests have been approved and can access their RSVP and portal links.
@@ -341,22 +375,16 @@ This action CANNOT be undone.`;
                                   <div>
                                     <span className="font-medium text-gray-700">Event Attendance:</span>
                                     <div className="mt-1 space-y-1">
-                                      <div>
-                                        <span className="text-sm font-medium">Dec 24 (Raaga Riti):</span>
-                                        <span className={`ml-2 px-2 py-1 text-xs rounded-full ${
-                                          guest.dec24Attendance ? 'bg-green-100 text-green-800' : 'bg-red-100 text-red-800'
-                                        }`}>
-                                          {guest.dec24Attendance ? 'Attending' : 'Not Attending'}
-                                        </span>
-                                      </div>
-                                      <div>
-                                        <span className="text-sm font-medium">Dec 25 (Wedding):</span>
-                                        <span className={`ml-2 px-2 py-1 text-xs rounded-full ${
-                                          guest.dec25Attendance ? 'bg-green-100 text-green-800' : 'bg-red-100 text-red-800'
-                                        }`}>
-                                          {guest.dec25Attendance ? 'Attending' : 'Not Attending'}
-                                        </span>
-                                      </div>
+                                      {events.filter(event => event.type !== 'accommodation').map(event => (
+                                        <div key={event.slug}>
+                                          <span className="text-sm font-medium">{event.name}:</span>
+                                          <span className={`ml-2 px-2 py-1 text-xs rounded-full ${
+                                            guest.events?.[event.slug] ? 'bg-green-100 text-green-800' : 'bg-red-100 text-red-800'
+                                          }`}>
+                                            {guest.events?.[event.slug] ? 'Attending' : 'Not Attending'}
+                                          </span>
+                                        </div>
+                                      ))}
                                     </div>
                                   </div>
                                   
@@ -364,17 +392,13 @@ This action CANNOT be undone.`;
                                   <div>
                                     <span className="font-medium text-gray-700">Accommodation:</span>
                                     <div className="mt-1">
-                                      {(guest.accommodationDec23 || guest.accommodationDec24 || guest.accommodationDec25) ? (
+                                      {events.some(event => event.type === 'accommodation' && guest.events?.[event.slug]) ? (
                                         <div className="space-y-1">
-                                          {guest.accommodationDec23 && (
-                                            <div className="text-sm text-green-600">✓ Dec 23rd</div>
-                                          )}
-                                          {guest.accommodationDec24 && (
-                                            <div className="text-sm text-green-600">✓ Dec 24th</div>
-                                          )}
-                                          {guest.accommodationDec25 && (
-                                            <div className="text-sm text-green-600">✓ Dec 25th</div>
-                                          )}
+                                          {events
+                                            .filter(event => event.type === 'accommodation' && guest.events?.[event.slug])
+                                            .map(event => (
+                                              <div key={event.slug} className="text-sm text-green-600">✓ {event.name}</div>
+                                            ))}
                                         </div>
                                       ) : (
                                         <span className="text-sm text-gray-500">No accommodation needed</span>
@@ -402,6 +426,7 @@ This action CANNOT be undone.`;
                 </table>
               </div>
             )}
+            <Pagination list={list} noun="guests" />
           </div>
 
           <div className="mt-6 bg-blue-50 border border-blue-200 rounded-lg p-4">
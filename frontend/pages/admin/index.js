@@ -21,20 +21,25 @@ export default function AdminDashboard() {
 
   const fetchStats = async () => {
     try {
-      // Fetch guest stats
-      const guestsResponse = await apiClient.get('/api/guests');
-      const guests = guestsResponse.data;
-      
+      // Fetch guest stats (totals only, so a single item per page is enough)
+      const [guests, confirmedGuests, pendingGuests] = await Promise.all([
+        apiClient.get('/api/guests', { params: { limit: 1 } }),
+        apiClient.get('/api/guests', { params: { limit: 1, q: 'rsvp:yes' } }),
+        apiClient.get('/api/guests', { params: { limit: 1, q: 'rsvp:pending' } }),
+      ]);
+
       // Fetch photo stats
-      const photosResponse = await apiClient.get('/api/admin/photos');
-      const photos = photosResponse.data;
+      const [photos, pendingPhotos] = await Promise.all([
+        apiClient.get('/api/admin/photos', { params: { limit: 1 } }),
+        apiClient.get('/api/admin/photos', { params: { limit: 1, status: 'pending' } }),
+      ]);
 
       setStats({
-        totalGuests: guests.length,
-        confirmedGuests: guests.filter(g => g.rsvpStatus === 'yes').length,
-        pendingGuests: guests.filter(g => g.rsvpStatus === 'pending').length,
-        totalPhotos: photos.length,
-        pendingPhotos: photos.filter(p => p.status === 'pending').length
+        totalGuests: guests.data.total,
+        confirmedGuests: confirmedGuests.data.total,
+        pendingGuests: pendingGuests.data.total,
+        totalPhotos: photos.data.total,
+        pendingPhotos: pendingPhotos.data.total
       });
       setLoading(false);
     } catch (error) {
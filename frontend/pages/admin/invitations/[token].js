@@ -0,0 +1,168 @@
+import { useState, useEffect } from 'react';
+import { useRouter } from 'next/router';
+import Head from 'next/head';
+import { api } from '../../../lib/api';
+import { useAuth } from '../../../lib/auth';
+
+const roleNames = {
+  owner: 'Owner',
+  planner: 'Planner',
+  'photo-moderator': 'Photo moderator',
+  viewer: 'Viewer',
+};
+
+export default function AcceptInvitation() {
+  const router = useRouter();
+  const { token } = router.query;
+  const { acceptInvitation } = useAuth();
+  const [invitation, setInvitation] = useState(null);
+  const [password, setPassword] = useState('');
+  const [confirmation, setConfirmation] = useState('');
+  const [loading, setLoading] = useState(true);
+  const [submitting, setSubmitting] = useState(false);
+  const [error, setError] = useState('');
+  const [invalid, setInvalid] = useState('');
+
+  useEffect(() => {
+    if (token) {
+      fetchInvitation();
+    }
+  }, [token]);
+
+  const fetchInvitation = async () => {
+    try {
+      const response = await api.getInvitation(token);
+      setInvitation(response.data);
+    } catch (err) {
+      setInvalid(err.response?.data?.error || 'This invitation is invalid or has expired');
+    }
+    setLoading(false);
+  };
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setError('');
+    if (password !== confirmation) {
+      setError('The passwords do not match');
+      return;
+    }
+
+    setSubmitting(true);
+    const result = await acceptInvitation(token, password);
+    if (result.success) {
+      router.push('/admin');
+      return;
+    }
+    setError(result.error);
+    setSubmitting(false);
+  };
+
+  if (loading) {
+    return (
+      <div className="min-h-screen flex items-center justify-center">
+        <div className="text-xl">Loading...</div>
+      </div>
+    );
+  }
+
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
+      <Head>
+        <title>Accept Invitation - Wedding Website</title>
+        <meta name="robots" content="noindex, nofollow" />
+      </Head>
+
+      <div className="max-w-md w-full space-y-8">
+        <div>
+          <h2 className="mt-6 text-center text-3xl font-extrabold text-gray-900">
+            Accept Invitation
+          </h2>
+          {invitation && (
+            <p className="mt-2 text-center text-sm text-gray-600">
+              You&apos;ve been invited to help manage the wedding website as{' '}
+              <span className="font-medium">{roleNames[invitation.role] || invitation.role}</span>.
+              Choose a password to create your account.
+            </p>
+          )}
+        </div>
+
+        {invalid ? (
+          <div className="rounded-md bg-red-50 p-4 text-center">
+            <div className="text-sm text-red-700">{invalid}</div>
+            <div className="mt-2 text-sm text-red-700">Ask whoever invited you to send a new invitation.</div>
+          </div>
+        ) : (
+          <form className="mt-8 space-y-6" onSubmit={handleSubmit}>
+            <div className="rounded-md shadow-sm -space-y-px">
+              <div>
+                <label htmlFor="email" className="sr-only">
+                  Email
+                </label>
+                <input
+                  id="email"
+                  name="email"
+                  type="email"
+                  autoComplete="username"
+                  className="appearance-none rounded-none relative block w-full px-3 py-2 border border-gray-300 bg-gray-100 text-gray-500 rounded-t-md sm:text-sm"
+                  value={invitation?.email || ''}
+                  readOnly
+                />
+              </div>
+              <div>
+                <label htmlFor="password" className="sr-only">
+                  Password
+                </label>
+                <input
+                  id="password"
+                  name="password"
+                  type="password"
+                  autoComplete="new-password"
+                  required
+                  minLength={10}
+                  className="appearance-none rounded-none relative block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 focus:outline-none focus:ring-blue-500 focus:border-blue-500 focus:z-10 sm:text-sm"
+                  placeholder="Password (at least 10 characters)"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  disabled={submitting}
+                />
+              </div>
+              <div>
+                <label htmlFor="confirmation" className="sr-only">
+                  Confirm password
+                </label>
+                <input
+                  id="confirmation"
+                  name="confirmation"
+                  type="password"
+                  autoComplete="new-password"
+                  required
+                  className="appearance-none rounded-none relative block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 rounded-b-md focus:outline-none focus:ring-blue-500 focus:border-blue-500 focus:z-10 sm:text-sm"
+                  placeholder="Confirm password"
+                  value={confirmation}
+                  onChange={(e) => setConfirmation(e.target.value)}
+                  disabled={submitting}
+                />
+              </div>
+            </div>
+
+            {error && (
+              <div className="rounded-md bg-red-50 p-4">
+                <div className="text-sm text-red-700">{error}</div>
+              </div>
+            )}
+
+            <div>
+              <button
+                type="submit"
+                disabled={submitting}
+                className="group relative w-full flex justify-center py-2 px-4 border border-transparent text-sm font-medium rounded-md text-white bg-blue-600 hover:bg-blue-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 disabled:opacity-50 disabled:cursor-not-allowed"
+              >
+                {submitting ? 'Creating account...' : 'Create account'}
+              </button>
+            </div>
+          </form>
+        )}
+      </div>
+    </div>
+  );
+}
@@ -1,6 +1,7 @@
 import { useState, useEffect } from 'react';
 import { useRouter } from 'next/router';
 import Head from 'next/head';
+import Link from 'next/link';
 import { useAuth } from '../../lib/auth';
 
 export default function AdminLogin() {
@@ -10,7 +11,9 @@ export default function AdminLogin() {
   });
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
-  const { login, isAuthenticated } = useAuth();
+  const [challenge, setChallenge] = useState(null);
+  const [code, setCode] = useState('');
+  const { login, verifyTwoFactor, isAuthenticated } = useAuth();
   const router = useRouter();
 
   useEffect(() => {
@@ -24,10 +27,14 @@ export default function AdminLogin() {
     setError('');
     setLoading(true);
 
-    const result = await login(credentials);
+    const result = challenge
+      ? await verifyTwoFactor(challenge.challengeToken, code)
+      : await login(credentials);
     
     if (result.success) {
       router.push('/admin');
+    } else if (result.twoFactor) {
+      setChallenge(result.twoFactor);
     } else {
       setError(result.error);
     }
@@ -59,6 +66,26 @@ export default function AdminLogin() {
         </div>
         
         <form className="mt-8 space-y-6" onSubmit={handleSubmit}>
+          {challenge ? (
+            <div>
+              <label htmlFor="code" className="block text-sm text-gray-700 mb-2">
+                Enter the code from your authenticator app, or a recovery code
+              </label>
+              <input
+                id="code"
+                name="code"
+                type="text"
+                autoComplete="one-time-code"
+                autoFocus
+                required
+                className="appearance-none rounded-md relative block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 focus:outline-none focus:ring-blue-500 focus:border-blue-500 sm:text-sm"
+                placeholder="123456"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                disabled={loading}
+              />
+            </div>
+          ) : (
           <div className="rounded-md shadow-sm -space-y-px">
             <div>
               <label htmlFor="email" className="sr-only">
@@ -93,6 +120,7 @@ export default function AdminLogin() {
               />
             </div>
           </div>
+          )}
 
           {error && (
             <div className="rounded-md bg-red-50 p-4">
@@ -106,9 +134,17 @@ export default function AdminLogin() {
               disabled={loading}
               className="group relative w-full flex justify-center py-2 px-4 border border-transparent text-sm font-medium rounded-md text-white bg-blue-600 hover:bg-blue-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 disabled:opacity-50 disabled:cursor-not-allowed"
             >
-              {loading ? 'Signing in...' : 'Sign in'}
+              {loading ? 'Signing in...' : challenge ? 'Verify' : 'Sign in'}
             </button>
           </div>
+
+          {!challenge && (
+            <div className="text-center">
+              <Link href="/admin/reset-password" className="text-sm text-blue-600 hover:text-blue-500">
+                Forgot your password?
+              </Link>
+            </div>
+          )}
         </form>
       </div>
     </div>
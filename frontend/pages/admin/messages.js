@@ -1,37 +1,12 @@
-import { useState, useEffect } from 'react';
 import Head from 'next/head';
 import AdminLayout from '../../components/AdminLayout';
 import ProtectedRoute from '../../components/ProtectedRoute';
-import { apiClient } from '../../lib/api';
+import Pagination from '../../components/Pagination';
+import { usePagedList } from '../../lib/usePagedList';
 
 export default function AdminMessages() {
-  const [messages, setMessages] = useState([]);
-  const [loading, setLoading] = useState(true);
-
-  useEffect(() => {
-    fetchMessages();
-  }, []);
-
-  const fetchMessages = async () => {
-    try {
-      const response = await apiClient.get('/api/messages');
-      console.log('Messages API response:', response.data);
-      
-      let messagesData = [];
-      if (Array.isArray(response.data)) {
-        messagesData = response.data;
-      } else if (response.data && Array.isArray(response.data.messages)) {
-        messagesData = response.data.messages;
-      }
-      
-      setMessages(messagesData);
-      setLoading(false);
-    } catch (error) {
-      console.error('Error fetching messages:', error);
-      setMessages([]);
-      setLoading(false);
-    }
-  };
+  const list = usePagedList('/api/messages');
+  const { items: messages, total, loading } = list;
 
   return (
     <ProtectedRoute>
@@ -52,7 +27,7 @@ export default function AdminMessages() {
             <div className="p-4 border-b">
               <div className="flex justify-between items-center">
                 <h2 className="text-lg font-semibold">
-                  Messages ({messages.length})
+                  Messages ({total})
                 </h2>
               </div>
             </div>
@@ -118,6 +93,7 @@ export default function AdminMessages() {
                 ))}
               </div>
             )}
+            <Pagination list={list} noun="messages" />
           </div>
         </div>
       </AdminLayout>
@@ -2,11 +2,13 @@ import { useState, useEffect } from 'react';
 import Head from 'next/head';
 import AdminLayout from '../../components/AdminLayout';
 import ProtectedRoute from '../../components/ProtectedRoute';
+import Pagination from '../../components/Pagination';
 import { apiClient } from '../../lib/api';
+import { usePagedList } from '../../lib/usePagedList';
 
 export default function AdminPhotos() {
-  const [photos, setPhotos] = useState([]);
-  const [loading, setLoading] = useState(true);
+  const list = usePagedList('/api/admin/photos');
+  const { items: photos, loading } = list;
   const [pendingPhotos, setPendingPhotos] = useState([]);
   const [selectedPhotos, setSelectedPhotos] = useState(new Set());
   const [selectedPendingPhotos, setSelectedPendingPhotos] = useState(new Set());
@@ -14,21 +16,11 @@ export default function AdminPhotos() {
   const [loadingBulkAction, setLoadingBulkAction] = useState(false);
 
   useEffect(() => {
-    fetchPhotos();
     fetchPendingPhotos();
     fetchAutoApproveSettings();
   }, []);
 
-  const fetchPhotos = async () => {
-    try {
-      const response = await apiClient.get('/api/admin/photos');
-      setPhotos(response.data);
-      setLoading(false);
-    } catch (error) {
-      console.error('Error fetching photos:', error);
-      setLoading(false);
-    }
-  };
+  const fetchPhotos = list.reload;
 
   const fetchPendingPhotos = async () => {
     try {
@@ -63,7 +55,7 @@ export default function AdminPhotos() {
     
     try {
       await apiClient.delete(`/api/admin/photos/${photoId}`);
-      setPhotos(prev => prev.filter(p => p.id !== photoId));
+      fetchPhotos();
     } catch (error) {
       alert('Error deleting photo');
     }
@@ -166,8 +158,8 @@ export default function AdminPhotos() {
         setPendingPhotos(prev => prev.filter(p => !selected.has(p.id)));
         setSelectedPendingPhotos(new Set());
       } else {
-        setPhotos(prev => prev.filter(p => !selected.has(p.id)));
         setSelectedPhotos(new Set());
+        fetchPhotos();
       }
       
       alert(`Successfully deleted ${selected.size} photos`);
@@ -322,7 +314,7 @@ export default function AdminPhotos() {
         <div>
           <div className="flex justify-between items-center mb-4">
             <h2 className="text-xl font-semibold">
-              Approved Photos ({photos.length})
+              Approved Photos ({list.total})
             </h2>
             
             {photos.length > 0 && (
@@ -410,6 +402,10 @@ export default function AdminPhotos() {
               No approved photos yet.
             </div>
           )}
+
+          <div className="mt-4 bg-white rounded-lg shadow">
+            <Pagination list={list} noun="photos" />
+          </div>
         </div>
         </div>
       </AdminLayout>
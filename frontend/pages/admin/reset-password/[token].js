@@ -0,0 +1,162 @@
+import { useState, useEffect } from 'react';
+import { useRouter } from 'next/router';
+import Head from 'next/head';
+import Link from 'next/link';
+import { api, clearTokens } from '../../../lib/api';
+
+export default function ResetPassword() {
+  const router = useRouter();
+  const { token } = router.query;
+  const [reset, setReset] = useState(null);
+  const [password, setPassword] = useState('');
+  const [confirmation, setConfirmation] = useState('');
+  const [loading, setLoading] = useState(true);
+  const [submitting, setSubmitting] = useState(false);
+  const [done, setDone] = useState(false);
+  const [error, setError] = useState('');
+  const [invalid, setInvalid] = useState('');
+
+  useEffect(() => {
+    if (token) {
+      fetchReset();
+    }
+  }, [token]);
+
+  const fetchReset = async () => {
+    try {
+      const response = await api.getPasswordReset(token);
+      setReset(response.data);
+    } catch (err) {
+      setInvalid(err.response?.data?.error || 'This reset link is invalid or has expired');
+    }
+    setLoading(false);
+  };
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setError('');
+    if (password !== confirmation) {
+      setError('The passwords do not match');
+      return;
+    }
+
+    setSubmitting(true);
+    try {
+      await api.resetPassword(token, password);
+      // Every session was ended, including any in this browser
+      clearTokens();
+      setDone(true);
+    } catch (err) {
+      setError(err.response?.data?.error || 'Failed to reset password');
+    }
+    setSubmitting(false);
+  };
+
+  if (loading) {
+    return (
+      <div className="min-h-screen flex items-center justify-center">
+        <div className="text-xl">Loading...</div>
+      </div>
+    );
+  }
+
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
+      <Head>
+        <title>Reset Password - Wedding Website</title>
+        <meta name="robots" content="noindex, nofollow" />
+      </Head>
+
+      <div className="max-w-md w-full space-y-8">
+        <div>
+          <h2 className="mt-6 text-center text-3xl font-extrabold text-gray-900">
+            Reset Password
+          </h2>
+          {reset && !done && (
+            <p className="mt-2 text-center text-sm text-gray-600">
+              Choose a new password for {reset.email}
+            </p>
+          )}
+        </div>
+
+        {invalid ? (
+          <div className="text-center space-y-4">
+            <div className="rounded-md bg-red-50 p-4">
+              <div className="text-sm text-red-700">{invalid}</div>
+            </div>
+            <Link href="/admin/reset-password" className="text-sm text-blue-600 hover:text-blue-500">
+              Request a new link
+            </Link>
+          </div>
+        ) : done ? (
+          <div className="text-center space-y-4">
+            <div className="rounded-md bg-green-50 p-4">
+              <div className="text-sm text-green-700">
+                Your password has been reset and you have been signed out everywhere.
+              </div>
+            </div>
+            <Link href="/admin/login" className="text-sm text-blue-600 hover:text-blue-500">
+              Sign in with your new password
+            </Link>
+          </div>
+        ) : (
+          <form className="mt-8 space-y-6" onSubmit={handleSubmit}>
+            <div className="rounded-md shadow-sm -space-y-px">
+              <div>
+                <label htmlFor="password" className="sr-only">
+                  New password
+                </label>
+                <input
+                  id="password"
+                  name="password"
+                  type="password"
+                  autoComplete="new-password"
+                  required
+                  minLength={10}
+                  className="appearance-none rounded-none relative block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 rounded-t-md focus:outline-none focus:ring-blue-500 focus:border-blue-500 focus:z-10 sm:text-sm"
+                  placeholder="New password (at least 10 characters)"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  disabled={submitting}
+                />
+              </div>
+              <div>
+                <label htmlFor="confirmation" className="sr-only">
+                  Confirm new password
+                </label>
+                <input
+                  id="confirmation"
+                  name="confirmation"
+                  type="password"
+                  autoComplete="new-password"
+                  required
+                  className="appearance-none rounded-none relative block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 rounded-b-md focus:outline-none focus:ring-blue-500 focus:border-blue-500 focus:z-10 sm:text-sm"
+                  placeholder="Confirm new password"
+                  value={confirmation}
+                  onChange={(e) => setConfirmation(e.target.value)}
+                  disabled={submitting}
+                />
+              </div>
+            </div>
+
+            {error && (
+              <div className="rounded-md bg-red-50 p-4">
+                <div className="text-sm text-red-700">{error}</div>
+              </div>
+            )}
+
+            <div>
+              <button
+                type="submit"
+                disabled={submitting}
+                className="group relative w-full flex justify-center py-2 px-4 border border-transparent text-sm font-medium rounded-md text-white bg-blue-600 hover:bg-blue-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 disabled:opacity-50 disabled:cursor-not-allowed"
+              >
+                {submitting ? 'Saving...' : 'Set new password'}
+              </button>
+            </div>
+          </form>
+        )}
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,93 @@
+import { useState } from 'react';
+import Head from 'next/head';
+import Link from 'next/link';
+import { api } from '../../../lib/api';
+
+export default function ForgotPassword() {
+  const [email, setEmail] = useState('');
+  const [loading, setLoading] = useState(false);
+  const [sent, setSent] = useState(false);
+  const [error, setError] = useState('');
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setError('');
+    setLoading(true);
+    try {
+      await api.requestPasswordReset(email);
+      setSent(true);
+    } catch (err) {
+      setError(err.response?.data?.error || 'Failed to request a reset link');
+    }
+    setLoading(false);
+  };
+
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
+      <Head>
+        <title>Forgot Password - Wedding Website</title>
+      </Head>
+
+      <div className="max-w-md w-full space-y-8">
+        <div>
+          <h2 className="mt-6 text-center text-3xl font-extrabold text-gray-900">
+            Forgot Password
+          </h2>
+          <p className="mt-2 text-center text-sm text-gray-600">
+            We&apos;ll email you a link to choose a new one
+          </p>
+        </div>
+
+        {sent ? (
+          <div className="rounded-md bg-green-50 p-4">
+            <div className="text-sm text-green-700">
+              If {email} has an account, a reset link is on its way. It works for one hour.
+            </div>
+          </div>
+        ) : (
+          <form className="mt-8 space-y-6" onSubmit={handleSubmit}>
+            <div>
+              <label htmlFor="email" className="sr-only">
+                Email
+              </label>
+              <input
+                id="email"
+                name="email"
+                type="email"
+                autoComplete="email"
+                required
+                className="appearance-none rounded-md relative block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 focus:outline-none focus:ring-blue-500 focus:border-blue-500 sm:text-sm"
+                placeholder="Email address"
+                value={email}
+                onChange={(e) => setEmail(e.target.value)}
+                disabled={loading}
+              />
+            </div>
+
+            {error && (
+              <div className="rounded-md bg-red-50 p-4">
+                <div className="text-sm text-red-700">{error}</div>
+              </div>
+            )}
+
+            <div>
+              <button
+                type="submit"
+                disabled={loading}
+                className="group relative w-full flex justify-center py-2 px-4 border border-transparent text-sm font-medium rounded-md text-white bg-blue-600 hover:bg-blue-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 disabled:opacity-50 disabled:cursor-not-allowed"
+              >
+                {loading ? 'Sending...' : 'Send reset link'}
+              </button>
+            </div>
+          </form>
+        )}
+
+        <div className="text-center">
+          <Link href="/admin/login" className="text-sm text-blue-600 hover:text-blue-500">
+            Back to sign in
+          </Link>
+        </div>
+      </div>
+    </div>
+  );
+}
@@ -2,20 +2,25 @@ import { useState, useEffect } from 'react';
 import Head from 'next/head';
 import AdminLayout from '../../components/AdminLayout';
 import ProtectedRoute from '../../components/ProtectedRoute';
+import Pagination from '../../components/Pagination';
 import { apiClient } from '../../lib/api';
+import { usePagedList } from '../../lib/usePagedList';
 import React from 'react';
 
+const emptyStats = {
+  total: 0,
+  yes: 0,
+  no: 0,
+  pending: 0,
+  totalAttending: 0
+};
+
 export default function AdminRsvps() {
-  const [rsvps, setRsvps] = useState([]);
-  const [loading, setLoading] = useState(true);
+  const list = usePagedList('/api/rsvps');
+  const { items: rsvps, loading } = list;
+  const stats = list.data.stats || emptyStats;
   const [expandedRsvps, setExpandedRsvps] = useState(new Set());
-  const [stats, setStats] = useState({
-    total: 0,
-    yes: 0,
-    no: 0,
-    pending: 0,
-    totalAttending: 0
-  });
+  const [events, setEvents] = useState([]);
 
   const toggleExpandedRsvp = (id) => {
     const newExpanded = new Set(expandedRsvps);
@@ -28,37 +33,15 @@ export default function AdminRsvps() {
   };
 
   useEffect(() => {
-    fetchRsvps();
+    fetchEvents();
   }, []);
 
-  const fetchRsvps = async () => {
+  const fetchEvents = async () => {
     try {
-      const response = await apiClient.get('/api/rsvps');
-      console.log('RSVP API response:', response.data);
-      
-      // Handle different response structures
-      let rsvpData = [];
-      if (Array.isArray(response.data)) {
-        rsvpData = response.data;
-      } else if (response.data && Array.isArray(response.data.rsvps)) {
-        rsvpData = response.data.rsvps;
-      } else if (response.data && Array.isArray(response.data.data)) {
-        rsvpData = response.data.data;
-      }
-      
-      setRsvps(rsvpData);
-      setStats(response.data.stats || {
-        total: 0,
-        yes: 0,
-        no: 0,
-        pending: 0,
-        totalAttending: 0
-      });
-      setLoading(false);
+      const response = await apiClient.get('/api/events');
+      setEvents(response.data);
     } catch (error) {
-      console.error('Error fetching RSVPs:', error);
-      setRsvps([]);
-      setLoading(false);
+      console.error('Error fetching events:', error);
     }
   };
 
@@ -252,22 +235,16 @@ export default function AdminRsvps() {
                                 <div>
                                   <span className="font-medium text-gray-700">Event Attendance:</span>
                                   <div className="mt-1 space-y-1">
-                                    <div>
-                                      <span className="text-sm font-medium">Dec 24 (Raaga Riti):</span>
-                                      <span className={`ml-2 px-2 py-1 text-xs rounded-full ${
-                                        rsvp.guest.dec24Attendance ? 'bg-green-100 text-green-800' : 'bg-red-100 text-red-800'
-                                      }`}>
-                                        {rsvp.guest.dec24Attendance ? 'Attending' : 'Not Attending'}
-                                      </span>
-                                    </div>
-                                    <div>
-                                      <span className="text-sm font-medium">Dec 25 (Wedding):</span>
-                                      <span className={`ml-2 px-2 py-1 text-xs rounded-full ${
-                                        rsvp.guest.dec25Attendance ? 'bg-green-100 text-green-800' : 'bg-red-100 text-red-800'
-                                      }`}>
-                                        {rsvp.guest.dec25Attendance ? 'Attending' : 'Not Attending'}
-                                      </span>
-                                    </div>
+                                    {events.filter(event => event.type !== 'accommodation').map(event => (
+                                      <div key={event.slug}>
+                                        <span className="text-sm font-medium">{event.name}:</span>
+                                        <span className={`ml-2 px-2 py-1 text-xs rounded-full ${
+                                          rsvp.guest.events?.[event.slug] ? 'bg-green-100 text-green-800' : 'bg-red-100 text-red-800'
+                                        }`}>
+                                          {rsvp.guest.events?.[event.slug] ? 'Attending' : 'Not Attending'}
+                                        </span>
+                                      </div>
+                                    ))}
                                   </div>
                                 </div>
                                 
@@ -275,17 +252,13 @@ export default function AdminRsvps() {
                                 <div>
                                   <span className="font-medium text-gray-700">Accommodation:</span>
                                   <div className="mt-1">
-                                    {(rsvp.guest.accommodationDec23 || rsvp.guest.accommodationDec24 || rsvp.guest.accommodationDec25) ? (
+                                    {events.some(event => event.type === 'accommodation' && rsvp.guest.events?.[event.slug]) ? (
                                       <div className="space-y-1">
-                                        {rsvp.guest.accommodationDec23 && (
-                                          <div className="text-sm text-green-600">✓ Dec 23rd</div>
-                                        )}
-                                        {rsvp.guest.accommodationDec24 && (
-                                          <div className="text-sm text-green-600">✓ Dec 24th</div>
-                                        )}
-                                        {rsvp.guest.accommodationDec25 && (
-                                          <div className="text-sm text-green-600">✓ Dec 25th</div>
-                                        )}
+                                        {events
+                                          .filter(event => event.type === 'accommodation' && rsvp.guest.events?.[event.slug])
+                                          .map(event => (
+                                            <div key={event.slug} className="text-sm text-green-600">✓ {event.name}</div>
+                                          ))}
                                       </div>
                                     ) : (
                                       <span className="text-sm text-gray-500">No accommodation needed</span>
@@ -329,6 +302,7 @@ export default function AdminRsvps() {
               </table>
             </div>
           )}
+          <Pagination list={list} noun="RSVPs" />
         </div>
         </div>
       </AdminLayout>
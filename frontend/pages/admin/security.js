@@ -0,0 +1,382 @@
+import { useState, useEffect, useCallback } from 'react';
+import Head from 'next/head';
+import AdminLayout from '../../components/AdminLayout';
+import ProtectedRoute from '../../components/ProtectedRoute';
+import QRCode from '../../components/QRCode';
+import { api } from '../../lib/api';
+import { useAuth } from '../../lib/auth';
+
+const inputClass =
+  'appearance-none rounded-md block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 focus:outline-none focus:ring-blue-500 focus:border-blue-500 sm:text-sm';
+const primaryButton =
+  'px-4 py-2 text-sm font-medium rounded-md text-white bg-blue-600 hover:bg-blue-700 disabled:opacity-50 disabled:cursor-not-allowed';
+const secondaryButton =
+  'px-4 py-2 text-sm font-medium rounded-md border border-gray-300 text-gray-700 hover:bg-gray-50 disabled:opacity-50 disabled:cursor-not-allowed';
+
+const errorMessage = (err, fallback) => err.response?.data?.error || fallback;
+
+// Secrets are easier to type from the screen in groups of four
+const formatSecret = (secret) => secret.match(/.{1,4}/g).join(' ');
+
+function RecoveryCodes({ codes, onDone }) {
+  const download = () => {
+    const blob = new Blob([codes.join('\n') + '\n'], { type: 'text/plain' });
+    const url = URL.createObjectURL(blob);
+    const link = document.createElement('a');
+    link.href = url;
+    link.download = 'recovery-codes.txt';
+    link.click();
+    URL.revokeObjectURL(url);
+  };
+
+  return (
+    <div className="space-y-4">
+      <div className="rounded-md bg-yellow-50 p-4 text-sm text-yellow-800">
+        Save these recovery codes somewhere safe. Each one signs you in once if you lose your phone,
+        and they won&apos;t be shown again.
+      </div>
+      <ul className="grid grid-cols-2 gap-2 font-mono text-sm text-gray-900">
+        {codes.map((code) => (
+          <li key={code} className="px-3 py-2 bg-gray-50 rounded border">
+            {code}
+          </li>
+        ))}
+      </ul>
+      <div className="flex space-x-3">
+        <button type="button" onClick={download} className={secondaryButton}>
+          Download
+        </button>
+        <button type="button" onClick={onDone} className={primaryButton}>
+          I&apos;ve saved them
+        </button>
+      </div>
+    </div>
+  );
+}
+
+function TwoFactorSection({ onChange }) {
+  const { user, refreshUser } = useAuth();
+  const [status, setStatus] = useState(null);
+  const [setup, setSetup] = useState(null);
+  const [recoveryCodes, setRecoveryCodes] = useState(null);
+  const [code, setCode] = useState('');
+  const [password, setPassword] = useState('');
+  const [action, setAction] = useState(null); // regenerate, disable
+  const [busy, setBusy] = useState(false);
+  const [error, setError] = useState('');
+
+  const loadStatus = useCallback(async () => {
+    try {
+      const response = await api.getTwoFactorStatus();
+      setStatus(response.data);
+    } catch (err) {
+      setError(errorMessage(err, 'Failed to load two-factor status'));
+    }
+  }, []);
+
+  useEffect(() => {
+    loadStatus();
+  }, [loadStatus]);
+
+  const run = async (fn) => {
+    setError('');
+    setBusy(true);
+    try {
+      await fn();
+      setCode('');
+      setPassword('');
+    } catch (err) {
+      setError(errorMessage(err, 'Something went wrong'));
+    }
+    setBusy(false);
+  };
+
+  const startSetup = () => run(async () => {
+    const response = await api.startTwoFactorSetup();
+    setSetup(response.data);
+  });
+
+  const enable = (e) => {
+    e.preventDefault();
+    run(async () => {
+      const response = await api.enableTwoFactor(code);
+      setSetup(null);
+      setRecoveryCodes(response.data.recoveryCodes);
+      await loadStatus();
+      // Clears the setup requirement, if there was one
+      await refreshUser();
+      onChange?.();
+    });
+  };
+
+  const regenerate = (e) => {
+    e.preventDefault();
+    run(async () => {
+      const response = await api.regenerateRecoveryCodes(code);
+      setAction(null);
+      setRecoveryCodes(response.data.recoveryCodes);
+      await loadStatus();
+    });
+  };
+
+  const disable = (e) => {
+    e.preventDefault();
+    run(async () => {
+      await api.disableTwoFactor(password, code);
+      setAction(null);
+      await loadStatus();
+      onChange?.();
+    });
+  };
+
+  const codeInput = (
+    <input
+      type="text"
+      inputMode="numeric"
+      autoComplete="one-time-code"
+      required
+      className={inputClass}
+      placeholder="6-digit code"
+      value={code}
+      onChange={(e) => setCode(e.target.value)}
+      disabled={busy}
+    />
+  );
+
+  let body;
+  if (!status) {
+    body = <div className="text-gray-500">Loading...</div>;
+  } else if (recoveryCodes) {
+    body = <RecoveryCodes codes={recoveryCodes} onDone={() => setRecoveryCodes(null)} />;
+  } else if (setup) {
+    body = (
+      <form onSubmit={enable} className="space-y-4">
+        <p className="text-sm text-gray-600">
+          Scan this QR code with an authenticator app such as Google Authenticator, 1Password or Authy,
+          then enter the code it shows.
+        </p>
+        <div className="flex flex-col sm:flex-row sm:items-center sm:space-x-6 space-y-4 sm:space-y-0">
+          <QRCode value={setup.uri} className="border rounded" />
+          <div className="text-sm text-gray-600">
+            <p>Can&apos;t scan it? Enter this key instead:</p>
+            <p className="mt-1 font-mono text-gray-900 break-all">{formatSecret(setup.secret)}</p>
+          </div>
+        </div>
+        <div className="max-w-xs">{codeInput}</div>
+        <div className="flex space-x-3">
+          <button type="submit" disabled={busy} className={primaryButton}>
+            {busy ? 'Checking...' : 'Turn on'}
+          </button>
+          <button type="button" onClick={() => setSetup(null)} disabled={busy} className={secondaryButton}>
+            Cancel
+          </button>
+        </div>
+      </form>
+    );
+  } else if (!status.enabled) {
+    body = (
+      <div className="space-y-4">
+        <p className="text-sm text-gray-600">
+          Two-factor authentication is off. Turn it on to require a code from your phone as well as
+          your password when you sign in.
+        </p>
+        <button type="button" onClick={startSetup} disabled={busy} className={primaryButton}>
+          Set up two-factor authentication
+        </button>
+      </div>
+    );
+  } else {
+    body = (
+      <div className="space-y-4">
+        <p className="text-sm text-gray-600">
+          Two-factor authentication is on. You have {status.recoveryCodesLeft} unused recovery
+          code{status.recoveryCodesLeft === 1 ? '' : 's'} left.
+        </p>
+
+        {action ? (
+          <form onSubmit={action === 'disable' ? disable : regenerate} className="space-y-3 max-w-xs">
+            {action === 'disable' && (
+              <input
+                type="password"
+                autoComplete="current-password"
+                required
+                className={inputClass}
+                placeholder="Password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                disabled={busy}
+              />
+            )}
+            {codeInput}
+            <div className="flex space-x-3">
+              <button type="submit" disabled={busy} className={primaryButton}>
+                {action === 'disable' ? 'Turn off' : 'Get new codes'}
+              </button>
+              <button type="button" onClick={() => setAction(null)} disabled={busy} className={secondaryButton}>
+                Cancel
+              </button>
+            </div>
+          </form>
+        ) : (
+          <div className="flex flex-wrap gap-3">
+            <button type="button" onClick={() => setAction('regenerate')} className={secondaryButton}>
+              New recovery codes
+            </button>
+            {status.required ? (
+              <span className="self-center text-sm text-gray-500">
+                It can&apos;t be turned off while it&apos;s required for every admin.
+              </span>
+            ) : (
+              <button type="button" onClick={() => setAction('disable')} className={secondaryButton}>
+                Turn off
+              </button>
+            )}
+          </div>
+        )}
+      </div>
+    );
+  }
+
+  return (
+    <div className="bg-white rounded-lg shadow">
+      <div className="p-4 border-b">
+        <h2 className="text-lg font-semibold">Two-factor authentication</h2>
+      </div>
+      <div className="p-6 space-y-4">
+        {user?.twoFactorSetupRequired && !recoveryCodes && (
+          <div className="rounded-md bg-yellow-50 p-4 text-sm text-yellow-800">
+            Two-factor authentication is required for every admin. Set it up to continue.
+          </div>
+        )}
+        {error && (
+          <div className="rounded-md bg-red-50 p-4 text-sm text-red-700">{error}</div>
+        )}
+        {body}
+      </div>
+    </div>
+  );
+}
+
+function TeamSection({ refreshKey }) {
+  const { user } = useAuth();
+  const [settings, setSettings] = useState(null);
+  const [users, setUsers] = useState([]);
+  const [busy, setBusy] = useState(false);
+  const [error, setError] = useState('');
+
+  const load = useCallback(async () => {
+    try {
+      const [settingsResponse, usersResponse] = await Promise.all([
+        api.getSecuritySettings(),
+        api.getUsers(),
+      ]);
+      setSettings(settingsResponse.data);
+      setUsers(usersResponse.data);
+    } catch (err) {
+      setError(errorMessage(err, 'Failed to load security settings'));
+    }
+  }, []);
+
+  useEffect(() => {
+    load();
+  }, [load, refreshKey]);
+
+  const toggleRequired = async () => {
+    setError('');
+    setBusy(true);
+    try {
+      const response = await api.updateSecuritySettings({ requireTwoFactor: !settings.requireTwoFactor });
+      setSettings(response.data);
+    } catch (err) {
+      setError(errorMessage(err, 'Failed to update security settings'));
+    }
+    setBusy(false);
+  };
+
+  const resetUser = async (target) => {
+    if (!confirm(`Turn off two-factor authentication for ${target.email}? They will be signed out everywhere.`)) {
+      return;
+    }
+    setError('');
+    try {
+      await api.resetUserTwoFactor(target.id);
+      await load();
+    } catch (err) {
+      setError(errorMessage(err, 'Failed to reset two-factor authentication'));
+    }
+  };
+
+  return (
+    <div className="bg-white rounded-lg shadow">
+      <div className="p-4 border-b">
+        <h2 className="text-lg font-semibold">Team</h2>
+      </div>
+      <div className="p-6 space-y-6">
+        {error && (
+          <div className="rounded-md bg-red-50 p-4 text-sm text-red-700">{error}</div>
+        )}
+
+        {settings && (
+          <div className="flex items-start justify-between space-x-4">
+            <div>
+              <p className="text-sm font-medium text-gray-900">Require two-factor authentication</p>
+              <p className="text-sm text-gray-500">
+                Admins without it must set it up before they can do anything else. You need it on
+                your own account first.
+              </p>
+            </div>
+            <button type="button" onClick={toggleRequired} disabled={busy} className={secondaryButton}>
+              {settings.requireTwoFactor ? 'Stop requiring' : 'Require'}
+            </button>
+          </div>
+        )}
+
+        <div className="divide-y divide-gray-200 border rounded-md">
+          {users.map((member) => (
+            <div key={member.id} className="flex items-center justify-between px-4 py-3">
+              <div>
+                <p className="text-sm font-medium text-gray-900">{member.email}</p>
+                <p className="text-xs text-gray-500">
+                  {member.role} &middot; two-factor {member.twoFactorEnabled ? 'on' : 'off'}
+                </p>
+              </div>
+              {member.twoFactorEnabled && member.id !== user?.id && (
+                <button type="button" onClick={() => resetUser(member)} className={secondaryButton}>
+                  Reset two-factor
+                </button>
+              )}
+            </div>
+          ))}
+        </div>
+      </div>
+    </div>
+  );
+}
+
+export default function AdminSecurity() {
+  const { user } = useAuth();
+  const [refreshKey, setRefreshKey] = useState(0);
+  const canManageUsers = user?.permissions?.includes('users:manage') && !user?.twoFactorSetupRequired;
+
+  return (
+    <ProtectedRoute>
+      <AdminLayout>
+        <Head>
+          <title>Security - Admin</title>
+        </Head>
+
+        <div className="p-6 space-y-6">
+          <div>
+            <h1 className="text-3xl font-bold text-gray-900">Security</h1>
+            <p className="text-gray-600 mt-2">
+              Protect your account with a code from your phone
+            </p>
+          </div>
+
+          <TwoFactorSection onChange={() => setRefreshKey((key) => key + 1)} />
+          {canManageUsers && <TeamSection refreshKey={refreshKey} />}
+        </div>
+      </AdminLayout>
+    </ProtectedRoute>
+  );
+}
@@ -8,6 +8,7 @@ import { Label } from '@/components/ui/label';
 import { Textarea } from '@/components/ui/textarea';
 import { Checkbox } from '@/components/ui/checkbox';
 import { ThemeToggle } from '@/components/ui/theme-toggle';
+import QuestionField from '@/components/QuestionField';
 import { MapPin, Calendar, Users, Camera, Plane, Check, Mail, Phone, User } from 'lucide-react';
 import gsap from 'gsap';
 import { ScrollTrigger } from 'gsap/dist/ScrollTrigger';
@@ -37,6 +38,7 @@ export default function Home() {
     lastName: '',
     email: '',
     phone: '',
+    smsOptIn: false,
   });
   const [submitted, setSubmitted] = useState(false);
   const [submitting, setSubmitting] = useState(false);
@@ -60,8 +62,11 @@ export default function Home() {
       dec24: false,
       dec25: false,
     },
+    answers: {},
     concerns: '',
   });
+  const [questions, setQuestions] = useState([]);
+  const memberQuestions = questions.filter((question) => question.perMember);
 
   const handleSubmit = async (e) => {
     e.preventDefault();
@@ -97,9 +102,22 @@ export default function Home() {
     }
 
     try {
+      const { dec24, dec25, accommodation, ...questionnaire } = questionnaireData;
       const submissionData = {
         ...formData,
-        ...(showQuestionnaire ? questionnaireData : {}),
+        ...(showQuestionnaire
+          ? {
+              ...questionnaire,
+              // Event slugs as configured by the admin
+              events: {
+                dec24: dec24.attendance,
+                dec25: dec25.attendance,
+                'accommodation-dec23': accommodation.dec23,
+                'accommodation-dec24': accommodation.dec24,
+                'accommodation-dec25': accommodation.dec25,
+              },
+            }
+          : {}),
       };
       console.log('Submitting registration data:', submissionData);
       console.log('Questionnaire shown:', showQuestionnaire);
@@ -121,6 +139,13 @@ export default function Home() {
     }));
   };
 
+  useEffect(() => {
+    apiClient
+      .get('/api/questions', { params: { stage: 'registration' } })
+      .then((response) => setQuestions(response.data))
+      .catch((err) => console.error('Failed to load questions:', err));
+  }, []);
+
   // Use useEffect with debounce to handle questionnaire visibility
   useEffect(() => {
     const timeoutId = setTimeout(() => {
@@ -195,6 +220,24 @@ export default function Home() {
     });
   };
 
+  const handleAnswerChange = (key, value) => {
+    setQuestionnaireData((prev) => ({
+      ...prev,
+      answers: { ...prev.answers, [key]: value },
+    }));
+  };
+
+  const handleMemberAnswerChange = (index, key, value) => {
+    setQuestionnaireData((prev) => {
+      const newMembers = [...prev.partyMembers];
+      newMembers[index] = {
+        ...newMembers[index],
+        answers: { ...(newMembers[index].answers || {}), [key]: value },
+      };
+      return { ...prev, partyMembers: newMembers };
+    });
+  };
+
   const handleDateChange = (date, field, value) => {
     setQuestionnaireData((prev) => ({
       ...prev,
@@ -911,6 +954,15 @@ export default function Home() {
                             placeholder="+1 (555) 123-4567"
                             className="h-10 sm:h-12 text-sm sm:text-base bg-background/50 border-input"
                           />
+                          {formData.phone.trim() && (
+                            <label className="flex items-center space-x-2 cursor-pointer text-xs sm:text-sm text-muted-foreground">
+                              <Checkbox
+                                checked={formData.smsOptIn}
+                                onCheckedChange={(checked) => handleInputChange('smsOptIn', checked === true)}
+                              />
+                              <span>Send me my RSVP link and reminders by text message</span>
+                            </label>
+                          )}
                         </div>
 
                         {showQuestionnaire && (
@@ -1087,6 +1139,18 @@ export default function Home() {
                                           </label>
                                         </div>
                                       </div>
+
+                                      {memberQuestions.map((question) => (
+                                        <QuestionField
+                                          key={question.key}
+                                          question={question}
+                                          name={`member-${index}-${question.key}`}
+                                          value={member.answers?.[question.key]}
+                                          onChange={(value) =>
+                                            handleMemberAnswerChange(index, question.key, value)
+                                          }
+                                        />
+                                      ))}
                                     </div>
                                   ))}
                                 </div>
@@ -1243,6 +1307,20 @@ export default function Home() {
                               </div>
                             </div>
 
+                            {/* Custom questions */}
+                            {questions.length > 0 && (
+                              <div className="space-y-4">
+                                {questions.map((question) => (
+                                  <QuestionField
+                                    key={question.key}
+                                    question={question}
+                                    value={questionnaireData.answers[question.key]}
+                                    onChange={(value) => handleAnswerChange(question.key, value)}
+                                  />
+                                ))}
+                              </div>
+                            )}
+
                             {/* Concerns */}
                             <div className="space-y-4">
                               <Label className="text-foreground font-medium text-lg">
@@ -14,6 +14,32 @@ resource "aws_ecs_cluster" "main" {
   tags = local.common_tags
 }
 
+# Signing keys for admin access tokens, as AUTH_SIGNING_KEYS. To rotate,
+# add a new id:secret pair to the secret, point AUTH_SIGNING_KEY_ID at it,
+# and drop the old pair once the tokens it signed have expired.
+resource "random_password" "auth_signing_key" {
+  length  = 64
+  special = false
+}
+
+resource "aws_secretsmanager_secret" "auth_signing_keys" {
+  name                    = "${local.name_prefix}-auth-signing-keys"
+  description             = "Access token signing keys for wedding app"
+  recovery_window_in_days = 0 # For immediate deletion in dev/test
+
+  tags = local.common_tags
+}
+
+resource "aws_secretsmanager_secret_version" "auth_signing_keys" {
+  secret_id     = aws_secretsmanager_secret.auth_signing_keys.id
+  secret_string = "k1:${random_password.auth_signing_key.result}"
+
+  # Rotated by hand after the first apply
+  lifecycle {
+    ignore_changes = [secret_string]
+  }
+}
+
 # CloudWatch Log Group
 resource "aws_cloudwatch_log_group" "ecs" {
   name              = "/ecs/${local.name_prefix}"
@@ -69,6 +95,10 @@ resource "aws_ecs_task_definition" "app" {
           name  = "REDIS_ADDR"
           value = "${aws_elasticache_cluster.redis.cache_nodes[0].address}:6379"
         },
+        {
+          name  = "STORAGE_BACKEND"
+          value = "s3"
+        },
         {
           name  = "S3_BUCKET"
           value = aws_s3_bucket.photos.id
@@ -83,6 +113,10 @@ resource "aws_ecs_task_definition" "app" {
         {
           name      = "DB_PASSWORD"
           valueFrom = aws_secretsmanager_secret.db_password.arn
+        },
+        {
+          name      = "AUTH_SIGNING_KEYS"
+          valueFrom = aws_secretsmanager_secret.auth_signing_keys.arn
         }
       ]
 
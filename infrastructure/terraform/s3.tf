@@ -76,6 +76,11 @@ resource "aws_s3_bucket_lifecycle_configuration" "photos" {
     noncurrent_version_expiration {
       noncurrent_days = 30
     }
+
+    # Clear out parts of video uploads that were never completed
+    abort_incomplete_multipart_upload {
+      days_after_initiation = 2
+    }
   }
 }
 